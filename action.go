@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 对分集执行的操作类型
+const (
+	ACTION_PAUSE    = "pause"    // 暂停分集
+	ACTION_PRIORITY = "priority" // 分集设为低带宽优先级，合集设为高带宽优先级
+	ACTION_LINK     = "link"     // 分集数据替换为指向合集文件的链接后继续做种
+	ACTION_DESELECT = "deselect" // 取消选择分集的文件，分集保留在客户端中
+)
+
+// 操作的中文名称
+func actionName(action string) string {
+	switch action {
+	case ACTION_PRIORITY:
+		return "调整带宽优先级（合集: 高, 分集: 低）"
+	case ACTION_LINK:
+		return "原地升级（分集数据替换为指向合集文件的链接）"
+	case ACTION_DESELECT:
+		return "取消选择分集文件（单文件种子改为暂停）"
+	case ACTION_OLD_PACK_PAUSE:
+		return "暂停旧版合集"
+	case ACTION_OLD_PACK_DELETE:
+		return "删除旧版合集"
+	case ACTION_DELETE:
+		return "删除分集及数据"
+	case ACTION_LABEL:
+		return fmt.Sprintf("为分集添加标签 %s", REVIEW_LABEL)
+	case ACTION_IDLE_LIMIT:
+		return "设置分集的空闲时间限制（空闲后由 Transmission 停止）"
+	case ACTION_BANDWIDTH_GROUP:
+		return "把分集移入限速带宽组（继续低速做种）"
+	case MANIFEST_KEPT:
+		return "手动保留（不处理）"
+	default:
+		return "暂停分集"
+	}
+}
+
+// 描述合集在当前操作下的处理方式
+func describeCollectionAction(action string, collection *transmissionrpc.Torrent) string {
+	if action == ACTION_PRIORITY {
+		return "不会被暂停, " + describePriorityChange(collection, PRIORITY_HIGH)
+	}
+	return "不会被暂停"
+}
+
+// 描述分集在当前操作下的处理方式
+func describeEpisodesAction(action string) string {
+	switch action {
+	case ACTION_PRIORITY:
+		return "将设为低带宽优先级"
+	case ACTION_LINK:
+		return "将替换为指向合集的链接"
+	case ACTION_DESELECT:
+		return "将取消选择文件，单文件种子改为暂停"
+	case ACTION_OLD_PACK_DELETE, ACTION_DELETE:
+		return "将删除种子及数据"
+	case ACTION_LABEL:
+		return fmt.Sprintf("将添加标签 %s", REVIEW_LABEL)
+	case ACTION_IDLE_LIMIT:
+		return "将设置空闲时间限制，空闲后由 Transmission 停止（不会立即停止）"
+	case ACTION_BANDWIDTH_GROUP:
+		return "将移入限速带宽组，继续低速做种（不会停止）"
+	}
+	return "将被暂停"
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// Transmission 的"最近活动"只包含最近60秒内有变化的种子和被删除的种子
+const RECENTLY_ACTIVE_WINDOW = 60 * time.Second
+
+// 守护模式等待下一轮时查询最近活动的间隔，需小于 RECENTLY_ACTIVE_WINDOW 才不会漏掉变化
+const RECENTLY_ACTIVE_POLL = 30 * time.Second
+
+// 判断是否有变化时比较的字段
+var recentlyActiveFields = []string{"id", "hashString", "name", "status", "error"}
+
+// 种子中与分析结果有关的状态：新增、删除、改名（元数据完成）、运行状态和错误变化都需要重新分析
+type torrentState struct {
+	Hash   string
+	Name   string
+	Status transmissionrpc.TorrentStatus
+	Error  int64
+}
+
+func newTorrentState(torrent *transmissionrpc.Torrent) torrentState {
+	var state torrentState
+	if torrent.HashString != nil {
+		state.Hash = *torrent.HashString
+	}
+	if torrent.Name != nil {
+		state.Name = *torrent.Name
+	}
+	if torrent.Status != nil {
+		state.Status = *torrent.Status
+	}
+	if torrent.Error != nil {
+		state.Error = *torrent.Error
+	}
+	return state
+}
+
+// 守护模式中记录上一次完整扫描后种子是否有变化，没有变化时跳过本轮分析；
+// 未开启时为nil，nil记录器总是要求完整扫描
+type ChangeWatcher struct {
+	states   map[int64]torrentState // 上一次完整扫描时的种子状态，为nil时需要完整扫描
+	reason   string                 // 需要完整扫描的原因，为空时没有变化
+	lastPoll time.Time
+}
+
+// 创建变化记录器，enabled 为false时返回nil
+func newChangeWatcher(enabled bool) *ChangeWatcher {
+	if !enabled {
+		return nil
+	}
+	return &ChangeWatcher{}
+}
+
+// 完整扫描和执行操作后记录当前的种子状态作为比较的基准。只获取少量字段，
+// 包含本轮操作造成的变化，不会因此在下一轮重新扫描；获取失败时下一轮完整扫描
+func (w *ChangeWatcher) reset(client *transmissionrpc.Client) {
+	if w == nil {
+		return
+	}
+	started := time.Now()
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.List)
+	torrents, err := client.TorrentGet(ctx, recentlyActiveFields, nil)
+	cancel()
+	w.reason = ""
+	if err != nil {
+		w.states = nil
+		return
+	}
+	w.states = make(map[int64]torrentState, len(torrents))
+	for i := range torrents {
+		if torrents[i].ID != nil {
+			w.states[*torrents[i].ID] = newTorrentState(&torrents[i])
+		}
+	}
+	w.lastPoll = started
+}
+
+// 标记下一轮需要完整扫描，保留第一个原因
+func (w *ChangeWatcher) markDirty(reason string) {
+	if w == nil || w.reason != "" {
+		return
+	}
+	w.reason = reason
+}
+
+// 是否需要完整扫描，返回原因
+func (w *ChangeWatcher) changed() (bool, string) {
+	if w == nil {
+		return true, ""
+	}
+	if w.states == nil {
+		return true, "没有上一轮的种子状态"
+	}
+	// 距上次查询超过最近活动的范围时，期间的变化可能没有被看到
+	if w.reason == "" && time.Since(w.lastPoll) > RECENTLY_ACTIVE_WINDOW {
+		w.reason = "距上次查询最近活动的种子超过 " + RECENTLY_ACTIVE_WINDOW.String()
+	}
+	return w.reason != "", w.reason
+}
+
+// 查询最近活动的种子，与上一次完整扫描时的状态比较；已确定需要完整扫描时不再查询
+func (w *ChangeWatcher) poll(client *transmissionrpc.Client) {
+	if w == nil || w.states == nil || w.reason != "" {
+		return
+	}
+	if time.Since(w.lastPoll) > RECENTLY_ACTIVE_WINDOW {
+		return
+	}
+	started := time.Now()
+	torrents, removed, err := recentlyActive(client)
+	if err != nil {
+		w.markDirty(fmt.Sprintf("查询最近活动的种子失败: %v", err))
+		return
+	}
+	w.lastPoll = started
+	for _, id := range removed {
+		if _, ok := w.states[id]; ok {
+			w.markDirty(fmt.Sprintf("种子 ID: %d 已被删除", id))
+			return
+		}
+	}
+	for i := range torrents {
+		torrent := &torrents[i]
+		if torrent.ID == nil {
+			continue
+		}
+		previous, ok := w.states[*torrent.ID]
+		if !ok {
+			w.markDirty(fmt.Sprintf("新增种子 ID: %d", *torrent.ID))
+			return
+		}
+		if newTorrentState(torrent) != previous {
+			w.markDirty(fmt.Sprintf("种子 ID: %d 的状态有变化", *torrent.ID))
+			return
+		}
+	}
+}
+
+// torrent-get 的 ids 为 "recently-active" 时的返回内容
+type recentlyActiveArguments struct {
+	Torrents []transmissionrpc.Torrent `json:"torrents"`
+	Removed  []int64                   `json:"removed"`
+}
+
+// 查询最近活动的种子和最近被删除的种子ID。transmissionrpc 没有提供这个查询
+func recentlyActive(client *transmissionrpc.Client) ([]transmissionrpc.Torrent, []int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+	defer cancel()
+	var arguments recentlyActiveArguments
+	err := rawRPC(ctx, client, "torrent-get", map[string]interface{}{
+		"ids":    "recently-active",
+		"fields": recentlyActiveFields,
+	}, &arguments)
+	if err != nil {
+		return nil, nil, err
+	}
+	return arguments.Torrents, arguments.Removed, nil
+}
+
+// RPC请求的返回内容，arguments 由调用方解析
+type rawRPCAnswer struct {
+	Arguments json.RawMessage `json:"arguments"`
+	Result    string          `json:"result"`
+}
+
+// 直接发送 transmissionrpc 没有提供的RPC请求，result 为nil时不解析返回的 arguments。
+// 使用客户端对应的RPC连接的地址、认证信息和HTTP客户端，不经过本机中转
+func rawRPC(ctx context.Context, client *transmissionrpc.Client, method string, arguments interface{}, result interface{}) error {
+	conn, err := rpcConnectionOf(client)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"method":    method,
+		"arguments": arguments,
+	})
+	if err != nil {
+		return err
+	}
+
+	// 会话ID由 sessionTransport 附加和重新协商
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, conn.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.SetBasicAuth(conn.user, conn.password)
+	response, err := conn.httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d", response.StatusCode)
+	}
+	var answer rawRPCAnswer
+	if err := json.NewDecoder(response.Body).Decode(&answer); err != nil {
+		return err
+	}
+	if answer.Result != "success" {
+		return fmt.Errorf("%s", answer.Result)
+	}
+	if result == nil || len(answer.Arguments) == 0 {
+		return nil
+	}
+	return json.Unmarshal(answer.Arguments, result)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+// 按 scan.json 重放守护模式的几轮：没有变化时跳过分析，有变化时完整扫描
+func TestDaemonSkipUnchanged(t *testing.T) {
+	client, opts := connectFixture(t, "scan.json")
+	capabilities := newServerCapabilities(17)
+	watcher := newChangeWatcher(opts.SkipUnchanged)
+	if watcher == nil {
+		t.Fatal("默认应开启 --skip-unchanged")
+	}
+	// 本轮分析之前获取的文件列表，完整扫描时会被清空
+	sentinel := func() bool {
+		_, ok := cachedTorrentFiles(999)
+		return ok
+	}
+	cycle := func(n int) (bool, string) {
+		var out bytes.Buffer
+		scanned := runDaemonStep(context.Background(), &out, client, capabilities, opts, n, nil, watcher)
+		return scanned, out.String()
+	}
+
+	if scanned, _ := cycle(1); !scanned {
+		t.Fatal("第一轮没有上一轮的种子状态，应完整扫描")
+	}
+
+	watcher.poll(client)
+	cacheTorrentFiles(999, torrentFiles(testFile{"sentinel.mkv", 1}))
+	scanned, out := cycle(2)
+	if scanned || !strings.Contains(out, "无变化，跳过本轮分析") {
+		t.Errorf("种子没有变化时应跳过分析，输出: %q", out)
+	}
+	if !sentinel() {
+		t.Error("跳过分析时不应清空文件列表缓存")
+	}
+
+	setReplayTorrent(t, 8, "status", 0)
+	watcher.poll(client)
+	scanned, out = cycle(3)
+	if !scanned || !strings.Contains(out, "需要重新分析: 种子 ID: 8 的状态有变化") {
+		t.Errorf("种子状态变化后应完整扫描，输出: %q", out)
+	}
+	if sentinel() {
+		t.Error("完整扫描应先清空文件列表缓存（resetTorrentFilesCache）")
+	}
+
+	watcher.poll(client)
+	if scanned, out := cycle(4); scanned {
+		t.Errorf("完整扫描后记录了新的状态，下一轮应跳过分析，输出: %q", out)
+	}
+}
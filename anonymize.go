@@ -0,0 +1,172 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// 打乱后单词的最短长度，避免较短的不同单词打乱后相同
+const ANON_MIN_WORD_LENGTH = 6
+
+// 打乱后的tracker主机名后缀
+const ANON_TRACKER_SUFFIX = ".tracker.invalid"
+
+// 不影响隐私、但分组和判断依赖的单词，匿名化时保留（不区分大小写）：
+// 季、合集、附加内容、版本和来源标识，编码和分辨率中的字母
+var anonKeptWords = map[string]bool{
+	"season": true, "seasons": true, "complete": true, "series": true, "specials": true, "special": true,
+	"extra": true, "extras": true, "bonus": true, "feature": true, "features": true, "featurette": true, "featurettes": true,
+	"behind": true, "the": true, "scenes": true, "sample": true, "ep": true,
+	"extended": true, "dual": true, "audio": true, "hybrid": true, "remux": true, "repack": true, "proper": true,
+	"web": true, "dl": true, "webrip": true, "bluray": true, "hdtv": true, "hdr": true, "sdr": true,
+	"avc": true, "hevc": true, "av": true, "uhd": true,
+	"全集": true, "特典": true, "花絮": true,
+}
+
+// 诊断包的匿名化：名称按单词打乱，同一个单词总是得到相同的结果，剧集标识、数字、单个字母、
+// 扩展名和 anonKeptWords 中的单词保留，分隔符和路径结构不变；hash和tracker主机名替换为加盐的hash。
+// 盐每次随机生成且只保存在内存中，诊断包中不包含盐和对应关系，无法还原
+type Anonymizer struct {
+	salt  []byte
+	mu    sync.Mutex
+	words map[string]string // 小写单词 -> 打乱后的单词，只在内存中
+}
+
+// 创建使用随机盐的匿名化器
+func newAnonymizer() *Anonymizer {
+	salt := make([]byte, 32)
+	if _, err := rand.Read(salt); err != nil {
+		panic(err)
+	}
+	return &Anonymizer{salt: salt, words: make(map[string]string)}
+}
+
+// 加盐的hash
+func (a *Anonymizer) digest(kind, value string) []byte {
+	mac := hmac.New(sha256.New, a.salt)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+// 单词是否保留原样
+func anonKeepsWord(word string) bool {
+	lower := strings.ToLower(word)
+	if len([]rune(word)) == 1 || anonKeptWords[lower] {
+		return true
+	}
+	extension := "." + lower
+	return videoExtensions[extension] || audioExtensions[extension] || subtitleExtensions[extension]
+}
+
+// 打乱一个单词：不区分大小写地映射为固定的小写字母串，再按原单词恢复ASCII字母的大小写
+func (a *Anonymizer) word(word string) string {
+	if anonKeepsWord(word) {
+		return word
+	}
+	lower := strings.ToLower(word)
+	a.mu.Lock()
+	scrambled, ok := a.words[lower]
+	if !ok {
+		runes := len([]rune(word))
+		length := max(runes, ANON_MIN_WORD_LENGTH)
+		var b strings.Builder
+		for counter := 0; b.Len() < length; counter++ {
+			for _, c := range a.digest("word", lower+string(rune('0'+counter))) {
+				if b.Len() == length {
+					break
+				}
+				b.WriteByte('a' + c%26)
+			}
+		}
+		scrambled = b.String()
+		a.words[lower] = scrambled
+	}
+	a.mu.Unlock()
+
+	result := []byte(scrambled)
+	for i, r := range []rune(word) {
+		if i < len(result) && r < unicode.MaxASCII && unicode.IsUpper(r) {
+			result[i] = byte(unicode.ToUpper(rune(result[i])))
+		}
+	}
+	return string(result)
+}
+
+// 匿名化名称或路径：连续的字母视为一个单词，数字、分隔符和其他字符不变，
+// 因此 S01E03、1080p、x264、.mkv 和目录结构保持原样
+func (a *Anonymizer) name(name string) string {
+	var b strings.Builder
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			b.WriteString(a.word(string(word)))
+			word = word[:0]
+		}
+	}
+	for _, r := range name {
+		if unicode.IsLetter(r) {
+			word = append(word, r)
+			continue
+		}
+		flush()
+		b.WriteRune(r)
+	}
+	flush()
+	return b.String()
+}
+
+// 把info hash替换为加盐的hash，长度和格式不变
+func (a *Anonymizer) hash(hash string) string {
+	if hash == "" {
+		return ""
+	}
+	return hex.EncodeToString(a.digest("hash", strings.ToLower(hash)))[:40]
+}
+
+// 把主机名替换为加盐的hash，同一主机总是得到相同的结果；带端口时保留端口
+func (a *Anonymizer) host(host string) string {
+	if host == "" {
+		return ""
+	}
+	hostname, port, err := net.SplitHostPort(host)
+	if err != nil {
+		hostname, port = host, ""
+	}
+	anonymized := hex.EncodeToString(a.digest("host", strings.ToLower(hostname)))[:12] + ANON_TRACKER_SUFFIX
+	if port != "" {
+		return net.JoinHostPort(anonymized, port)
+	}
+	return anonymized
+}
+
+// 把announce等地址替换为只含匿名主机名的地址，路径和参数中可能有passkey，全部去掉
+func (a *Anonymizer) url(address string) string {
+	parsed, err := url.Parse(address)
+	if err != nil || parsed.Hostname() == "" {
+		return ""
+	}
+	return parsed.Scheme + "://" + a.host(parsed.Host) + "/announce"
+}
+
+// tracker错误信息只保留匹配的已失效种子规则，其他内容替换为固定的说明，不保留原文
+func anonMessage(message string, patterns []string) string {
+	if message == "" {
+		return ""
+	}
+	lower := strings.ToLower(message)
+	for _, pattern := range patterns {
+		if pattern != "" && strings.Contains(lower, strings.ToLower(pattern)) {
+			return pattern
+		}
+	}
+	return "tracker error"
+}
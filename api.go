@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 接口返回的最近操作历史条数
+const API_HISTORY_LIMIT = 500
+
+// 守护模式退出时等待接口请求结束的时间
+const API_SHUTDOWN_TIMEOUT = 5 * time.Second
+
+// 最近一轮完成的扫描结果，接口只读取该快照，扫描进行中不会返回不完整的数据
+type APISnapshot struct {
+	Cycle   int             `json:"cycle"`
+	Time    time.Time       `json:"time"`
+	Summary CycleSummary    `json:"summary"`
+	Groups  []PlanGroup     `json:"groups"`
+	History []HistoryRecord `json:"history"`
+}
+
+// 守护模式的只读HTTP接口
+type APIServer struct {
+	mu       sync.RWMutex
+	snapshot *APISnapshot
+	token    string
+	scans    chan struct{} // 立即扫描的请求，最多排队一个
+	server   *http.Server
+}
+
+// 未指定 --api-listen 时返回 nil
+func newAPIServer(opts Options) *APIServer {
+	if opts.APIListen == "" {
+		return nil
+	}
+	s := &APIServer{token: opts.APIToken, scans: make(chan struct{}, 1)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/groups", s.handleGroups)
+	mux.HandleFunc("GET /api/groups/{hash}", s.handleGroup)
+	mux.HandleFunc("GET /api/stats", s.handleStats)
+	mux.HandleFunc("GET /api/history", s.handleHistory)
+	mux.HandleFunc("POST /api/scan", s.handleScan)
+	s.server = &http.Server{Addr: opts.APIListen, Handler: s.authorize(mux)}
+	return s
+}
+
+// 开始监听，ctx 结束时关闭接口
+func (s *APIServer) start(ctx context.Context) {
+	if s == nil {
+		return
+	}
+	listener, err := net.Listen("tcp", s.server.Addr)
+	if err != nil {
+		log.Fatalf("接口监听 %s 失败: %v", s.server.Addr, err)
+	}
+	fmt.Printf("接口已启动: http://%s/api/\n", listener.Addr())
+	go func() {
+		if err := s.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.Printf("接口服务出错: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), API_SHUTDOWN_TIMEOUT)
+		defer cancel()
+		if err := s.server.Shutdown(shutdownCtx); err != nil {
+			log.Printf("关闭接口失败: %v", err)
+		}
+	}()
+}
+
+// 立即扫描的请求，未启用接口时返回 nil（永远不会收到）
+func (s *APIServer) scanRequests() <-chan struct{} {
+	if s == nil {
+		return nil
+	}
+	return s.scans
+}
+
+// 一轮扫描完成后更新快照
+func (s *APIServer) update(summary CycleSummary, plan Plan) {
+	if s == nil {
+		return
+	}
+	records, err := loadHistory(historyPath())
+	if err != nil {
+		log.Printf("读取操作历史失败，接口不返回历史: %v", err)
+	}
+	if len(records) > API_HISTORY_LIMIT {
+		records = records[len(records)-API_HISTORY_LIMIT:]
+	}
+	snapshot := &APISnapshot{
+		Cycle:   summary.Cycle,
+		Time:    summary.Time,
+		Summary: summary,
+		Groups:  plan.Groups,
+		History: records,
+	}
+	s.mu.Lock()
+	s.snapshot = snapshot
+	s.mu.Unlock()
+}
+
+// 当前快照，第一轮扫描完成前返回 nil
+func (s *APIServer) current() *APISnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snapshot
+}
+
+// 指定 --api-token 时要求请求带有 Authorization: Bearer <token>
+func (s *APIServer) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeAPIError(w, http.StatusUnauthorized, "未授权")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// 读取快照，尚无完成的扫描时返回 503
+func (s *APIServer) requireSnapshot(w http.ResponseWriter) *APISnapshot {
+	snapshot := s.current()
+	if snapshot == nil {
+		writeAPIError(w, http.StatusServiceUnavailable, "第一轮扫描尚未完成")
+	}
+	return snapshot
+}
+
+func (s *APIServer) handleGroups(w http.ResponseWriter, r *http.Request) {
+	snapshot := s.requireSnapshot(w)
+	if snapshot == nil {
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, map[string]interface{}{
+		"cycle":  snapshot.Cycle,
+		"time":   snapshot.Time,
+		"groups": snapshot.Groups,
+	})
+}
+
+// 按合集或分集的hash查找组
+func (s *APIServer) handleGroup(w http.ResponseWriter, r *http.Request) {
+	snapshot := s.requireSnapshot(w)
+	if snapshot == nil {
+		return
+	}
+	hash := r.PathValue("hash")
+	for _, group := range snapshot.Groups {
+		if strings.EqualFold(group.Collection.Hash, hash) {
+			writeAPIJSON(w, http.StatusOK, group)
+			return
+		}
+		for _, episode := range group.Episodes {
+			if strings.EqualFold(episode.Hash, hash) {
+				writeAPIJSON(w, http.StatusOK, group)
+				return
+			}
+		}
+	}
+	writeAPIError(w, http.StatusNotFound, fmt.Sprintf("未找到包含 %s 的组", hash))
+}
+
+func (s *APIServer) handleStats(w http.ResponseWriter, r *http.Request) {
+	snapshot := s.requireSnapshot(w)
+	if snapshot == nil {
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, snapshot.Summary)
+}
+
+func (s *APIServer) handleHistory(w http.ResponseWriter, r *http.Request) {
+	snapshot := s.requireSnapshot(w)
+	if snapshot == nil {
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, map[string]interface{}{
+		"cycle":   snapshot.Cycle,
+		"history": snapshot.History,
+	})
+}
+
+// 请求立即扫描，已有排队的请求时合并
+func (s *APIServer) handleScan(w http.ResponseWriter, r *http.Request) {
+	select {
+	case s.scans <- struct{}{}:
+	default:
+	}
+	writeAPIJSON(w, http.StatusAccepted, map[string]string{"status": "已安排立即扫描"})
+}
+
+func writeAPIJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		log.Printf("写入接口响应失败: %v", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, map[string]string{"error": message})
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 执行计划前按hash查询到的种子状态分类
+type ApplyClassification struct {
+	Pending Plan          // 仍需执行的组和分集
+	Done    []PlanTorrent // 已经处于目标状态的分集（如已暂停），不再计入成功数量
+	Missing []PlanTorrent // 已不存在的分集，或合集已不存在的组中的分集
+}
+
+// 按hash查询计划中全部合集和分集的当前状态
+func lookupPlanTorrents(client *transmissionrpc.Client, plan Plan) ([]transmissionrpc.Torrent, error) {
+	var hashes []string
+	for _, group := range plan.Groups {
+		hashes = append(hashes, group.Collection.Hash)
+		for _, episode := range group.Episodes {
+			hashes = append(hashes, episode.Hash)
+		}
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+	defer cancel()
+	return client.TorrentGetHashes(ctx, baseTorrentFields, hashes)
+}
+
+// 分集是否已经处于操作的目标状态，重复执行计划时不再处理
+func episodeAlreadyApplied(torrent *transmissionrpc.Torrent, action string) bool {
+	switch {
+	case pausesEpisode(action):
+		return alreadyStopped(torrent)
+	case action == ACTION_PRIORITY:
+		return torrent.BandwidthPriority != nil && *torrent.BandwidthPriority == PRIORITY_LOW
+	case action == ACTION_IDLE_LIMIT:
+		return alreadyStopped(torrent) || (torrent.SeedIdleMode != nil && *torrent.SeedIdleMode == SEED_IDLE_MODE_CUSTOM)
+	}
+	return false
+}
+
+// 把计划中的分集分为需要执行、已完成和不存在三类
+func classifyPlan(plan Plan, torrents []transmissionrpc.Torrent, defaultAction string) ApplyClassification {
+	byHash := make(map[string]*transmissionrpc.Torrent)
+	for i := range torrents {
+		if torrents[i].HashString != nil {
+			byHash[*torrents[i].HashString] = &torrents[i]
+		}
+	}
+
+	classification := ApplyClassification{Pending: plan}
+	classification.Pending.Groups = nil
+	for _, group := range plan.Groups {
+		if _, ok := byHash[group.Collection.Hash]; !ok {
+			// 合集已不存在时整组跳过，不能再依赖合集做种
+			classification.Missing = append(classification.Missing, group.Episodes...)
+			continue
+		}
+		pending := group
+		pending.Episodes = nil
+		for _, episode := range group.Episodes {
+			torrent, ok := byHash[episode.Hash]
+			if !ok {
+				classification.Missing = append(classification.Missing, episode)
+				continue
+			}
+			action := episode.Action
+			if action == "" {
+				action = defaultAction
+			}
+			if episodeAlreadyApplied(torrent, action) {
+				classification.Done = append(classification.Done, episode)
+				continue
+			}
+			pending.Episodes = append(pending.Episodes, episode)
+		}
+		if len(pending.Episodes) > 0 {
+			classification.Pending.Groups = append(classification.Pending.Groups, pending)
+		}
+	}
+	return classification
+}
+
+// 需要执行的分集数量
+func (c ApplyClassification) pendingCount() int {
+	count := 0
+	for _, group := range c.Pending.Groups {
+		count += len(group.Episodes)
+	}
+	return count
+}
+
+// 显示已完成和不存在的分集
+func (c ApplyClassification) print() {
+	if len(c.Done) > 0 {
+		fmt.Printf("\n已完成 %d 个分集，跳过:\n", len(c.Done))
+		for _, episode := range c.Done {
+			fmt.Printf("  ID: %d, %s\n", episode.ID, episode.Name)
+		}
+	}
+	if len(c.Missing) > 0 {
+		fmt.Printf("\n不存在，跳过 %d 个分集:\n", len(c.Missing))
+		for _, episode := range c.Missing {
+			fmt.Printf("  %s (%s)\n", episode.Name, episode.Hash)
+		}
+	}
+}
+
+// 显示执行结果：执行成功、已完成跳过、不存在跳过
+func (c ApplyClassification) printSummary(successCount int) {
+	fmt.Printf("\n计划执行统计: 执行 %d 个分集（成功 %d 个）, 已完成跳过 %d 个, 不存在跳过 %d 个\n",
+		c.pendingCount(), successCount, len(c.Done), len(c.Missing))
+}
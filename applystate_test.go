@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// 修改重放数据中的种子：按ID设置字段，value 为nil时删除该种子
+func setReplayTorrent(t *testing.T, id int64, field string, value interface{}) {
+	t.Helper()
+	idText, _ := json.Marshal(id)
+	for i, torrent := range dumpReplay.Torrents {
+		if string(torrent["id"]) != string(idText) {
+			continue
+		}
+		if value == nil {
+			dumpReplay.Torrents = append(dumpReplay.Torrents[:i], dumpReplay.Torrents[i+1:]...)
+			return
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		torrent[field] = data
+		return
+	}
+	t.Fatalf("重放数据中没有种子 ID: %d", id)
+}
+
+// 计划中各分集的ID
+func planTorrentIDs(torrents []PlanTorrent) []int64 {
+	var ids []int64
+	for _, torrent := range torrents {
+		ids = append(ids, torrent.ID)
+	}
+	return ids
+}
+
+func TestClassifyPlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  string
+		change  func(t *testing.T)
+		pending []int64
+		done    []int64
+		missing []int64
+	}{
+		{
+			name:    "首次执行",
+			action:  ACTION_PAUSE,
+			change:  func(t *testing.T) {},
+			pending: []int64{3, 2},
+		},
+		{
+			name:    "再次执行时跳过已暂停的分集",
+			action:  ACTION_PAUSE,
+			change:  func(t *testing.T) { setReplayTorrent(t, 3, "status", 0) },
+			pending: []int64{2},
+			done:    []int64{3},
+		},
+		{
+			name:    "已删除的分集",
+			action:  ACTION_PAUSE,
+			change:  func(t *testing.T) { setReplayTorrent(t, 2, "", nil) },
+			pending: []int64{3},
+			missing: []int64{2},
+		},
+		{
+			name:    "合集已删除时整组跳过",
+			action:  ACTION_PAUSE,
+			change:  func(t *testing.T) { setReplayTorrent(t, 1, "", nil) },
+			missing: []int64{3, 2},
+		},
+		{
+			name:    "已是低优先级的分集",
+			action:  ACTION_PRIORITY,
+			change:  func(t *testing.T) { setReplayTorrent(t, 2, "bandwidthPriority", PRIORITY_LOW) },
+			pending: []int64{3},
+			done:    []int64{2},
+		},
+		{
+			name:    "暂停的分集对优先级操作仍需执行",
+			action:  ACTION_PRIORITY,
+			change:  func(t *testing.T) { setReplayTorrent(t, 2, "status", 0) },
+			pending: []int64{3, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, result, opts := scanFixture(t, "scan.json", "--action", tt.action)
+			plan := buildPlan(result, opts)
+			tt.change(t)
+
+			torrents, err := lookupPlanTorrents(client, plan)
+			if err != nil {
+				t.Fatal(err)
+			}
+			classification := classifyPlan(plan, torrents, plan.Action)
+			var pending []int64
+			for _, group := range classification.Pending.Groups {
+				pending = append(pending, planTorrentIDs(group.Episodes)...)
+			}
+			if !reflect.DeepEqual(pending, tt.pending) {
+				t.Errorf("需要执行 %v，应为 %v", pending, tt.pending)
+			}
+			if done := planTorrentIDs(classification.Done); !reflect.DeepEqual(done, tt.done) {
+				t.Errorf("已完成 %v，应为 %v", done, tt.done)
+			}
+			if missing := planTorrentIDs(classification.Missing); !reflect.DeepEqual(missing, tt.missing) {
+				t.Errorf("不存在 %v，应为 %v", missing, tt.missing)
+			}
+			if classification.pendingCount() != len(tt.pending) {
+				t.Errorf("pendingCount() = %d，应为 %d", classification.pendingCount(), len(tt.pending))
+			}
+		})
+	}
+}
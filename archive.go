@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 内容主要是压缩包分卷、不作为合集和分集处理的跳过原因
+const SKIP_ARCHIVE_PACK = "archive_pack"
+
+// 压缩包文件大小占全部文件的比例达到该值时视为压缩包种子
+const ARCHIVE_PACK_RATIO = 0.8
+
+// 压缩包及分卷的扩展名：rar、r00~r99、zip、z01~z99、7z 及 7z.001 等分卷
+var archiveFileRegex = regexp.MustCompile(`(?i)\.(?:rar|r\d{2}|zip|z\d{2}|7z|7z\.\d{3})$`)
+
+// 文件是否是压缩包或压缩包分卷
+func isArchiveFile(filePath string) bool {
+	return archiveFileRegex.MatchString(filePath)
+}
+
+// 压缩包种子：旧式合集常以 rar/zip 分卷发布，文件名不会与分集的视频文件匹配，
+// 但数量和大小可能碰巧满足合集条件。按大小统计，不计填充文件
+func archivePack(files []*transmissionrpc.TorrentFile) bool {
+	var total, archived int64
+	for _, file := range files {
+		if isPaddingFile(file) {
+			continue
+		}
+		total += file.Length
+		if isArchiveFile(file.Name) {
+			archived += file.Length
+		}
+	}
+	return total > 0 && float64(archived) >= float64(total)*ARCHIVE_PACK_RATIO
+}
+
+// 去掉指定ID的种子
+func withoutTorrent(torrents []transmissionrpc.Torrent, id int64) []transmissionrpc.Torrent {
+	var kept []transmissionrpc.Torrent
+	for _, torrent := range torrents {
+		if torrent.ID == nil || *torrent.ID != id {
+			kept = append(kept, torrent)
+		}
+	}
+	return kept
+}
+
+// 压缩包种子的跳过记录
+func archivePackRecord(name string, torrent transmissionrpc.Torrent, files []*transmissionrpc.TorrentFile) SkipRecord {
+	count := 0
+	for _, file := range files {
+		if isArchiveFile(file.Name) {
+			count++
+		}
+	}
+	return SkipRecord{
+		Reason:   SKIP_ARCHIVE_PACK,
+		Name:     name,
+		Detail:   fmt.Sprintf("ID: %d 的内容主要是 %d 个压缩包文件，不作为合集或分集", *torrent.ID, count),
+		Torrents: []*transmissionrpc.Torrent{&torrent},
+	}
+}
+
+// 显示分析中发现的压缩包种子，便于手动清理。只有与其他种子同名、获取过文件列表的种子会被检查
+func printArchivePacks(w io.Writer, skipped []SkipRecord) {
+	var packs []*transmissionrpc.Torrent
+	for _, record := range skipped {
+		if record.Reason == SKIP_ARCHIVE_PACK {
+			packs = append(packs, record.Torrents...)
+		}
+	}
+	if len(packs) == 0 {
+		return
+	}
+	now := time.Now()
+	fmt.Fprintf(w, "\n===== 压缩包种子（%d 个，可手动清理）=====\n", len(packs))
+	for i, torrent := range packs {
+		line := fmt.Sprintf("  %d. ID: %d, %s", i+1, *torrent.ID, *torrent.Name)
+		if torrent.SizeWhenDone != nil {
+			line += fmt.Sprintf(", 大小: %s", formatSize(int64((*torrent.SizeWhenDone).Byte())))
+		}
+		if torrent.AddedDate != nil {
+			line += fmt.Sprintf(", 已添加 %d 天", int(now.Sub(*torrent.AddedDate).Hours()/24))
+		}
+		fmt.Fprintln(w, line)
+	}
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 计划中大小的来源：离线分析种子备份时为种子元数据中的文件大小之和，而不是服务器的 sizeWhenDone
+const PLAN_SIZE_METADATA = "metadata"
+
+// 从种子文件得到的一个种子
+type BackupTorrent struct {
+	Hash     string
+	Name     string
+	Files    []*transmissionrpc.TorrentFile
+	Private  bool
+	Trackers [][]string // 按层级的tracker地址
+	Created  int64      // 种子的创建时间（Unix时间），没有时为0
+}
+
+// 解析种子文件：支持单文件和多文件种子，名称和路径优先使用 .utf-8 键；
+// 只有 BitTorrent v2 信息（没有 v1 文件列表）的种子无法计算 v1 info hash，返回错误
+func parseTorrentFile(data []byte) (BackupTorrent, error) {
+	root, rawInfo, err := decodeTorrentFile(data)
+	if err != nil {
+		return BackupTorrent{}, err
+	}
+	info, ok := root["info"].(map[string]interface{})
+	if !ok || rawInfo == nil {
+		return BackupTorrent{}, fmt.Errorf("没有 info 字典")
+	}
+	name, ok := bencodeString(info, "name")
+	if !ok || name == "" {
+		return BackupTorrent{}, fmt.Errorf("没有名称")
+	}
+	name = strings.ToValidUTF8(name, "�")
+	sum := sha1.Sum(rawInfo)
+	torrent := BackupTorrent{
+		Hash: hex.EncodeToString(sum[:]),
+		Name: name,
+	}
+
+	if length, ok := bencodeInt(info, "length"); ok {
+		// 单文件种子：文件名即种子名称
+		torrent.Files = []*transmissionrpc.TorrentFile{{Name: name, Length: length}}
+	} else if files, ok := info["files"].([]interface{}); ok {
+		// 多文件种子：文件路径为 名称/路径各部分
+		for i, item := range files {
+			file, ok := item.(map[string]interface{})
+			if !ok {
+				return BackupTorrent{}, fmt.Errorf("第 %d 个文件格式错误", i+1)
+			}
+			length, ok := bencodeInt(file, "length")
+			if !ok {
+				return BackupTorrent{}, fmt.Errorf("第 %d 个文件没有大小", i+1)
+			}
+			parts, ok := bencodeStrings(file, "path")
+			if !ok || len(parts) == 0 {
+				return BackupTorrent{}, fmt.Errorf("第 %d 个文件没有路径", i+1)
+			}
+			path := strings.ToValidUTF8(name+"/"+strings.Join(parts, "/"), "�")
+			torrent.Files = append(torrent.Files, &transmissionrpc.TorrentFile{Name: path, Length: length})
+		}
+	} else {
+		return BackupTorrent{}, fmt.Errorf("没有 v1 文件信息（只有 BitTorrent v2 信息的种子不支持）")
+	}
+
+	if private, ok := bencodeInt(info, "private"); ok && private == 1 {
+		torrent.Private = true
+	}
+	if tiers, ok := root["announce-list"].([]interface{}); ok {
+		for _, item := range tiers {
+			tier, ok := item.([]interface{})
+			if !ok {
+				continue
+			}
+			var urls []string
+			for _, announce := range tier {
+				if url, ok := announce.(string); ok && url != "" {
+					urls = append(urls, url)
+				}
+			}
+			if len(urls) > 0 {
+				torrent.Trackers = append(torrent.Trackers, urls)
+			}
+		}
+	}
+	if len(torrent.Trackers) == 0 {
+		if announce, ok := root["announce"].(string); ok && announce != "" {
+			torrent.Trackers = [][]string{{announce}}
+		}
+	}
+	torrent.Created, _ = bencodeInt(root, "creation date")
+	return torrent, nil
+}
+
+// Transmission 的 .resume 文件中离线分析用到的内容，没有 .resume 文件时按已完成、正在做种处理
+type BackupResume struct {
+	Found       bool
+	AddedDate   int64
+	DoneDate    int64
+	Paused      bool
+	Complete    bool
+	Uploaded    int64
+	Destination string
+	Labels      []string
+}
+
+// 读取与种子文件同名的 .resume 文件（Transmission 配置目录中与 torrents 同级的 resume 目录）
+func loadBackupResume(torrentPath string) BackupResume {
+	base := strings.TrimSuffix(filepath.Base(torrentPath), filepath.Ext(torrentPath))
+	path := filepath.Join(filepath.Dir(filepath.Dir(torrentPath)), "resume", base+".resume")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return BackupResume{Complete: true}
+	}
+	value, err := decodeBencode(data)
+	dict, ok := value.(map[string]interface{})
+	if err != nil || !ok {
+		log.Printf("读取 %s 失败，按已完成处理: %v", path, err)
+		return BackupResume{Complete: true}
+	}
+	resume := BackupResume{Found: true}
+	resume.AddedDate, _ = bencodeInt(dict, "added-date")
+	resume.DoneDate, _ = bencodeInt(dict, "done-date")
+	paused, _ := bencodeInt(dict, "paused")
+	resume.Paused = paused != 0
+	resume.Uploaded, _ = bencodeInt(dict, "uploaded")
+	resume.Destination, _ = bencodeString(dict, "destination")
+	resume.Labels, _ = bencodeStrings(dict, "labels")
+	if progress, ok := dict["progress"].(map[string]interface{}); ok {
+		have, _ := progress["have"].(string)
+		blocks, _ := progress["blocks"].(string)
+		resume.Complete = have == "all" || blocks == "all"
+	}
+	return resume
+}
+
+// 种子文件和 .resume 文件组合成 torrent-get 返回的种子字段
+func backupTorrentFields(id int64, torrent BackupTorrent, resume BackupResume) (map[string]json.RawMessage, error) {
+	var size int64
+	files := make([]map[string]interface{}, 0, len(torrent.Files))
+	wanted := make([]int, 0, len(torrent.Files))
+	for _, file := range torrent.Files {
+		size += file.Length
+		completed := int64(0)
+		if resume.Complete {
+			completed = file.Length
+		}
+		files = append(files, map[string]interface{}{"name": file.Name, "length": file.Length, "bytesCompleted": completed})
+		wanted = append(wanted, 1)
+	}
+	trackers := []map[string]interface{}{}
+	for tier, urls := range torrent.Trackers {
+		for _, url := range urls {
+			trackers = append(trackers, map[string]interface{}{"announce": url, "id": len(trackers), "scrape": "", "tier": tier})
+		}
+	}
+
+	status := transmissionrpc.TorrentStatusSeed
+	percentDone := 1.0
+	if !resume.Complete {
+		status, percentDone = transmissionrpc.TorrentStatusDownload, 0
+	}
+	if resume.Paused {
+		status = transmissionrpc.TorrentStatusStopped
+	}
+	addedDate := resume.AddedDate
+	if addedDate == 0 {
+		addedDate = torrent.Created
+	}
+	ratio := 0.0
+	if size > 0 {
+		ratio = float64(resume.Uploaded) / float64(size)
+	}
+	labels := resume.Labels
+	if labels == nil {
+		labels = []string{}
+	}
+
+	values := map[string]interface{}{
+		"id":                      id,
+		"name":                    torrent.Name,
+		"hashString":              torrent.Hash,
+		"sizeWhenDone":            size,
+		"totalSize":               size,
+		"status":                  status,
+		"bandwidthPriority":       0,
+		"uploadedEver":            resume.Uploaded,
+		"uploadRatio":             ratio,
+		"secondsSeeding":          0,
+		"trackers":                trackers,
+		"trackerStats":            []interface{}{},
+		"percentDone":             percentDone,
+		"rateUpload":              0,
+		"uploadLimit":             0,
+		"uploadLimited":           false,
+		"doneDate":                resume.DoneDate,
+		"addedDate":               addedDate,
+		"isPrivate":               torrent.Private,
+		"error":                   0,
+		"errorString":             "",
+		"peersConnected":          0,
+		"webseeds":                []string{},
+		"isFinished":              false,
+		"seedRatioMode":           0,
+		"seedRatioLimit":          0,
+		"seedIdleMode":            0,
+		"seedIdleLimit":           0,
+		"metadataPercentComplete": 1,
+		"labels":                  labels,
+		"files":                   files,
+		"wanted":                  wanted,
+	}
+	// 没有 .resume 文件时下载位置未知，不返回该字段，不参与下载位置的比较
+	if resume.Destination != "" {
+		values["downloadDir"] = resume.Destination
+	}
+	fields := make(map[string]json.RawMessage, len(values))
+	for name, value := range values {
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = data
+	}
+	return fields, nil
+}
+
+// 读取 Transmission 的种子备份目录（配置目录中的 torrents），重建离线分析用的RPC数据：
+// 种子按文件名排序编号，同一 info hash 的种子只保留一个；无法解析的文件打印警告后跳过
+func loadTorrentBackup(dir string) (DiagRPC, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.torrent"))
+	if err != nil {
+		return DiagRPC{}, err
+	}
+	if len(paths) == 0 {
+		return DiagRPC{}, fmt.Errorf("%s 中没有 .torrent 文件", dir)
+	}
+	sort.Strings(paths)
+
+	// 会话信息只提供RPC版本，使标签字段被请求；其他会话方法返回空内容
+	session, err := json.Marshal(map[string]interface{}{
+		"rpc-version":         RPC_VERSION_LABELS,
+		"rpc-version-minimum": 1,
+		"version":             "offline",
+	})
+	if err != nil {
+		return DiagRPC{}, err
+	}
+	rpc := DiagRPC{Methods: map[string]json.RawMessage{"session-get": session}}
+	seen := make(map[string]bool)
+	resumed, failed := 0, 0
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("读取种子文件 %s 失败: %v", path, err)
+			failed++
+			continue
+		}
+		torrent, err := parseTorrentFile(data)
+		if err != nil {
+			log.Printf("解析种子文件 %s 失败: %v", path, err)
+			failed++
+			continue
+		}
+		if seen[torrent.Hash] {
+			continue
+		}
+		seen[torrent.Hash] = true
+		resume := loadBackupResume(path)
+		if resume.Found {
+			resumed++
+		}
+		fields, err := backupTorrentFields(int64(len(rpc.Torrents)+1), torrent, resume)
+		if err != nil {
+			return DiagRPC{}, err
+		}
+		rpc.Torrents = append(rpc.Torrents, fields)
+	}
+	fmt.Printf("从 %s 读取了 %d 个种子（%d 个有 .resume 文件），%d 个文件无法解析\n", dir, len(rpc.Torrents), resumed, failed)
+	return rpc, nil
+}
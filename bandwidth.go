@@ -0,0 +1,186 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 把分集移入限速很低的带宽组，分集继续以很低的速度做种，而不是暂停
+const ACTION_BANDWIDTH_GROUP = "bandwidth-group"
+
+// --bandwidth-group 的默认值
+const DEFAULT_BANDWIDTH_GROUP = "dups"
+
+// 带宽组不存在时创建的上传限速（KB/s），--bandwidth-group-limit 的默认值
+const DEFAULT_BANDWIDTH_GROUP_LIMIT = 10
+
+// group-get 返回的带宽组设置，速度单位为 KB/s
+type BandwidthGroup struct {
+	Name                  string `json:"name"`
+	HonorsSessionLimits   bool   `json:"honorsSessionLimits"`
+	SpeedLimitUpEnabled   bool   `json:"speed-limit-up-enabled"`
+	SpeedLimitUp          int64  `json:"speed-limit-up"`
+	SpeedLimitDownEnabled bool   `json:"speed-limit-down-enabled"`
+	SpeedLimitDown        int64  `json:"speed-limit-down"`
+}
+
+// 查询带宽组，不存在时返回nil
+func getBandwidthGroup(ctx context.Context, client *transmissionrpc.Client, name string) (*BandwidthGroup, error) {
+	var arguments struct {
+		Groups []BandwidthGroup `json:"group"`
+	}
+	if err := rawRPC(ctx, client, "group-get", map[string]interface{}{"group": []string{name}}, &arguments); err != nil {
+		return nil, err
+	}
+	for i := range arguments.Groups {
+		if arguments.Groups[i].Name == name {
+			return &arguments.Groups[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// 确认带宽组存在：已存在时保留其设置，不存在时按 limit 创建只限制上传速度的带宽组
+func ensureBandwidthGroup(client *transmissionrpc.Client, name string, limit int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+	defer cancel()
+	group, err := getBandwidthGroup(ctx, client, name)
+	if err != nil {
+		return err
+	}
+	if group != nil {
+		if group.SpeedLimitUpEnabled {
+			fmt.Printf("带宽组 \"%s\" 已存在，上传限速 %d KB/s\n", name, group.SpeedLimitUp)
+		} else {
+			fmt.Printf("警告: 带宽组 \"%s\" 已存在但没有启用上传限速，移入的分集不会被限速\n", name)
+		}
+		return nil
+	}
+	fmt.Printf("带宽组 \"%s\" 不存在，正在创建（上传限速 %d KB/s）...\n", name, limit)
+	return rawRPC(ctx, client, "group-set", map[string]interface{}{
+		"name":                   name,
+		"honorsSessionLimits":    true,
+		"speed-limit-up-enabled": true,
+		"speed-limit-up":         limit,
+	}, nil)
+}
+
+// 查询种子当前所在的带宽组，不在任何组中时为空字符串
+func torrentBandwidthGroups(ctx context.Context, client *transmissionrpc.Client, ids []int64) (map[int64]string, error) {
+	var arguments struct {
+		Torrents []struct {
+			ID    int64  `json:"id"`
+			Group string `json:"group"`
+		} `json:"torrents"`
+	}
+	err := rawRPC(ctx, client, "torrent-get", map[string]interface{}{
+		"ids":    ids,
+		"fields": []string{"id", "group"},
+	}, &arguments)
+	if err != nil {
+		return nil, err
+	}
+	groups := make(map[int64]string, len(arguments.Torrents))
+	for _, torrent := range arguments.Torrents {
+		groups[torrent.ID] = torrent.Group
+	}
+	return groups, nil
+}
+
+// 设置种子所在的带宽组，空字符串表示移出带宽组
+func setTorrentBandwidthGroup(ctx context.Context, client *transmissionrpc.Client, torrentID int64, group string) error {
+	return rawRPC(ctx, client, "torrent-set", map[string]interface{}{
+		"ids":   []int64{torrentID},
+		"group": group,
+	}, nil)
+}
+
+// 把分集移入带宽组，已在该组中的分集跳过；原来所在的带宽组记录到操作历史以便撤销。
+// 带宽组不存在时先按 limit 创建，创建失败时不移动任何分集
+func bandwidthGroupEpisodes(ctx context.Context, client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup, name string, limit int, history *HistoryWriter, throttle *ActionThrottle) (int, int, int) {
+	appliedCount, skippedCount, failedCount := 0, 0, 0
+	var ids []int64
+	for _, group := range duplicateGroups {
+		for _, episode := range group.Episodes {
+			if episode != nil && episode.ID != nil {
+				ids = append(ids, *episode.ID)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return 0, 0, 0
+	}
+
+	if err := ensureBandwidthGroup(client, name, limit); err != nil {
+		fmt.Printf("无法确认或创建带宽组 \"%s\": %v\n", name, err)
+		return 0, 0, len(ids)
+	}
+	queryCtx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+	current, err := torrentBandwidthGroups(queryCtx, client, ids)
+	cancel()
+	if err != nil {
+		fmt.Printf("查询分集所在的带宽组失败: %v\n", err)
+		return 0, 0, len(ids)
+	}
+
+	for _, groupName := range sortedGroupNames(duplicateGroups) {
+		var episodes []*transmissionrpc.Torrent
+		for _, episode := range duplicateGroups[groupName].Episodes {
+			if episode == nil || episode.ID == nil {
+				continue
+			}
+			if current[*episode.ID] == name {
+				skippedCount++
+				continue
+			}
+			episodes = append(episodes, episode)
+		}
+		episodes = throttle.take(ctx, groupName, episodes)
+		if len(episodes) == 0 {
+			continue
+		}
+
+		fmt.Printf("正在把 \"%s\" 的 %d 个分集移入带宽组 \"%s\"...\n", groupName, len(episodes), name)
+		for i, episode := range episodes {
+			if !throttle.wait(ctx) {
+				throttle.deferRest(groupName, episodes[i:], DEFERRED_INTERRUPTED)
+				break
+			}
+			actionCtx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+			err := setTorrentBandwidthGroup(actionCtx, client, *episode.ID, name)
+			cancel()
+			if err != nil {
+				fmt.Printf("移入带宽组失败 ID: %d: %v\n", *episode.ID, err)
+				failedCount++
+				continue
+			}
+			prevGroup := current[*episode.ID]
+			record := newHistoryRecord(ACTION_BANDWIDTH_GROUP, groupName, episode)
+			record.PrevGroup = &prevGroup
+			history.Record(record)
+			keptExport.succeed(groupName)
+			runNotices.succeed(groupName)
+			appliedCount++
+		}
+	}
+	return appliedCount, skippedCount, failedCount
+}
+
+// 撤销时显示的原带宽组
+func describePrevBandwidthGroup(prevGroup *string) string {
+	if prevGroup == nil || *prevGroup == "" {
+		return "无（移出带宽组）"
+	}
+	return fmt.Sprintf(" \"%s\"", *prevGroup)
+}
+
+// 把分集移回原来所在的带宽组，原来不在任何组中时移出带宽组
+func restoreBandwidthGroup(ctx context.Context, client *transmissionrpc.Client, torrentID int64, record HistoryRecord) error {
+	prevGroup := ""
+	if record.PrevGroup != nil {
+		prevGroup = *record.PrevGroup
+	}
+	return setTorrentBandwidthGroup(ctx, client, torrentID, prevGroup)
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// --benchmark 统计的阶段
+const (
+	BENCH_LIST     = "获取种子列表"
+	BENCH_FILES    = "获取文件列表"
+	BENCH_GROUPING = "分组"
+	BENCH_OVERLAP  = "重叠分析"
+	BENCH_CHECKS   = "策略和保护检查"
+	BENCH_REPORT   = "报告渲染"
+)
+
+// 一个阶段的耗时统计
+type StageTiming struct {
+	Stage   string        `json:"stage"`
+	Calls   int           `json:"calls"`
+	Total   time.Duration `json:"total_ns"`
+	P50     time.Duration `json:"p50_ns"`
+	P95     time.Duration `json:"p95_ns"`
+	samples []time.Duration
+}
+
+// 按阶段记录耗时和调用次数，未开启 --benchmark 时为nil，nil收集器不记录任何内容；
+// 分组分析并行获取文件列表时可能同时记录
+type Benchmark struct {
+	mu      sync.Mutex
+	stages  []*StageTiming
+	byStage map[string]*StageTiming
+}
+
+// 本次运行的耗时收集器
+var benchmark *Benchmark
+
+// 开启耗时统计
+func enableBenchmark() {
+	benchmark = &Benchmark{byStage: make(map[string]*StageTiming)}
+}
+
+// 开始计时，返回的函数结束计时并记录一次调用，可以直接 defer benchmark.start(...)()
+func (b *Benchmark) start(stage string) func() {
+	if b == nil {
+		return func() {}
+	}
+	started := time.Now()
+	return func() {
+		b.record(stage, time.Since(started))
+	}
+}
+
+// 记录一次调用的耗时
+func (b *Benchmark) record(stage string, elapsed time.Duration) {
+	if b == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	timing, ok := b.byStage[stage]
+	if !ok {
+		timing = &StageTiming{Stage: stage}
+		b.byStage[stage] = timing
+		b.stages = append(b.stages, timing)
+	}
+	timing.Calls++
+	timing.Total += elapsed
+	timing.samples = append(timing.samples, elapsed)
+}
+
+// 按记录顺序返回各阶段的统计，计算 p50 和 p95
+func (b *Benchmark) timings() []StageTiming {
+	if b == nil {
+		return nil
+	}
+	result := make([]StageTiming, 0, len(b.stages))
+	for _, timing := range b.stages {
+		sorted := append([]time.Duration{}, timing.samples...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		entry := *timing
+		entry.P50 = percentile(sorted, 0.50)
+		entry.P95 = percentile(sorted, 0.95)
+		entry.samples = nil
+		result = append(result, entry)
+	}
+	return result
+}
+
+// 已排序样本的百分位数（最近秩法）
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p*float64(len(sorted))+0.999999) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(sorted) {
+		rank = len(sorted) - 1
+	}
+	return sorted[rank]
+}
+
+// 显示耗时表
+func (b *Benchmark) print() {
+	if b == nil {
+		return
+	}
+	fmt.Println("\n===== 耗时统计 =====")
+	// 阶段名称为中文，放在最后一列避免对齐问题
+	fmt.Printf("%8s %12s %12s %12s  %s\n", "calls", "total", "p50", "p95", "阶段")
+	for _, timing := range b.timings() {
+		fmt.Printf("%8d %12s %12s %12s  %s\n", timing.Calls,
+			timing.Total.Round(time.Millisecond), timing.P50.Round(time.Microsecond), timing.P95.Round(time.Microsecond), timing.Stage)
+	}
+	fmt.Println("重叠分析和策略检查的耗时包含其中的文件列表获取；文件列表有缓存，只统计实际的RPC请求")
+}
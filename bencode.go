@@ -0,0 +1,188 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// bencode 解码结果：字符串为 string（可能不是有效的UTF-8），整数为 int64，
+// 列表为 []interface{}，字典为 map[string]interface{}
+type bencodeDecoder struct {
+	data  []byte
+	pos   int
+	depth int
+
+	// 顶层字典中 info 的值在 data 中的范围，用于计算 info hash
+	infoStart int
+	infoEnd   int
+}
+
+// 解码一个完整的 bencode 值，末尾不能有多余的数据
+func decodeBencode(data []byte) (interface{}, error) {
+	decoder := &bencodeDecoder{data: data, infoStart: -1}
+	value, err := decoder.value()
+	if err != nil {
+		return nil, err
+	}
+	if decoder.pos != len(data) {
+		return nil, fmt.Errorf("bencode 数据在位置 %d 后有多余内容", decoder.pos)
+	}
+	return value, nil
+}
+
+// 解码种子文件，同时返回顶层 info 字典的原始数据（没有时为nil）
+func decodeTorrentFile(data []byte) (map[string]interface{}, []byte, error) {
+	decoder := &bencodeDecoder{data: data, infoStart: -1}
+	value, err := decoder.value()
+	if err != nil {
+		return nil, nil, err
+	}
+	root, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, nil, fmt.Errorf("种子文件的顶层不是字典")
+	}
+	if decoder.infoStart < 0 {
+		return root, nil, nil
+	}
+	return root, data[decoder.infoStart:decoder.infoEnd], nil
+}
+
+func (d *bencodeDecoder) value() (interface{}, error) {
+	if d.pos >= len(d.data) {
+		return nil, fmt.Errorf("bencode 数据意外结束")
+	}
+	switch c := d.data[d.pos]; {
+	case c == 'i':
+		return d.integer()
+	case c == 'l':
+		return d.list()
+	case c == 'd':
+		return d.dict()
+	case c >= '0' && c <= '9':
+		return d.str()
+	default:
+		return nil, fmt.Errorf("bencode 数据在位置 %d 有无效的字符 %q", d.pos, c)
+	}
+}
+
+// 整数：i<数字>e
+func (d *bencodeDecoder) integer() (int64, error) {
+	start := d.pos + 1
+	end := start
+	for end < len(d.data) && d.data[end] != 'e' {
+		end++
+	}
+	if end >= len(d.data) {
+		return 0, fmt.Errorf("bencode 整数在位置 %d 没有结束", d.pos)
+	}
+	n, err := strconv.ParseInt(string(d.data[start:end]), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bencode 整数在位置 %d 无效: %v", d.pos, err)
+	}
+	d.pos = end + 1
+	return n, nil
+}
+
+// 字符串：<长度>:<内容>
+func (d *bencodeDecoder) str() (string, error) {
+	colon := d.pos
+	for colon < len(d.data) && d.data[colon] != ':' {
+		colon++
+	}
+	if colon >= len(d.data) {
+		return "", fmt.Errorf("bencode 字符串在位置 %d 没有长度分隔符", d.pos)
+	}
+	length, err := strconv.Atoi(string(d.data[d.pos:colon]))
+	if err != nil || length < 0 || colon+1+length > len(d.data) {
+		return "", fmt.Errorf("bencode 字符串在位置 %d 的长度无效", d.pos)
+	}
+	start := colon + 1
+	d.pos = start + length
+	return string(d.data[start:d.pos]), nil
+}
+
+// 列表：l<值>...e
+func (d *bencodeDecoder) list() ([]interface{}, error) {
+	d.pos++
+	d.depth++
+	defer func() { d.depth-- }()
+	list := []interface{}{}
+	for {
+		if d.pos >= len(d.data) {
+			return nil, fmt.Errorf("bencode 列表没有结束")
+		}
+		if d.data[d.pos] == 'e' {
+			d.pos++
+			return list, nil
+		}
+		item, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, item)
+	}
+}
+
+// 字典：d<字符串键><值>...e，记录顶层字典中 info 的值的范围
+func (d *bencodeDecoder) dict() (map[string]interface{}, error) {
+	d.pos++
+	d.depth++
+	defer func() { d.depth-- }()
+	dict := make(map[string]interface{})
+	for {
+		if d.pos >= len(d.data) {
+			return nil, fmt.Errorf("bencode 字典没有结束")
+		}
+		if d.data[d.pos] == 'e' {
+			d.pos++
+			return dict, nil
+		}
+		key, err := d.str()
+		if err != nil {
+			return nil, err
+		}
+		start := d.pos
+		item, err := d.value()
+		if err != nil {
+			return nil, err
+		}
+		if d.depth == 1 && key == "info" {
+			d.infoStart, d.infoEnd = start, d.pos
+		}
+		dict[key] = item
+	}
+}
+
+// 字典中的字符串值，优先使用 key.utf-8（部分客户端在原键中保存本地编码的名称）
+func bencodeString(dict map[string]interface{}, key string) (string, bool) {
+	if value, ok := dict[key+".utf-8"].(string); ok {
+		return value, true
+	}
+	value, ok := dict[key].(string)
+	return value, ok
+}
+
+// 字典中的整数值
+func bencodeInt(dict map[string]interface{}, key string) (int64, bool) {
+	value, ok := dict[key].(int64)
+	return value, ok
+}
+
+// 字典中的字符串列表（如文件路径），优先使用 key.utf-8
+func bencodeStrings(dict map[string]interface{}, key string) ([]string, bool) {
+	list, ok := dict[key+".utf-8"].([]interface{})
+	if !ok {
+		if list, ok = dict[key].([]interface{}); !ok {
+			return nil, false
+		}
+	}
+	values := make([]string, 0, len(list))
+	for _, item := range list {
+		value, ok := item.(string)
+		if !ok {
+			return nil, false
+		}
+		values = append(values, value)
+	}
+	return values, true
+}
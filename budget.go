@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 达到暂停配额时未处理的原因
+const DEFERRED_BUDGET = "未处理，已达暂停配额"
+
+// 暂停配额：限制本工具暂停后仍处于停止状态的种子总数（跨多次运行），
+// 已恢复运行或已删除的种子不再占用配额
+type PauseBudget struct {
+	limit    int // 配额，0 表示不限制
+	used     int // 已占用的配额
+	reported bool
+}
+
+// 根据操作历史和种子当前状态计算已占用的配额
+func newPauseBudget(client *transmissionrpc.Client, limit int) *PauseBudget {
+	budget := &PauseBudget{limit: limit}
+	if limit <= 0 {
+		return budget
+	}
+	used, err := countStoppedByTool(client)
+	if err != nil {
+		log.Printf("统计已暂停的种子失败，暂停配额按 0 计算: %v", err)
+	}
+	budget.used = used
+	fmt.Printf("暂停配额: 已使用 %d/%d\n", budget.used, budget.limit)
+	return budget
+}
+
+// 操作历史中暂停过、当前仍处于停止状态的种子数量
+func countStoppedByTool(client *transmissionrpc.Client) (int, error) {
+	records, err := loadHistory(historyPath())
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool)
+	var hashes []string
+	for _, record := range records {
+		if record.Action == ACTION_PAUSE && record.Hash != "" && !seen[record.Hash] {
+			seen[record.Hash] = true
+			hashes = append(hashes, record.Hash)
+		}
+	}
+	if len(hashes) == 0 {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+	defer cancel()
+	torrents, err := client.TorrentGetHashes(ctx, []string{"id", "hashString", "status"}, hashes)
+	if err != nil {
+		return 0, err
+	}
+	stopped := 0
+	for i := range torrents {
+		if alreadyStopped(&torrents[i]) {
+			stopped++
+		}
+	}
+	return stopped, nil
+}
+
+// 剩余的配额
+func (b *PauseBudget) remaining() int {
+	if b.used >= b.limit {
+		return 0
+	}
+	return b.limit - b.used
+}
+
+// 按剩余配额取出本次可以暂停的分集，其余记为未处理
+func (b *PauseBudget) take(group string, episodes []*transmissionrpc.Torrent, throttle *ActionThrottle) []*transmissionrpc.Torrent {
+	if b.limit <= 0 {
+		return episodes
+	}
+	count, newlyStopped := len(episodes), 0
+	for i, episode := range episodes {
+		// 已经停止的分集不会占用新的配额
+		if alreadyStopped(episode) {
+			continue
+		}
+		if newlyStopped >= b.remaining() {
+			count = i
+			break
+		}
+		newlyStopped++
+	}
+	if count < len(episodes) {
+		if !b.reported {
+			fmt.Printf("已达暂停配额 (%d/%d)，不再暂停更多分集\n", b.used+newlyStopped, b.limit)
+			b.reported = true
+		}
+		throttle.deferRest(group, episodes[count:], DEFERRED_BUDGET)
+	}
+	return episodes[:count]
+}
+
+// 记录新暂停的分集占用的配额
+func (b *PauseBudget) consume(count int) {
+	b.used += count
+}
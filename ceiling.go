@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 删除数据上限：限制一次运行中会删除数据的操作（原地升级、删除旧版合集、删除已失效种子）涉及的总大小，
+// 超过上限后剩余的分集改为暂停，已失效种子不再删除
+type DeleteCeiling struct {
+	limit           int64 // 上限（字节），0 表示不限制
+	scheduled       int64 // 已安排删除的大小
+	downgraded      int64 // 改为暂停的大小
+	downgradedCount int
+	reported        bool
+
+	refused      int64 // 超过上限而不删除的已失效种子的大小
+	refusedCount int
+}
+
+func newDeleteCeiling(limit int64) *DeleteCeiling {
+	return &DeleteCeiling{limit: limit}
+}
+
+// 按组名顺序安排删除，超过上限的分集拆到另一组，改为暂停。完全相同的副本与原分集共用数据，
+// 原分集安排删除时副本不再计算大小，原分集改为暂停时副本也改为暂停
+func (c *DeleteCeiling) split(duplicateGroups map[string]DuplicateGroup) (map[string]DuplicateGroup, map[string]DuplicateGroup) {
+	if c.limit <= 0 {
+		return duplicateGroups, nil
+	}
+	allowed := make(map[string]DuplicateGroup)
+	downgraded := make(map[string]DuplicateGroup)
+	for _, groupName := range sortedGroupNames(duplicateGroups) {
+		group := duplicateGroups[groupName]
+		paused := make(map[int64]bool)
+		for _, episode := range group.Episodes {
+			if _, isCopy := group.copyOf(episode); isCopy {
+				continue
+			}
+			if !c.schedule(torrentBytes(episode)) {
+				paused[*episode.ID] = true
+			}
+		}
+		var keep, pause []*transmissionrpc.Torrent
+		for _, episode := range group.Episodes {
+			if first, isCopy := group.copyOf(episode); isCopy && paused[first] {
+				c.downgradedCount++
+				pause = append(pause, episode)
+				continue
+			}
+			if episode.ID != nil && paused[*episode.ID] {
+				pause = append(pause, episode)
+				continue
+			}
+			keep = append(keep, episode)
+		}
+		if len(keep) > 0 {
+			allowedGroup := group
+			allowedGroup.Episodes = keep
+			allowed[groupName] = allowedGroup
+		}
+		if len(pause) > 0 {
+			pausedGroup := group
+			pausedGroup.Episodes = pause
+			downgraded[groupName] = pausedGroup
+		}
+	}
+	return allowed, downgraded
+}
+
+// 按顺序安排删除不属于任何组的种子（如已失效种子），超过上限的种子不删除，返回可以删除的种子
+func (c *DeleteCeiling) admit(torrents []*transmissionrpc.Torrent) []*transmissionrpc.Torrent {
+	if c.limit <= 0 {
+		return torrents
+	}
+	var allowed []*transmissionrpc.Torrent
+	for _, torrent := range torrents {
+		size := torrentBytes(torrent)
+		if !c.fits(size) {
+			c.refusedCount++
+			c.refused += size
+			c.reportReached()
+			continue
+		}
+		c.scheduled += size
+		allowed = append(allowed, torrent)
+	}
+	return allowed
+}
+
+// 安排删除一个分集，超过上限时记为改为暂停并返回false
+func (c *DeleteCeiling) schedule(size int64) bool {
+	if !c.fits(size) {
+		c.reportReached()
+		c.downgraded += size
+		c.downgradedCount++
+		return false
+	}
+	c.scheduled += size
+	return true
+}
+
+// 删除该大小后是否仍不超过上限
+func (c *DeleteCeiling) fits(size int64) bool {
+	return c.scheduled+size <= c.limit
+}
+
+// 第一次达到上限时提示
+func (c *DeleteCeiling) reportReached() {
+	if c.reported {
+		return
+	}
+	fmt.Printf("\n!!! 已达到删除数据上限 %s（已安排 %s），其余会删除数据的操作改为暂停或不执行 !!!\n", formatSize(c.limit), formatSize(c.scheduled))
+	c.reported = true
+}
+
+// 显示删除和改为暂停的大小
+func (c *DeleteCeiling) printSummary() {
+	if c.limit <= 0 || (c.scheduled == 0 && c.downgradedCount == 0 && c.refusedCount == 0) {
+		return
+	}
+	fmt.Printf("\n删除数据上限 %s: 安排删除 %s, 改为暂停 %d 个分集共 %s", formatSize(c.limit), formatSize(c.scheduled), c.downgradedCount, formatSize(c.downgraded))
+	if c.refusedCount > 0 {
+		fmt.Printf(", 未删除 %d 个已失效种子共 %s", c.refusedCount, formatSize(c.refused))
+	}
+	fmt.Println()
+}
+
+// 会删除数据的操作改为暂停时使用的操作，旧版合集仍与普通分集分开执行
+func downgradedAction(action string) string {
+	if action == ACTION_OLD_PACK_DELETE {
+		return ACTION_OLD_PACK_PAUSE
+	}
+	return ACTION_PAUSE
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// 各组中允许删除和改为暂停的分集ID
+func splitIDs(groups map[string]DuplicateGroup) map[string][]int64 {
+	ids := make(map[string][]int64)
+	for name, group := range groups {
+		for _, episode := range group.Episodes {
+			ids[name] = append(ids[name], *episode.ID)
+		}
+		sort.Slice(ids[name], func(i, j int) bool { return ids[name][i] < ids[name][j] })
+	}
+	return ids
+}
+
+func TestDeleteCeilingSplit(t *testing.T) {
+	newGroups := func() map[string]DuplicateGroup {
+		return map[string]DuplicateGroup{
+			"A": {Confidence: 1, Episodes: rankedGroup(1, 400, 400).Episodes},
+			"B": {Confidence: 0.9, Episodes: rankedGroup(1, 300).Episodes},
+		}
+	}
+	tests := []struct {
+		name       string
+		limit      int64
+		allowed    map[string][]int64
+		downgraded map[string][]int64
+		scheduled  int64
+	}{
+		{"不限制", 0, map[string][]int64{"A": {1, 2}, "B": {1}}, map[string][]int64{}, 0},
+		{"全部在上限内", 1100, map[string][]int64{"A": {1, 2}, "B": {1}}, map[string][]int64{}, 1100},
+		{"按置信度顺序安排，超出的改为暂停", 800, map[string][]int64{"A": {1, 2}}, map[string][]int64{"B": {1}}, 800},
+		{"较小的分集仍可放入剩余额度", 700, map[string][]int64{"A": {1}, "B": {1}}, map[string][]int64{"A": {2}}, 700},
+		{"上限小于任何分集", 100, map[string][]int64{}, map[string][]int64{"A": {1, 2}, "B": {1}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ceiling := newDeleteCeiling(tt.limit)
+			allowed, downgraded := ceiling.split(newGroups())
+			if got := splitIDs(allowed); !reflect.DeepEqual(got, tt.allowed) {
+				t.Errorf("删除 %v，应为 %v", got, tt.allowed)
+			}
+			if got := splitIDs(downgraded); !reflect.DeepEqual(got, tt.downgraded) {
+				t.Errorf("改为暂停 %v，应为 %v", got, tt.downgraded)
+			}
+			if ceiling.scheduled != tt.scheduled {
+				t.Errorf("安排删除 %d 字节，应为 %d 字节", ceiling.scheduled, tt.scheduled)
+			}
+		})
+	}
+}
+
+// 上限对一次运行中的各种操作累计计算，副本只计算一次
+func TestDeleteCeilingAcrossBuckets(t *testing.T) {
+	ceiling := newDeleteCeiling(1000)
+	withCopy := rankedGroup(1, 600, 600)
+	withCopy.EpisodeCopies = map[int64]int64{2: 1}
+	allowed, downgraded := ceiling.split(map[string]DuplicateGroup{"A": withCopy})
+	if len(allowed["A"].Episodes) != 2 || len(downgraded) != 0 || ceiling.scheduled != 600 {
+		t.Fatalf("副本应与原分集一起删除且只计算一次: 删除 %v, 暂停 %v, 已安排 %d", splitIDs(allowed), splitIDs(downgraded), ceiling.scheduled)
+	}
+
+	allowed, downgraded = ceiling.split(map[string]DuplicateGroup{"B": rankedGroup(1, 500)})
+	if len(allowed) != 0 || len(downgraded["B"].Episodes) != 1 {
+		t.Errorf("之前的操作已用去额度，应改为暂停: 删除 %v, 暂停 %v", splitIDs(allowed), splitIDs(downgraded))
+	}
+	if ceiling.downgradedCount != 1 || ceiling.downgraded != 500 {
+		t.Errorf("改为暂停 %d 个共 %d 字节，应为 1 个共 500 字节", ceiling.downgradedCount, ceiling.downgraded)
+	}
+}
+
+func TestDowngradedAction(t *testing.T) {
+	tests := map[string]string{
+		ACTION_LINK:            ACTION_PAUSE,
+		ACTION_OLD_PACK_DELETE: ACTION_OLD_PACK_PAUSE,
+	}
+	for action, want := range tests {
+		if got := downgradedAction(action); got != want {
+			t.Errorf("downgradedAction(%s) = %s, want %s", action, got, want)
+		}
+	}
+}
+
+// 原分集超过上限改为暂停时，副本不能按0字节安排删除
+func TestDeleteCeilingCopyFollowsOriginal(t *testing.T) {
+	ceiling := newDeleteCeiling(500)
+	withCopy := rankedGroup(1, 600, 600, 300)
+	withCopy.EpisodeCopies = map[int64]int64{2: 1}
+	allowed, downgraded := ceiling.split(map[string]DuplicateGroup{"A": withCopy})
+	if got, want := splitIDs(allowed), map[string][]int64{"A": {3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("删除 %v，应为 %v", got, want)
+	}
+	if got, want := splitIDs(downgraded), map[string][]int64{"A": {1, 2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("改为暂停 %v，应为 %v", got, want)
+	}
+	if ceiling.scheduled != 300 || ceiling.downgradedCount != 2 || ceiling.downgraded != 600 {
+		t.Errorf("已安排 %d, 改为暂停 %d 个共 %d，应为 300, 2 个共 600", ceiling.scheduled, ceiling.downgradedCount, ceiling.downgraded)
+	}
+}
+
+// 已失效种子与重复分集共用额度，超过上限的不删除
+func TestDeleteCeilingAdmit(t *testing.T) {
+	ceiling := newDeleteCeiling(1000)
+	unregistered := rankedGroup(1, 400, 700, 200).Episodes
+	if got := ceiling.admit(unregistered); len(got) != 2 || *got[0].ID != 1 || *got[1].ID != 3 {
+		t.Fatalf("admit() 允许删除 %d 个种子，应为ID 1和3", len(got))
+	}
+	if ceiling.refusedCount != 1 || ceiling.refused != 700 || ceiling.scheduled != 600 {
+		t.Errorf("未删除 %d 个共 %d, 已安排 %d，应为 1 个共 700, 600", ceiling.refusedCount, ceiling.refused, ceiling.scheduled)
+	}
+
+	allowed, downgraded := ceiling.split(map[string]DuplicateGroup{"A": rankedGroup(1, 500, 300)})
+	if got, want := splitIDs(allowed), map[string][]int64{"A": {2}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("删除 %v，应为 %v", got, want)
+	}
+	if got, want := splitIDs(downgraded), map[string][]int64{"A": {1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("改为暂停 %v，应为 %v", got, want)
+	}
+
+	if got := newDeleteCeiling(0).admit(unregistered); len(got) != 3 {
+		t.Errorf("不限制时应全部允许删除，得到 %d 个", len(got))
+	}
+}
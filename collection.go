@@ -0,0 +1,75 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 没有剧集标识时，内容文件达到该数量的种子才可能是合集
+const COLLECTION_MIN_FILES = 3
+
+// 合集名称筛选中表示不限制名称结尾
+const COLLECTION_SUFFIX_ANY = "*"
+
+// 种子能否作为合集：内容文件中至少有两个不同的剧集标识，或内容文件数量达到阈值
+func collectionEligible(files []*transmissionrpc.TorrentFile) bool {
+	content := contentFiles(files)
+	if len(content) >= COLLECTION_MIN_FILES {
+		return true
+	}
+	markers := make(map[string]bool)
+	for _, file := range content {
+		if marker := extractEpisodeMarker(getFileName(file.Name)); marker != "" {
+			markers[marker] = true
+		}
+	}
+	return len(markers) >= 2
+}
+
+// 名称是否以任一筛选结尾结尾，* 匹配任意名称
+func matchSuffix(name string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if suffix == COLLECTION_SUFFIX_ANY || (suffix != "" && strings.HasSuffix(name, suffix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// 按名称结尾筛选后，再加入名称结尾匹配 --collection-suffix 的种子作为合集候选，
+// 返回参与分组的种子和只能作为合集的种子ID
+func collectionCandidates(torrents, filteredTorrents []transmissionrpc.Torrent, opts Options) ([]transmissionrpc.Torrent, map[int64]bool) {
+	collectionOnly := make(map[int64]bool)
+	if len(opts.SuffixFilters) == 0 || len(opts.CollectionSuffixes) == 0 {
+		return filteredTorrents, collectionOnly
+	}
+
+	matched := make(map[int64]bool)
+	for _, torrent := range filteredTorrents {
+		if torrent.ID != nil {
+			matched[*torrent.ID] = true
+		}
+	}
+	candidates := append([]transmissionrpc.Torrent{}, filteredTorrents...)
+	for _, torrent := range torrents {
+		if torrent.ID == nil || torrent.Name == nil || matched[*torrent.ID] {
+			continue
+		}
+		if matchSuffix(canonicalName(*torrent.Name), opts.CollectionSuffixes) {
+			candidates = append(candidates, torrent)
+			collectionOnly[*torrent.ID] = true
+		}
+	}
+	return candidates, collectionOnly
+}
+
+// 组内种子是否全部只能作为合集
+func onlyCollectionCandidates(group []transmissionrpc.Torrent, collectionOnly map[int64]bool) bool {
+	for _, torrent := range group {
+		if torrent.ID == nil || !collectionOnly[*torrent.ID] {
+			return false
+		}
+	}
+	return true
+}
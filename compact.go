@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// 输出格式
+const (
+	FORMAT_TEXT    = "text"    // 完整的中文报告
+	FORMAT_COMPACT = "compact" // 每个需要处理的组一行，便于管道处理
+)
+
+// 紧凑格式：切换后标准输出只写入紧凑格式的行，其余提示、报告和日志改为写入标准错误。
+// 返回原来的标准输出
+func redirectChatter() io.Writer {
+	out := os.Stdout
+	os.Stdout = os.Stderr
+	return out
+}
+
+// 以紧凑格式输出计划中的组，每组一行，字段以制表符分隔：
+// 组名、合集ID、分集ID（逗号分隔）、可释放大小（MB）、置信度。格式固定，修改时需同步README
+func writeCompact(w io.Writer, plan Plan) error {
+	for _, group := range plan.Groups {
+		ids := make([]string, 0, len(group.Episodes))
+		var reclaim int64
+		for _, episode := range group.Episodes {
+			ids = append(ids, strconv.FormatInt(episode.ID, 10))
+			if episode.CopyOf == 0 {
+				reclaim += episode.Size
+			}
+		}
+		_, err := fmt.Fprintf(w, "%s\t%d\t%s\t%.2f\t%.2f\n",
+			compactField(group.Name), group.Collection.ID, strings.Join(ids, ","), float64(reclaim)/1024/1024, group.Confidence)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 去掉字段中的制表符和换行，保证每组只占一行
+func compactField(value string) string {
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(value)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 合集未完成或未通过校验时暂缓处理显示的策略名称
+const INCOMPLETE_GATE_POLICY = "合集未完成或未校验"
+
+// Transmission 的本地错误（如数据文件丢失），tracker错误和警告不影响合集内容
+const TR_STAT_LOCAL_ERROR = 3
+
+// 合集不完整的原因：未下载完成、正在校验或有本地错误，完整时返回空
+func collectionIncompleteReason(collection *transmissionrpc.Torrent) string {
+	if collection == nil {
+		return "没有合集"
+	}
+	if collection.PercentDone == nil || *collection.PercentDone < 1 {
+		percent := 0.0
+		if collection.PercentDone != nil {
+			percent = *collection.PercentDone * 100
+		}
+		return fmt.Sprintf("合集尚未下载完成 (%.1f%%)", percent)
+	}
+	if collection.Status != nil && (*collection.Status == transmissionrpc.TorrentStatusCheck || *collection.Status == transmissionrpc.TorrentStatusCheckWait) {
+		return "合集正在校验"
+	}
+	if collection.Error != nil && *collection.Error == TR_STAT_LOCAL_ERROR {
+		message := ""
+		if collection.ErrorString != nil {
+			message = strings.TrimSpace(*collection.ErrorString)
+		}
+		return fmt.Sprintf("合集数据有错误: %s", message)
+	}
+	return ""
+}
+
+// 按 --require-complete-collection 暂缓合集不完整的组：合集未下载完成、正在校验或数据有错误时，
+// 停止分集后这些内容可能没有完整的副本，分集全部移到仅供参考部分
+func applyCompleteCollection(result *ScanResult, required bool) {
+	if !required {
+		return
+	}
+	for name, group := range result.DuplicateGroups {
+		reason := collectionIncompleteReason(group.Collection)
+		if reason == "" {
+			continue
+		}
+		for _, episode := range group.Episodes {
+			group.GatedEpisodes = append(group.GatedEpisodes, GatedEpisode{
+				Episode: episode,
+				Policy:  INCOMPLETE_GATE_POLICY,
+				Reason:  reason,
+			})
+		}
+		group.Episodes = nil
+		delete(result.DuplicateGroups, name)
+		result.GatedGroups[name] = group
+	}
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 置信度各项证据的权重，合计为1
+const (
+	CONFIDENCE_WEIGHT_CONTAINMENT = 0.6  // 分集文件在合集中的包含比例
+	CONFIDENCE_WEIGHT_MARKERS     = 0.25 // 剧集标识一致
+	CONFIDENCE_WEIGHT_SIZE        = 0.15 // 分集大小之和不超过合集
+	CONFIDENCE_NO_MARKERS_FACTOR  = 0.4  // 没有剧集标识时只计入部分标识权重
+)
+
+// 默认的大小误差，与 --size-tolerance 的默认值 1KiB 相同
+const DEFAULT_SIZE_TOLERANCE = 1024
+
+// 比较大小时允许的误差（字节）：分集大小之和超过合集不超过该值时仍视为一致，
+// 分集与合集的大小相差不超过该值时视为大小相同
+var sizeTolerance float64 = DEFAULT_SIZE_TOLERANCE
+
+// 设置比较大小时允许的误差
+func setSizeTolerance(bytes int64) {
+	sizeTolerance = float64(bytes)
+}
+
+// 合集大小与分集大小之和的默认比例上限：超过时合集和分集很可能只是碰巧有同名的通用文件（如 cover.jpg）
+const DEFAULT_MAX_SIZE_RATIO = 30
+
+// 合集大小与分集大小之和的比例上限，0 表示不检查
+var maxSizeRatio float64 = DEFAULT_MAX_SIZE_RATIO
+
+// 设置合集与分集的大小比例上限
+func setMaxSizeRatio(ratio float64) {
+	maxSizeRatio = ratio
+}
+
+// 判断合集和分集关系的证据
+type GroupEvidence struct {
+	Containment    float64 // 各分集文件在合集中的包含比例，取最低值
+	HasMarkers     bool    // 分集文件中是否有剧集标识
+	MarkersAgree   bool    // 分集的剧集标识是否全部出现在合集中
+	SizeConsistent bool    // 分集大小之和是否不超过合集大小
+
+	VariantTokens   []string // 只出现在合集或分集一方名称中的版本标识，如 extended
+	VariantMismatch bool     // 有版本标识差异且文件大小不完全相同，需人工确认
+
+	ClassOverlaps []ClassOverlap // 全部分集按文件类别在合集中找到的数量
+
+	PaddingFiles int             // 比较时排除的填充文件和空文件数量（合集和全部分集）
+	PaddingBytes map[int64]int64 // 合集和各分集填充文件的大小之和（字节），按种子ID
+
+	SizeRatio     float64 // 合集大小与分集大小之和的比例，分集大小为0时为0
+	RatioExceeded bool    // 比例超过 --max-size-ratio，需人工确认
+}
+
+// 根据证据计算置信度（0~1）
+func confidenceScore(evidence GroupEvidence) float64 {
+	score := CONFIDENCE_WEIGHT_CONTAINMENT * evidence.Containment
+	if evidence.HasMarkers {
+		if evidence.MarkersAgree {
+			score += CONFIDENCE_WEIGHT_MARKERS
+		}
+	} else {
+		score += CONFIDENCE_WEIGHT_MARKERS * CONFIDENCE_NO_MARKERS_FACTOR
+	}
+	if evidence.SizeConsistent && !evidence.RatioExceeded {
+		score += CONFIDENCE_WEIGHT_SIZE
+	}
+	return score
+}
+
+// 证据描述，用于报告
+func (e GroupEvidence) describe() string {
+	markers := "无剧集标识"
+	if e.HasMarkers {
+		if e.MarkersAgree {
+			markers = "剧集标识一致"
+		} else {
+			markers = "剧集标识不一致"
+		}
+	}
+	size := "大小之和一致"
+	if !e.SizeConsistent {
+		size = "大小之和超过合集"
+	}
+	description := fmt.Sprintf("包含 %.0f%%, %s, %s", e.Containment*100, markers, size)
+	if len(e.ClassOverlaps) > 0 {
+		description = fmt.Sprintf("包含 %.0f%% (%s), %s, %s", e.Containment*100, describeClassOverlaps(e.ClassOverlaps), markers, size)
+	}
+	if e.PaddingFiles > 0 {
+		description += fmt.Sprintf(", 排除填充文件 %d 个", e.PaddingFiles)
+	}
+	if e.RatioExceeded {
+		description += fmt.Sprintf(", 合集是分集大小之和的 %.1f 倍，超过上限 %g 倍，需人工确认", e.SizeRatio, maxSizeRatio)
+	}
+	if len(e.VariantTokens) > 0 {
+		if e.VariantMismatch {
+			description += fmt.Sprintf(", 版本标识不同: %s, 版本差异，需人工确认", strings.Join(e.VariantTokens, "/"))
+		} else {
+			description += fmt.Sprintf(", 版本标识不同: %s, 文件大小完全相同", strings.Join(e.VariantTokens, "/"))
+		}
+	}
+	return description
+}
+
+// 检查分集文件的剧集标识是否全部出现在合集中，返回分集是否有标识和是否一致
+func markerAgreement(collectionFiles, episodeFiles []*transmissionrpc.TorrentFile) (bool, bool) {
+	collectionMarkers := make(map[string]bool)
+	for _, file := range collectionFiles {
+		if marker := extractEpisodeMarker(file.Name); marker != "" {
+			collectionMarkers[marker] = true
+		}
+	}
+
+	hasMarkers, agree := false, true
+	for _, file := range episodeFiles {
+		marker := extractEpisodeMarker(file.Name)
+		if marker == "" {
+			continue
+		}
+		hasMarkers = true
+		if !collectionMarkers[marker] {
+			agree = false
+		}
+	}
+	return hasMarkers, agree
+}
+
+// 收集一组分集的证据，episodeFiles 与 episodes 一一对应
+func collectGroupEvidence(collection *transmissionrpc.Torrent, collectionFiles []*transmissionrpc.TorrentFile, episodes []*transmissionrpc.Torrent, episodeFiles [][]*transmissionrpc.TorrentFile) GroupEvidence {
+	evidence := GroupEvidence{Containment: 1, MarkersAgree: true, PaddingBytes: make(map[int64]int64)}
+	evidence.recordPadding(collection, collectionFiles)
+	collectionFiles, evidence.PaddingFiles = withoutPadding(collectionFiles)
+	variantTokens := make(map[string]bool)
+	for i, files := range episodeFiles {
+		if i < len(episodes) {
+			evidence.recordPadding(episodes[i], files)
+		}
+		files, excluded := withoutPadding(files)
+		evidence.PaddingFiles += excluded
+		if len(files) > 0 {
+			// 有视频文件时包含比例只按视频文件计算
+			containment, ok := videoOverlap(collectionFiles, files)
+			if !ok {
+				_, matchCount := checkActualEpisodeOverlap(collectionFiles, files)
+				containment = float64(matchCount) / float64(len(files))
+			}
+			if containment < evidence.Containment {
+				evidence.Containment = containment
+			}
+			evidence.ClassOverlaps = mergeClassOverlaps(evidence.ClassOverlaps, classOverlaps(collectionFiles, files))
+		}
+		hasMarkers, agree := markerAgreement(collectionFiles, files)
+		if hasMarkers {
+			evidence.HasMarkers = true
+			evidence.MarkersAgree = evidence.MarkersAgree && agree
+		}
+
+		// 版本标识不同的分集（如 Extended 合集）即使文件名相同，内容也可能不同，要求文件大小完全相同
+		if collection != nil && collection.Name != nil && episodes[i] != nil && episodes[i].Name != nil {
+			if difference := variantDifference(*collection.Name, *episodes[i].Name); len(difference) > 0 {
+				for _, token := range difference {
+					variantTokens[token] = true
+				}
+				if !exactSizeMatch(collectionFiles, files) {
+					evidence.VariantMismatch = true
+				}
+			}
+		}
+	}
+	for token := range variantTokens {
+		evidence.VariantTokens = append(evidence.VariantTokens, token)
+	}
+	sort.Strings(evidence.VariantTokens)
+	if !evidence.HasMarkers {
+		evidence.MarkersAgree = false
+	}
+
+	// 完全相同的分集（辅种）可能共用数据，分集大小之和只计算一次
+	collectionSize, episodesSize := sizeSums(collection, distinctEpisodes(episodes, episodeFiles), evidence.PaddingBytes)
+	evidence.SizeConsistent = episodesSize <= collectionSize+sizeTolerance
+	if episodesSize > 0 {
+		evidence.SizeRatio = collectionSize / episodesSize
+	}
+	evidence.RatioExceeded = maxSizeRatio > 0 && evidence.SizeRatio > maxSizeRatio
+	return evidence
+}
+
+// 记录种子文件列表中填充文件的大小
+func (e *GroupEvidence) recordPadding(torrent *transmissionrpc.Torrent, files []*transmissionrpc.TorrentFile) {
+	if torrent == nil || torrent.ID == nil {
+		return
+	}
+	if padding := paddingBytes(files); padding > 0 {
+		e.PaddingBytes[*torrent.ID] = padding
+	}
+}
+
+// 计算合集大小和分集大小之和（字节），不含 padding 中记录的填充文件
+func sizeSums(collection *transmissionrpc.Torrent, episodes []*transmissionrpc.Torrent, padding map[int64]int64) (float64, float64) {
+	collectionSize := float64(contentBytes(collection, padding))
+	var episodesSize float64
+	for _, episode := range episodes {
+		episodesSize += float64(contentBytes(episode, padding))
+	}
+	return collectionSize, episodesSize
+}
+
+// 按置信度从高到低排列组名，置信度相同时按名称排列
+func sortedGroupNames(duplicateGroups map[string]DuplicateGroup) []string {
+	names := make([]string, 0, len(duplicateGroups))
+	for name := range duplicateGroups {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		confidenceI, confidenceJ := duplicateGroups[names[i]].Confidence, duplicateGroups[names[j]].Confidence
+		if confidenceI != confidenceJ {
+			return confidenceI > confidenceJ
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// 把置信度低于阈值的组移到需人工确认的部分
+func demoteLowConfidence(result *ScanResult, minConfidence float64) {
+	if minConfidence <= 0 {
+		return
+	}
+	for name, group := range result.DuplicateGroups {
+		if group.Confidence < minConfidence {
+			delete(result.DuplicateGroups, name)
+			result.LowConfidenceGroups[name] = group
+		}
+	}
+}
+
+// 获取文件列表后收集证据并计算置信度，获取失败时置信度为0
+func fetchGroupEvidence(client *transmissionrpc.Client, collection *transmissionrpc.Torrent, episodes []*transmissionrpc.Torrent) (GroupEvidence, float64) {
+	collectionFiles, err := getTorrentFiles(client, collection.ID)
+	if err != nil {
+		return GroupEvidence{}, 0
+	}
+	var episodeFiles [][]*transmissionrpc.TorrentFile
+	for _, episode := range episodes {
+		files, err := getTorrentFiles(client, episode.ID)
+		if err != nil {
+			return GroupEvidence{}, 0
+		}
+		episodeFiles = append(episodeFiles, files)
+	}
+	evidence := collectGroupEvidence(collection, collectionFiles, episodes, episodeFiles)
+	return evidence, confidenceScore(evidence)
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+func TestConfidenceScore(t *testing.T) {
+	tests := []struct {
+		name     string
+		evidence GroupEvidence
+		want     float64
+	}{
+		{"完全包含且标识一致", GroupEvidence{Containment: 1, HasMarkers: true, MarkersAgree: true, SizeConsistent: true}, 1},
+		{"标识不一致", GroupEvidence{Containment: 1, HasMarkers: true, SizeConsistent: true}, 0.75},
+		{"没有剧集标识", GroupEvidence{Containment: 0.55, SizeConsistent: true}, 0.58},
+		{"大小之和超过合集", GroupEvidence{Containment: 1, HasMarkers: true, MarkersAgree: true}, 0.85},
+		{"大小比例超过上限", GroupEvidence{Containment: 1, HasMarkers: true, MarkersAgree: true, SizeConsistent: true, RatioExceeded: true}, 0.85},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := confidenceScore(tt.evidence); math.Abs(got-tt.want) > 0.005 {
+				t.Errorf("confidenceScore() = %.3f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectGroupEvidenceSizeTolerance(t *testing.T) {
+	collectionFiles := []testFile{
+		{"Show.S01/Show.S01E01.mkv", 1_000_000},
+		{"Show.S01/Show.S01E02.mkv", 1_000_000},
+	}
+	tests := []struct {
+		name      string
+		tolerance int64
+		excess    int64 // 分集比合集中对应文件多出的字节数
+		want      bool
+	}{
+		{"大小相同", DEFAULT_SIZE_TOLERANCE, 0, true},
+		{"超出部分在默认误差内", DEFAULT_SIZE_TOLERANCE, 1024, true},
+		{"超出默认误差", DEFAULT_SIZE_TOLERANCE, 1025, false},
+		{"调大误差", 4096, 4000, true},
+		{"不允许误差", 0, 1, false},
+	}
+	defer setSizeTolerance(DEFAULT_SIZE_TOLERANCE)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setSizeTolerance(tt.tolerance)
+			collection := testTorrent(1, "Show.S01", collectionFiles...)
+			episode := []testFile{{"Show.S01E01/Show.S01E01.mkv", 1_000_000 + tt.excess}}
+			evidence := collectGroupEvidence(collection, torrentFiles(collectionFiles...),
+				[]*transmissionrpc.Torrent{testTorrent(2, "Show.S01E01", episode...), testTorrent(3, "Show.S01E02", collectionFiles[1])},
+				[][]*transmissionrpc.TorrentFile{torrentFiles(episode...), torrentFiles(collectionFiles[1])})
+			if evidence.SizeConsistent != tt.want {
+				t.Errorf("SizeConsistent = %v, want %v", evidence.SizeConsistent, tt.want)
+			}
+		})
+	}
+}
+
+func TestMarkerAgreement(t *testing.T) {
+	collection := torrentFiles(
+		testFile{"Show.S01/Show.S01E01.mkv", 1000},
+		testFile{"Show.S01/Show.S01E02.mkv", 1000},
+	)
+	tests := []struct {
+		name       string
+		episode    []testFile
+		hasMarkers bool
+		agree      bool
+	}{
+		{"标识一致", []testFile{{"E01/Show.S01E01.mkv", 1000}}, true, true},
+		{"合集中没有的标识", []testFile{{"E03/Show.S01E03.mkv", 1000}}, true, false},
+		{"部分标识不一致", []testFile{{"E02-E03/Show.S01E02.mkv", 1000}, {"E02-E03/Show.S01E03.mkv", 1000}}, true, false},
+		{"没有剧集标识", []testFile{{"Show/episode.mkv", 1000}}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hasMarkers, agree := markerAgreement(collection, torrentFiles(tt.episode...))
+			if hasMarkers != tt.hasMarkers || agree != tt.agree {
+				t.Errorf("markerAgreement() = %v, %v, want %v, %v", hasMarkers, agree, tt.hasMarkers, tt.agree)
+			}
+		})
+	}
+}
+
+func TestSortedGroupNames(t *testing.T) {
+	groups := map[string]DuplicateGroup{
+		"B": {Confidence: 0.9},
+		"A": {Confidence: 0.9},
+		"C": {Confidence: 1},
+		"D": {Confidence: 0.58},
+	}
+	want := []string{"C", "A", "B", "D"}
+	if got := sortedGroupNames(groups); !reflect.DeepEqual(got, want) {
+		t.Errorf("sortedGroupNames() = %v, want %v", got, want)
+	}
+}
+
+func TestDemoteLowConfidence(t *testing.T) {
+	tests := []struct {
+		name          string
+		minConfidence float64
+		actionable    []string
+		lowConfidence []string
+	}{
+		{"不限制", 0, []string{"certain", "likely", "sketchy"}, nil},
+		{"阈值 0.8", 0.8, []string{"certain", "likely"}, []string{"sketchy"}},
+		{"等于阈值的组保留", 0.85, []string{"certain", "likely"}, []string{"sketchy"}},
+		{"阈值 1", 1, []string{"certain"}, []string{"likely", "sketchy"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := &ScanResult{
+				DuplicateGroups: map[string]DuplicateGroup{
+					"certain": {Confidence: 1},
+					"likely":  {Confidence: 0.85},
+					"sketchy": {Confidence: 0.58},
+				},
+				LowConfidenceGroups: make(map[string]DuplicateGroup),
+			}
+			demoteLowConfidence(result, tt.minConfidence)
+			actionable := sortedGroupNames(result.DuplicateGroups)
+			lowConfidence := sortedGroupNames(result.LowConfidenceGroups)
+			sort.Strings(actionable)
+			sort.Strings(lowConfidence)
+			if len(lowConfidence) == 0 {
+				lowConfidence = nil
+			}
+			if !reflect.DeepEqual(actionable, tt.actionable) || !reflect.DeepEqual(lowConfidence, tt.lowConfidence) {
+				t.Errorf("需要处理 %v、需人工确认 %v，应为 %v、%v", actionable, lowConfidence, tt.actionable, tt.lowConfidence)
+			}
+		})
+	}
+}
+
+// generic.json: 40 集的大合集与同名的小种子只有通用文件名 episode.mkv 和 cover.jpg 相同
+func TestSizeRatioFixture(t *testing.T) {
+	const name = "Show.G.S01.1080p.WEB"
+	client, result, opts := scanFixture(t, "generic.json")
+	group, ok := result.LowConfidenceGroups[name]
+	if !ok || len(result.DuplicateGroups) != 0 {
+		t.Fatalf("大小比例异常的组应移到需人工确认，需要处理的组: %v", sortedGroupNames(result.DuplicateGroups))
+	}
+	if !group.Evidence.RatioExceeded || group.Evidence.SizeRatio < 1000 {
+		t.Errorf("合集是分集的 %.1f 倍，应超过上限", group.Evidence.SizeRatio)
+	}
+
+	var report bytes.Buffer
+	printReport(&report, fetchReportFiles(client, result.DuplicateGroups), result, opts.Action, false)
+	for _, want := range []string{"--- 需人工确认（1 组", "合集是分集大小之和的 1587.6 倍，超过上限 30 倍，需人工确认"} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("报告中没有 %q:\n%s", want, report.String())
+		}
+	}
+
+	// 不检查比例时同一组的置信度更高，作为普通的组处理
+	_, unbounded, _ := scanFixture(t, "generic.json", "--max-size-ratio", "0")
+	plain, ok := unbounded.DuplicateGroups[name]
+	if !ok {
+		t.Fatal("--max-size-ratio 0 时应作为需要处理的组")
+	}
+	if plain.Confidence <= group.Confidence {
+		t.Errorf("不检查比例时置信度 %.2f，应高于超过上限时的 %.2f", plain.Confidence, group.Confidence)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import "bufio"
+
+// 其他平台的终端默认使用UTF-8，不需要设置
+func setupConsole() {}
+
+// 读取密码
+func readPassword(reader *bufio.Reader) string {
+	password, _ := reader.ReadString('\n')
+	return password
+}
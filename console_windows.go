@@ -0,0 +1,45 @@
+//go:build windows
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+var (
+	kernel32               = syscall.NewLazyDLL("kernel32.dll")
+	procSetConsoleCP       = kernel32.NewProc("SetConsoleCP")
+	procSetConsoleOutputCP = kernel32.NewProc("SetConsoleOutputCP")
+	procSetConsoleMode     = kernel32.NewProc("SetConsoleMode")
+)
+
+const (
+	CP_UTF8           = 65001  // UTF-8 代码页
+	ENABLE_ECHO_INPUT = 0x0004 // 控制台输入回显
+)
+
+// 把控制台的输入输出代码页设为UTF-8，避免中文显示为乱码
+func setupConsole() {
+	procSetConsoleOutputCP.Call(CP_UTF8)
+	procSetConsoleCP.Call(CP_UTF8)
+}
+
+// 读取密码，输入时关闭控制台回显；标准输入不是控制台（如重定向）时按普通输入读取
+func readPassword(reader *bufio.Reader) string {
+	handle := syscall.Handle(os.Stdin.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		password, _ := reader.ReadString('\n')
+		return password
+	}
+
+	procSetConsoleMode.Call(uintptr(handle), uintptr(mode&^ENABLE_ECHO_INPUT))
+	defer procSetConsoleMode.Call(uintptr(handle), uintptr(mode))
+	password, _ := reader.ReadString('\n')
+	// 回显关闭时回车不会换行
+	fmt.Println()
+	return password
+}
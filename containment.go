@@ -0,0 +1,123 @@
+package main
+
+import (
+	"path"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 辅助文件扩展名，这些文件不计入内容文件
+var auxiliaryExtensions = map[string]bool{
+	".nfo":  true,
+	".txt":  true,
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+	".url":  true,
+	".sfv":  true,
+	".md5":  true,
+	".db":   true,
+}
+
+// 字幕文件扩展名，比较文件数量时与辅助文件一样视为附加文件
+var subtitleExtensions = map[string]bool{
+	".srt": true,
+	".ass": true,
+	".ssa": true,
+	".sub": true,
+	".idx": true,
+	".sup": true,
+	".vtt": true,
+}
+
+// 比较文件数量时，分集最多可以比合集多出的附加文件（辅助文件和字幕）数量
+var extraFileTolerance = DEFAULT_EXTRA_FILE_TOLERANCE
+
+// 默认的附加文件容差
+const DEFAULT_EXTRA_FILE_TOLERANCE = 5
+
+// 设置附加文件容差
+func setExtraFileTolerance(tolerance int) {
+	extraFileTolerance = tolerance
+}
+
+// 只有部分内容包含在合集中的分集
+type PartialEpisode struct {
+	Episode        *transmissionrpc.Torrent
+	UncoveredFiles []string // 合集中找不到的内容文件
+}
+
+// 判断是否为辅助文件（说明、图片、校验文件和样片）
+func isAuxiliaryFile(filePath string) bool {
+	lowerPath := strings.ToLower(filePath)
+	if auxiliaryExtensions[path.Ext(lowerPath)] {
+		return true
+	}
+	for _, part := range strings.FieldsFunc(lowerPath, func(r rune) bool { return r == '/' || r == '\\' }) {
+		if part == "sample" || part == "samples" {
+			return true
+		}
+	}
+	return strings.Contains(getFileName(lowerPath), "sample")
+}
+
+// 过滤掉辅助文件和填充文件，只保留内容文件
+func contentFiles(files []*transmissionrpc.TorrentFile) []*transmissionrpc.TorrentFile {
+	var result []*transmissionrpc.TorrentFile
+	for _, file := range files {
+		if !isAuxiliaryFile(file.Name) && !isPaddingFile(file) {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// 比较文件数量时的主要文件：去掉辅助文件和字幕
+func primaryFiles(files []*transmissionrpc.TorrentFile) []*transmissionrpc.TorrentFile {
+	var result []*transmissionrpc.TorrentFile
+	for _, file := range contentFiles(files) {
+		if !subtitleExtensions[path.Ext(strings.ToLower(file.Name))] {
+			result = append(result, file)
+		}
+	}
+	return result
+}
+
+// 文件数量是否可能是合集与分集的关系：合集的主要文件不少于分集，
+// 分集比合集多出的附加文件（样片、说明、字幕等）不超过容差；填充文件不计入
+func fileCountCompatible(collectionFiles, episodeFiles []*transmissionrpc.TorrentFile) bool {
+	collectionFiles, _ = withoutPadding(collectionFiles)
+	episodeFiles, _ = withoutPadding(episodeFiles)
+	collectionPrimary := len(primaryFiles(collectionFiles))
+	episodePrimary := len(primaryFiles(episodeFiles))
+	if collectionPrimary < episodePrimary {
+		return false
+	}
+	extra := (len(episodeFiles) - episodePrimary) - (len(collectionFiles) - collectionPrimary)
+	return extra <= extraFileTolerance
+}
+
+// 返回分集中在合集里找不到的主要文件。字幕与样片、说明一样是附加文件，数量由 --extra-file-tolerance 限制，
+// 合集不带字幕时不算未包含；分集只有字幕等附加文件时按全部内容文件比较
+func uncoveredEpisodeFiles(collectionFiles, episodeFiles []*transmissionrpc.TorrentFile) []string {
+	required := primaryFiles(episodeFiles)
+	if len(required) == 0 {
+		required = contentFiles(episodeFiles)
+	}
+	var uncovered []string
+	for _, episodeFile := range required {
+		found := false
+		for _, collectionFile := range collectionFiles {
+			if fileNamesMatch(episodeFile.Name, collectionFile.Name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			uncovered = append(uncovered, episodeFile.Name)
+		}
+	}
+	return uncovered
+}
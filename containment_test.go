@@ -0,0 +1,142 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUncoveredEpisodeFiles(t *testing.T) {
+	leanPack := torrentFiles(
+		testFile{"Show.S01/Show.S01E01.mkv", 1000},
+		testFile{"Show.S01/Show.S01E02.mkv", 1000},
+		testFile{"Show.S01/Show.S01E03.mkv", 1000},
+	)
+	tests := []struct {
+		name       string
+		collection []testFile
+		episode    []testFile
+		want       []string
+	}{
+		{
+			name:    "字幕和样片不要求包含在合集中",
+			episode: []testFile{{"Show.S01E02/Show.S01E02.mkv", 1000}, {"Show.S01E02/Show.S01E02.srt", 10}, {"Show.S01E02/Sample/sample.mkv", 50}},
+		},
+		{
+			name:    "合集缺少的视频文件",
+			episode: []testFile{{"Show.S01E02/Show.S01E02.mkv", 1000}, {"Show.S01E02/Show.S01E04.mkv", 1000}},
+			want:    []string{"Show.S01E02/Show.S01E04.mkv"},
+		},
+		{
+			name:       "只包含部分分集的合集",
+			collection: []testFile{{"Show.S01/Show.S01E01.mkv", 1000}},
+			episode:    []testFile{{"Show.S01E01-E02/Show.S01E01.mkv", 1000}, {"Show.S01E01-E02/Show.S01E02.mkv", 1000}},
+			want:       []string{"Show.S01E01-E02/Show.S01E02.mkv"},
+		},
+		{
+			name:    "只有字幕的分集按字幕比较",
+			episode: []testFile{{"Show.S01E02.srt", 10}},
+			want:    []string{"Show.S01E02.srt"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := leanPack
+			if tt.collection != nil {
+				collection = torrentFiles(tt.collection...)
+			}
+			got := uncoveredEpisodeFiles(collection, torrentFiles(tt.episode...))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("uncoveredEpisodeFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileCountCompatible(t *testing.T) {
+	leanPack := []testFile{
+		{"Show.S01/Show.S01E01.mkv", 1000},
+		{"Show.S01/Show.S01E02.mkv", 1000},
+		{"Show.S01/Show.S01E03.mkv", 1000},
+	}
+	tests := []struct {
+		name       string
+		tolerance  int
+		collection []testFile
+		episode    []testFile
+		want       bool
+	}{
+		{
+			name:       "分集带字幕和样片",
+			tolerance:  DEFAULT_EXTRA_FILE_TOLERANCE,
+			collection: leanPack,
+			episode:    []testFile{{"E02/Show.S01E02.mkv", 1000}, {"E02/Show.S01E02.srt", 10}, {"E02/Sample/sample.mkv", 50}},
+			want:       true,
+		},
+		{
+			name:       "附加文件超出容差",
+			tolerance:  1,
+			collection: leanPack,
+			episode:    []testFile{{"E02/Show.S01E02.mkv", 1000}, {"E02/Show.S01E02.srt", 10}, {"E02/Sample/sample.mkv", 50}},
+			want:       false,
+		},
+		{
+			name:       "分集的主要文件多于合集",
+			tolerance:  DEFAULT_EXTRA_FILE_TOLERANCE,
+			collection: []testFile{{"Show.S01/Show.S01E01.mkv", 1000}},
+			episode:    []testFile{{"E01/Show.S01E01.mkv", 1000}, {"E01/Show.S01E02.mkv", 1000}},
+			want:       false,
+		},
+		{
+			name:       "填充文件不计入",
+			tolerance:  0,
+			collection: leanPack,
+			episode:    []testFile{{"E02/Show.S01E02.mkv", 1000}, {"E02/.pad/0", 0}},
+			want:       true,
+		},
+	}
+	defer setExtraFileTolerance(DEFAULT_EXTRA_FILE_TOLERANCE)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setExtraFileTolerance(tt.tolerance)
+			got := fileCountCompatible(torrentFiles(tt.collection...), torrentFiles(tt.episode...))
+			if got != tt.want {
+				t.Errorf("fileCountCompatible() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 双集分集（E01+E02）与只有 E01 的合集：默认报告为部分包含，关闭 --require-full-containment 时按重叠比例处理
+func TestRequireFullContainment(t *testing.T) {
+	const groupName = "Show.C.S01.720p.WEB"
+	tests := []struct {
+		name       string
+		args       []string
+		actionable bool
+	}{
+		{"默认要求全部包含", nil, false},
+		{"不要求全部包含", []string{"--require-full-containment=false"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, result, _ := scanFixture(t, "scan.json", tt.args...)
+			if _, ok := result.DuplicateGroups[groupName]; ok != tt.actionable {
+				t.Fatalf("%s 在需要处理的组中: %t，应为 %t", groupName, ok, tt.actionable)
+			}
+			if tt.actionable {
+				return
+			}
+			group, ok := result.PartialGroups[groupName]
+			if !ok {
+				t.Fatalf("%s 应在部分包含的组中", groupName)
+			}
+			if len(group.PartialEpisodes) != 1 {
+				t.Fatalf("部分包含的分集 %d 个，应为 1 个", len(group.PartialEpisodes))
+			}
+			want := []string{groupName + "/Show.C.S01E02.720p.WEB.mkv"}
+			if got := group.PartialEpisodes[0].UncoveredFiles; !reflect.DeepEqual(got, want) {
+				t.Errorf("合集中找不到的文件 = %v，应为 %v", got, want)
+			}
+		})
+	}
+}
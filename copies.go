@@ -0,0 +1,106 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 分集的文件列表签名：全部文件的路径和大小，文件列表和大小都相同的分集签名相同
+func episodeSignature(files []*transmissionrpc.TorrentFile) string {
+	entries := make([]string, 0, len(files))
+	for _, file := range files {
+		entries = append(entries, fmt.Sprintf("%s\x00%d", file.Name, file.Length))
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, "\x00\x00")
+}
+
+// 判断分集是否完全相同的签名：大小和文件列表签名都相同
+func copySignature(episode *transmissionrpc.Torrent, files []*transmissionrpc.TorrentFile) string {
+	return fmt.Sprintf("%d\x00%s", torrentBytes(episode), episodeSignature(files))
+}
+
+// 去掉完全相同的分集，只保留第一个；episodeFiles 与 episodes 一一对应，没有文件列表的分集都保留
+func distinctEpisodes(episodes []*transmissionrpc.Torrent, episodeFiles [][]*transmissionrpc.TorrentFile) []*transmissionrpc.Torrent {
+	seen := make(map[string]bool)
+	distinct := make([]*transmissionrpc.Torrent, 0, len(episodes))
+	for i, episode := range episodes {
+		if episode != nil && i < len(episodeFiles) && len(episodeFiles[i]) > 0 {
+			signature := copySignature(episode, episodeFiles[i])
+			if seen[signature] {
+				continue
+			}
+			seen[signature] = true
+		}
+		distinct = append(distinct, episode)
+	}
+	return distinct
+}
+
+// 找出组内完全相同的分集（如同一集的两个辅种），后出现的记为第一个分集的副本。
+// 副本照常执行操作，报告中合并为一行，可释放空间只计算一次（辅种可能共用数据）
+func applyIdenticalEpisodes(client *transmissionrpc.Client, result *ScanResult) {
+	for name, group := range result.DuplicateGroups {
+		firstBySignature := make(map[string]int64)
+		for _, episode := range group.Episodes {
+			if episode == nil || episode.ID == nil || episode.SizeWhenDone == nil {
+				continue
+			}
+			files, err := getTorrentFiles(client, episode.ID)
+			if err != nil || len(files) == 0 {
+				continue
+			}
+			signature := copySignature(episode, files)
+			first, ok := firstBySignature[signature]
+			if !ok {
+				firstBySignature[signature] = *episode.ID
+				continue
+			}
+			if group.EpisodeCopies == nil {
+				group.EpisodeCopies = make(map[int64]int64)
+			}
+			group.EpisodeCopies[*episode.ID] = first
+		}
+		result.DuplicateGroups[name] = group
+	}
+}
+
+// 分集是否为组内另一个仍需处理的分集的副本，返回该分集的ID
+func (g DuplicateGroup) copyOf(episode *transmissionrpc.Torrent) (int64, bool) {
+	if episode == nil || episode.ID == nil {
+		return 0, false
+	}
+	first, ok := g.EpisodeCopies[*episode.ID]
+	if !ok {
+		return 0, false
+	}
+	for _, other := range g.Episodes {
+		if other != nil && other.ID != nil && *other.ID == first {
+			return first, true
+		}
+	}
+	return 0, false
+}
+
+// 仍需处理的分集中与该分集相同的副本的ID
+func (g DuplicateGroup) copiesOf(episode *transmissionrpc.Torrent) []int64 {
+	var copies []int64
+	for _, other := range g.Episodes {
+		if first, ok := g.copyOf(other); ok && episode.ID != nil && first == *episode.ID {
+			copies = append(copies, *other.ID)
+		}
+	}
+	return copies
+}
+
+// 报告中副本的说明，如 "x2 副本 (ID: 12, 15)"
+func describeCopies(episode *transmissionrpc.Torrent, copies []int64) string {
+	ids := []string{torrentIDText(episode)}
+	for _, id := range copies {
+		ids = append(ids, fmt.Sprint(id))
+	}
+	return fmt.Sprintf("x%d 副本 (ID: %s)", len(ids), strings.Join(ids, ", "))
+}
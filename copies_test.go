@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// 在重放数据中加入种子 from 的辅种：文件列表和大小相同，ID和hash不同
+func copyReplayTorrent(t *testing.T, from, id int64, hash string) {
+	t.Helper()
+	fromText, _ := json.Marshal(from)
+	for _, torrent := range dumpReplay.Torrents {
+		if string(torrent["id"]) != string(fromText) {
+			continue
+		}
+		duplicate := make(map[string]json.RawMessage, len(torrent))
+		for field, value := range torrent {
+			duplicate[field] = value
+		}
+		duplicate["id"], _ = json.Marshal(id)
+		duplicate["hashString"], _ = json.Marshal(hash)
+		dumpReplay.Torrents = append(dumpReplay.Torrents, duplicate)
+		return
+	}
+	t.Fatalf("重放数据中没有种子 ID: %d", from)
+}
+
+// scan.json 中 Show.A 的分集 ID 2 有一个完全相同的辅种 ID 12
+func TestIdenticalEpisodesFixture(t *testing.T) {
+	const name = "Show.A.S01.1080p.WEB-DL"
+	client, opts := connectFixture(t, "scan.json")
+	before, err := scan(client, detectCapabilities(client), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reclaim := groupReclaimBytes(before.DuplicateGroups[name])
+
+	copyReplayTorrent(t, 2, 12, "c0ffee0000000000000000000000000000000012")
+	result, err := scan(client, detectCapabilities(client), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	group := result.DuplicateGroups[name]
+	if len(group.Episodes) != 3 {
+		t.Fatalf("分集 %v，辅种应照常作为分集", splitIDs(result.DuplicateGroups)[name])
+	}
+	if first, ok := group.EpisodeCopies[12]; !ok || first != 2 {
+		t.Errorf("副本 %v，ID 12 应为 ID 2 的副本", group.EpisodeCopies)
+	}
+	if got := groupReclaimBytes(group); got != reclaim {
+		t.Errorf("可释放 %d 字节，副本只计算一次，应与没有副本时相同: %d 字节", got, reclaim)
+	}
+
+	var report bytes.Buffer
+	printReport(&report, fetchReportFiles(client, result.DuplicateGroups), result, opts.Action, false)
+	if !strings.Contains(report.String(), "x2 副本 (ID: 2, 12)") {
+		t.Errorf("报告中没有合并副本的一行:\n%s", report.String())
+	}
+	if strings.Contains(report.String(), "ID: 12, 大小") {
+		t.Errorf("副本不应单独列为一行:\n%s", report.String())
+	}
+
+	var top bytes.Buffer
+	printTopGroups(&top, result, []string{name}, 1)
+	if want := "可释放 " + formatSize(reclaim); !strings.Contains(top.String(), want) {
+		t.Errorf("排名列表中没有 %q:\n%s", want, top.String())
+	}
+}
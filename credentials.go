@@ -0,0 +1,196 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// 连接参数的环境变量
+const (
+	ENV_HOST     = "TRANSMISSION_HOST"
+	ENV_PORT     = "TRANSMISSION_PORT"
+	ENV_USER     = "TRANSMISSION_USER"
+	ENV_PASSWORD = "TRANSMISSION_PASSWORD"
+	ENV_HTTPS    = "TRANSMISSION_HTTPS"
+)
+
+// 连接参数的来源，优先级: 命令行参数 > 环境变量 > netrc > 交互输入
+const (
+	SOURCE_FLAG    = "命令行参数"
+	SOURCE_ENV     = "环境变量"
+	SOURCE_NETRC   = "netrc"
+	SOURCE_PROMPT  = "交互输入"
+	SOURCE_DEFAULT = "默认值"
+)
+
+// netrc 文件中的一个条目，Machine 为空表示 default 条目
+type NetrcEntry struct {
+	Machine  string
+	Login    string
+	Password string
+}
+
+// netrc 文件的位置：NETRC 环境变量，否则为主目录下的 .netrc（Windows 为 _netrc）
+func netrcPath(getenv func(string) string) string {
+	if path := getenv("NETRC"); path != "" {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	name := ".netrc"
+	if filepath.Separator == '\\' {
+		name = "_netrc"
+	}
+	return filepath.Join(home, name)
+}
+
+// 解析 netrc 文件的 machine、default、login 和 password，忽略 account 和 macdef
+func parseNetrc(data string) []NetrcEntry {
+	var entries []NetrcEntry
+	var current *NetrcEntry
+	fields := strings.Fields(data)
+	for i := 0; i < len(fields); i++ {
+		next := func() string {
+			if i+1 < len(fields) {
+				i++
+				return fields[i]
+			}
+			return ""
+		}
+		switch fields[i] {
+		case "machine":
+			entries = append(entries, NetrcEntry{Machine: next()})
+			current = &entries[len(entries)-1]
+		case "default":
+			entries = append(entries, NetrcEntry{})
+			current = &entries[len(entries)-1]
+		case "login":
+			if value := next(); current != nil {
+				current.Login = value
+			}
+		case "password":
+			if value := next(); current != nil {
+				current.Password = value
+			}
+		case "account":
+			next()
+		case "macdef":
+			// 宏定义到空行为止，strings.Fields 已丢弃空行，之后的条目无法可靠区分，停止解析
+			return entries
+		}
+	}
+	return entries
+}
+
+// 查找与服务器地址匹配的条目，没有时使用 default 条目
+func lookupNetrc(entries []NetrcEntry, host string) (NetrcEntry, bool) {
+	for _, entry := range entries {
+		if entry.Machine != "" && strings.EqualFold(entry.Machine, host) {
+			return entry, true
+		}
+	}
+	for _, entry := range entries {
+		if entry.Machine == "" {
+			return entry, true
+		}
+	}
+	return NetrcEntry{}, false
+}
+
+// 按优先级确定连接参数：命令行参数 > 环境变量 > netrc。flagsSet 为通过命令行指定的参数名，
+// netrc 为空时不读取 netrc。返回是否有参数来自命令行、环境变量或 netrc（此时不再提示输入）
+func resolveConnection(params *ConnectionParams, flagsSet map[string]bool, getenv func(string) string, netrc []NetrcEntry) (bool, error) {
+	params.Sources = make(map[string]string)
+	provided := false
+	source := func(name string) string {
+		if flagsSet[name] {
+			provided = true
+			return SOURCE_FLAG
+		}
+		return ""
+	}
+
+	for _, field := range []struct {
+		Flag  string
+		Env   string
+		Apply func(string) error
+	}{
+		{"host", ENV_HOST, func(value string) error { params.Address = value; return nil }},
+		{"port", ENV_PORT, func(value string) error {
+			port, err := strconv.Atoi(value)
+			if err != nil || port <= 0 {
+				return fmt.Errorf("无效的 %s: %s", ENV_PORT, value)
+			}
+			params.Port = port
+			return nil
+		}},
+		{"https", ENV_HTTPS, func(value string) error {
+			https, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("无效的 %s: %s（可选: true, false）", ENV_HTTPS, value)
+			}
+			params.HTTPS = https
+			return nil
+		}},
+		{"user", ENV_USER, func(value string) error { params.Username = value; return nil }},
+		{"password", ENV_PASSWORD, func(value string) error { params.Password = value; return nil }},
+	} {
+		if from := source(field.Flag); from != "" {
+			params.Sources[field.Flag] = from
+			continue
+		}
+		value := getenv(field.Env)
+		if value == "" {
+			params.Sources[field.Flag] = SOURCE_DEFAULT
+			continue
+		}
+		if err := field.Apply(value); err != nil {
+			return false, err
+		}
+		params.Sources[field.Flag] = SOURCE_ENV
+		provided = true
+	}
+
+	if entry, ok := lookupNetrc(netrc, params.Address); ok {
+		if params.Sources["user"] == SOURCE_DEFAULT && entry.Login != "" {
+			params.Username = entry.Login
+			params.Sources["user"] = SOURCE_NETRC
+			provided = true
+		}
+		if params.Sources["password"] == SOURCE_DEFAULT && entry.Password != "" {
+			params.Password = entry.Password
+			params.Sources["password"] = SOURCE_NETRC
+			provided = true
+		}
+	}
+	if flagsSet["unix-socket"] {
+		provided = true
+	}
+	return provided, nil
+}
+
+// 读取 --netrc 指定使用的 netrc 文件
+func loadNetrc(getenv func(string) string) ([]NetrcEntry, error) {
+	path := netrcPath(getenv)
+	if path == "" {
+		return nil, fmt.Errorf("无法确定 netrc 文件的位置")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 netrc 文件失败: %v", err)
+	}
+	return parseNetrc(string(data)), nil
+}
+
+// 连接参数的来源说明，来源未知时为空
+func (params ConnectionParams) sourceHint(name string) string {
+	if source := params.Sources[name]; source != "" {
+		return fmt.Sprintf("（来源: %s）", source)
+	}
+	return ""
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNetrc(t *testing.T) {
+	data := `machine seedbox.example login alice password s3cret
+machine other.example
+  login bob
+  account ignored
+  password hunter2
+default login guest password guest
+macdef init
+  echo ignored
+machine after.macdef login mallory password x`
+	want := []NetrcEntry{
+		{Machine: "seedbox.example", Login: "alice", Password: "s3cret"},
+		{Machine: "other.example", Login: "bob", Password: "hunter2"},
+		{Login: "guest", Password: "guest"},
+	}
+	if got := parseNetrc(data); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseNetrc() = %+v, want %+v", got, want)
+	}
+}
+
+func TestLookupNetrc(t *testing.T) {
+	entries := []NetrcEntry{
+		{Login: "guest", Password: "guest"},
+		{Machine: "Seedbox.Example", Login: "alice", Password: "s3cret"},
+	}
+	tests := []struct {
+		host  string
+		login string
+		ok    bool
+	}{
+		{"seedbox.example", "alice", true},
+		{"unknown.example", "guest", true},
+	}
+	for _, tt := range tests {
+		entry, ok := lookupNetrc(entries, tt.host)
+		if ok != tt.ok || entry.Login != tt.login {
+			t.Errorf("lookupNetrc(%q) = %q, %t, want %q, %t", tt.host, entry.Login, ok, tt.login, tt.ok)
+		}
+	}
+	if _, ok := lookupNetrc(entries[1:], "unknown.example"); ok {
+		t.Error("没有 default 条目时不应匹配其他主机")
+	}
+}
+
+func TestResolveConnectionPrecedence(t *testing.T) {
+	netrc := []NetrcEntry{{Machine: "seedbox.example", Login: "netrc-user", Password: "netrc-pass"}}
+	tests := []struct {
+		name     string
+		flags    map[string]string // 命令行指定的参数及其值
+		env      map[string]string
+		netrc    []NetrcEntry
+		want     ConnectionParams
+		sources  map[string]string
+		provided bool
+	}{
+		{
+			name:     "全部默认",
+			want:     ConnectionParams{Address: "localhost", Port: 9091},
+			sources:  map[string]string{"host": SOURCE_DEFAULT, "port": SOURCE_DEFAULT, "https": SOURCE_DEFAULT, "user": SOURCE_DEFAULT, "password": SOURCE_DEFAULT},
+			provided: false,
+		},
+		{
+			name:     "环境变量",
+			env:      map[string]string{ENV_HOST: "seedbox.example", ENV_PORT: "443", ENV_HTTPS: "true", ENV_USER: "env-user", ENV_PASSWORD: "env-pass"},
+			want:     ConnectionParams{Address: "seedbox.example", Port: 443, HTTPS: true, Username: "env-user", Password: "env-pass"},
+			sources:  map[string]string{"host": SOURCE_ENV, "port": SOURCE_ENV, "https": SOURCE_ENV, "user": SOURCE_ENV, "password": SOURCE_ENV},
+			provided: true,
+		},
+		{
+			name:     "命令行优先于环境变量",
+			flags:    map[string]string{"user": "flag-user", "password": "flag-pass"},
+			env:      map[string]string{ENV_USER: "env-user", ENV_PASSWORD: "env-pass", ENV_PORT: "9092"},
+			want:     ConnectionParams{Address: "localhost", Port: 9092, Username: "flag-user", Password: "flag-pass"},
+			sources:  map[string]string{"host": SOURCE_DEFAULT, "port": SOURCE_ENV, "https": SOURCE_DEFAULT, "user": SOURCE_FLAG, "password": SOURCE_FLAG},
+			provided: true,
+		},
+		{
+			name:     "环境变量优先于 netrc",
+			env:      map[string]string{ENV_HOST: "seedbox.example", ENV_USER: "env-user"},
+			netrc:    netrc,
+			want:     ConnectionParams{Address: "seedbox.example", Port: 9091, Username: "env-user", Password: "netrc-pass"},
+			sources:  map[string]string{"host": SOURCE_ENV, "port": SOURCE_DEFAULT, "https": SOURCE_DEFAULT, "user": SOURCE_ENV, "password": SOURCE_NETRC},
+			provided: true,
+		},
+		{
+			name:     "netrc 按命令行指定的主机匹配",
+			flags:    map[string]string{"host": "seedbox.example"},
+			env:      map[string]string{ENV_HOST: "other.example"},
+			netrc:    netrc,
+			want:     ConnectionParams{Address: "seedbox.example", Port: 9091, Username: "netrc-user", Password: "netrc-pass"},
+			sources:  map[string]string{"host": SOURCE_FLAG, "port": SOURCE_DEFAULT, "https": SOURCE_DEFAULT, "user": SOURCE_NETRC, "password": SOURCE_NETRC},
+			provided: true,
+		},
+		{
+			name:     "netrc 没有匹配的主机",
+			netrc:    netrc,
+			want:     ConnectionParams{Address: "localhost", Port: 9091},
+			sources:  map[string]string{"host": SOURCE_DEFAULT, "port": SOURCE_DEFAULT, "https": SOURCE_DEFAULT, "user": SOURCE_DEFAULT, "password": SOURCE_DEFAULT},
+			provided: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 命令行参数在解析时已写入，resolveConnection 只负责其余来源
+			params := ConnectionParams{Address: "localhost", Port: 9091}
+			flagsSet := make(map[string]bool)
+			for name, value := range tt.flags {
+				flagsSet[name] = true
+				switch name {
+				case "host":
+					params.Address = value
+				case "user":
+					params.Username = value
+				case "password":
+					params.Password = value
+				}
+			}
+			getenv := func(name string) string { return tt.env[name] }
+
+			provided, err := resolveConnection(&params, flagsSet, getenv, tt.netrc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if provided != tt.provided {
+				t.Errorf("provided = %t, want %t", provided, tt.provided)
+			}
+			sources := params.Sources
+			params.Sources = nil
+			if !reflect.DeepEqual(params, tt.want) {
+				t.Errorf("params = %+v, want %+v", params, tt.want)
+			}
+			if !reflect.DeepEqual(sources, tt.sources) {
+				t.Errorf("sources = %v, want %v", sources, tt.sources)
+			}
+		})
+	}
+}
+
+func TestResolveConnectionInvalidEnv(t *testing.T) {
+	for _, env := range []map[string]string{
+		{ENV_PORT: "abc"},
+		{ENV_PORT: "0"},
+		{ENV_HTTPS: "maybe"},
+	} {
+		params := ConnectionParams{Address: "localhost", Port: 9091}
+		getenv := func(name string) string { return env[name] }
+		if _, err := resolveConnection(&params, nil, getenv, nil); err == nil {
+			t.Errorf("环境变量 %v 应返回错误", env)
+		}
+	}
+}
+
+func TestSourceHintHidesPassword(t *testing.T) {
+	params := ConnectionParams{Password: "s3cret", Sources: map[string]string{"password": SOURCE_ENV}}
+	hint := params.sourceHint("password")
+	if strings.Contains(hint, "s3cret") || !strings.Contains(hint, SOURCE_ENV) {
+		t.Errorf("sourceHint() = %q，应只包含来源", hint)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 剪辑版本不同、不作为合集和分集处理的跳过原因
+const SKIP_CUT_MISMATCH = "cut_mismatch"
+
+// 一个剪辑版本标识：同一剧集的不同剪辑或来源（如无修正版和电视播出版）内容不同，文件名相近时也可能通过包含检查
+type CutToken struct {
+	Name  string
+	Regex *regexp.Regexp
+}
+
+// 默认的剪辑版本标识，格式与 --cut-token 相同
+var defaultCutTokenSpecs = []string{
+	`uncensored=(?i)\buncen(?:sored)?\b|无修|無修`,
+	`censored=(?i)\bcensored\b`,
+	`directors-cut=(?i)\bdirector'?s[ ._\-]?cut\b`,
+	`broadcast=(?i)\bbroadcast\b|\btv[ ._\-]?ver(?:sion)?\b`,
+	`bd=(?i)\b(?:bd|bdrip|bdmv|blu[ ._\-]?ray)\b`,
+	`web=(?i)\bweb(?:[ ._\-]?(?:dl|rip))?\b`,
+}
+
+// 当前生效的剪辑版本标识
+var cutTokens = mustParseCutTokens(defaultCutTokenSpecs)
+
+// 解析 "名称=正则" 格式的剪辑版本标识
+func parseCutToken(spec string) (CutToken, error) {
+	name, expr, found := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+	if !found || name == "" || expr == "" {
+		return CutToken{}, fmt.Errorf("剪辑版本标识格式应为 名称=正则: %s", spec)
+	}
+	regex, err := regexp.Compile(expr)
+	if err != nil {
+		return CutToken{}, fmt.Errorf("剪辑版本标识 %s 的正则无效: %v", name, err)
+	}
+	return CutToken{Name: name, Regex: regex}, nil
+}
+
+func mustParseCutTokens(specs []string) []CutToken {
+	var tokens []CutToken
+	for _, spec := range specs {
+		token, err := parseCutToken(spec)
+		if err != nil {
+			panic(err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// 设置剪辑版本标识，替换默认列表
+func setCutTokens(tokens []CutToken) {
+	cutTokens = tokens
+}
+
+// 种子的剪辑版本标识：种子名称和内容文件路径中出现的全部标识，按名称排序
+func torrentCutTokens(torrent *transmissionrpc.Torrent, files []*transmissionrpc.TorrentFile) []string {
+	names := make([]string, 0, len(files)+1)
+	if torrent != nil && torrent.Name != nil {
+		names = append(names, *torrent.Name)
+	}
+	for _, file := range contentFiles(files) {
+		names = append(names, file.Name)
+	}
+	var found []string
+	for _, token := range cutTokens {
+		for _, name := range names {
+			if token.Regex.MatchString(name) {
+				found = append(found, token.Name)
+				break
+			}
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// 双方的剪辑版本标识是否完全相同；一方有而另一方没有的标识也视为不同（如无修正版合集和未标注的分集）
+func cutTokensAgree(collectionTokens, episodeTokens []string) bool {
+	return strings.Join(collectionTokens, ",") == strings.Join(episodeTokens, ",")
+}
+
+// 剪辑版本标识的描述，没有时为 "无标识"
+func describeCutTokens(tokens []string) string {
+	if len(tokens) == 0 {
+		return "无标识"
+	}
+	return strings.Join(tokens, "/")
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTorrentCutTokens(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []testFile
+		want  []string
+	}{
+		{"Show.S01.Uncensored.1080p.WEB-DL", nil, []string{"uncensored", "web"}},
+		{"Show.S01.Censored.BDRip", nil, []string{"bd", "censored"}},
+		{"Show.S01", []testFile{{"Show.S01/无修/E01.mkv", 1}}, []string{"uncensored"}},
+		{"Show.S01", []testFile{{"Show.S01/Sample/censored.mkv", 1}}, nil},
+		{"Show.S01.Directors.Cut", nil, []string{"directors-cut"}},
+	}
+	for _, tt := range tests {
+		torrent := testTorrent(1, tt.name)
+		if got := torrentCutTokens(torrent, torrentFiles(tt.files...)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("torrentCutTokens(%s, %v) = %v, want %v", tt.name, tt.files, got, tt.want)
+		}
+	}
+}
+
+// cut.json: ID 1 为无修正版合集，ID 2 为文件名相同的有修正版分集，ID 3 为无修正版分集
+func TestCutMismatchFixture(t *testing.T) {
+	const name = "Show.U.S01.1080p.WEB"
+	for _, allow := range []bool{false, true} {
+		var args []string
+		if allow {
+			args = []string{"--allow-cross-cut"}
+		}
+		_, result, _ := scanFixture(t, "cut.json", args...)
+
+		want := []int64{3}
+		if allow {
+			want = []int64{2, 3}
+		}
+		if got := splitIDs(result.DuplicateGroups)[name]; !reflect.DeepEqual(got, want) {
+			t.Errorf("--allow-cross-cut=%v: 分集 %v，应为 %v", allow, got, want)
+		}
+
+		var skipped *SkipRecord
+		for i, record := range result.Skipped {
+			if record.Reason == SKIP_CUT_MISMATCH {
+				skipped = &result.Skipped[i]
+			}
+		}
+		if allow {
+			if skipped != nil {
+				t.Errorf("指定 --allow-cross-cut 后不应跳过: %s", skipped.Detail)
+			}
+			continue
+		}
+		if skipped == nil {
+			t.Fatal("有修正版分集 ID 2 应因版本不同而跳过")
+		}
+		if wantDetail := "版本不同，已跳过: 合集 ID: 1 (uncensored/web), 分集 ID: 2 (censored/web)"; skipped.Detail != wantDetail {
+			t.Errorf("跳过原因 %q，应为 %q", skipped.Detail, wantDetail)
+		}
+	}
+}
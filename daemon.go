@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 一轮守护扫描的统计，同时写入状态目录作为指标
+type CycleSummary struct {
+	Cycle                     int       `json:"cycle"`
+	Time                      time.Time `json:"time"`
+	TorrentCount              int       `json:"torrent_count"`
+	GroupCount                int       `json:"group_count"`
+	EpisodeCount              int       `json:"episode_count"`
+	SameSizeGroupCount        int       `json:"same_size_group_count"`
+	HandledGroupCount         int       `json:"handled_group_count"`          // 分集已全部暂停的组数量
+	DuplicateUploadDeltaBytes int64     `json:"duplicate_upload_delta_bytes"` // 重复分集自上次扫描以来的上传量
+	SpeedLimited              bool      `json:"speed_limited"`                // 扫描时会话是否处于上传限速状态
+	ActionsTaken              int       `json:"actions_taken"`
+	UnregisteredRemoved       int       `json:"unregistered_removed"` // 删除的已失效种子数量
+
+	SessionRenegotiations int64 `json:"session_renegotiations"` // 本轮重新协商会话ID的次数
+
+	StaleMagnetsRemoved int `json:"stale_magnets_removed"` // 删除的过期磁力链接数量
+
+	MissingDataRemoved int `json:"missing_data_removed"` // 删除的数据缺失分集数量
+
+	TwoPhase *TwoPhaseSummary `json:"two_phase,omitempty"` // 两阶段暂停的结果，未使用 --two-phase 时为空
+}
+
+// 守护模式：按间隔循环扫描，收到中断信号时退出
+func runDaemon(opts Options) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	printConnectionParams(opts.Connection)
+	fmt.Printf("种子名称筛选结尾: %s\n", describeSuffixFilters(opts.SuffixFilters))
+	fmt.Printf("操作: %s\n", actionName(opts.Action))
+	printPresetSettings(os.Stdout, opts.Preset, opts.PresetSettings)
+	printRuleSettings(os.Stdout, opts.Strictness, opts.Rules)
+	if !opts.Yes {
+		fmt.Println("未指定 --yes，守护模式只扫描和报告，不执行操作")
+	}
+	fmt.Printf("守护模式已启动，扫描间隔: %s\n", opts.Interval)
+	if opts.PauseWindow != nil {
+		fmt.Printf("暂停时段: %s，时段外只扫描，时段结束时重新开始时段内暂停的分集\n", opts.PauseWindow.describe())
+		enableWindowPauses()
+	}
+
+	client, err := connect(opts.Connection)
+	if err != nil {
+		log.Fatalf("无法连接到 Transmission 服务器%s: %v", opts.Connection.proxyHint(), err)
+	}
+	capabilities := detectCapabilities(client)
+	capabilities.require(opts)
+
+	api := newAPIServer(opts)
+	api.start(ctx)
+
+	// 等待期间定期查询最近活动的种子，没有变化时跳过下一轮的分析
+	watcher := newChangeWatcher(opts.SkipUnchanged)
+	var polls <-chan time.Time
+	if watcher != nil {
+		ticker := time.NewTicker(RECENTLY_ACTIVE_POLL)
+		defer ticker.Stop()
+		polls = ticker.C
+	}
+	var lastInWindow bool
+
+	for cycle := 1; ; cycle++ {
+		fmt.Printf("\n===== 第 %d 轮扫描 (%s) =====\n", cycle, time.Now().Format("2006-01-02 15:04:05"))
+		// 进入或离开暂停时段时需要暂停或重新开始分集
+		if opts.PauseWindow != nil {
+			inWindow := opts.PauseWindow.contains(time.Now())
+			if cycle > 1 && inWindow != lastInWindow {
+				watcher.markDirty("进入或离开暂停时段")
+			}
+			lastInWindow = inWindow
+		}
+		runDaemonStep(ctx, os.Stdout, client, capabilities, opts, cycle, api, watcher)
+
+		wait := time.After(daemonWait(opts))
+	waiting:
+		for {
+			select {
+			case <-ctx.Done():
+				fmt.Println("收到退出信号，守护模式已停止")
+				return
+			case <-api.scanRequests():
+				fmt.Println("收到接口的扫描请求，立即扫描")
+				watcher.markDirty("接口的扫描请求")
+				break waiting
+			case <-polls:
+				watcher.poll(client)
+			case <-wait:
+				break waiting
+			}
+		}
+	}
+}
+
+// 守护模式的一轮：种子有变化（或未开启 --skip-unchanged）时完整扫描并执行操作，否则跳过分析。
+// 返回是否完整扫描
+func runDaemonStep(ctx context.Context, w io.Writer, client *transmissionrpc.Client, capabilities ServerCapabilities, opts Options, cycle int, api *APIServer, watcher *ChangeWatcher) bool {
+	changed, reason := watcher.changed()
+	if !changed {
+		fmt.Fprintln(w, "无变化，跳过本轮分析")
+		return false
+	}
+	if reason != "" && cycle > 1 {
+		fmt.Fprintf(w, "需要重新分析: %s\n", reason)
+	}
+	runDaemonCycle(ctx, client, capabilities, opts, cycle, api, watcher)
+	return true
+}
+
+// 执行一轮守护扫描，出错时只记录日志，等待下一轮；完成后更新接口的快照
+func runDaemonCycle(ctx context.Context, client *transmissionrpc.Client, capabilities ServerCapabilities, opts Options, cycle int, api *APIServer, watcher *ChangeWatcher) {
+	renegotiationsBefore := sessionRenegotiations.Load()
+	result, err := scan(client, capabilities, opts)
+	if err != nil {
+		log.Printf("获取 torrent 列表失败%s: %v", opts.Connection.proxyHint(), err)
+		return
+	}
+	if result.Interrupted {
+		// 收到退出信号，本轮不执行操作
+		return
+	}
+	resolveSuffixSuggestions(nil, result, opts)
+
+	// 接口返回执行操作前识别的组
+	plan := buildPlan(result, opts)
+
+	printSpeedLimitNotice(os.Stdout, result.SpeedLimits)
+	printUnregisteredTorrents(os.Stdout, result.Unregistered)
+	printMagnetStubs(os.Stdout, result.MagnetStubs, opts.Verbose)
+	printMissingData(os.Stdout, result)
+	if opts.PackDuplicates {
+		// 守护模式只报告重复的季合集，不处理
+		printPackDuplicates(findPackDuplicates(client, result.Torrents))
+	}
+	printSkipSummary(os.Stdout, result, opts.Verbose)
+
+	summary := CycleSummary{
+		Cycle:              cycle,
+		Time:               time.Now(),
+		TorrentCount:       len(result.Torrents),
+		GroupCount:         len(result.DuplicateGroups),
+		SameSizeGroupCount: len(result.SameSizeGroups),
+		HandledGroupCount:  len(result.HandledGroups),
+		SpeedLimited:       result.SpeedLimits.Active(),
+	}
+	for _, group := range result.DuplicateGroups {
+		summary.EpisodeCount += len(group.Episodes)
+	}
+
+	// 统计重复分集自上次扫描以来的上传量
+	snapshot, err := loadUploadSnapshot(uploadSnapshotPath())
+	if err != nil {
+		log.Printf("读取上传量快照失败，将重新记录: %v", err)
+		snapshot = nil
+	}
+	delta, known := duplicateUploadDelta(snapshot, result.DuplicateGroups)
+	summary.DuplicateUploadDeltaBytes = delta
+	if err := saveUploadSnapshot(uploadSnapshotPath(), newUploadSnapshot(result.Torrents)); err != nil {
+		log.Printf("保存上传量快照失败: %v", err)
+	}
+
+	// 每轮单独计算处理数量上限，未处理的种子留到下一轮
+	throttle := newActionThrottle(opts.MaxActions, opts.ActionDelay)
+	ceiling := newDeleteCeiling(opts.MaxDeleteSize)
+	if opts.Yes && opts.RemoveUnregistered {
+		summary.UnregisteredRemoved = removeUnregistered(ctx, client, result, opts.DryRun, throttle, ceiling)
+	}
+	if opts.Yes && opts.RemoveStaleMagnets > 0 {
+		summary.StaleMagnetsRemoved = removeStaleMagnets(ctx, client, result, opts.RemoveStaleMagnets, opts.DryRun, throttle)
+	}
+	if opts.Yes && opts.RemoveMissingData {
+		summary.MissingDataRemoved = removeMissingData(ctx, client, result, opts.DryRun, throttle)
+	}
+	inWindow := opts.PauseWindow == nil || opts.PauseWindow.contains(time.Now())
+	if !inWindow {
+		windowPauses.resume(ctx, client, opts.DryRun)
+	}
+	if opts.TwoPhase {
+		history := newHistoryWriter()
+		summary.TwoPhase = runTwoPhase(ctx, nil, client, result, opts, history, throttle, inWindow)
+		if summary.TwoPhase != nil {
+			summary.ActionsTaken = summary.TwoPhase.Paused
+		}
+		windowPauses.save()
+	} else if opts.Yes && len(result.DuplicateGroups) > 0 && inWindow {
+		var before *SessionSnapshot
+		if !opts.DryRun {
+			before = sampleSessionBefore(client)
+		}
+		history := newHistoryWriter()
+		relocateMisplacedEpisodes(ctx, nil, client, result.DuplicateGroups, opts)
+		summary.ActionsTaken = applyAction(ctx, nil, client, result.DuplicateGroups, opts, history, throttle, ceiling)
+		windowPauses.save()
+		printSessionImpact(ctx, client, before, !opts.NoStatsWait)
+	}
+
+	throttle.printDeferred()
+	ceiling.printSummary()
+
+	// 记录本轮结束时的种子状态；有未处理的种子时下一轮仍需完整扫描
+	watcher.reset(client)
+	if len(throttle.Deferred) > 0 {
+		watcher.markDirty(fmt.Sprintf("上一轮有 %d 个种子未处理", len(throttle.Deferred)))
+	}
+
+	// 记录本轮汇总，与上一轮（可能在守护进程重启之前）比较
+	trend := ""
+	entry := newTrendEntry(summary, result.DuplicateGroups)
+	if previous, found, err := recordTrend(trendPath(), entry, opts.TrendRetention); err != nil {
+		log.Printf("写入趋势记录失败: %v", err)
+	} else if found {
+		trend = ", 较上一轮: " + entry.deltaFrom(previous).describe()
+	}
+
+	fmt.Printf("\n本轮统计: 种子 %d 个, 需要处理的组 %d 组 (分集 %d 个), 已处理的组 %d 组, 只有大小相同分集的组 %d 组, 执行操作 %d 个%s\n",
+		summary.TorrentCount, summary.GroupCount, summary.EpisodeCount, summary.HandledGroupCount, summary.SameSizeGroupCount, summary.ActionsTaken, trend)
+	summary.SessionRenegotiations = sessionRenegotiations.Load() - renegotiationsBefore
+	printSessionRenegotiations(summary.SessionRenegotiations)
+	if known > 0 {
+		fmt.Printf("重复分集自上次扫描以来额外上传 %s\n", formatSize(delta))
+		if summary.SpeedLimited {
+			fmt.Println("当前处于限速模式，上传量不代表真实能力")
+		}
+	} else if summary.EpisodeCount > 0 {
+		fmt.Println("重复分集暂无上一轮的上传量记录，已记录本轮基线")
+	}
+
+	if err := writeCycleSummary(filepath.Join(stateDir(), "metrics.json"), summary); err != nil {
+		log.Printf("写入扫描指标失败: %v", err)
+	}
+	api.update(summary, plan)
+}
+
+// 到下一轮扫描的等待时间，设置了暂停时段时不超过到下一个时段边界的时间
+func daemonWait(opts Options) time.Duration {
+	wait := opts.Interval
+	if opts.PauseWindow != nil {
+		// 多等一秒，确保醒来时已经越过边界
+		if boundary := opts.PauseWindow.untilNextBoundary(time.Now()) + time.Second; boundary < wait {
+			wait = boundary
+		}
+	}
+	return wait
+}
+
+// 写入最近一轮扫描的指标
+func writeCycleSummary(path string, summary CycleSummary) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 深度扫描按文件内容匹配的组的来源，按名称分组的组来源为空
+const ORIGIN_CONTENT = "content"
+
+// 深度扫描每获取多少个种子的文件列表显示一次进度
+const DEEP_SCAN_PROGRESS_STEP = 50
+
+// 组来源的中文名称
+func originName(origin string) string {
+	if origin == ORIGIN_CONTENT {
+		return "内容匹配"
+	}
+	return "名称匹配"
+}
+
+// 文件的索引键：小写的文件名和大小
+func fileKey(file *transmissionrpc.TorrentFile) string {
+	return fmt.Sprintf("%s:%d", strings.ToLower(getFileName(file.Name)), file.Length)
+}
+
+// 深度扫描：不依赖名称，按（文件名, 大小）为全部种子的内容文件建立索引，
+// 内容文件中至少 --deep-scan-min-percent 出现在另一个内容文件更多的种子中时，把它作为该种子的分集，组成"内容匹配"的组
+func applyDeepScan(client *transmissionrpc.Client, result *ScanResult, torrents []transmissionrpc.Torrent, collectionOnly map[int64]bool, opts Options) {
+	// 已在按名称分组中作为分集的种子不再参与
+	grouped := make(map[int64]bool)
+	for _, groups := range allGroupMaps(result) {
+		for _, group := range groups {
+			for _, episode := range group.Episodes {
+				if episode != nil && episode.ID != nil {
+					grouped[*episode.ID] = true
+				}
+			}
+		}
+	}
+
+	var candidates []*transmissionrpc.Torrent
+	for i := range torrents {
+		torrent := &torrents[i]
+		if torrent.ID == nil || torrent.Name == nil || torrent.SizeWhenDone == nil || metadataPending(torrent) {
+			continue
+		}
+		candidates = append(candidates, torrent)
+	}
+
+	files, index := buildContentIndex(client, candidates, "深度扫描", false)
+
+	byID := make(map[int64]*transmissionrpc.Torrent)
+	for _, torrent := range candidates {
+		byID[*torrent.ID] = torrent
+	}
+
+	// 为每个种子找出包含其内容文件最多的另一个种子
+	episodesOf := make(map[int64][]*transmissionrpc.Torrent)
+	isEpisode := make(map[int64]bool)
+	shares := make(map[int64]float64)
+	for _, torrent := range candidates {
+		id := *torrent.ID
+		content := files[id]
+		if grouped[id] || collectionOnly[id] || len(content) == 0 {
+			continue
+		}
+		// 只有状态符合要求的种子才作为分集，仍可以作为其他种子的合集
+		if excludedByStatus(torrent, opts.ExcludeStatuses) {
+			result.StatusExcluded[id] = true
+			continue
+		}
+		counts := make(map[int64]int)
+		for _, file := range content {
+			for _, otherID := range index[fileKey(file)] {
+				if otherID != id {
+					counts[otherID]++
+				}
+			}
+		}
+		bestID, bestCount := int64(0), 0
+		for otherID, count := range counts {
+			if count > bestCount || (count == bestCount && otherID < bestID) {
+				bestID, bestCount = otherID, count
+			}
+		}
+		share := float64(bestCount) / float64(len(content))
+		if bestCount == 0 || share < opts.DeepScanMinShare {
+			continue
+		}
+		// 合集的内容文件必须比分集多，大小相同的种子（辅种）不处理
+		if len(files[bestID]) <= len(content) {
+			continue
+		}
+		// 名称相同的种子已经由按名称分组处理
+		if sameGroupKey(opts, torrent, byID[bestID]) {
+			continue
+		}
+		episodesOf[bestID] = append(episodesOf[bestID], torrent)
+		isEpisode[id] = true
+		shares[id] = share
+	}
+
+	added := 0
+	for collectionID, episodes := range episodesOf {
+		// 合集本身也是其他种子的分集时，只保留最外层的关系
+		if isEpisode[collectionID] {
+			continue
+		}
+		collection := byID[collectionID]
+		sort.Slice(episodes, func(i, j int) bool {
+			return *episodes[i].ID < *episodes[j].ID
+		})
+		var episodeFiles [][]*transmissionrpc.TorrentFile
+		minShare := 1.0
+		for _, episode := range episodes {
+			episodeFiles = append(episodeFiles, files[*episode.ID])
+			if shares[*episode.ID] < minShare {
+				minShare = shares[*episode.ID]
+			}
+		}
+		// 包含比例按（文件名, 大小）匹配的结果计算，不区分文件名大小写
+		evidence := collectGroupEvidence(collection, files[collectionID], episodes, episodeFiles)
+		evidence.Containment = minShare
+		// 索引中只有内容文件，填充文件的大小按完整的文件列表记录
+		for _, torrent := range append([]*transmissionrpc.Torrent{collection}, episodes...) {
+			if torrentFiles, err := getTorrentFiles(client, torrent.ID); err == nil {
+				evidence.recordPadding(torrent, torrentFiles)
+			}
+		}
+		name := fmt.Sprintf("%s（内容匹配）", canonicalName(*collection.Name))
+		result.DuplicateGroups[name] = DuplicateGroup{
+			Collection:      collection,
+			Episodes:        episodes,
+			HasFileOverlaps: true,
+			Origin:          ORIGIN_CONTENT,
+			Evidence:        evidence,
+			Confidence:      confidenceScore(evidence),
+		}
+		added++
+	}
+	fmt.Printf("深度扫描: 按内容匹配找到 %d 组\n", added)
+}
+
+// 按（文件名, 大小）为种子的内容文件建立索引，返回各种子的内容文件和每个文件键所在的种子ID，
+// 获取文件列表失败的种子不在索引中；label 为进度提示的前缀。completedOnly 时重新获取文件列表和选择状态，
+// 只索引已选择且已下载完成的文件
+func buildContentIndex(client *transmissionrpc.Client, torrents []*transmissionrpc.Torrent, label string, completedOnly bool) (map[int64][]*transmissionrpc.TorrentFile, map[string][]int64) {
+	fmt.Printf("%s: 获取 %d 个种子的文件列表...\n", label, len(torrents))
+	files := make(map[int64][]*transmissionrpc.TorrentFile)
+	index := make(map[string][]int64)
+	for i, torrent := range torrents {
+		if (i+1)%DEEP_SCAN_PROGRESS_STEP == 0 || i+1 == len(torrents) {
+			fmt.Printf("%s: 已获取 %d/%d\n", label, i+1, len(torrents))
+		}
+		var torrentFiles []*transmissionrpc.TorrentFile
+		var err error
+		if completedOnly {
+			var wanted []bool
+			torrentFiles, wanted, err = getWantedFiles(client, *torrent.ID)
+			torrentFiles = completedFiles(torrentFiles, wanted)
+		} else {
+			torrentFiles, err = getTorrentFiles(client, torrent.ID)
+		}
+		if err != nil {
+			continue
+		}
+		content := contentFiles(torrentFiles)
+		files[*torrent.ID] = content
+		seen := make(map[string]bool)
+		for _, file := range content {
+			key := fileKey(file)
+			if !seen[key] {
+				seen[key] = true
+				index[key] = append(index[key], *torrent.ID)
+			}
+		}
+	}
+	return files, index
+}
+
+// 已选择且已下载完成的文件，没有选择状态的文件按已选择处理
+func completedFiles(files []*transmissionrpc.TorrentFile, wanted []bool) []*transmissionrpc.TorrentFile {
+	var result []*transmissionrpc.TorrentFile
+	for i, file := range files {
+		if file == nil || (i < len(wanted) && !wanted[i]) || file.BytesCompleted < file.Length {
+			continue
+		}
+		result = append(result, file)
+	}
+	return result
+}
+
+// 两个种子按名称是否属于同一组
+func sameGroupKey(opts Options, a, b *transmissionrpc.Torrent) bool {
+	if a == nil || b == nil || a.Name == nil || b.Name == nil {
+		return false
+	}
+	keyA, _ := groupKey(opts.NameMap, canonicalName(*a.Name))
+	keyB, _ := groupKey(opts.NameMap, canonicalName(*b.Name))
+	return keyA == keyB
+}
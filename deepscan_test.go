@@ -0,0 +1,35 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCompletedFiles(t *testing.T) {
+	files := torrentFiles(
+		testFile{"Show/E01.mkv", 1000},
+		testFile{"Show/E02.mkv", 1000},
+		testFile{"Show/E03.mkv", 1000},
+		testFile{"Show/E04.mkv", 1000},
+	)
+	files[1].BytesCompleted = 999
+	tests := []struct {
+		name   string
+		wanted []bool
+		want   []string
+	}{
+		{"未下载完成的文件不计入", nil, []string{"Show/E01.mkv", "Show/E03.mkv", "Show/E04.mkv"}},
+		{"未选择的文件不计入", []bool{true, true, false, true}, []string{"Show/E01.mkv", "Show/E04.mkv"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, file := range completedFiles(files, tt.wanted) {
+				got = append(got, file.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("completedFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
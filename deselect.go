@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 单个分集取消选择的结果
+const (
+	DESELECT_DONE   = "done"
+	DESELECT_PAUSED = "paused" // 单文件种子改为暂停
+	DESELECT_FAILED = "failed"
+)
+
+// 获取分集的文件列表和选择状态，用于取消选择
+func getWantedFiles(client *transmissionrpc.Client, torrentID int64) ([]*transmissionrpc.TorrentFile, []bool, error) {
+	torrents, err := getWithRetry(client, []string{"id", "files", "wanted"}, []int64{torrentID}, timeouts.Files, fmt.Sprintf("获取种子 ID: %d 文件选择状态", torrentID))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(torrents) == 0 {
+		return nil, nil, fmt.Errorf("种子 ID: %d 不存在", torrentID)
+	}
+	return torrents[0].Files, torrents[0].Wanted, nil
+}
+
+// 取消选择分集的全部文件，分集保留在客户端中不再下载；单文件种子无法取消选择全部文件，改为暂停。
+// 返回取消选择成功、改为暂停成功和失败的数量
+func deselectEpisodes(ctx context.Context, client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup, history *HistoryWriter, throttle *ActionThrottle) (int, int, int) {
+	deselectedCount, pausedCount, failedCount := 0, 0, 0
+
+	for _, groupName := range sortedGroupNames(duplicateGroups) {
+		var episodes []*transmissionrpc.Torrent
+		for _, episode := range duplicateGroups[groupName].Episodes {
+			if episode != nil && episode.ID != nil {
+				episodes = append(episodes, episode)
+			}
+		}
+		episodes = throttle.take(ctx, groupName, episodes)
+		if len(episodes) == 0 {
+			continue
+		}
+
+		fmt.Printf("正在取消选择 \"%s\" 的 %d 个分集的文件...\n", groupName, len(episodes))
+		for i, episode := range episodes {
+			if !throttle.wait(ctx) {
+				throttle.deferRest(groupName, episodes[i:], DEFERRED_INTERRUPTED)
+				break
+			}
+			switch deselectEpisode(client, groupName, episode, history) {
+			case DESELECT_DONE:
+				deselectedCount++
+				keptExport.succeed(groupName)
+				runNotices.succeed(groupName)
+			case DESELECT_PAUSED:
+				pausedCount++
+				keptExport.succeed(groupName)
+				runNotices.succeed(groupName)
+			default:
+				failedCount++
+			}
+		}
+	}
+
+	return deselectedCount, pausedCount, failedCount
+}
+
+// 取消选择单个分集当前已选择的文件，并记录被取消的文件以便撤销
+func deselectEpisode(client *transmissionrpc.Client, groupName string, episode *transmissionrpc.Torrent, history *HistoryWriter) string {
+	files, wanted, err := getWantedFiles(client, *episode.ID)
+	if err != nil {
+		fmt.Printf("获取分集 ID: %d 文件列表失败: %v\n", *episode.ID, err)
+		return DESELECT_FAILED
+	}
+
+	// Transmission 不允许取消选择种子的全部文件，单文件种子只能暂停
+	if len(files) <= 1 {
+		fmt.Printf("分集 ID: %d 是单文件种子，无法取消选择，改为暂停\n", *episode.ID)
+		if !stopTorrent(client, *episode.ID, timeouts.Action) {
+			return DESELECT_FAILED
+		}
+		recordPause(history, groupName, episode)
+		return DESELECT_PAUSED
+	}
+
+	var wantedIndices []int64
+	for i := range files {
+		if i >= len(wanted) || wanted[i] {
+			wantedIndices = append(wantedIndices, int64(i))
+		}
+	}
+	if len(wantedIndices) == 0 {
+		fmt.Printf("分集 ID: %d 的文件已全部取消选择，跳过\n", *episode.ID)
+		return DESELECT_DONE
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+	err = client.TorrentSet(ctx, transmissionrpc.TorrentSetPayload{
+		IDs:           []int64{*episode.ID},
+		FilesUnwanted: wantedIndices,
+	})
+	cancel()
+	if err != nil {
+		fmt.Printf("取消选择分集 ID: %d 的文件失败: %v\n", *episode.ID, err)
+		return DESELECT_FAILED
+	}
+
+	record := newHistoryRecord(ACTION_DESELECT, groupName, episode)
+	record.PrevWantedFiles = wantedIndices
+	history.Record(record)
+	fmt.Printf("成功取消选择分集 ID: %d 的 %d 个文件\n", *episode.ID, len(wantedIndices))
+	return DESELECT_DONE
+}
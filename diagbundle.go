@@ -0,0 +1,588 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 诊断包格式的版本
+const DIAG_BUNDLE_VERSION = 1
+
+// 诊断包中的文件
+const (
+	DIAG_FILE_BUNDLE    = "bundle.json"
+	DIAG_FILE_RPC       = "rpc.json"
+	DIAG_FILE_DECISIONS = "decisions.json"
+)
+
+// 记录到诊断包的只读RPC方法，其他方法（暂停、设置等）不记录
+var diagRecordedMethods = map[string]bool{
+	"torrent-get":   true,
+	"session-get":   true,
+	"session-stats": true,
+	"free-space":    true,
+}
+
+// 不写入诊断包的参数：连接参数、通知地址和目录路径；值为文件路径的参数（fileFlags）同样不写入
+var diagDroppedFlags = map[string]bool{
+	"host":            true,
+	"port":            true,
+	"https":           true,
+	"user":            true,
+	"password":        true,
+	"netrc":           true,
+	"proxy":           true,
+	"api-listen":      true,
+	"api-token":       true,
+	"discord-webhook": true,
+	"data-root":       true,
+	"collection-dir":  true,
+}
+
+// 值为名称结尾、写入诊断包时与种子名称一样匿名化的参数
+var diagNameFlags = map[string]bool{
+	"suffix":            true,
+	"collection-suffix": true,
+}
+
+// 诊断包的说明文件
+type DiagBundle struct {
+	Version     int       `json:"version"`
+	CreatedAt   time.Time `json:"created_at"`
+	ToolVersion string    `json:"tool_version"`
+	GoVersion   string    `json:"go_version"`
+	Platform    string    `json:"platform"`
+	Args        []string  `json:"args"` // 去掉连接参数和本机路径、名称结尾已匿名化的命令行参数，重放时使用
+}
+
+// 诊断包中记录的RPC返回内容：每个种子合并全部 torrent-get 返回的字段，其他方法保留最后一次的返回
+type DiagRPC struct {
+	Torrents []map[string]json.RawMessage `json:"torrents"`
+	Methods  map[string]json.RawMessage   `json:"methods"`
+}
+
+// 诊断包中记录的分析结果，名称已匿名化
+type DiagDecisions struct {
+	Groups       []DiagGroup `json:"groups"`
+	Skipped      []DiagSkip  `json:"skipped"`
+	Unregistered []int64     `json:"unregistered,omitempty"`
+}
+
+// 一个组的分析结果
+type DiagGroup struct {
+	Kind          string         `json:"kind"`
+	Name          string         `json:"name"`
+	CollectionID  int64          `json:"collection_id"`
+	Episodes      []DiagEpisode  `json:"episodes"`
+	Confidence    float64        `json:"confidence"`
+	Containment   float64        `json:"containment"`
+	ClassOverlaps []ClassOverlap `json:"class_overlaps,omitempty"`
+
+	PaddingFiles int `json:"padding_files,omitempty"`
+}
+
+// 组内的一个分集及其操作
+type DiagEpisode struct {
+	ID     int64  `json:"id"`
+	Action string `json:"action"`
+}
+
+// 跳过的种子组
+type DiagSkip struct {
+	Reason     string  `json:"reason"`
+	Name       string  `json:"name"`
+	TorrentIDs []int64 `json:"torrent_ids,omitempty"`
+}
+
+// allGroupMaps 中各类组在诊断包中的名称，顺序相同
+var diagGroupKinds = []string{"duplicate", "low_confidence", "same_size", "partial", "oversized", "gated", "active", "handled", "below_min_episodes"}
+
+// 记录本次运行的RPC返回内容和分析结果，结束时匿名化后写入诊断包；
+// 未指定 --diag-bundle 时为nil，nil记录器不记录任何内容
+type DiagRecorder struct {
+	path     string
+	args     []string
+	patterns []string // 判断种子已失效的tracker错误信息，匿名化时保留匹配的规则
+
+	mu        sync.Mutex
+	torrents  map[int64]map[string]json.RawMessage
+	methods   map[string]json.RawMessage
+	result    *ScanResult
+	operation string
+}
+
+// 本次运行的诊断包记录器
+var diagBundle *DiagRecorder
+
+// 开启诊断包记录
+func enableDiagBundle(path string, args []string, patterns []string) {
+	diagBundle = &DiagRecorder{
+		path:     path,
+		args:     args,
+		patterns: patterns,
+		torrents: make(map[int64]map[string]json.RawMessage),
+		methods:  make(map[string]json.RawMessage),
+	}
+}
+
+// 工具的版本：构建信息中的模块版本，没有时使用源码版本
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	revision, modified := "(devel)", ""
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			if setting.Value == "true" {
+				modified = "+dirty"
+			}
+		}
+	}
+	return revision + modified
+}
+
+// 在客户端的HTTP连接外层记录RPC返回内容，在设置会话ID处理之后调用
+func (r *DiagRecorder) wrap(client *transmissionrpc.Client) error {
+	if r == nil {
+		return nil
+	}
+	httpClient, err := rpcHTTPClient(client)
+	if err != nil {
+		return err
+	}
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	httpClient.Transport = &diagTransport{base: base, recorder: r}
+	return nil
+}
+
+// 记录只读RPC请求返回内容的HTTP层
+type diagTransport struct {
+	base     http.RoundTripper
+	recorder *DiagRecorder
+}
+
+func (t *diagTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	var body []byte
+	if request.Body != nil {
+		var err error
+		body, err = io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	response, err := t.base.RoundTrip(request)
+	if err != nil || response.StatusCode != http.StatusOK {
+		return response, err
+	}
+	answer, err := io.ReadAll(response.Body)
+	response.Body.Close()
+	response.Body = io.NopCloser(bytes.NewReader(answer))
+	if err != nil {
+		return response, nil
+	}
+	t.recorder.record(body, answer)
+	return response, nil
+}
+
+// RPC请求和返回的格式
+type diagRequest struct {
+	Method    string          `json:"method"`
+	Arguments json.RawMessage `json:"arguments"`
+	Tag       json.RawMessage `json:"tag"`
+}
+
+type diagAnswer struct {
+	Arguments json.RawMessage `json:"arguments"`
+	Result    string          `json:"result"`
+}
+
+// 记录一次RPC返回：torrent-get 按种子ID合并字段，其他只读方法保留最后一次的返回
+func (r *DiagRecorder) record(requestBody, answerBody []byte) {
+	var request diagRequest
+	var answer diagAnswer
+	if json.Unmarshal(requestBody, &request) != nil || json.Unmarshal(answerBody, &answer) != nil {
+		return
+	}
+	if !diagRecordedMethods[request.Method] || answer.Result != "success" {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if request.Method != "torrent-get" {
+		r.methods[request.Method] = answer.Arguments
+		return
+	}
+	var arguments struct {
+		Torrents []map[string]json.RawMessage `json:"torrents"`
+	}
+	if json.Unmarshal(answer.Arguments, &arguments) != nil {
+		return
+	}
+	// 获取文件列表时只请求 files 字段，返回中没有ID，按请求的ID记录
+	var requested struct {
+		IDs []int64 `json:"ids"`
+	}
+	json.Unmarshal(request.Arguments, &requested)
+	for _, torrent := range arguments.Torrents {
+		var id int64
+		if json.Unmarshal(torrent["id"], &id) != nil {
+			if len(requested.IDs) != 1 || len(arguments.Torrents) != 1 {
+				continue
+			}
+			id = requested.IDs[0]
+		}
+		merged, ok := r.torrents[id]
+		if !ok {
+			merged = make(map[string]json.RawMessage)
+			r.torrents[id] = merged
+		}
+		for field, value := range torrent {
+			merged[field] = value
+		}
+	}
+}
+
+// 记录扫描结果，守护模式中保留最后一轮
+func (r *DiagRecorder) recordScan(result *ScanResult, action string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.result = result
+	r.operation = action
+}
+
+// 匿名化一个种子的字段：名称、路径和标签按单词打乱，hash和tracker主机名替换为加盐的hash，
+// tracker错误信息只保留匹配的已失效规则；其他字段中的字符串全部清空，只保留数字和布尔值
+func (r *DiagRecorder) anonymizeTorrent(anonymizer *Anonymizer, torrent map[string]json.RawMessage) map[string]json.RawMessage {
+	anonymized := make(map[string]json.RawMessage, len(torrent))
+	for field, raw := range torrent {
+		var value interface{}
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+		if decoder.Decode(&value) != nil {
+			continue
+		}
+		switch field {
+		case "name", "downloadDir":
+			value = mapString(value, anonymizer.name)
+		case "hashString":
+			value = mapString(value, anonymizer.hash)
+		case "errorString":
+			value = mapString(value, func(message string) string { return anonMessage(message, r.patterns) })
+		case "labels":
+			value = mapStrings(value, anonymizer.name)
+		case "webseeds":
+			value = mapStrings(value, anonymizer.url)
+		case "files":
+			value = mapObjects(value, func(key string, item interface{}) interface{} {
+				if key == "name" {
+					return mapString(item, anonymizer.name)
+				}
+				return scrubStrings(item)
+			})
+		case "trackers", "trackerStats":
+			value = mapObjects(value, func(key string, item interface{}) interface{} {
+				switch key {
+				case "announce", "scrape":
+					return mapString(item, anonymizer.url)
+				case "host":
+					return mapString(item, anonymizer.host)
+				case "lastAnnounceResult", "lastScrapeResult":
+					return mapString(item, func(message string) string { return anonMessage(message, r.patterns) })
+				}
+				return scrubStrings(item)
+			})
+		default:
+			value = scrubStrings(value)
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			continue
+		}
+		anonymized[field] = data
+	}
+	return anonymized
+}
+
+// 对字符串值应用转换，其他类型不变
+func mapString(value interface{}, convert func(string) string) interface{} {
+	if text, ok := value.(string); ok {
+		return convert(text)
+	}
+	return value
+}
+
+// 对字符串数组的每一项应用转换
+func mapStrings(value interface{}, convert func(string) string) interface{} {
+	items, ok := value.([]interface{})
+	if !ok {
+		return scrubStrings(value)
+	}
+	for i, item := range items {
+		items[i] = mapString(item, convert)
+	}
+	return items
+}
+
+// 对对象数组中每个对象的每个字段应用转换
+func mapObjects(value interface{}, convert func(key string, item interface{}) interface{}) interface{} {
+	items, ok := value.([]interface{})
+	if !ok {
+		return scrubStrings(value)
+	}
+	for _, item := range items {
+		if object, ok := item.(map[string]interface{}); ok {
+			for key, field := range object {
+				object[key] = convert(key, field)
+			}
+		}
+	}
+	return items
+}
+
+// 清空值中的全部字符串，数字、布尔值和结构保留
+func scrubStrings(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case string:
+		return ""
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = scrubStrings(item)
+		}
+	case map[string]interface{}:
+		for key, item := range typed {
+			typed[key] = scrubStrings(item)
+		}
+	}
+	return value
+}
+
+// 匿名化 session-get 等方法的返回：保留版本号，下载目录按路径匿名化，其他字符串清空
+func anonymizeMethod(anonymizer *Anonymizer, raw json.RawMessage) json.RawMessage {
+	var arguments map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if decoder.Decode(&arguments) != nil {
+		return json.RawMessage("{}")
+	}
+	for key, value := range arguments {
+		switch key {
+		case "version":
+		case "download-dir", "path":
+			arguments[key] = mapString(value, anonymizer.name)
+		default:
+			arguments[key] = scrubStrings(value)
+		}
+	}
+	data, err := json.Marshal(arguments)
+	if err != nil {
+		return json.RawMessage("{}")
+	}
+	return data
+}
+
+// 去掉连接参数和本机路径，名称结尾按种子名称的方式匿名化；可重复的参数逐个保留
+func diagArgs(anonymizer *Anonymizer, args []string) []string {
+	fs := newFlagSet(&Options{}, &rawFlags{})
+	fs.SetOutput(io.Discard)
+	if fs.Parse(args) != nil {
+		return nil
+	}
+	var kept []string
+	fs.Visit(func(f *flag.Flag) {
+		if diagDroppedFlags[f.Name] || fileFlags[f.Name] {
+			return
+		}
+		if list, ok := f.Value.(*stringList); ok {
+			for _, item := range *list {
+				kept = append(kept, "--"+f.Name+"="+item)
+			}
+			return
+		}
+		value := f.Value.String()
+		if diagNameFlags[f.Name] {
+			value = anonymizer.name(value)
+		}
+		kept = append(kept, "--"+f.Name+"="+value)
+	})
+	return kept
+}
+
+// 生成匿名化的分析结果
+func (r *DiagRecorder) decisions(anonymizer *Anonymizer) DiagDecisions {
+	decisions := DiagDecisions{Groups: []DiagGroup{}, Skipped: []DiagSkip{}}
+	if r.result == nil {
+		return decisions
+	}
+	for i, groups := range allGroupMaps(r.result) {
+		for _, name := range sortedGroupNames(groups) {
+			group := groups[name]
+			diagGroup := DiagGroup{
+				Kind:          diagGroupKinds[i],
+				Name:          anonymizer.name(name),
+				Episodes:      []DiagEpisode{},
+				Confidence:    group.Confidence,
+				Containment:   group.Evidence.Containment,
+				ClassOverlaps: group.Evidence.ClassOverlaps,
+				PaddingFiles:  group.Evidence.PaddingFiles,
+			}
+			if group.Collection != nil && group.Collection.ID != nil {
+				diagGroup.CollectionID = *group.Collection.ID
+			}
+			for _, episode := range group.Episodes {
+				if episode != nil && episode.ID != nil {
+					diagGroup.Episodes = append(diagGroup.Episodes, DiagEpisode{ID: *episode.ID, Action: group.episodeAction(episode, r.operation)})
+				}
+			}
+			decisions.Groups = append(decisions.Groups, diagGroup)
+		}
+	}
+	for _, skip := range r.result.Skipped {
+		diagSkip := DiagSkip{Reason: skip.Reason, Name: anonymizer.name(skip.Name)}
+		for _, torrent := range skip.Torrents {
+			if torrent != nil && torrent.ID != nil {
+				diagSkip.TorrentIDs = append(diagSkip.TorrentIDs, *torrent.ID)
+			}
+		}
+		decisions.Skipped = append(decisions.Skipped, diagSkip)
+	}
+	for _, item := range unregisteredTargets(r.result) {
+		decisions.Unregistered = append(decisions.Unregistered, *item.Torrent.ID)
+	}
+	return decisions
+}
+
+// 匿名化记录的内容并写入诊断包（zip），对应关系和盐不写入；写入失败时只显示警告
+func (r *DiagRecorder) write() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	anonymizer := newAnonymizer()
+
+	bundle := DiagBundle{
+		Version:     DIAG_BUNDLE_VERSION,
+		CreatedAt:   time.Now(),
+		ToolVersion: toolVersion(),
+		GoVersion:   runtime.Version(),
+		Platform:    runtime.GOOS + "/" + runtime.GOARCH,
+		Args:        diagArgs(anonymizer, r.args),
+	}
+	rpc := DiagRPC{Torrents: []map[string]json.RawMessage{}, Methods: make(map[string]json.RawMessage)}
+	ids := make([]int64, 0, len(r.torrents))
+	for id := range r.torrents {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		rpc.Torrents = append(rpc.Torrents, r.anonymizeTorrent(anonymizer, r.torrents[id]))
+	}
+	for method, arguments := range r.methods {
+		rpc.Methods[method] = anonymizeMethod(anonymizer, arguments)
+	}
+
+	if err := saveDiagBundle(r.path, bundle, rpc, r.decisions(anonymizer)); err != nil {
+		log.Printf("写入诊断包失败: %v", err)
+		return
+	}
+	fmt.Printf("已写入诊断包 %s（%d 个种子，名称、hash和tracker已匿名化）\n", r.path, len(rpc.Torrents))
+}
+
+// 把诊断包的各部分写入zip文件
+func saveDiagBundle(path string, bundle DiagBundle, rpc DiagRPC, decisions DiagDecisions) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	archive := zip.NewWriter(file)
+	for _, entry := range []struct {
+		Name  string
+		Value interface{}
+	}{
+		{DIAG_FILE_BUNDLE, bundle},
+		{DIAG_FILE_RPC, rpc},
+		{DIAG_FILE_DECISIONS, decisions},
+	} {
+		data, err := json.MarshalIndent(entry.Value, "", "  ")
+		if err != nil {
+			file.Close()
+			return err
+		}
+		writer, err := archive.Create(entry.Name)
+		if err != nil {
+			file.Close()
+			return err
+		}
+		if _, err := writer.Write(data); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// 读取诊断包，用于 --from-dump 重放
+func loadDiagBundle(path string) (DiagBundle, DiagRPC, error) {
+	var bundle DiagBundle
+	var rpc DiagRPC
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return bundle, rpc, err
+	}
+	defer archive.Close()
+	for _, entry := range []struct {
+		Name  string
+		Value interface{}
+	}{
+		{DIAG_FILE_BUNDLE, &bundle},
+		{DIAG_FILE_RPC, &rpc},
+	} {
+		file, err := archive.Open(entry.Name)
+		if err != nil {
+			return bundle, rpc, fmt.Errorf("诊断包中缺少 %s: %w", entry.Name, err)
+		}
+		err = json.NewDecoder(file).Decode(entry.Value)
+		file.Close()
+		if err != nil {
+			return bundle, rpc, fmt.Errorf("诊断包中的 %s 格式错误: %w", entry.Name, err)
+		}
+	}
+	if bundle.Version > DIAG_BUNDLE_VERSION {
+		return bundle, rpc, fmt.Errorf("诊断包版本 %d 高于当前支持的版本 %d，请升级后重放", bundle.Version, DIAG_BUNDLE_VERSION)
+	}
+	return bundle, rpc, nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// 未指定 --discord-webhook 时读取的环境变量，避免webhook地址出现在进程列表中
+const ENV_DISCORD_WEBHOOK = "DISCORD_WEBHOOK_URL"
+
+// Discord 对消息的限制
+const (
+	DISCORD_MAX_EMBEDS        = 10   // 每条消息的embed数量
+	DISCORD_MAX_MESSAGE_CHARS = 6000 // 每条消息中全部embed的字符数
+	DISCORD_MAX_TITLE         = 256
+	DISCORD_MAX_DESCRIPTION   = 4096
+	DISCORD_MAX_FIELD_VALUE   = 1024
+)
+
+// 通知中最多列出的组名数量和每个组名的最大长度，超出部分截断
+const (
+	DISCORD_MAX_GROUP_NAMES = 50
+	DISCORD_MAX_GROUP_NAME  = 200
+)
+
+// embed 的颜色：全部成功为绿色，有失败时为橙色
+const (
+	DISCORD_COLOR_OK      = 0x2ecc71
+	DISCORD_COLOR_FAILURE = 0xe67e22
+)
+
+// Discord webhook 消息
+type DiscordMessage struct {
+	Embeds []DiscordEmbed `json:"embeds"`
+}
+
+type DiscordEmbed struct {
+	Title       string         `json:"title,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+	Fields      []DiscordField `json:"fields,omitempty"`
+}
+
+type DiscordField struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Inline bool   `json:"inline,omitempty"`
+}
+
+// embed 中计入 Discord 字符数限制的部分
+func (e DiscordEmbed) chars() int {
+	count := utf8.RuneCountInString(e.Title) + utf8.RuneCountInString(e.Description)
+	for _, field := range e.Fields {
+		count += utf8.RuneCountInString(field.Name) + utf8.RuneCountInString(field.Value)
+	}
+	return count
+}
+
+// 把结果发送到 Discord webhook
+type DiscordNotifier struct {
+	webhook string
+	client  *http.Client
+}
+
+// 检查webhook地址并创建通知渠道
+func newDiscordNotifier(webhook string) (*DiscordNotifier, error) {
+	parsed, err := url.Parse(webhook)
+	if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" {
+		return nil, fmt.Errorf("需要 http 或 https 地址，如 https://discord.com/api/webhooks/<id>/<token>")
+	}
+	return &DiscordNotifier{webhook: webhook, client: &http.Client{}}, nil
+}
+
+func (d *DiscordNotifier) Name() string {
+	return "Discord"
+}
+
+// 发送结果：第一个embed为统计，之后为组名列表；超过 Discord 限制时分为多条消息
+func (d *DiscordNotifier) Notify(summary RunSummary) error {
+	for _, message := range splitDiscordEmbeds(discordEmbeds(summary)) {
+		if err := d.post(message); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 按字符数截断，超出时以省略号结尾
+func truncateRunes(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:limit-1]) + "…"
+}
+
+// 结果对应的embed：统计和组名列表，组名列表按描述长度限制分为多个embed
+func discordEmbeds(summary RunSummary) []DiscordEmbed {
+	color := DISCORD_COLOR_OK
+	if summary.Failed > 0 {
+		color = DISCORD_COLOR_FAILURE
+	}
+	timestamp := summary.Time.Format(time.RFC3339)
+	stats := DiscordEmbed{
+		Title:     truncateRunes("delete-episode: "+summary.Server, DISCORD_MAX_TITLE),
+		Color:     color,
+		Timestamp: timestamp,
+		Fields: []DiscordField{
+			{Name: "处理的组", Value: strconv.Itoa(len(summary.Groups)), Inline: true},
+			{Name: "暂停的分集", Value: strconv.Itoa(summary.Paused), Inline: true},
+			{Name: "删除的分集", Value: strconv.Itoa(summary.Deleted), Inline: true},
+			{Name: "其他操作", Value: strconv.Itoa(summary.Other), Inline: true},
+			{Name: "释放空间", Value: formatSize(summary.Reclaimed), Inline: true},
+			{Name: "失败", Value: strconv.Itoa(summary.Failed), Inline: true},
+		},
+	}
+	if summary.SessionRenegotiations > 0 {
+		stats.Fields = append(stats.Fields, DiscordField{Name: "会话ID重新协商", Value: strconv.FormatInt(summary.SessionRenegotiations, 10), Inline: true})
+	}
+	embeds := []DiscordEmbed{stats}
+
+	names := summary.Groups
+	var more int
+	if len(names) > DISCORD_MAX_GROUP_NAMES {
+		more = len(names) - DISCORD_MAX_GROUP_NAMES
+		names = names[:DISCORD_MAX_GROUP_NAMES]
+	}
+	lines := make([]string, 0, len(names)+1)
+	for _, name := range names {
+		lines = append(lines, "- "+truncateRunes(name, DISCORD_MAX_GROUP_NAME))
+	}
+	if more > 0 {
+		lines = append(lines, fmt.Sprintf("… 另有 %d 组", more))
+	}
+
+	var current []string
+	currentChars := 0
+	flush := func() {
+		if len(current) == 0 {
+			return
+		}
+		title := "处理的组"
+		if len(embeds) > 1 {
+			title = "处理的组（续）"
+		}
+		embeds = append(embeds, DiscordEmbed{Title: title, Description: strings.Join(current, "\n"), Color: color, Timestamp: timestamp})
+		current, currentChars = nil, 0
+	}
+	for _, line := range lines {
+		length := utf8.RuneCountInString(line) + 1
+		if currentChars+length > DISCORD_MAX_DESCRIPTION {
+			flush()
+		}
+		current = append(current, line)
+		currentChars += length
+	}
+	flush()
+	return embeds
+}
+
+// 把embed分为多条消息，每条消息不超过 Discord 的embed数量和字符数限制
+func splitDiscordEmbeds(embeds []DiscordEmbed) []DiscordMessage {
+	var messages []DiscordMessage
+	var current DiscordMessage
+	currentChars := 0
+	for _, embed := range embeds {
+		chars := embed.chars()
+		if len(current.Embeds) > 0 && (len(current.Embeds) >= DISCORD_MAX_EMBEDS || currentChars+chars > DISCORD_MAX_MESSAGE_CHARS) {
+			messages = append(messages, current)
+			current, currentChars = DiscordMessage{}, 0
+		}
+		current.Embeds = append(current.Embeds, embed)
+		currentChars += chars
+	}
+	if len(current.Embeds) > 0 {
+		messages = append(messages, current)
+	}
+	return messages
+}
+
+// 发送一条消息，被限流（429）时按 Discord 返回的等待时间重试，最多 MAX_RETRIES 次
+func (d *DiscordNotifier) post(message DiscordMessage) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, d.webhook, bytes.NewReader(body))
+		if err != nil {
+			cancel()
+			return err
+		}
+		request.Header.Set("Content-Type", "application/json")
+		response, err := d.client.Do(request)
+		if err != nil {
+			cancel()
+			return err
+		}
+		content, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+		response.Body.Close()
+		cancel()
+
+		if response.StatusCode == http.StatusTooManyRequests && attempt < MAX_RETRIES {
+			wait := discordRetryAfter(response.Header, content)
+			fmt.Printf("Discord 限流，%s 后重试 (%d/%d)\n", wait.Round(time.Millisecond), attempt+1, MAX_RETRIES)
+			time.Sleep(wait)
+			continue
+		}
+		if response.StatusCode < 200 || response.StatusCode >= 300 {
+			return fmt.Errorf("HTTP %d: %s", response.StatusCode, strings.TrimSpace(string(content)))
+		}
+		return nil
+	}
+}
+
+// 限流响应中建议的等待时间：优先使用响应内容中的 retry_after（秒，可以有小数），
+// 其次是 Retry-After 头，都没有时等待1秒
+func discordRetryAfter(header http.Header, content []byte) time.Duration {
+	var body struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if json.Unmarshal(content, &body) == nil && body.RetryAfter > 0 {
+		return time.Duration(body.RetryAfter * float64(time.Second))
+	}
+	if seconds, err := strconv.ParseFloat(header.Get("Retry-After"), 64); err == nil && seconds > 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	return time.Second
+}
@@ -0,0 +1,384 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// 误判记录、备注等状态文件的当前格式版本；没有 version 字段的旧文件为版本 0
+const STATE_FILE_VERSION = 1
+
+// doctor 命令对一个文件的检查结果
+type DoctorCheck struct {
+	Name    string
+	Path    string
+	Size    int64
+	Entries int
+	Status  string
+	Parsed  bool // 文件能正常解析
+}
+
+// doctor 命令执行的修改
+type DoctorActions []string
+
+func (a *DoctorActions) add(format string, args ...interface{}) {
+	*a = append(*a, fmt.Sprintf(format, args...))
+}
+
+// 状态文件中的格式版本
+type stateFileHeader struct {
+	Version int `json:"version"`
+}
+
+// doctor 命令：检查状态目录中的文件能否解析，显示条目数量和大小，
+// 迁移旧格式，隔离损坏的文件；指定 --prune 时清理服务器上已不存在的种子的记录
+func runDoctor(reader *bufio.Reader, args []string) {
+	// 开头的非参数为计划文件
+	var planPaths []string
+	for len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		planPaths = append(planPaths, args[0])
+		args = args[1:]
+	}
+	opts := parseOptions(args)
+
+	var known map[string]bool
+	if opts.Prune {
+		known = serverHashes(reader, opts)
+	}
+
+	fmt.Printf("状态目录: %s\n", stateDir())
+	var actions DoctorActions
+	checks := []DoctorCheck{
+		checkHistoryFile(known, &actions),
+		checkIgnoresFile(known, &actions),
+		checkNotesFile(known, &actions),
+		checkUploadSnapshotFile(known, &actions),
+		checkMetricsFile(&actions),
+	}
+	for _, path := range planPaths {
+		checks = append(checks, checkPlanFile(path))
+	}
+
+	fmt.Println("\n文件检查:")
+	for _, check := range checks {
+		line := fmt.Sprintf("  %s (%s): %s", check.Name, check.Path, check.Status)
+		if check.Parsed {
+			line += fmt.Sprintf(", %d 条, %s", check.Entries, formatSize(check.Size))
+		}
+		fmt.Println(line)
+	}
+
+	if len(actions) == 0 {
+		fmt.Println("\n未做任何修改")
+		return
+	}
+	fmt.Printf("\n已执行 %d 项修改:\n", len(actions))
+	for _, action := range actions {
+		fmt.Printf("  - %s\n", action)
+	}
+}
+
+// 连接服务器获取全部种子的hash
+func serverHashes(reader *bufio.Reader, opts Options) map[string]bool {
+	params := opts.Connection
+	if !opts.ConnectionSet {
+		params = readConnectionParams(reader)
+		params.Proxy = opts.Connection.Proxy
+	}
+	client, err := connect(params)
+	if err != nil {
+		log.Fatalf("无法连接到 Transmission 服务器%s: %v", params.proxyHint(), err)
+	}
+	torrents, err := getTorrentsChunked(client, []string{"id", "hashString"})
+	if err != nil {
+		log.Fatalf("获取 torrent 列表失败%s: %v", params.proxyHint(), err)
+	}
+	known := make(map[string]bool)
+	for _, torrent := range torrents {
+		if torrent.HashString != nil {
+			known[strings.ToLower(*torrent.HashString)] = true
+		}
+	}
+	return known
+}
+
+// 服务器上是否已不存在该hash的种子，未获取种子列表或hash为空时返回false
+func hashGone(known map[string]bool, hash string) bool {
+	return known != nil && hash != "" && !known[strings.ToLower(hash)]
+}
+
+// 文件大小，不存在时返回 -1
+func fileSize(path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return -1
+	}
+	return info.Size()
+}
+
+// 把损坏的文件改名为 .broken，已有同名文件时加上时间
+func quarantine(path string, actions *DoctorActions) string {
+	target := path + ".broken"
+	if _, err := os.Stat(target); err == nil {
+		target = fmt.Sprintf("%s.%s.broken", path, time.Now().Format("20060102-150405"))
+	}
+	if err := os.Rename(path, target); err != nil {
+		return fmt.Sprintf("损坏，隔离失败: %v", err)
+	}
+	actions.add("已隔离损坏的文件 %s -> %s", filepath.Base(path), filepath.Base(target))
+	return "损坏，已隔离为 " + filepath.Base(target)
+}
+
+// 读取状态文件的格式版本
+func stateFileVersion(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	var header stateFileHeader
+	if err := json.Unmarshal(data, &header); err != nil {
+		return 0, err
+	}
+	return header.Version, nil
+}
+
+// 检查版本：较新版本写入的文件不修改，旧版本需要迁移时返回true
+func checkStateVersion(path string, check *DoctorCheck) (bool, bool) {
+	version, err := stateFileVersion(path)
+	if err != nil {
+		return false, false
+	}
+	if version > STATE_FILE_VERSION {
+		check.Status = fmt.Sprintf("由较新版本写入（格式版本 %d，当前支持 %d），未修改", version, STATE_FILE_VERSION)
+		return false, true
+	}
+	return version < STATE_FILE_VERSION, false
+}
+
+// 检查操作历史，清理时删除服务器上已不存在的种子的记录
+func checkHistoryFile(known map[string]bool, actions *DoctorActions) DoctorCheck {
+	path := historyPath()
+	check := DoctorCheck{Name: "操作历史", Path: path, Size: fileSize(path)}
+	if check.Size < 0 {
+		check.Status = "不存在"
+		return check
+	}
+	records, err := loadHistory(path)
+	if err != nil {
+		check.Status = quarantine(path, actions)
+		return check
+	}
+	check.Entries = len(records)
+	check.Status = "正常"
+	check.Parsed = true
+
+	var kept []HistoryRecord
+	for _, record := range records {
+		if record.Action != ACTION_UNDO && hashGone(known, record.Hash) {
+			continue
+		}
+		kept = append(kept, record)
+	}
+	if removed := len(records) - len(kept); removed > 0 {
+		if err := saveHistory(path, kept); err != nil {
+			check.Status = fmt.Sprintf("清理失败: %v", err)
+			return check
+		}
+		actions.add("操作历史: 删除 %d 条已不存在的种子的记录", removed)
+		check.Entries, check.Size = len(kept), fileSize(path)
+	}
+	return check
+}
+
+// 重写全部历史记录
+func saveHistory(path string, records []HistoryRecord) error {
+	var builder strings.Builder
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		builder.Write(data)
+		builder.WriteByte('\n')
+	}
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, []byte(builder.String()), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// 检查误判记录，迁移旧格式，清理时删除合集或分集已不存在的记录
+func checkIgnoresFile(known map[string]bool, actions *DoctorActions) DoctorCheck {
+	path := ignoresPath()
+	check := DoctorCheck{Name: "误判记录", Path: path, Size: fileSize(path)}
+	if check.Size < 0 {
+		check.Status = "不存在"
+		return check
+	}
+	pairs, err := loadIgnores(path)
+	if err != nil {
+		check.Status = quarantine(path, actions)
+		return check
+	}
+	check.Entries = len(pairs)
+	check.Status = "正常"
+	check.Parsed = true
+	migrate, newer := checkStateVersion(path, &check)
+	if newer {
+		return check
+	}
+
+	var kept []IgnoredPair
+	for _, pair := range pairs {
+		if hashGone(known, pair.CollectionHash) || hashGone(known, pair.EpisodeHash) {
+			continue
+		}
+		kept = append(kept, pair)
+	}
+	removed := len(pairs) - len(kept)
+	if removed == 0 && !migrate {
+		return check
+	}
+	if err := saveIgnores(path, kept); err != nil {
+		check.Status = fmt.Sprintf("保存失败: %v", err)
+		return check
+	}
+	if migrate {
+		actions.add("误判记录: 已迁移到格式版本 %d", STATE_FILE_VERSION)
+	}
+	if removed > 0 {
+		actions.add("误判记录: 删除 %d 条已不存在的种子的记录", removed)
+	}
+	check.Entries, check.Size = len(kept), fileSize(path)
+	return check
+}
+
+// 检查备注，迁移旧格式，清理时删除合集已不存在的备注
+func checkNotesFile(known map[string]bool, actions *DoctorActions) DoctorCheck {
+	path := notesPath()
+	check := DoctorCheck{Name: "组备注", Path: path, Size: fileSize(path)}
+	if check.Size < 0 {
+		check.Status = "不存在"
+		return check
+	}
+	notes, err := loadNotes(path)
+	if err != nil {
+		check.Status = quarantine(path, actions)
+		return check
+	}
+	check.Entries = len(notes)
+	check.Status = "正常"
+	check.Parsed = true
+	migrate, newer := checkStateVersion(path, &check)
+	if newer {
+		return check
+	}
+
+	var kept []GroupNote
+	for _, note := range notes {
+		if hashGone(known, note.CollectionHash) {
+			continue
+		}
+		kept = append(kept, note)
+	}
+	removed := len(notes) - len(kept)
+	if removed == 0 && !migrate {
+		return check
+	}
+	if err := saveNotes(path, kept); err != nil {
+		check.Status = fmt.Sprintf("保存失败: %v", err)
+		return check
+	}
+	if migrate {
+		actions.add("组备注: 已迁移到格式版本 %d", STATE_FILE_VERSION)
+	}
+	if removed > 0 {
+		actions.add("组备注: 删除 %d 条已不存在的合集的备注", removed)
+	}
+	check.Entries, check.Size = len(kept), fileSize(path)
+	return check
+}
+
+// 检查上传量快照，清理时删除已不存在的种子
+func checkUploadSnapshotFile(known map[string]bool, actions *DoctorActions) DoctorCheck {
+	path := uploadSnapshotPath()
+	check := DoctorCheck{Name: "上传量快照", Path: path, Size: fileSize(path)}
+	if check.Size < 0 {
+		check.Status = "不存在"
+		return check
+	}
+	snapshot, err := loadUploadSnapshot(path)
+	if err != nil {
+		check.Status = quarantine(path, actions)
+		return check
+	}
+	check.Entries = len(snapshot)
+	check.Status = "正常"
+	check.Parsed = true
+
+	removed := 0
+	for hash := range snapshot {
+		if hashGone(known, hash) {
+			delete(snapshot, hash)
+			removed++
+		}
+	}
+	if removed == 0 {
+		return check
+	}
+	if err := saveUploadSnapshot(path, snapshot); err != nil {
+		check.Status = fmt.Sprintf("保存失败: %v", err)
+		return check
+	}
+	actions.add("上传量快照: 删除 %d 个已不存在的种子", removed)
+	check.Entries, check.Size = len(snapshot), fileSize(path)
+	return check
+}
+
+// 检查守护模式的扫描指标
+func checkMetricsFile(actions *DoctorActions) DoctorCheck {
+	path := filepath.Join(stateDir(), "metrics.json")
+	check := DoctorCheck{Name: "扫描指标", Path: path, Size: fileSize(path)}
+	if check.Size < 0 {
+		check.Status = "不存在"
+		return check
+	}
+	data, err := os.ReadFile(path)
+	var summary CycleSummary
+	if err == nil {
+		err = json.Unmarshal(data, &summary)
+	}
+	if err != nil {
+		check.Status = quarantine(path, actions)
+		return check
+	}
+	check.Entries = 1
+	check.Parsed = true
+	check.Status = fmt.Sprintf("正常（第 %d 轮，%s）", summary.Cycle, summary.Time.Format("2006-01-02 15:04:05"))
+	return check
+}
+
+// 检查计划文件，计划由用户指定路径，损坏时不隔离
+func checkPlanFile(path string) DoctorCheck {
+	check := DoctorCheck{Name: "计划", Path: path, Size: fileSize(path)}
+	if check.Size < 0 {
+		check.Status = "不存在"
+		return check
+	}
+	plan, err := loadPlan(path)
+	if err != nil {
+		check.Status = fmt.Sprintf("无法读取: %v", err)
+		return check
+	}
+	check.Entries = len(plan.Groups)
+	check.Parsed = true
+	check.Status = fmt.Sprintf("正常（%s 创建）", plan.CreatedAt.Format("2006-01-02 15:04:05"))
+	return check
+}
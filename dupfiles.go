@@ -0,0 +1,95 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 按文件名索引的文件：同一文件名可能对应多个文件（合集中不同目录的同名文件，或制作有误的合集中重复的路径）
+type fileNameIndex map[string][]*transmissionrpc.TorrentFile
+
+// 按文件名为文件建立索引，同名文件全部保留
+func newFileNameIndex(files []*transmissionrpc.TorrentFile) fileNameIndex {
+	index := make(fileNameIndex)
+	for _, file := range files {
+		name := getFileName(file.Name)
+		index[name] = append(index[name], file)
+	}
+	return index
+}
+
+// 同名文件中大小相同或最接近的一个，没有同名文件时返回nil
+func (index fileNameIndex) bestBySize(name string, size int64) *transmissionrpc.TorrentFile {
+	return closestBySize(index[name], size)
+}
+
+// 候选文件中大小相同或最接近的一个，大小差距相同时使用先出现的文件
+func closestBySize(candidates []*transmissionrpc.TorrentFile, size int64) *transmissionrpc.TorrentFile {
+	var best *transmissionrpc.TorrentFile
+	var bestDiff int64
+	for _, candidate := range candidates {
+		diff := candidate.Length - size
+		if diff < 0 {
+			diff = -diff
+		}
+		if best == nil || diff < bestDiff {
+			best, bestDiff = candidate, diff
+		}
+	}
+	return best
+}
+
+// 为每个分集文件在合集中找到匹配的文件，没有时为nil。每个合集文件最多匹配一个分集文件，
+// 有多个候选时使用大小相同或最接近的文件，重复的路径不会使匹配数量虚高
+func matchFiles(episodeFiles, collectionFiles []*transmissionrpc.TorrentFile, match func(episodePath, collectionPath string) bool) []*transmissionrpc.TorrentFile {
+	used := make(map[*transmissionrpc.TorrentFile]bool)
+	matched := make([]*transmissionrpc.TorrentFile, len(episodeFiles))
+	for i, episodeFile := range episodeFiles {
+		var candidates []*transmissionrpc.TorrentFile
+		for _, collectionFile := range collectionFiles {
+			if !used[collectionFile] && match(episodeFile.Name, collectionFile.Name) {
+				candidates = append(candidates, collectionFile)
+			}
+		}
+		if best := closestBySize(candidates, episodeFile.Length); best != nil {
+			used[best] = true
+			matched[i] = best
+		}
+	}
+	return matched
+}
+
+// 找到匹配文件的分集文件数量
+func matchedCount(matched []*transmissionrpc.TorrentFile) int {
+	count := 0
+	for _, file := range matched {
+		if file != nil {
+			count++
+		}
+	}
+	return count
+}
+
+// 同一种子中重复出现的相对路径，按第二次出现的顺序，每个路径只列一次
+func duplicatePaths(files []*transmissionrpc.TorrentFile) []string {
+	counts := make(map[string]int)
+	var duplicates []string
+	for _, file := range files {
+		counts[file.Name]++
+		if counts[file.Name] == 2 {
+			duplicates = append(duplicates, file.Name)
+		}
+	}
+	return duplicates
+}
+
+// 种子中有重复的路径时打印警告，这通常说明合集制作有误；每次扫描每个种子只在获取文件列表时检查一次
+func warnDuplicatePaths(torrentID int64, files []*transmissionrpc.TorrentFile) {
+	duplicates := duplicatePaths(files)
+	if len(duplicates) == 0 {
+		return
+	}
+	log.Printf("警告: 种子 ID: %d 中有 %d 个重复的文件路径（%s），可能是制作有误的合集，同名文件按大小匹配", torrentID, len(duplicates), strings.Join(duplicates, ", "))
+}
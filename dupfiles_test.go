@@ -0,0 +1,105 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 制作有误的合集：同一相对路径出现两次，大小不同
+var brokenPack = torrentFiles(
+	testFile{"Show.S01/Show.S01E01.mkv", 1000},
+	testFile{"Show.S01/Show.S01E02.mkv", 900},
+	testFile{"Show.S01/Show.S01E02.mkv", 1100},
+	testFile{"Show.S01/Show.S01E03.mkv", 1000},
+)
+
+func TestDuplicatePaths(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []testFile
+		want  []string
+	}{
+		{"没有重复", []testFile{{"a.mkv", 1}, {"b.mkv", 1}}, nil},
+		{"不同目录的同名文件不是重复路径", []testFile{{"S01/a.mkv", 1}, {"S02/a.mkv", 1}}, nil},
+		{"按第二次出现的顺序，每个路径只列一次", []testFile{{"b.mkv", 1}, {"a.mkv", 1}, {"a.mkv", 2}, {"b.mkv", 2}, {"a.mkv", 3}}, []string{"a.mkv", "b.mkv"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := duplicatePaths(torrentFiles(tt.files...)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("duplicatePaths() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileNameIndexBestBySize(t *testing.T) {
+	index := newFileNameIndex(brokenPack)
+	if got := len(index["Show.S01E02.mkv"]); got != 2 {
+		t.Fatalf("同名文件 %d 个，应全部保留 2 个", got)
+	}
+	tests := []struct {
+		size int64
+		want int64
+	}{
+		{1100, 1100},
+		{900, 900},
+		{1050, 1100},
+		{1000, 900}, // 差距相同时使用先出现的文件
+	}
+	for _, tt := range tests {
+		if got := index.bestBySize("Show.S01E02.mkv", tt.size); got == nil || got.Length != tt.want {
+			t.Errorf("bestBySize(%d) = %v，应为大小 %d 的文件", tt.size, got, tt.want)
+		}
+	}
+	if got := index.bestBySize("Show.S01E04.mkv", 1000); got != nil {
+		t.Errorf("没有同名文件时应返回nil，得到 %v", got)
+	}
+}
+
+func TestMatchFilesDuplicatedPaths(t *testing.T) {
+	sameName := func(episodePath, collectionPath string) bool {
+		return getFileName(episodePath) == getFileName(collectionPath)
+	}
+	tests := []struct {
+		name    string
+		episode []testFile
+		want    []int64 // 各分集文件匹配的合集文件大小，0 表示没有匹配
+		count   int
+	}{
+		{
+			name:    "按大小匹配重复路径中的一个",
+			episode: []testFile{{"E02/Show.S01E02.mkv", 1100}},
+			want:    []int64{1100},
+			count:   1,
+		},
+		{
+			name:    "合集文件不会被两个分集文件重复匹配",
+			episode: []testFile{{"E01/Show.S01E01.mkv", 1000}, {"a/Show.S01E01.mkv", 1000}},
+			want:    []int64{1000, 0},
+			count:   1,
+		},
+		{
+			name:    "重复路径各匹配一次，不会虚高",
+			episode: []testFile{{"a/Show.S01E02.mkv", 900}, {"b/Show.S01E02.mkv", 1100}, {"c/Show.S01E02.mkv", 1000}},
+			want:    []int64{900, 1100, 0},
+			count:   2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched := matchFiles(torrentFiles(tt.episode...), brokenPack, sameName)
+			got := make([]int64, len(matched))
+			for i, file := range matched {
+				if file != nil {
+					got[i] = file.Length
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matchFiles() 匹配大小 %v, want %v", got, tt.want)
+			}
+			if count := matchedCount(matched); count != tt.count {
+				t.Errorf("matchedCount() = %d, want %d", count, tt.count)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 附加内容（字幕包、花絮）显示的策略名称
+const EXTRAS_POLICY = "附加内容"
+
+// 字幕和花絮文件占内容的比例达到该值时视为附加内容
+const EXTRAS_SHARE = 0.9
+
+// 花絮所在的目录名，如 Extras、Bonus、Featurettes
+var extrasDirRegex = regexp.MustCompile(`(?i)^(?:extras?|bonus(?:[ ._\-]?features?)?|featurettes?|behind[ ._\-]the[ ._\-]scenes|特典|花絮)$`)
+
+// 是否为字幕或花絮文件
+func isExtrasFile(filePath string) bool {
+	if subtitleExtensions[path.Ext(strings.ToLower(filePath))] {
+		return true
+	}
+	parts := strings.FieldsFunc(filePath, func(r rune) bool { return r == '/' || r == '\\' })
+	for _, part := range parts[:max(len(parts)-1, 0)] {
+		if extrasDirRegex.MatchString(part) {
+			return true
+		}
+	}
+	return false
+}
+
+// 字幕和花絮文件占内容文件的比例，按大小计算；大小未知时按文件数量计算
+func extrasShare(files []*transmissionrpc.TorrentFile) float64 {
+	content := contentFiles(files)
+	if len(content) == 0 {
+		return 0
+	}
+	var total, extras int64
+	var extrasCount int
+	for _, file := range content {
+		total += file.Length
+		if isExtrasFile(file.Name) {
+			extras += file.Length
+			extrasCount++
+		}
+	}
+	if total <= 0 {
+		return float64(extrasCount) / float64(len(content))
+	}
+	return float64(extras) / float64(total)
+}
+
+// 找出组内的附加内容：名称与剧集相同的字幕包或花絮种子与合集的字幕文件重合，
+// 但用途不同，默认列为被策略暂缓，指定 --include-extras 时照常处理
+func applyExtras(client *transmissionrpc.Client, result *ScanResult, includeExtras bool) {
+	for name, group := range result.DuplicateGroups {
+		var remaining []*transmissionrpc.Torrent
+		for _, episode := range group.Episodes {
+			if episode == nil || episode.ID == nil {
+				remaining = append(remaining, episode)
+				continue
+			}
+			files, err := getTorrentFiles(client, episode.ID)
+			if err != nil {
+				remaining = append(remaining, episode)
+				continue
+			}
+			share := extrasShare(files)
+			if share < EXTRAS_SHARE {
+				remaining = append(remaining, episode)
+				continue
+			}
+
+			policy := fmt.Sprintf("%s（字幕/花絮占 %.0f%%）", EXTRAS_POLICY, share*100)
+			if includeExtras {
+				if group.EpisodePolicies == nil {
+					group.EpisodePolicies = make(map[int64]string)
+				}
+				group.EpisodePolicies[*episode.ID] = policy
+				remaining = append(remaining, episode)
+				continue
+			}
+			group.GatedEpisodes = append(group.GatedEpisodes, GatedEpisode{
+				Episode: episode,
+				Policy:  policy,
+				Reason:  "字幕包或花絮，使用 --include-extras 处理",
+			})
+		}
+
+		group.Episodes = remaining
+		if len(remaining) == 0 {
+			delete(result.DuplicateGroups, name)
+			result.GatedGroups[name] = group
+			continue
+		}
+		result.DuplicateGroups[name] = group
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtrasShare(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []testFile
+		want  float64
+	}{
+		{"字幕包", []testFile{{"Subs/E01.en.srt", 100}, {"Subs/E01.zh.ass", 100}}, 1},
+		{"花絮目录", []testFile{{"Show/Bonus Features/Making.Of.mkv", 300}, {"Show/Featurettes/Cast.mkv", 100}}, 1},
+		{"带字幕的分集", []testFile{{"Show/E01.mkv", 900}, {"Show/E01.srt", 100}}, 0.1},
+		{"文件名中的 Extras 不是目录", []testFile{{"Show/Show.Extras.S01E01.mkv", 100}}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := extrasShare(torrentFiles(tt.files...)); got != tt.want {
+				t.Errorf("extrasShare() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// extras.json: ID 1 为带字幕和花絮的合集，ID 2 为同名的字幕包，ID 3 为同名的花絮种子，ID 4 为普通分集
+func TestExtrasFixture(t *testing.T) {
+	const name = "Show.F.S01.1080p.BluRay"
+	for _, include := range []bool{false, true} {
+		var args []string
+		if include {
+			args = []string{"--include-extras"}
+		}
+		_, result, _ := scanFixture(t, "extras.json", args...)
+		group, ok := result.DuplicateGroups[name]
+		if !ok {
+			t.Fatalf("--include-extras=%v: 没有找到需要处理的组", include)
+		}
+
+		// 字幕包没有视频文件，不是视频合集的分集，无论是否指定 --include-extras 都不处理
+		skipped := false
+		for _, record := range result.Skipped {
+			if record.Reason == SKIP_NO_VIDEO && len(record.Torrents) == 1 && *record.Torrents[0].ID == 2 {
+				skipped = true
+			}
+		}
+		if !skipped {
+			t.Errorf("--include-extras=%v: 字幕包 ID 2 应列为没有视频文件而跳过", include)
+		}
+
+		wantEpisodes := []int64{4}
+		if include {
+			wantEpisodes = []int64{3, 4}
+		}
+		if got := splitIDs(result.DuplicateGroups)[name]; !reflect.DeepEqual(got, wantEpisodes) {
+			t.Errorf("--include-extras=%v: 需要处理的分集 %v，应为 %v", include, got, wantEpisodes)
+		}
+
+		const policy = "附加内容（字幕/花絮占 100%）"
+		if include {
+			if got := group.EpisodePolicies[3]; got != policy {
+				t.Errorf("花絮 ID 3 的策略 %q，应为 %q", got, policy)
+			}
+			if len(group.GatedEpisodes) != 0 {
+				t.Errorf("指定 --include-extras 后不应有暂缓的分集，得到 %d 个", len(group.GatedEpisodes))
+			}
+			continue
+		}
+		if len(group.GatedEpisodes) != 1 || *group.GatedEpisodes[0].Episode.ID != 3 || group.GatedEpisodes[0].Policy != policy {
+			t.Errorf("花絮 ID 3 应列为被策略暂缓（%s），得到 %+v", policy, group.GatedEpisodes)
+		}
+	}
+}
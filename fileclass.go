@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 按扩展名划分的文件类别
+const (
+	FILE_CLASS_VIDEO    = "video"
+	FILE_CLASS_AUDIO    = "audio"
+	FILE_CLASS_SUBTITLE = "subs"
+	FILE_CLASS_OTHER    = "other"
+)
+
+// 文件类别按固定顺序显示
+var fileClassOrder = []string{FILE_CLASS_VIDEO, FILE_CLASS_AUDIO, FILE_CLASS_SUBTITLE, FILE_CLASS_OTHER}
+
+// 文件类别的中文名称
+var fileClassLabels = map[string]string{
+	FILE_CLASS_VIDEO:    "视频",
+	FILE_CLASS_AUDIO:    "音频",
+	FILE_CLASS_SUBTITLE: "字幕",
+	FILE_CLASS_OTHER:    "其他",
+}
+
+// 视频文件扩展名
+var videoExtensions = map[string]bool{
+	".mkv":  true,
+	".mp4":  true,
+	".m4v":  true,
+	".ts":   true,
+	".m2ts": true,
+	".avi":  true,
+	".wmv":  true,
+	".mov":  true,
+	".webm": true,
+	".mpg":  true,
+	".mpeg": true,
+	".flv":  true,
+	".rmvb": true,
+	".vob":  true,
+}
+
+// 音频文件扩展名（独立音轨、原声等）
+var audioExtensions = map[string]bool{
+	".mka":  true,
+	".flac": true,
+	".mp3":  true,
+	".aac":  true,
+	".m4a":  true,
+	".ac3":  true,
+	".dts":  true,
+	".ogg":  true,
+	".opus": true,
+	".wav":  true,
+}
+
+// 判断分集与合集关系时视频文件的重叠比例下限
+var videoOverlapThreshold = DEFAULT_VIDEO_OVERLAP
+
+// 默认的视频重叠比例下限
+const DEFAULT_VIDEO_OVERLAP = 0.5
+
+// 设置视频重叠比例下限（0~1）
+func setVideoOverlapThreshold(threshold float64) {
+	videoOverlapThreshold = threshold
+}
+
+// 文件的类别，按扩展名判断
+func fileClass(filePath string) string {
+	extension := path.Ext(strings.ToLower(filePath))
+	switch {
+	case videoExtensions[extension]:
+		return FILE_CLASS_VIDEO
+	case audioExtensions[extension]:
+		return FILE_CLASS_AUDIO
+	case subtitleExtensions[extension]:
+		return FILE_CLASS_SUBTITLE
+	}
+	return FILE_CLASS_OTHER
+}
+
+// 一种类别的分集文件在合集中找到的数量
+type ClassOverlap struct {
+	Class   string
+	Matched int
+	Total   int
+}
+
+// 按类别统计分集的内容文件在合集中找到的数量，只包含分集有文件的类别，按固定顺序
+func classOverlaps(collectionFiles, episodeFiles []*transmissionrpc.TorrentFile) []ClassOverlap {
+	byClass := make(map[string]*ClassOverlap)
+	content := contentFiles(episodeFiles)
+	matched := matchFiles(content, collectionFiles, fileNamesMatch)
+	for i, episodeFile := range content {
+		class := fileClass(episodeFile.Name)
+		overlap, ok := byClass[class]
+		if !ok {
+			overlap = &ClassOverlap{Class: class}
+			byClass[class] = overlap
+		}
+		overlap.Total++
+		if matched[i] != nil {
+			overlap.Matched++
+		}
+	}
+	var overlaps []ClassOverlap
+	for _, class := range fileClassOrder {
+		if overlap, ok := byClass[class]; ok {
+			overlaps = append(overlaps, *overlap)
+		}
+	}
+	return overlaps
+}
+
+// 合并多个分集的按类别统计，按固定顺序
+func mergeClassOverlaps(total []ClassOverlap, overlaps []ClassOverlap) []ClassOverlap {
+	byClass := make(map[string]ClassOverlap)
+	for _, list := range [][]ClassOverlap{total, overlaps} {
+		for _, overlap := range list {
+			merged := byClass[overlap.Class]
+			merged.Class = overlap.Class
+			merged.Matched += overlap.Matched
+			merged.Total += overlap.Total
+			byClass[overlap.Class] = merged
+		}
+	}
+	var merged []ClassOverlap
+	for _, class := range fileClassOrder {
+		if overlap, ok := byClass[class]; ok {
+			merged = append(merged, overlap)
+		}
+	}
+	return merged
+}
+
+// 描述按类别的重叠，如 "视频 8/8, 字幕 2/6"
+func describeClassOverlaps(overlaps []ClassOverlap) string {
+	var parts []string
+	for _, overlap := range overlaps {
+		parts = append(parts, fmt.Sprintf("%s %d/%d", fileClassLabels[overlap.Class], overlap.Matched, overlap.Total))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// 种子中的视频文件数量
+func videoFileCount(files []*transmissionrpc.TorrentFile) int {
+	count := 0
+	for _, file := range contentFiles(files) {
+		if fileClass(file.Name) == FILE_CLASS_VIDEO {
+			count++
+		}
+	}
+	return count
+}
+
+// 分集没有视频文件而合集有：不可能是视频合集的分集（如同名的字幕包）
+func lacksVideo(collectionFiles, episodeFiles []*transmissionrpc.TorrentFile) bool {
+	return videoFileCount(episodeFiles) == 0 && videoFileCount(collectionFiles) > 0
+}
+
+// 分集的视频文件在合集中找到的比例，分集没有视频文件时第二个值为false
+func videoOverlap(collectionFiles, episodeFiles []*transmissionrpc.TorrentFile) (float64, bool) {
+	for _, overlap := range classOverlaps(collectionFiles, episodeFiles) {
+		if overlap.Class == FILE_CLASS_VIDEO {
+			return float64(overlap.Matched) / float64(overlap.Total), true
+		}
+	}
+	return 0, false
+}
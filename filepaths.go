@@ -0,0 +1,83 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 只表示季的目录名，如 Season 1、S01、第1季，比较剧名时跳过
+var seasonDirRegex = regexp.MustCompile(`(?i)^(?:season[ ._]?\d{1,2}|s\d{1,2}|第[ ._]?\d{1,2}[ ._]?季|specials?)$`)
+
+// 文件名匹配时是否还要求上级目录的剧名一致
+var requireParentMatch bool
+
+// 设置是否要求上级目录的剧名一致
+func setRequireParentMatch(require bool) {
+	requireParentMatch = require
+}
+
+// 文件路径中的目录，从最内层到最外层
+func parentDirs(filePath string) []string {
+	parts := strings.FieldsFunc(filePath, func(r rune) bool { return r == '/' || r == '\\' })
+	if len(parts) <= 1 {
+		return nil
+	}
+	dirs := parts[:len(parts)-1]
+	reversed := make([]string, len(dirs))
+	for i, dir := range dirs {
+		reversed[len(dirs)-1-i] = dir
+	}
+	return reversed
+}
+
+// 文件所在目录的剧名：从最内层开始跳过只表示季的目录，能识别剧名时使用剧名，
+// 否则使用小写且去掉分隔符的目录名；没有目录时返回空
+func parentShowKey(filePath string) string {
+	for _, dir := range parentDirs(filePath) {
+		name := canonicalName(dir)
+		if seasonDirRegex.MatchString(strings.TrimSpace(name)) {
+			continue
+		}
+		if key := seriesShowKey(name); key != "" {
+			return key
+		}
+		normalized := strings.ToLower(strings.NewReplacer(".", " ", "_", " ", "-", " ").Replace(name))
+		return strings.Join(strings.Fields(normalized), " ")
+	}
+	return ""
+}
+
+// 两个文件的上级目录的剧名是否一致，未开启 --require-parent-match 或任一方没有目录时视为一致
+func parentsCompatible(episodePath, collectionPath string) bool {
+	if !requireParentMatch {
+		return true
+	}
+	episodeKey, collectionKey := parentShowKey(episodePath), parentShowKey(collectionPath)
+	return episodeKey == "" || collectionKey == "" || episodeKey == collectionKey
+}
+
+// 按文件名匹配分集文件和合集文件：文件名相同或合集文件名包含分集文件名，
+// 开启 --require-parent-match 时上级目录的剧名也必须一致
+func fileNamesMatch(episodePath, collectionPath string) bool {
+	episodeFileName, collectionFileName := getFileName(episodePath), getFileName(collectionPath)
+	if episodeFileName != collectionFileName && !strings.Contains(collectionFileName, episodeFileName) {
+		return false
+	}
+	return parentsCompatible(episodePath, collectionPath)
+}
+
+// 报告中显示的文件路径：去掉种子的根目录，保留其下的相对路径，
+// 同名文件位于不同目录时可以区分；单文件种子显示文件名
+func displayPath(torrent *transmissionrpc.Torrent, filePath string) string {
+	if torrent == nil || torrent.Name == nil {
+		return filePath
+	}
+	for _, separator := range []string{"/", "\\"} {
+		if relative, ok := strings.CutPrefix(filePath, *torrent.Name+separator); ok && relative != "" {
+			return relative
+		}
+	}
+	return filePath
+}
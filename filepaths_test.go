@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestParentShowKey(t *testing.T) {
+	tests := map[string]string{
+		"Show.S01/E03.mkv":               "show",
+		"Other.Show/E03.mkv":             "other show",
+		"Other.Show/Season 1/E03.mkv":    "other show",
+		"[Group] Show S01 1080p/E03.mkv": "show",
+		"Show.S01/Specials/E00.mkv":      "show",
+		"E03.mkv":                        "",
+		"Other.Show\\Season 01\\E03.mkv": "other show",
+	}
+	for filePath, want := range tests {
+		if got := parentShowKey(filePath); got != want {
+			t.Errorf("parentShowKey(%q) = %q, want %q", filePath, got, want)
+		}
+	}
+}
+
+// 不同剧集的同名文件：未开启 --require-parent-match 时视为重叠，开启后不再重叠
+func TestRequireParentMatch(t *testing.T) {
+	t.Cleanup(func() { setRequireParentMatch(false) })
+	collection := torrentFiles(testFile{"Show.S01/E01.mkv", 1000}, testFile{"Show.S01/E02.mkv", 1000}, testFile{"Show.S01/E03.mkv", 1000})
+
+	tests := []struct {
+		require bool
+		path    string
+		overlap bool
+	}{
+		{false, "Other.Show/E03.mkv", true},
+		{true, "Other.Show/E03.mkv", false},
+		{true, "Show.S01/Season 1/E03.mkv", true},
+	}
+	for _, tt := range tests {
+		setRequireParentMatch(tt.require)
+		if got := fileNamesMatch(tt.path, "Show.S01/E03.mkv"); got != tt.overlap {
+			t.Errorf("--require-parent-match=%v: fileNamesMatch(%q) = %v, want %v", tt.require, tt.path, got, tt.overlap)
+		}
+		episode := torrentFiles(testFile{tt.path, 1000})
+		if got, _ := checkActualEpisodeOverlap(collection, episode); got != tt.overlap {
+			t.Errorf("--require-parent-match=%v: %s 与合集重叠 = %v, want %v", tt.require, tt.path, got, tt.overlap)
+		}
+	}
+}
@@ -2,9 +2,9 @@ module delete-episode
 
 go 1.22.5
 
-require github.com/hekmon/transmissionrpc/v2 v2.0.1
-
 require (
-	github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
-	github.com/hekmon/cunits/v2 v2.1.0 // indirect
+	github.com/hekmon/cunits/v2 v2.1.0
+	github.com/hekmon/transmissionrpc/v2 v2.0.1
 )
+
+require github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
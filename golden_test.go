@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+var update = flag.Bool("update", false, "按当前输出重新生成 testdata 中的 golden 文件")
+
+// 用 testdata 中记录的RPC数据扫描，不连接服务器；误判记录、备注等状态文件使用临时目录
+func scanFixture(t *testing.T, fixture string, args ...string) (*transmissionrpc.Client, *ScanResult, Options) {
+	t.Helper()
+	client, opts := connectFixture(t, fixture, args...)
+	result, err := scan(client, detectCapabilities(client), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, result, opts
+}
+
+// 解析参数并创建由 testdata 中记录的RPC数据回答请求的客户端
+func connectFixture(t *testing.T, fixture string, args ...string) (*transmissionrpc.Client, Options) {
+	t.Helper()
+	t.Setenv("DELETE_EPISODE_STATE_DIR", t.TempDir())
+	data, err := os.ReadFile(filepath.Join("testdata", fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rpc DiagRPC
+	if err := json.Unmarshal(data, &rpc); err != nil {
+		t.Fatalf("解析 %s 失败: %v", fixture, err)
+	}
+
+	opts := parseOptions(args)
+	enableDumpReplay(rpc)
+	t.Cleanup(func() { dumpReplay = nil })
+	client, err := connect(opts.Connection)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, opts
+}
+
+// 与 testdata 中的 golden 文件比较，指定 -update 时用当前输出覆盖 golden 文件
+func assertGolden(t *testing.T, golden string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", golden)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取 %s 失败（可用 -update 生成）: %v", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("输出与 %s 不一致（确认改动无误后用 -update 更新）\n--- 当前输出 ---\n%s", path, got)
+	}
+}
+
+func TestReportGolden(t *testing.T) {
+	tests := []struct {
+		name   string
+		args   []string
+		golden string
+	}{
+		{"text", nil, "report.golden"},
+		{"verbose", []string{"--verbose"}, "report_verbose.golden"},
+		// 并行分析与逐组分析的报告完全相同
+		{"sequential", []string{"--parallel", "1"}, "report.golden"},
+		{"parallel", []string{"--parallel", "8"}, "report.golden"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, result, opts := scanFixture(t, "scan.json", tt.args...)
+			var out bytes.Buffer
+			printReport(&out, fetchReportFiles(client, result.DuplicateGroups), result, opts.Action, opts.Verbose)
+			assertGolden(t, tt.golden, out.Bytes())
+		})
+	}
+}
+
+func TestCompactGolden(t *testing.T) {
+	_, result, opts := scanFixture(t, "scan.json", "--format", FORMAT_COMPACT)
+	var out bytes.Buffer
+	if err := writeCompact(&out, buildPlan(result, opts)); err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "compact.golden", out.Bytes())
+}
+
+func TestExportKeptGolden(t *testing.T) {
+	client, result, _ := scanFixture(t, "scan.json")
+	path := filepath.Join(t.TempDir(), "kept.json")
+	exporter := &KeptExporter{
+		path:        path,
+		collections: make(map[string]*transmissionrpc.Torrent),
+		succeeded:   make(map[string]bool),
+	}
+	exporter.track(result.DuplicateGroups)
+	for name := range result.DuplicateGroups {
+		exporter.succeed(name)
+	}
+	exporter.write(client)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertGolden(t, "export_kept.golden", data)
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 分集是否已经停止，暂停操作对其无需再执行
+func alreadyStopped(episode *transmissionrpc.Torrent) bool {
+	return episode.Status != nil && *episode.Status == transmissionrpc.TorrentStatusStopped
+}
+
+// 把操作为暂停且已经停止的分集移出待处理列表，全部分集都已停止的组移到"已处理"部分，
+// 重复运行时只对仍在运行的分集执行操作
+func applyAlreadyHandled(result *ScanResult, action string) {
+	for name, group := range result.DuplicateGroups {
+		var remaining []*transmissionrpc.Torrent
+		for _, episode := range group.Episodes {
+			if episode != nil && episode.ID != nil && alreadyStopped(episode) && pausesEpisode(group.episodeAction(episode, action)) {
+				group.HandledEpisodes = append(group.HandledEpisodes, episode)
+				continue
+			}
+			remaining = append(remaining, episode)
+		}
+		if len(group.HandledEpisodes) == 0 {
+			continue
+		}
+
+		group.Episodes = remaining
+		if len(remaining) == 0 {
+			delete(result.DuplicateGroups, name)
+			result.HandledGroups[name] = group
+			continue
+		}
+		result.DuplicateGroups[name] = group
+	}
+}
+
+// 显示已经暂停、无需再处理的分集
+func printHandledEpisodes(w io.Writer, handledEpisodes []*transmissionrpc.Torrent) {
+	if len(handledEpisodes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "已暂停 %d 个分集(无需操作):\n", len(handledEpisodes))
+	for i, episode := range handledEpisodes {
+		if episode.SizeWhenDone == nil {
+			continue
+		}
+		fmt.Fprintf(w, "  %d. ID: %d, 大小: %s\n", i+1, *episode.ID, formatSize(torrentBytes(episode)))
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/hekmon/cunits/v2"
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 测试用的种子文件：路径和大小
+type testFile struct {
+	Name   string
+	Length int64
+}
+
+// 按路径和大小构造种子文件列表
+func torrentFiles(files ...testFile) []*transmissionrpc.TorrentFile {
+	result := make([]*transmissionrpc.TorrentFile, len(files))
+	for i, file := range files {
+		result[i] = &transmissionrpc.TorrentFile{Name: file.Name, Length: file.Length, BytesCompleted: file.Length}
+	}
+	return result
+}
+
+// 构造测试用的种子，大小为各文件大小之和
+func testTorrent(id int64, name string, files ...testFile) *transmissionrpc.Torrent {
+	var total int64
+	for _, file := range files {
+		total += file.Length
+	}
+	size := cunits.Bits(total * 8)
+	return &transmissionrpc.Torrent{ID: &id, Name: &name, TotalSize: &size, SizeWhenDone: &size}
+}
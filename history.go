@@ -0,0 +1,357 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 撤销记录使用的操作类型
+const ACTION_UNDO = "undo"
+
+// 一条操作历史记录，用于撤销
+type HistoryRecord struct {
+	RunID                 string    `json:"run_id"`
+	Time                  time.Time `json:"time"`
+	Action                string    `json:"action"`
+	Group                 string    `json:"group,omitempty"`
+	TorrentID             int64     `json:"torrent_id,omitempty"`
+	Hash                  string    `json:"hash,omitempty"`
+	Name                  string    `json:"name,omitempty"`
+	PrevBandwidthPriority *int64    `json:"prev_bandwidth_priority,omitempty"` // 调整优先级前的带宽优先级
+	PrevWantedFiles       []int64   `json:"prev_wanted_files,omitempty"`       // 取消选择前已选择的文件序号
+	PrevSeedRatioMode     *int64    `json:"prev_seed_ratio_mode,omitempty"`    // 取消合集做种限制前的分享率模式
+	PrevSeedIdleMode      *int64    `json:"prev_seed_idle_mode,omitempty"`     // 取消合集做种限制前的空闲时间模式
+	PrevLabels            []string  `json:"prev_labels,omitempty"`             // 添加标签前的标签
+	UndoneRunID           string    `json:"undone_run_id,omitempty"`           // 撤销记录对应的运行ID
+
+	PrevSeedIdleLimit *int64 `json:"prev_seed_idle_limit,omitempty"` // 设置空闲时间限制前的空闲时间（分钟）
+
+	PrevGroup *string `json:"prev_group,omitempty"` // 移入带宽组前所在的带宽组，空字符串表示不在任何组中
+
+	PlannedAction string `json:"planned_action,omitempty"` // 清单记录中计划执行的操作
+
+	LinkedFiles []LinkOperation `json:"linked_files,omitempty"` // 原地升级替换为链接的分集文件
+}
+
+// 追加写入操作历史文件
+type HistoryWriter struct {
+	runID string
+	path  string
+	count int
+}
+
+// 获取状态目录（存放操作历史等文件）
+func stateDir() string {
+	if dir := os.Getenv("DELETE_EPISODE_STATE_DIR"); dir != "" {
+		return dir
+	}
+	if dir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(dir, "delete-episode")
+	}
+	return ".delete-episode"
+}
+
+// 操作历史文件路径
+func historyPath() string {
+	return filepath.Join(stateDir(), "history.jsonl")
+}
+
+// 为本次运行创建历史记录写入器
+func newHistoryWriter() *HistoryWriter {
+	return &HistoryWriter{
+		runID: time.Now().Format("20060102-150405"),
+		path:  historyPath(),
+	}
+}
+
+// 根据种子信息创建一条历史记录
+func newHistoryRecord(action, groupName string, torrent *transmissionrpc.Torrent) HistoryRecord {
+	record := HistoryRecord{
+		Time:   time.Now(),
+		Action: action,
+		Group:  groupName,
+	}
+	if torrent.ID != nil {
+		record.TorrentID = *torrent.ID
+	}
+	if torrent.HashString != nil {
+		record.Hash = *torrent.HashString
+	}
+	if torrent.Name != nil {
+		record.Name = *torrent.Name
+	}
+	return record
+}
+
+// 写入一条历史记录，写入失败只打印警告不影响操作
+func (w *HistoryWriter) Record(record HistoryRecord) {
+	record.RunID = w.runID
+	if err := appendHistory(w.path, record); err != nil {
+		log.Printf("写入操作历史失败: %v", err)
+		return
+	}
+	w.count++
+}
+
+// 本次运行写入的记录数
+func (w *HistoryWriter) Count() int {
+	return w.count
+}
+
+// 追加一条记录到历史文件
+func appendHistory(path string, record HistoryRecord) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// 读取全部历史记录
+func loadHistory(path string) ([]HistoryRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []HistoryRecord
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var record HistoryRecord
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return nil, fmt.Errorf("历史文件第 %d 行格式错误: %v", lineNumber, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// 找出最近一次尚未撤销的运行及其记录，安全模式已回滚的暂停不需要撤销
+func lastUndoableRun(records []HistoryRecord) (string, []HistoryRecord) {
+	undone := make(map[string]bool)
+	for _, record := range records {
+		if record.Action == ACTION_UNDO {
+			undone[record.UndoneRunID] = true
+		}
+	}
+
+	checked := make(map[string]bool)
+	for i := len(records) - 1; i >= 0; i-- {
+		runID := records[i].RunID
+		if records[i].Action == ACTION_UNDO || undone[runID] || checked[runID] {
+			continue
+		}
+		checked[runID] = true
+		if runRecords := undoableRecords(records, runID); len(runRecords) > 0 {
+			return runID, runRecords
+		}
+	}
+	return "", nil
+}
+
+// 一次运行中需要撤销的记录
+func undoableRecords(records []HistoryRecord, runID string) []HistoryRecord {
+	rolledBack := make(map[string]bool)
+	for _, record := range records {
+		if record.RunID == runID && record.Action == ACTION_ROLLBACK {
+			rolledBack[historyKey(record)] = true
+		}
+	}
+
+	var runRecords []HistoryRecord
+	for _, record := range records {
+		if record.RunID != runID || record.Action == ACTION_UNDO || record.Action == ACTION_ROLLBACK || record.Action == ACTION_PENDING_VETO || record.Action == ACTION_PLANNED {
+			continue
+		}
+		if record.Action == ACTION_PAUSE && rolledBack[historyKey(record)] {
+			continue
+		}
+		runRecords = append(runRecords, record)
+	}
+	return runRecords
+}
+
+// 历史记录对应的种子，优先使用hash
+func historyKey(record HistoryRecord) string {
+	if record.Hash != "" {
+		return record.Hash
+	}
+	return fmt.Sprint(record.TorrentID)
+}
+
+// 撤销最近一次运行的操作：恢复被暂停的分集，还原被修改的带宽优先级，重新选择被取消选择的文件，
+// 恢复被取消的合集做种限制，把原地升级的链接恢复为独立的文件
+func runUndo(reader *bufio.Reader, opts Options) {
+	records, err := loadHistory(historyPath())
+	if err != nil {
+		log.Fatalf("读取操作历史失败: %v", err)
+	}
+
+	runID, runRecords := lastUndoableRun(records)
+	if runID == "" {
+		fmt.Println("没有可撤销的操作")
+		return
+	}
+
+	fmt.Printf("最近一次操作 (%s) 共 %d 条记录:\n", runID, len(runRecords))
+	for i, record := range runRecords {
+		switch record.Action {
+		case ACTION_PRIORITY:
+			if record.PrevBandwidthPriority != nil {
+				fmt.Printf("  %d. 恢复优先级为%s: %s\n", i+1, priorityName(*record.PrevBandwidthPriority), record.Name)
+			}
+		case ACTION_PAUSE:
+			fmt.Printf("  %d. 恢复运行: %s\n", i+1, record.Name)
+		case ACTION_DESELECT:
+			fmt.Printf("  %d. 重新选择 %d 个文件: %s\n", i+1, len(record.PrevWantedFiles), record.Name)
+		case ACTION_UNLIMIT:
+			fmt.Printf("  %d. 恢复合集的做种限制并停止: %s\n", i+1, record.Name)
+		case ACTION_LABEL:
+			fmt.Printf("  %d. 移除标签 %s: %s\n", i+1, REVIEW_LABEL, record.Name)
+		case ACTION_IDLE_LIMIT:
+			fmt.Printf("  %d. 恢复空闲时间设置并重新开始: %s\n", i+1, record.Name)
+		case ACTION_BANDWIDTH_GROUP:
+			fmt.Printf("  %d. 恢复带宽组为%s: %s\n", i+1, describePrevBandwidthGroup(record.PrevGroup), record.Name)
+		case ACTION_PENDING_LABEL:
+			fmt.Printf("  %d. 移除标签 %s: %s\n", i+1, TWO_PHASE_LABEL, record.Name)
+		case ACTION_PENDING_CLEAR:
+			fmt.Printf("  %d. 恢复标签 %s: %s\n", i+1, TWO_PHASE_LABEL, record.Name)
+		case ACTION_LINK:
+			fmt.Printf("  %d. 把 %d 个链接恢复为独立的文件（需要 %s 空间）: %s\n", i+1, len(record.LinkedFiles), formatSize(linkedBytes(record.LinkedFiles)), record.Name)
+		}
+	}
+
+	params := opts.Connection
+	if !opts.ConnectionSet {
+		params = readConnectionParams(reader)
+		params.Proxy = opts.Connection.Proxy
+	}
+	printConnectionParams(params)
+	if !opts.Yes {
+		fmt.Print("确认撤销以上操作？(y/n) [默认: y]: ")
+		confirmInput, _ := reader.ReadString('\n')
+		confirmInput = strings.TrimSpace(confirmInput)
+		if confirmInput != "" && strings.ToLower(confirmInput) != "y" {
+			fmt.Println("已取消操作")
+			return
+		}
+	}
+
+	client, err := connect(params)
+	if err != nil {
+		log.Fatalf("无法连接到 Transmission 服务器%s: %v", params.proxyHint(), err)
+	}
+
+	restoredCount, missingCount, failedCount := 0, 0, 0
+	for _, record := range runRecords {
+		// 种子ID在Transmission重启后可能变化，按hash重新查找
+		torrentID, found := lookupTorrentID(client, record)
+		if !found {
+			fmt.Printf("种子已不存在，跳过: %s\n", record.Name)
+			missingCount++
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+		switch record.Action {
+		case ACTION_PRIORITY:
+			if record.PrevBandwidthPriority == nil {
+				cancel()
+				continue
+			}
+			err = client.TorrentSet(ctx, transmissionrpc.TorrentSetPayload{
+				IDs:               []int64{torrentID},
+				BandwidthPriority: record.PrevBandwidthPriority,
+			})
+		case ACTION_PAUSE:
+			err = client.TorrentStartIDs(ctx, []int64{torrentID})
+		case ACTION_DESELECT:
+			err = client.TorrentSet(ctx, transmissionrpc.TorrentSetPayload{
+				IDs:         []int64{torrentID},
+				FilesWanted: record.PrevWantedFiles,
+			})
+		case ACTION_UNLIMIT:
+			err = restoreSeedLimits(ctx, client, torrentID, record)
+		case ACTION_IDLE_LIMIT:
+			err = restoreIdleLimit(ctx, client, torrentID, record)
+		case ACTION_BANDWIDTH_GROUP:
+			err = restoreBandwidthGroup(ctx, client, torrentID, record)
+		case ACTION_LINK:
+			err = restoreLinkedFiles(record.LinkedFiles)
+		case ACTION_LABEL, ACTION_PENDING_LABEL, ACTION_PENDING_CLEAR:
+			// 空列表（而不是 null）才会清除全部标签
+			labels := append([]string{}, record.PrevLabels...)
+			err = client.TorrentSet(ctx, transmissionrpc.TorrentSetPayload{
+				IDs:    []int64{torrentID},
+				Labels: labels,
+			})
+		}
+		cancel()
+
+		if err != nil {
+			fmt.Printf("撤销失败 ID: %d (%s): %v\n", torrentID, record.Name, err)
+			failedCount++
+			continue
+		}
+		restoredCount++
+	}
+
+	if err := appendHistory(historyPath(), HistoryRecord{
+		RunID:       time.Now().Format("20060102-150405"),
+		Time:        time.Now(),
+		Action:      ACTION_UNDO,
+		UndoneRunID: runID,
+	}); err != nil {
+		log.Printf("写入操作历史失败: %v", err)
+	}
+
+	fmt.Printf("\n撤销完成: 成功恢复 %d 个种子, 已不存在 %d 个, 失败 %d 个\n", restoredCount, missingCount, failedCount)
+}
+
+// 按hash查找种子当前的ID，没有hash时使用记录中的ID
+func lookupTorrentID(client *transmissionrpc.Client, record HistoryRecord) (int64, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+	defer cancel()
+
+	var torrents []transmissionrpc.Torrent
+	var err error
+	if record.Hash != "" {
+		torrents, err = client.TorrentGetHashes(ctx, []string{"id", "hashString"}, []string{record.Hash})
+	} else {
+		torrents, err = client.TorrentGet(ctx, []string{"id", "hashString"}, []int64{record.TorrentID})
+	}
+	if err != nil || len(torrents) == 0 || torrents[0].ID == nil {
+		return 0, false
+	}
+	return *torrents[0].ID, true
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// 执行后命令的默认超时时间
+const DEFAULT_POST_HOOK_TIMEOUT = 5 * time.Minute
+
+// 执行后命令超时被终止后，等待其输出结束的时间
+const POST_HOOK_WAIT_DELAY = 5 * time.Second
+
+// 操作成功但执行后命令失败时的退出码
+const EXIT_HOOK_FAILED = 4
+
+// 本次运行中执行后命令是否失败过
+var postHookFailed atomic.Bool
+
+// 执行后命令：每次执行操作后运行用户指定的命令（如刷新媒体库），与其他通知渠道一样在有操作成功或失败时运行，
+// 试运行时不运行。命令通过 shell 执行，结果的JSON写入临时文件（路径见环境变量）并从标准输入传入，
+// 统计数量通过环境变量传入
+type PostHookNotifier struct {
+	command string
+	timeout time.Duration
+}
+
+func newPostHookNotifier(command string, timeout time.Duration) *PostHookNotifier {
+	return &PostHookNotifier{command: command, timeout: timeout}
+}
+
+func (h *PostHookNotifier) Name() string {
+	return "执行后命令"
+}
+
+// 运行命令，命令的输出直接显示；命令失败或超时时返回错误并记录，运行结束时以 EXIT_HOOK_FAILED 退出
+func (h *PostHookNotifier) Notify(summary RunSummary) error {
+	err := h.run(summary)
+	if err != nil {
+		postHookFailed.Store(true)
+	}
+	return err
+}
+
+func (h *PostHookNotifier) run(summary RunSummary) error {
+	report, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	file, err := os.CreateTemp("", "delete-episode-report-*.json")
+	if err != nil {
+		return fmt.Errorf("写入结果文件失败: %v", err)
+	}
+	defer os.Remove(file.Name())
+	_, err = file.Write(report)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("写入结果文件失败: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), h.timeout)
+	defer cancel()
+	cmd := shellCommand(ctx, h.command)
+	cmd.Stdin = bytes.NewReader(report)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.WaitDelay = POST_HOOK_WAIT_DELAY
+	cmd.Env = append(os.Environ(), postHookEnv(summary, file.Name())...)
+	fmt.Printf("运行执行后命令: %s\n", h.command)
+	err = cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("超过 %s 未结束，已终止", h.timeout)
+	}
+	return err
+}
+
+// 通过系统的 shell 执行命令，命令中可以带参数
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "sh", "-c", command)
+}
+
+// 传给执行后命令的环境变量
+func postHookEnv(summary RunSummary, reportPath string) []string {
+	return []string{
+		"DELETE_EPISODE_REPORT=" + reportPath,
+		"DELETE_EPISODE_SERVER=" + summary.Server,
+		"DELETE_EPISODE_GROUPS=" + strconv.Itoa(len(summary.Groups)),
+		"DELETE_EPISODE_PAUSED=" + strconv.Itoa(summary.Paused),
+		"DELETE_EPISODE_DELETED=" + strconv.Itoa(summary.Deleted),
+		"DELETE_EPISODE_OTHER=" + strconv.Itoa(summary.Other),
+		"DELETE_EPISODE_FAILED=" + strconv.Itoa(summary.Failed),
+		"DELETE_EPISODE_RECLAIMED_BYTES=" + strconv.FormatInt(summary.Reclaimed, 10),
+	}
+}
+
+// 执行后命令失败过时以 EXIT_HOOK_FAILED 退出；操作本身的结果不受影响
+func exitOnPostHookFailure() {
+	if postHookFailed.Load() {
+		fmt.Fprintln(os.Stderr, "操作已完成，但执行后命令失败")
+		os.Exit(EXIT_HOOK_FAILED)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 为分集设置很短的空闲时间限制，由 Transmission 在分集空闲后自行停止，而不是立即暂停
+const ACTION_IDLE_LIMIT = "idle-limit"
+
+// --idle-minutes 的默认值
+const DEFAULT_IDLE_MINUTES = 30
+
+// 分集是否已经设置了指定的空闲时间限制
+func hasIdleLimit(torrent *transmissionrpc.Torrent, minutes int) bool {
+	return torrent.SeedIdleMode != nil && *torrent.SeedIdleMode == SEED_IDLE_MODE_CUSTOM &&
+		torrent.SeedIdleLimit != nil && *torrent.SeedIdleLimit == int64(minutes)
+}
+
+// 为分集设置空闲时间限制，已停止或已有相同限制的分集跳过；原来的空闲时间设置记录到操作历史以便撤销。
+// 设置后分集仍在运行，实际停止由 Transmission 在分集空闲满指定时间后执行
+func idleLimitEpisodes(ctx context.Context, client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup, minutes int, history *HistoryWriter, throttle *ActionThrottle) (int, int, int, int) {
+	appliedCount, stoppedCount, skippedCount, failedCount := 0, 0, 0, 0
+	mode := SEED_IDLE_MODE_CUSTOM
+	limit := time.Duration(minutes) * time.Minute
+	for _, groupName := range sortedGroupNames(duplicateGroups) {
+		var episodes []*transmissionrpc.Torrent
+		for _, episode := range duplicateGroups[groupName].Episodes {
+			if episode == nil || episode.ID == nil {
+				continue
+			}
+			if alreadyStopped(episode) {
+				stoppedCount++
+				continue
+			}
+			if hasIdleLimit(episode, minutes) {
+				skippedCount++
+				continue
+			}
+			episodes = append(episodes, episode)
+		}
+		episodes = throttle.take(ctx, groupName, episodes)
+		if len(episodes) == 0 {
+			continue
+		}
+
+		fmt.Printf("正在为 \"%s\" 的 %d 个分集设置空闲 %d 分钟后停止...\n", groupName, len(episodes), minutes)
+		for i, episode := range episodes {
+			if !throttle.wait(ctx) {
+				throttle.deferRest(groupName, episodes[i:], DEFERRED_INTERRUPTED)
+				break
+			}
+			actionCtx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+			err := client.TorrentSet(actionCtx, transmissionrpc.TorrentSetPayload{
+				IDs:           []int64{*episode.ID},
+				SeedIdleMode:  &mode,
+				SeedIdleLimit: &limit,
+			})
+			cancel()
+			if err != nil {
+				fmt.Printf("设置空闲时间限制失败 ID: %d: %v\n", *episode.ID, err)
+				failedCount++
+				continue
+			}
+			record := newHistoryRecord(ACTION_IDLE_LIMIT, groupName, episode)
+			record.PrevSeedIdleMode = episode.SeedIdleMode
+			record.PrevSeedIdleLimit = episode.SeedIdleLimit
+			history.Record(record)
+			keptExport.succeed(groupName)
+			runNotices.succeed(groupName)
+			appliedCount++
+		}
+	}
+	return appliedCount, stoppedCount, skippedCount, failedCount
+}
+
+// 恢复分集原来的空闲时间设置；分集可能已因空闲限制被停止，恢复后重新开始
+func restoreIdleLimit(ctx context.Context, client *transmissionrpc.Client, torrentID int64, record HistoryRecord) error {
+	payload := transmissionrpc.TorrentSetPayload{
+		IDs:          []int64{torrentID},
+		SeedIdleMode: record.PrevSeedIdleMode,
+	}
+	if record.PrevSeedIdleLimit != nil {
+		limit := time.Duration(*record.PrevSeedIdleLimit) * time.Minute
+		payload.SeedIdleLimit = &limit
+	}
+	if err := client.TorrentSet(ctx, payload); err != nil {
+		return err
+	}
+	return client.TorrentStartIDs(ctx, []int64{torrentID})
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 一条被用户标记为误判的合集-分集对
+type IgnoredPair struct {
+	CollectionHash string    `json:"collection_hash"`
+	EpisodeHash    string    `json:"episode_hash"`
+	Group          string    `json:"group"`
+	CollectionName string    `json:"collection_name,omitempty"`
+	EpisodeName    string    `json:"episode_name,omitempty"`
+	Time           time.Time `json:"time"`
+}
+
+// 误判记录文件格式
+type IgnoreFile struct {
+	Version int           `json:"version"`
+	Pairs   []IgnoredPair `json:"pairs"`
+}
+
+// 误判记录文件路径
+func ignoresPath() string {
+	return filepath.Join(stateDir(), "ignores.json")
+}
+
+// 合集-分集对的键
+func pairKey(collectionHash, episodeHash string) string {
+	return collectionHash + ":" + episodeHash
+}
+
+// 读取误判记录，文件不存在时返回空
+func loadIgnores(path string) ([]IgnoredPair, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var file IgnoreFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("误判记录文件格式错误: %v", err)
+	}
+	return file.Pairs, nil
+}
+
+// 保存误判记录
+func saveIgnores(path string, pairs []IgnoredPair) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(IgnoreFile{Version: STATE_FILE_VERSION, Pairs: pairs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// 把一组中的全部合集-分集对标记为误判，已记录的对不重复添加，返回新增数量
+func ignoreGroup(groupName string, group DuplicateGroup) (int, error) {
+	pairs, err := loadIgnores(ignoresPath())
+	if err != nil {
+		return 0, err
+	}
+	known := make(map[string]bool)
+	for _, pair := range pairs {
+		known[pairKey(pair.CollectionHash, pair.EpisodeHash)] = true
+	}
+
+	collection := group.Collection
+	if collection == nil || collection.HashString == nil {
+		return 0, fmt.Errorf("合集缺少hash，无法记录")
+	}
+	added := 0
+	for _, episode := range group.Episodes {
+		if episode == nil || episode.HashString == nil {
+			continue
+		}
+		key := pairKey(*collection.HashString, *episode.HashString)
+		if known[key] {
+			continue
+		}
+		known[key] = true
+		pair := IgnoredPair{
+			CollectionHash: *collection.HashString,
+			EpisodeHash:    *episode.HashString,
+			Group:          groupName,
+			Time:           time.Now(),
+		}
+		if collection.Name != nil {
+			pair.CollectionName = *collection.Name
+		}
+		if episode.Name != nil {
+			pair.EpisodeName = *episode.Name
+		}
+		pairs = append(pairs, pair)
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	return added, saveIgnores(ignoresPath(), pairs)
+}
+
+// 从扫描结果中去掉被标记为误判的分集，返回被忽略的分集数量
+func applyIgnores(result *ScanResult, pairs []IgnoredPair) int {
+	if len(pairs) == 0 {
+		return 0
+	}
+	ignored := make(map[string]bool)
+	for _, pair := range pairs {
+		ignored[pairKey(pair.CollectionHash, pair.EpisodeHash)] = true
+	}
+
+	suppressed := 0
+	for _, groups := range []map[string]DuplicateGroup{result.DuplicateGroups, result.SameSizeGroups, result.OversizedGroups} {
+		for name, group := range groups {
+			if group.Collection == nil || group.Collection.HashString == nil {
+				continue
+			}
+			var kept []*transmissionrpc.Torrent
+			for _, episode := range group.Episodes {
+				if episode != nil && episode.HashString != nil && ignored[pairKey(*group.Collection.HashString, *episode.HashString)] {
+					suppressed++
+					continue
+				}
+				kept = append(kept, episode)
+			}
+			if len(kept) == len(group.Episodes) {
+				continue
+			}
+			if len(kept) == 0 {
+				delete(groups, name)
+				continue
+			}
+			group.Episodes = kept
+			groups[name] = group
+		}
+	}
+	return suppressed
+}
+
+// 交互模式下让用户选择误判的组，记录后从本次操作中去掉；也可以用 note 命令为组添加备注
+func selectFalsePositives(reader *bufio.Reader, result *ScanResult) {
+	names := sortedGroupNames(result.DuplicateGroups)
+	fmt.Println("\n需要处理的组:")
+	for i, name := range names {
+		fmt.Printf("  %d. %s\n", i+1, name)
+	}
+
+	var input string
+	for {
+		fmt.Print("输入误判的组编号，以后不再显示（多个以,分隔；输入 note <编号> <备注> 添加备注；keep <编号> [ID|E03|/正则/] 保留组内的分集；直接回车跳过）: ")
+		line, _ := reader.ReadString('\n')
+		input = strings.TrimSpace(line)
+		if index, expressions, isKeep, err := parseKeepCommand(input); isKeep {
+			if err == nil && (index < 1 || index > len(names)) {
+				err = fmt.Errorf("无效的组编号: %d", index)
+			}
+			if err != nil {
+				fmt.Println(err)
+				continue
+			}
+			selectKeptEpisodes(reader, result, names[index-1], expressions)
+			continue
+		}
+		index, text, isNote, err := parseNoteCommand(input)
+		if !isNote {
+			break
+		}
+		if err == nil && (index < 1 || index > len(names)) {
+			err = fmt.Errorf("无效的组编号: %d", index)
+		}
+		if err != nil {
+			fmt.Println(err)
+			continue
+		}
+		name := names[index-1]
+		if err := setGroupNote(name, result.DuplicateGroups[name], text); err != nil {
+			log.Printf("保存备注失败: %v", err)
+			continue
+		}
+		if text == "" {
+			fmt.Printf("已删除 \"%s\" 的备注\n", name)
+		} else {
+			fmt.Printf("已保存 \"%s\" 的备注\n", name)
+		}
+	}
+	if input == "" {
+		return
+	}
+
+	for _, field := range strings.Split(input, ",") {
+		index, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || index < 1 || index > len(names) {
+			fmt.Printf("无效的组编号: %s\n", strings.TrimSpace(field))
+			continue
+		}
+		name := names[index-1]
+		group, ok := result.DuplicateGroups[name]
+		if !ok {
+			continue
+		}
+		added, err := ignoreGroup(name, group)
+		if err != nil {
+			log.Printf("记录误判失败: %v", err)
+			continue
+		}
+		delete(result.DuplicateGroups, name)
+		result.SuppressedCount += len(group.Episodes)
+		fmt.Printf("已将 \"%s\" 标记为误判（新增 %d 对）\n", name, added)
+	}
+}
+
+// ignore 命令：扫描后把指定的组标记为误判
+func runIgnore(reader *bufio.Reader, args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, "用法: delete-episode ignore <组名> [参数]")
+		os.Exit(2)
+	}
+	groupName := args[0]
+	opts := parseOptions(args[1:])
+
+	params := opts.Connection
+	if !opts.ConnectionSet {
+		params = readConnectionParams(reader)
+		params.Proxy = opts.Connection.Proxy
+	}
+	opts.Connection = params
+
+	client, err := connect(params)
+	if err != nil {
+		log.Fatalf("无法连接到 Transmission 服务器%s: %v", params.proxyHint(), err)
+	}
+	result, err := scan(client, detectCapabilities(client), opts)
+	if err != nil {
+		log.Fatalf("获取 torrent 列表失败%s: %v", params.proxyHint(), err)
+	}
+	if result.Interrupted {
+		fmt.Println("分析已中断，未标记误判")
+		os.Exit(1)
+	}
+
+	for _, groups := range []map[string]DuplicateGroup{result.DuplicateGroups, result.LowConfidenceGroups, result.SameSizeGroups, result.OversizedGroups, result.BelowMinEpisodesGroups} {
+		if group, ok := groups[groupName]; ok {
+			added, err := ignoreGroup(groupName, group)
+			if err != nil {
+				log.Fatalf("记录误判失败: %v", err)
+			}
+			fmt.Printf("已将 \"%s\" 标记为误判（新增 %d 对）\n", groupName, added)
+			return
+		}
+	}
+	fmt.Printf("未找到组: %s\n", groupName)
+	os.Exit(1)
+}
+
+// ignores 命令：列出或删除误判记录
+func runIgnores(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "用法: delete-episode ignores list|remove <序号|组名>")
+		os.Exit(2)
+	}
+	pairs, err := loadIgnores(ignoresPath())
+	if err != nil {
+		log.Fatalf("读取误判记录失败: %v", err)
+	}
+
+	switch args[0] {
+	case "list":
+		if len(pairs) == 0 {
+			fmt.Println("没有误判记录")
+			return
+		}
+		fmt.Printf("共 %d 条误判记录:\n", len(pairs))
+		for i, pair := range pairs {
+			fmt.Printf("  %d. %s (%s)\n", i+1, pair.Group, pair.Time.Format("2006-01-02 15:04:05"))
+			fmt.Printf("     合集: %s %s\n", pair.CollectionHash, pair.CollectionName)
+			fmt.Printf("     分集: %s %s\n", pair.EpisodeHash, pair.EpisodeName)
+		}
+	case "remove":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "用法: delete-episode ignores remove <序号|组名>")
+			os.Exit(2)
+		}
+		target := args[1]
+		index, indexErr := strconv.Atoi(target)
+		var kept []IgnoredPair
+		for i, pair := range pairs {
+			if (indexErr == nil && i+1 == index) || (indexErr != nil && pair.Group == target) {
+				continue
+			}
+			kept = append(kept, pair)
+		}
+		removed := len(pairs) - len(kept)
+		if removed == 0 {
+			fmt.Printf("未找到误判记录: %s\n", target)
+			os.Exit(1)
+		}
+		if err := saveIgnores(ignoresPath(), kept); err != nil {
+			log.Fatalf("保存误判记录失败: %v", err)
+		}
+		fmt.Printf("已删除 %d 条误判记录\n", removed)
+	default:
+		fmt.Fprintf(os.Stderr, "未知的子命令: %s（可选: list, remove）\n", args[0])
+		os.Exit(2)
+	}
+}
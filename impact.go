@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 超过tracker影响比例而暂缓的组显示的策略名称
+const TRACKER_IMPACT_POLICY = "tracker影响比例"
+
+// 没有tracker的种子在影响统计中的名称
+const NO_TRACKER_HOST = "(无tracker)"
+
+// 一个tracker上本次会被停止做种的种子数量
+type TrackerImpact struct {
+	Host     string
+	Total    int // 本机在该tracker上的种子总数
+	Affected int // 本次会被暂停、删除或取消选择的种子数量
+	Deferred int // 因超过 --max-tracker-impact 暂缓的种子数量
+}
+
+// 占该tracker种子总数的百分比
+func (i TrackerImpact) percent() float64 {
+	if i.Total == 0 {
+		return 0
+	}
+	return float64(i.Affected) * 100 / float64(i.Total)
+}
+
+// 操作是否会使分集停止做种（idle-limit 在分集空闲后停止）
+func stopsSeeding(action string) bool {
+	return pausesEpisode(action) || action == ACTION_IDLE_LIMIT || action == ACTION_DESELECT || action == ACTION_DELETE || action == ACTION_OLD_PACK_DELETE
+}
+
+// 种子在影响统计中所属的tracker，没有tracker时归入 NO_TRACKER_HOST
+func impactHosts(torrent *transmissionrpc.Torrent) []string {
+	if hosts := trackerHosts(torrent); len(hosts) > 0 {
+		return hosts
+	}
+	return []string{NO_TRACKER_HOST}
+}
+
+// 按tracker统计本次会被停止做种的种子占本机在该tracker上种子总数的比例。
+// 设置了 --max-tracker-impact 时按置信度从高到低加入各组，会使任一tracker超过比例的组整组暂缓，
+// 因此暂缓的总是置信度较低的组；已经停止的分集不计入
+func applyTrackerImpact(result *ScanResult, action string, maxPercent float64) {
+	impacts := make(map[string]*TrackerImpact)
+	impactOf := func(host string) *TrackerImpact {
+		if impacts[host] == nil {
+			impacts[host] = &TrackerImpact{Host: host}
+		}
+		return impacts[host]
+	}
+	for i := range result.Torrents {
+		for _, host := range impactHosts(&result.Torrents[i]) {
+			impactOf(host).Total++
+		}
+	}
+
+	for _, name := range sortedGroupNames(result.DuplicateGroups) {
+		group := result.DuplicateGroups[name]
+		counts := make(map[string]int)
+		for _, episode := range group.Episodes {
+			if episode == nil || alreadyStopped(episode) || !stopsSeeding(group.episodeAction(episode, action)) {
+				continue
+			}
+			for _, host := range impactHosts(episode) {
+				counts[host]++
+			}
+		}
+
+		var exceeded []string
+		if maxPercent > 0 {
+			for host, count := range counts {
+				impact := impactOf(host)
+				if float64(impact.Affected+count)*100 > maxPercent*float64(impact.Total) {
+					exceeded = append(exceeded, fmt.Sprintf("%s %d/%d", host, impact.Affected+count, impact.Total))
+				}
+			}
+		}
+		if len(exceeded) == 0 {
+			for host, count := range counts {
+				impactOf(host).Affected += count
+			}
+			continue
+		}
+
+		sort.Strings(exceeded)
+		reason := fmt.Sprintf("处理后 %s 个种子被停止，超过 %g%%", strings.Join(exceeded, "、"), maxPercent)
+		for host, count := range counts {
+			impactOf(host).Deferred += count
+		}
+		for _, episode := range group.Episodes {
+			group.GatedEpisodes = append(group.GatedEpisodes, GatedEpisode{
+				Episode: episode,
+				Policy:  TRACKER_IMPACT_POLICY,
+				Reason:  reason,
+			})
+		}
+		group.Episodes = nil
+		delete(result.DuplicateGroups, name)
+		result.GatedGroups[name] = group
+	}
+
+	result.TrackerImpacts = nil
+	result.TrackerImpactLimit = maxPercent
+	for _, impact := range impacts {
+		if impact.Affected > 0 || impact.Deferred > 0 {
+			result.TrackerImpacts = append(result.TrackerImpacts, *impact)
+		}
+	}
+	sort.Slice(result.TrackerImpacts, func(i, j int) bool {
+		a, b := result.TrackerImpacts[i], result.TrackerImpacts[j]
+		if a.percent() != b.percent() {
+			return a.percent() > b.percent()
+		}
+		return a.Host < b.Host
+	})
+}
+
+// 显示各tracker本次会被停止做种的种子比例，没有设置上限时也显示
+func printTrackerImpact(w io.Writer, impacts []TrackerImpact, maxPercent float64) {
+	if len(impacts) == 0 {
+		return
+	}
+	title := "\n--- 各tracker受影响的种子 ---"
+	if maxPercent > 0 {
+		title = fmt.Sprintf("\n--- 各tracker受影响的种子（上限 %g%%）---", maxPercent)
+	}
+	fmt.Fprintln(w, title)
+	for _, impact := range impacts {
+		line := fmt.Sprintf("  %s: %d/%d (%.1f%%)", impact.Host, impact.Affected, impact.Total, impact.percent())
+		if impact.Deferred > 0 {
+			line += fmt.Sprintf("，暂缓 %d 个", impact.Deferred)
+		}
+		fmt.Fprintln(w, line)
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// inspect 命令额外请求的字段
+var inspectExtraFields = []string{"error", "files", "wanted"}
+
+// inspect 命令输出的种子信息，包含识别流程使用的全部字段和由此得出的结果
+type InspectReport struct {
+	ID            int64         `json:"id"`
+	Hash          string        `json:"hash"`
+	Name          string        `json:"name"`
+	CanonicalName string        `json:"canonical_name"` // 分组和筛选使用的规范名称
+	SizeWhenDone  int64         `json:"size_when_done"`
+	PercentDone   float64       `json:"percent_done"`
+	Status        string        `json:"status"`
+	Labels        []string      `json:"labels,omitempty"`
+	Trackers      []string      `json:"trackers"`
+	DownloadDir   string        `json:"download_dir"`
+	AddedDate     *time.Time    `json:"added_date,omitempty"`
+	DoneDate      *time.Time    `json:"done_date,omitempty"`
+	Error         int64         `json:"error"`
+	ErrorString   string        `json:"error_string,omitempty"`
+	Quality       string        `json:"quality"`             // 识别的分辨率和编码
+	Markers       []string      `json:"markers"`             // 内容文件中不同的剧集标识
+	Collection    bool          `json:"collection_eligible"` // 能否作为合集
+	Files         []InspectFile `json:"files"`
+}
+
+// 种子中的一个文件及识别结果
+type InspectFile struct {
+	Index     int    `json:"index"`
+	Name      string `json:"name"`
+	Length    int64  `json:"length"`
+	Wanted    bool   `json:"wanted"`
+	Auxiliary bool   `json:"auxiliary"`        // 辅助文件，不计入内容文件
+	Marker    string `json:"marker,omitempty"` // 剧集标识，未识别时为空
+}
+
+// inspect 命令：显示指定种子从RPC获取的原始字段和识别结果，用于排查误判
+func runInspect(reader *bufio.Reader, args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, "用法: delete-episode inspect <ID|hash> [参数]")
+		os.Exit(2)
+	}
+	target := strings.TrimSpace(args[0])
+	opts := parseOptions(args[1:])
+
+	params := opts.Connection
+	if !opts.ConnectionSet {
+		params = readConnectionParams(reader)
+		params.Proxy = opts.Connection.Proxy
+	}
+	client, err := connect(params)
+	if err != nil {
+		log.Fatalf("无法连接到 Transmission 服务器%s: %v", params.proxyHint(), err)
+	}
+
+	torrent, err := inspectTorrent(client, target)
+	if err != nil {
+		log.Fatalf("获取种子 %s 失败%s: %v", target, params.proxyHint(), err)
+	}
+	report := newInspectReport(torrent)
+
+	if opts.JSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			log.Fatalf("生成JSON失败: %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+	report.print()
+}
+
+// 按ID或hash获取种子的全部相关字段，不打印服务器版本信息，避免混入JSON输出
+func inspectTorrent(client *transmissionrpc.Client, target string) (transmissionrpc.Torrent, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+	_, serverVersion, _, err := client.RPCVersion(ctx)
+	cancel()
+	if err != nil {
+		serverVersion = 0
+	}
+	fields := append(newServerCapabilities(serverVersion).torrentFields(), inspectExtraFields...)
+
+	ctx, cancel = context.WithTimeout(context.Background(), timeouts.Files)
+	defer cancel()
+	var torrents []transmissionrpc.Torrent
+	if id, parseErr := strconv.ParseInt(target, 10, 64); parseErr == nil && len(target) < 40 {
+		torrents, err = client.TorrentGet(ctx, fields, []int64{id})
+	} else {
+		torrents, err = client.TorrentGetHashes(ctx, fields, []string{strings.ToLower(target)})
+	}
+	if err != nil {
+		return transmissionrpc.Torrent{}, err
+	}
+	if len(torrents) == 0 {
+		return transmissionrpc.Torrent{}, fmt.Errorf("种子不存在")
+	}
+	return torrents[0], nil
+}
+
+// 根据种子字段生成检查报告
+func newInspectReport(torrent transmissionrpc.Torrent) InspectReport {
+	planTorrent := newPlanTorrent(&torrent)
+	report := InspectReport{
+		ID:            planTorrent.ID,
+		Hash:          planTorrent.Hash,
+		Name:          planTorrent.Name,
+		CanonicalName: canonicalName(planTorrent.Name),
+		SizeWhenDone:  planTorrent.Size,
+		Labels:        torrent.Labels,
+		Trackers:      []string{},
+		AddedDate:     torrent.AddedDate,
+		DoneDate:      torrent.DoneDate,
+		Quality:       torrentQuality(&torrent, torrent.Files).describe(),
+		Markers:       []string{},
+		Collection:    collectionEligible(torrent.Files),
+		Files:         []InspectFile{},
+	}
+	if torrent.PercentDone != nil {
+		report.PercentDone = *torrent.PercentDone
+	}
+	if torrent.Status != nil {
+		report.Status = torrent.Status.String()
+	}
+	for _, tracker := range torrent.Trackers {
+		if tracker != nil {
+			report.Trackers = append(report.Trackers, tracker.Announce)
+		}
+	}
+	if torrent.DownloadDir != nil {
+		report.DownloadDir = *torrent.DownloadDir
+	}
+	if torrent.Error != nil {
+		report.Error = *torrent.Error
+	}
+	if torrent.ErrorString != nil {
+		report.ErrorString = *torrent.ErrorString
+	}
+
+	markers := make(map[string]bool)
+	for i, file := range torrent.Files {
+		if file == nil {
+			continue
+		}
+		inspectFile := InspectFile{
+			Index:     i,
+			Name:      file.Name,
+			Length:    file.Length,
+			Wanted:    i >= len(torrent.Wanted) || torrent.Wanted[i],
+			Auxiliary: isAuxiliaryFile(file.Name),
+			Marker:    extractEpisodeMarker(getFileName(file.Name)),
+		}
+		if inspectFile.Marker != "" && !inspectFile.Auxiliary {
+			markers[inspectFile.Marker] = true
+		}
+		report.Files = append(report.Files, inspectFile)
+	}
+	for marker := range markers {
+		report.Markers = append(report.Markers, marker)
+	}
+	sort.Strings(report.Markers)
+	return report
+}
+
+// 显示检查报告
+func (r InspectReport) print() {
+	fmt.Printf("ID: %d\n", r.ID)
+	fmt.Printf("hash: %s\n", r.Hash)
+	fmt.Printf("名称: %s\n", r.Name)
+	fmt.Printf("规范名称: %s\n", r.CanonicalName)
+	fmt.Printf("大小: %s, 完成 %.0f%%\n", formatSize(r.SizeWhenDone), r.PercentDone*100)
+	fmt.Printf("状态: %s\n", r.Status)
+	if len(r.Labels) > 0 {
+		fmt.Printf("标签: %s\n", strings.Join(r.Labels, ", "))
+	}
+	fmt.Printf("tracker: %s\n", strings.Join(r.Trackers, ", "))
+	fmt.Printf("下载目录: %s\n", r.DownloadDir)
+	if r.AddedDate != nil {
+		fmt.Printf("添加时间: %s\n", r.AddedDate.Format("2006-01-02 15:04:05"))
+	}
+	if r.DoneDate != nil && !r.DoneDate.IsZero() && r.DoneDate.Unix() > 0 {
+		fmt.Printf("完成时间: %s\n", r.DoneDate.Format("2006-01-02 15:04:05"))
+	}
+	if r.Error != 0 || r.ErrorString != "" {
+		fmt.Printf("错误: %d %s\n", r.Error, r.ErrorString)
+	}
+	fmt.Printf("分辨率/编码: %s\n", r.Quality)
+	fmt.Printf("剧集标识: %s\n", strings.Join(r.Markers, ", "))
+	fmt.Printf("能否作为合集: %t\n", r.Collection)
+
+	fmt.Printf("文件（%d 个）:\n", len(r.Files))
+	for _, file := range r.Files {
+		line := fmt.Sprintf("  %d. %s, %s", file.Index, file.Name, formatSize(file.Length))
+		if !file.Wanted {
+			line += ", 未选择"
+		}
+		if file.Auxiliary {
+			line += ", 辅助文件"
+		}
+		if file.Marker != "" {
+			line += ", 标识: " + file.Marker
+		} else {
+			line += ", 无标识"
+		}
+		fmt.Println(line)
+	}
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 最终执行清单中交互选择保留的分集使用的操作名称，不是可执行的操作
+const MANIFEST_KEPT = "kept"
+
+// 保留表达式中的集号，如 E03、S01E03、EP3
+var keepMarkerRegex = regexp.MustCompile(`(?i)^(?:S(\d+))?EP?(\d+)$`)
+
+// 解析 "keep <编号> [表达式...]" 命令，第三个返回值表示输入是否为 keep 命令
+func parseKeepCommand(input string) (int, []string, bool, error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || fields[0] != "keep" {
+		return 0, nil, false, nil
+	}
+	if len(fields) < 2 {
+		return 0, nil, true, fmt.Errorf("用法: keep <编号> [ID|E03|/正则/ ...]（不带表达式时列出该组的分集）")
+	}
+	index, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, nil, true, fmt.Errorf("无效的组编号: %s", fields[1])
+	}
+	return index, fields[2:], true, nil
+}
+
+// 分集名称中的集号，按当前的剧集标识规则提取
+func episodeNameMarker(episode *transmissionrpc.Torrent) string {
+	if episode.Name == nil {
+		return ""
+	}
+	return extractEpisodeMarker(*episode.Name)
+}
+
+// 把一个保留表达式解析为组内的分集：纯数字为种子ID，/.../ 为匹配分集名称的正则，
+// E03 或 S01E03 为集号。集号不带季且匹配到不同季的分集时视为有歧义
+func resolveKeepExpression(episodes []*transmissionrpc.Torrent, expression string) ([]*transmissionrpc.Torrent, error) {
+	if id, err := strconv.ParseInt(expression, 10, 64); err == nil {
+		for _, episode := range episodes {
+			if *episode.ID == id {
+				return []*transmissionrpc.Torrent{episode}, nil
+			}
+		}
+		return nil, fmt.Errorf("%s: 本组没有ID为 %d 的分集", expression, id)
+	}
+
+	if len(expression) >= 2 && strings.HasPrefix(expression, "/") && strings.HasSuffix(expression, "/") {
+		regex, err := regexp.Compile(expression[1 : len(expression)-1])
+		if err != nil {
+			return nil, fmt.Errorf("%s: 正则无效: %v", expression, err)
+		}
+		var matched []*transmissionrpc.Torrent
+		for _, episode := range episodes {
+			if episode.Name != nil && regex.MatchString(*episode.Name) {
+				matched = append(matched, episode)
+			}
+		}
+		if len(matched) == 0 {
+			return nil, fmt.Errorf("%s: 没有匹配的分集名称", expression)
+		}
+		return matched, nil
+	}
+
+	parts := keepMarkerRegex.FindStringSubmatch(expression)
+	if parts == nil {
+		return nil, fmt.Errorf("%s: 无法识别，应为种子ID、集号（如 E03、S01E03）或 /正则/", expression)
+	}
+	suffix := "E" + padNumber(parts[2])
+	if parts[1] != "" {
+		suffix = "S" + padNumber(parts[1]) + suffix
+	}
+	var matched []*transmissionrpc.Torrent
+	markers := make(map[string]bool)
+	for _, episode := range episodes {
+		marker := episodeNameMarker(episode)
+		if marker == "" {
+			continue
+		}
+		// 带季时要求完整匹配，不带季时匹配任意季的该集（S01E03、名称:E03）
+		if (parts[1] != "" && marker == suffix) || (parts[1] == "" && strings.HasSuffix(marker, suffix)) {
+			matched = append(matched, episode)
+			markers[marker] = true
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("%s: 没有集号为 %s 的分集", expression, suffix)
+	}
+	if len(markers) > 1 {
+		var names []string
+		for _, episode := range matched {
+			names = append(names, episodeNameMarker(episode))
+		}
+		return nil, fmt.Errorf("%s: 有歧义，匹配到 %s，请指定季（如 S01%s）或使用ID", expression, strings.Join(uniqueStrings(names), ", "), suffix)
+	}
+	return matched, nil
+}
+
+// 去掉重复的字符串，保留第一次出现的顺序
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, value := range values {
+		if !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	return unique
+}
+
+// 解析全部保留表达式，返回去重后按组内顺序排列的分集；任何一个表达式无效时返回全部错误，不保留任何分集
+func resolveKeepExpressions(episodes []*transmissionrpc.Torrent, expressions []string) ([]*transmissionrpc.Torrent, []error) {
+	selected := make(map[int64]bool)
+	var errs []error
+	for _, expression := range expressions {
+		matched, err := resolveKeepExpression(episodes, expression)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		for _, episode := range matched {
+			selected[*episode.ID] = true
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	var kept []*transmissionrpc.Torrent
+	for _, episode := range episodes {
+		if selected[*episode.ID] {
+			kept = append(kept, episode)
+		}
+	}
+	return kept, nil
+}
+
+// 组内可以被保留的分集
+func keepCandidates(group DuplicateGroup) []*transmissionrpc.Torrent {
+	var candidates []*transmissionrpc.Torrent
+	for _, episode := range group.Episodes {
+		if episode != nil && episode.ID != nil {
+			candidates = append(candidates, episode)
+		}
+	}
+	return candidates
+}
+
+// 交互选择组内要保留的分集：解析表达式后列出解析结果，确认后从分集中移除，
+// 保留的分集不会被处理，在最终执行清单中单独列出。没有表达式时先列出分集再提示输入
+func selectKeptEpisodes(reader *bufio.Reader, result *ScanResult, name string, expressions []string) {
+	group := result.DuplicateGroups[name]
+	candidates := keepCandidates(group)
+	if len(expressions) == 0 {
+		fmt.Printf("\"%s\" 的分集:\n", name)
+		for _, episode := range candidates {
+			fmt.Printf("  ID: %d, %s\n", *episode.ID, *episode.Name)
+		}
+		fmt.Print("要保留的分集（种子ID、集号如 E03、/正则/，以空格分隔；直接回车取消）: ")
+		line, _ := reader.ReadString('\n')
+		expressions = strings.Fields(line)
+		if len(expressions) == 0 {
+			return
+		}
+	}
+
+	kept, errs := resolveKeepExpressions(candidates, expressions)
+	if len(errs) > 0 {
+		fmt.Println("保留表达式有误，未保留任何分集:")
+		for _, err := range errs {
+			fmt.Printf("  %v\n", err)
+		}
+		return
+	}
+	if len(kept) == len(candidates) {
+		fmt.Printf("表达式匹配了 \"%s\" 的全部分集，未保留任何分集；如果该组是误判，请输入组编号标记\n", name)
+		return
+	}
+
+	fmt.Printf("将保留 \"%s\" 的 %d 个分集:\n", name, len(kept))
+	for _, episode := range kept {
+		fmt.Printf("  ID: %d, %s\n", *episode.ID, *episode.Name)
+	}
+	fmt.Print("确认保留? (y/n) [默认: y]: ")
+	answer, _ := reader.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer != "" && answer != "y" {
+		fmt.Println("未保留任何分集")
+		return
+	}
+
+	keptIDs := make(map[int64]bool)
+	for _, episode := range kept {
+		keptIDs[*episode.ID] = true
+	}
+	var remaining []*transmissionrpc.Torrent
+	for _, episode := range group.Episodes {
+		if episode != nil && episode.ID != nil && keptIDs[*episode.ID] {
+			continue
+		}
+		remaining = append(remaining, episode)
+	}
+	group.Episodes = remaining
+	group.KeptEpisodes = append(group.KeptEpisodes, kept...)
+	result.DuplicateGroups[name] = group
+	fmt.Printf("已保留 \"%s\" 的 %d 个分集，剩余 %d 个分集将被处理\n", name, len(kept), len(keepCandidates(group)))
+}
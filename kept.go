@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// --export-kept 文件格式的版本
+const KEPT_EXPORT_VERSION = 1
+
+// 保留的合集导出文件，供辅种工具在其他tracker上重新做种
+type KeptExportFile struct {
+	Version     int              `json:"version"`
+	Collections []KeptCollection `json:"collections"`
+}
+
+// 操作成功的组保留的合集，不含任何分集信息
+type KeptCollection struct {
+	Name        string     `json:"name"`
+	InfoHash    string     `json:"info_hash"`
+	Size        int64      `json:"size"` // 字节
+	DownloadDir string     `json:"download_dir"`
+	Groups      []string   `json:"groups"` // 合集所在的组名
+	Files       []KeptFile `json:"files"`
+}
+
+// 合集中的一个文件，路径相对于下载目录
+type KeptFile struct {
+	Path   string `json:"path"`
+	Length int64  `json:"length"`
+}
+
+// 记录操作成功的组保留的合集，未指定 --export-kept 时为nil，nil导出器不记录任何内容
+type KeptExporter struct {
+	path        string
+	collections map[string]*transmissionrpc.Torrent // 组名 -> 合集
+	succeeded   map[string]bool                     // 本次运行至少有一个分集操作成功的组
+}
+
+// 本次运行的保留合集导出器
+var keptExport *KeptExporter
+
+// 开启保留合集导出
+func enableKeptExport(path string) {
+	keptExport = &KeptExporter{
+		path:        path,
+		collections: make(map[string]*transmissionrpc.Torrent),
+		succeeded:   make(map[string]bool),
+	}
+}
+
+// 记录即将执行操作的组的合集
+func (e *KeptExporter) track(duplicateGroups map[string]DuplicateGroup) {
+	if e == nil {
+		return
+	}
+	for name, group := range duplicateGroups {
+		if group.Collection != nil {
+			e.collections[name] = group.Collection
+		}
+	}
+}
+
+// 标记组内有分集操作成功
+func (e *KeptExporter) succeed(groupName string) {
+	if e == nil {
+		return
+	}
+	e.succeeded[groupName] = true
+}
+
+// 按hash合并操作成功的组的合集，按hash排序，文件按种子中的顺序，
+// 不含时间等每次运行都不同的内容，相同的状态导出相同的文件
+func (e *KeptExporter) build(client *transmissionrpc.Client) KeptExportFile {
+	byHash := make(map[string]*KeptCollection)
+	for name := range e.succeeded {
+		collection := e.collections[name]
+		if collection == nil || collection.HashString == nil {
+			continue
+		}
+		hash := *collection.HashString
+		if kept, ok := byHash[hash]; ok {
+			kept.Groups = append(kept.Groups, name)
+			continue
+		}
+		kept := &KeptCollection{InfoHash: hash, Groups: []string{name}, Files: []KeptFile{}}
+		if collection.Name != nil {
+			kept.Name = *collection.Name
+		}
+		if collection.SizeWhenDone != nil {
+			kept.Size = int64((*collection.SizeWhenDone).Byte())
+		}
+		if collection.DownloadDir != nil {
+			kept.DownloadDir = *collection.DownloadDir
+		}
+		files, err := getTorrentFiles(client, collection.ID)
+		if err != nil {
+			log.Printf("获取合集 %s 的文件列表失败，导出时不含文件列表: %v", hash, err)
+		}
+		for _, file := range files {
+			kept.Files = append(kept.Files, KeptFile{Path: file.Name, Length: file.Length})
+		}
+		byHash[hash] = kept
+	}
+
+	export := KeptExportFile{Version: KEPT_EXPORT_VERSION, Collections: []KeptCollection{}}
+	for _, kept := range byHash {
+		sort.Strings(kept.Groups)
+		export.Collections = append(export.Collections, *kept)
+	}
+	sort.Slice(export.Collections, func(i, j int) bool {
+		return export.Collections[i].InfoHash < export.Collections[j].InfoHash
+	})
+	return export
+}
+
+// 写入导出文件，守护模式下每轮写入到目前为止的全部合集，写入失败只打印警告
+func (e *KeptExporter) write(client *transmissionrpc.Client) {
+	if e == nil {
+		return
+	}
+	export := e.build(client)
+	data, err := json.MarshalIndent(export, "", "  ")
+	if err == nil {
+		err = os.WriteFile(e.path, append(data, '\n'), 0o644)
+	}
+	if err != nil {
+		log.Printf("导出保留的合集失败: %v", err)
+		return
+	}
+	fmt.Printf("已导出 %d 个保留的合集到 %s\n", len(export.Collections), e.path)
+}
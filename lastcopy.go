@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 删除前检查使用的字段
+var lastCopyFields = []string{"id", "name", "hashString", "metadataPercentComplete"}
+
+// 分集中在其他种子里都找不到的文件，删除分集数据后这些内容将没有任何副本
+type LastCopyEpisode struct {
+	Episode *transmissionrpc.Torrent
+	Files   []*transmissionrpc.TorrentFile
+}
+
+// 操作是否会删除分集种子（删除旧版合集、审阅文件中的 delete），原地升级会先确认合集中有对应的文件，不需要检查
+func removesEpisodeTorrents(action string) bool {
+	return action == ACTION_OLD_PACK_DELETE || action == ACTION_DELETE
+}
+
+// 找出各组中会被删除数据的分集里，在保留的种子（本次不删除的全部种子，不只是组的合集）中都找不到的内容文件。
+// 使用与深度扫描相同的（文件名, 大小）索引，保留的种子中只计算已选择且已下载完成的文件
+func findLastCopies(client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup) (map[string][]LastCopyEpisode, error) {
+	removing := make(map[int64]bool)
+	var deleting []string
+	for _, groupName := range sortedGroupNames(duplicateGroups) {
+		group := duplicateGroups[groupName]
+		counted := false
+		for _, episode := range group.Episodes {
+			if episode == nil || episode.ID == nil {
+				continue
+			}
+			removing[*episode.ID] = true
+			if !counted && deletesEpisodeFiles(group, episode) {
+				deleting = append(deleting, groupName)
+				counted = true
+			}
+		}
+	}
+	if len(deleting) == 0 {
+		return nil, nil
+	}
+
+	torrents, err := getWithRetry(client, lastCopyFields, nil, timeouts.List, "获取种子列表")
+	if err != nil {
+		return nil, err
+	}
+	var kept []*transmissionrpc.Torrent
+	for i := range torrents {
+		torrent := &torrents[i]
+		if torrent.ID != nil && !removing[*torrent.ID] && !metadataPending(torrent) {
+			kept = append(kept, torrent)
+		}
+	}
+	_, index := buildContentIndex(client, kept, "删除前检查", true)
+
+	lastCopies := make(map[string][]LastCopyEpisode)
+	for _, groupName := range deleting {
+		group := duplicateGroups[groupName]
+		for _, episode := range group.Episodes {
+			if episode == nil || episode.ID == nil || !deletesEpisodeFiles(group, episode) {
+				continue
+			}
+			files, err := getTorrentFiles(client, episode.ID)
+			if err != nil {
+				return nil, fmt.Errorf("获取分集 ID: %d 的文件列表失败: %w", *episode.ID, err)
+			}
+			var missing []*transmissionrpc.TorrentFile
+			for _, file := range contentFiles(files) {
+				if len(index[fileKey(file)]) == 0 {
+					missing = append(missing, file)
+				}
+			}
+			if len(missing) > 0 {
+				lastCopies[groupName] = append(lastCopies[groupName], LastCopyEpisode{Episode: episode, Files: missing})
+			}
+		}
+	}
+	return lastCopies, nil
+}
+
+// 删除分集数据前的检查：有文件在其他种子中都不存在的组列出这些文件，需要单独确认。
+// 未确认、无法确认（--yes、守护模式）或检查失败时，这些分集拆到另一组，改为暂停；试运行只列出
+func confirmLastCopies(reader *bufio.Reader, client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup, opts Options) (map[string]DuplicateGroup, map[string]DuplicateGroup) {
+	lastCopies, err := findLastCopies(client, duplicateGroups)
+	if err != nil {
+		fmt.Printf("\n!!! 删除前检查失败，无法确认文件在其他种子中是否还有副本，会删除数据的分集改为暂停: %v !!!\n", err)
+		return nil, duplicateGroups
+	}
+	if len(lastCopies) == 0 {
+		return duplicateGroups, nil
+	}
+
+	fmt.Printf("\n!!! %d 组中有分集的文件在其他种子中都不存在，删除后这些内容将没有任何副本 !!!\n", len(lastCopies))
+	allowed := make(map[string]DuplicateGroup)
+	unconfirmed := make(map[string]DuplicateGroup)
+	for _, groupName := range sortedGroupNames(duplicateGroups) {
+		group := duplicateGroups[groupName]
+		episodes, ok := lastCopies[groupName]
+		if !ok {
+			allowed[groupName] = group
+			continue
+		}
+		fmt.Printf("\n组名: %s\n", groupName)
+		atRisk := make(map[int64]bool)
+		for _, lastCopy := range episodes {
+			atRisk[*lastCopy.Episode.ID] = true
+			fmt.Printf("  分集 ID: %d 有 %d 个文件没有其他副本:\n", *lastCopy.Episode.ID, len(lastCopy.Files))
+			for _, file := range lastCopy.Files {
+				fmt.Printf("    - %s (%s)\n", displayPath(lastCopy.Episode, file.Name), formatSize(file.Length))
+			}
+		}
+		if opts.DryRun {
+			allowed[groupName] = group
+			continue
+		}
+
+		confirmed := false
+		if reader != nil && !opts.Yes {
+			fmt.Print("确认删除这些分集及其数据? 否则改为暂停 (y/N): ")
+			answer, _ := reader.ReadString('\n')
+			confirmed = strings.ToLower(strings.TrimSpace(answer)) == "y"
+		} else {
+			fmt.Println("  非交互运行，无法确认，这些分集改为暂停")
+		}
+		if confirmed {
+			allowed[groupName] = group
+			continue
+		}
+
+		var keep, pause []*transmissionrpc.Torrent
+		for _, episode := range group.Episodes {
+			if episode != nil && episode.ID != nil && atRisk[*episode.ID] {
+				pause = append(pause, episode)
+			} else {
+				keep = append(keep, episode)
+			}
+		}
+		if len(keep) > 0 {
+			allowedGroup := group
+			allowedGroup.Episodes = keep
+			allowed[groupName] = allowedGroup
+		}
+		pausedGroup := group
+		pausedGroup.Episodes = pause
+		unconfirmed[groupName] = pausedGroup
+	}
+	return allowed, unconfirmed
+}
+
+// 合并两组拆分出的分集，同名的组合并分集列表
+func mergeEpisodeGroups(a, b map[string]DuplicateGroup) map[string]DuplicateGroup {
+	if len(b) == 0 {
+		return a
+	}
+	merged := make(map[string]DuplicateGroup)
+	for name, group := range a {
+		merged[name] = group
+	}
+	for name, group := range b {
+		if existing, ok := merged[name]; ok {
+			existing.Episodes = append(append([]*transmissionrpc.Torrent{}, existing.Episodes...), group.Episodes...)
+			merged[name] = existing
+			continue
+		}
+		merged[name] = group
+	}
+	return merged
+}
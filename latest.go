@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 用于排序的剧集标识：SxxEyy 以及多集文件中连续的集数，如 S01E07E08、S01E07-E08
+var (
+	multiEpisodeRegex  = regexp.MustCompile(`(?i)s(\d+)((?:[ ._\-]?e\d+)+)`)
+	episodeNumberRegex = regexp.MustCompile(`(?i)e(\d+)`)
+	namedEpisodeRegex  = regexp.MustCompile(`^(.*):E(\d+)$`)
+)
+
+// 分集中最新一集的标识
+type LatestMarker struct {
+	Marker string // 显示用的标识，如 S01E08
+	Key    string // 排序键，数字补齐后可按字符串比较
+}
+
+// 剧集标识的排序键，季和集补齐四位；日期标识本身可以按字符串比较
+func markerSortKey(marker string) string {
+	if matches := multiEpisodeRegex.FindStringSubmatch(marker); matches != nil {
+		return fmt.Sprintf("S%04dE%04d", atoi(matches[1]), highestEpisodeNumber(matches[2]))
+	}
+	if matches := namedEpisodeRegex.FindStringSubmatch(marker); matches != nil {
+		return fmt.Sprintf("%s:E%04d", matches[1], atoi(matches[2]))
+	}
+	return marker
+}
+
+// 连续集数中最大的集数，如 "E07E08" 为8
+func highestEpisodeNumber(episodes string) int {
+	highest := 0
+	for _, matches := range episodeNumberRegex.FindAllStringSubmatch(episodes, -1) {
+		if number := atoi(matches[1]); number > highest {
+			highest = number
+		}
+	}
+	return highest
+}
+
+func atoi(value string) int {
+	number, _ := strconv.Atoi(value)
+	return number
+}
+
+// 分集内容文件中最新的一集，多集种子或多集文件取最大的集数；没有剧集标识时返回false
+func latestEpisodeMarker(files []*transmissionrpc.TorrentFile) (LatestMarker, bool) {
+	var latest LatestMarker
+	found := false
+	for _, file := range contentFiles(files) {
+		fileName := getFileName(file.Name)
+		var candidate LatestMarker
+		if matches := multiEpisodeRegex.FindStringSubmatch(fileName); matches != nil {
+			candidate = LatestMarker{Marker: fmt.Sprintf("S%02dE%02d", atoi(matches[1]), highestEpisodeNumber(matches[2]))}
+		} else if marker := extractEpisodeMarker(fileName); marker != "" {
+			candidate = LatestMarker{Marker: marker}
+		} else {
+			continue
+		}
+		candidate.Key = markerSortKey(candidate.Marker)
+		if !found || candidate.Key > latest.Key {
+			latest = candidate
+			found = true
+		}
+	}
+	return latest, found
+}
+
+// 按 --keep-latest 保留每组最新的N个分集：按分集中最新一集的标识排序，标识相同或没有标识时按添加时间；
+// 组内分集都没有剧集标识时不应用，并在报告中注明
+func applyKeepLatest(client *transmissionrpc.Client, result *ScanResult, keepLatest int) {
+	if keepLatest <= 0 {
+		return
+	}
+
+	for name, group := range result.DuplicateGroups {
+		markers := make(map[int64]LatestMarker)
+		var episodes, oldPacks []*transmissionrpc.Torrent
+		for _, episode := range group.Episodes {
+			if episode == nil || episode.ID == nil {
+				continue
+			}
+			// 旧版合集不参与排序，按 --old-pack-action 处理
+			if group.OldPacks[*episode.ID] {
+				oldPacks = append(oldPacks, episode)
+				continue
+			}
+			episodes = append(episodes, episode)
+			files, err := getTorrentFiles(client, episode.ID)
+			if err != nil {
+				continue
+			}
+			if marker, ok := latestEpisodeMarker(files); ok {
+				markers[*episode.ID] = marker
+			}
+		}
+		if len(markers) == 0 {
+			group.KeepLatestNote = fmt.Sprintf("无法识别分集的剧集标识，未应用 --keep-latest %d", keepLatest)
+			result.DuplicateGroups[name] = group
+			continue
+		}
+
+		// 最新的排在前面
+		sort.SliceStable(episodes, func(i, j int) bool {
+			keyI, keyJ := markers[*episodes[i].ID].Key, markers[*episodes[j].ID].Key
+			if keyI != keyJ {
+				return keyI > keyJ
+			}
+			return addedAfter(episodes[i], episodes[j])
+		})
+
+		remaining := oldPacks
+		for i, episode := range episodes {
+			if i >= keepLatest {
+				remaining = append(remaining, episode)
+				continue
+			}
+			reason := "保留最新分集"
+			if marker, ok := markers[*episode.ID]; ok {
+				reason = fmt.Sprintf("保留最新分集（%s）", marker.Marker)
+			}
+			group.GatedEpisodes = append(group.GatedEpisodes, GatedEpisode{
+				Episode: episode,
+				Policy:  fmt.Sprintf("保留最新 %d 个分集", keepLatest),
+				Reason:  reason,
+			})
+		}
+
+		group.Episodes = remaining
+		if len(remaining) == 0 {
+			// 分集全部是最新的，移到仅供参考的部分
+			delete(result.DuplicateGroups, name)
+			result.GatedGroups[name] = group
+			continue
+		}
+		result.DuplicateGroups[name] = group
+	}
+}
+
+// 种子a是否比b添加得晚，添加时间未知时视为较早
+func addedAfter(a, b *transmissionrpc.Torrent) bool {
+	if a.AddedDate == nil || b.AddedDate == nil {
+		return a.AddedDate != nil
+	}
+	return a.AddedDate.After(*b.AddedDate)
+}
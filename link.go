@@ -0,0 +1,482 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 链接类型
+const (
+	LINK_SYMLINK  = "symlink"  // 符号链接（使用相对路径）
+	LINK_HARDLINK = "hardlink" // 硬链接，要求分集和合集在同一文件系统
+)
+
+const (
+	LINK_BACKUP_SUFFIX  = ".delete-episode.bak" // 替换前分集文件的备份后缀，校验通过后删除
+	LINK_VERIFY_TIMEOUT = 30 * time.Minute      // 等待分集校验完成的最长时间
+	LINK_RESTORE_SUFFIX = ".delete-episode.tmp" // 撤销时复制合集文件的临时文件后缀，复制完成后替换链接
+)
+
+// Transmission路径与本地路径的映射，本地路径是允许操作的根目录
+type DataRoot struct {
+	Remote string // Transmission中的下载目录前缀
+	Local  string // 本机上对应的目录
+}
+
+// 一个分集文件替换为合集文件链接的操作，执行后记录到操作历史
+type LinkOperation struct {
+	EpisodeFile    string `json:"episode_file"`    // 分集文件的本地路径
+	CollectionFile string `json:"collection_file"` // 对应合集文件的本地路径
+	Size           int64  `json:"size"`
+}
+
+// 一个分集的原地升级计划
+type LinkPlan struct {
+	GroupName  string
+	Collection *transmissionrpc.Torrent
+	Episode    *transmissionrpc.Torrent
+	Operations []LinkOperation
+}
+
+// 解析数据目录映射，格式为 Transmission路径=本地路径，两者相同时可只写一个
+func parseDataRoot(spec string) (DataRoot, error) {
+	remote, local, found := strings.Cut(spec, "=")
+	if !found {
+		local = remote
+	}
+	remote = strings.TrimSpace(remote)
+	local = strings.TrimSpace(local)
+	if !path.IsAbs(remote) || !filepath.IsAbs(local) {
+		return DataRoot{}, fmt.Errorf("无效的数据目录映射 %q: 路径必须是绝对路径", spec)
+	}
+	return DataRoot{Remote: path.Clean(remote), Local: filepath.Clean(local)}, nil
+}
+
+// 把Transmission中的文件路径映射为本地路径，不在任何数据目录下时返回错误
+func mapLocalPath(roots []DataRoot, remotePath string) (string, DataRoot, error) {
+	remotePath = path.Clean(remotePath)
+	for _, root := range roots {
+		if remotePath != root.Remote && !strings.HasPrefix(remotePath, strings.TrimSuffix(root.Remote, "/")+"/") {
+			continue
+		}
+		relPath := strings.TrimPrefix(strings.TrimPrefix(remotePath, root.Remote), "/")
+		localPath := filepath.Join(root.Local, filepath.FromSlash(relPath))
+		if !isUnderDir(root.Local, localPath) {
+			break
+		}
+		return localPath, root, nil
+	}
+	return "", DataRoot{}, fmt.Errorf("路径不在配置的数据目录下: %s", remotePath)
+}
+
+// 判断路径是否位于目录之内
+func isUnderDir(dir, target string) bool {
+	relPath, err := filepath.Rel(dir, target)
+	if err != nil {
+		return false
+	}
+	return relPath != ".." && !strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// 解析符号链接后检查路径仍在数据目录之内
+func checkResolvedUnderRoot(root DataRoot, localPath string) error {
+	resolvedRoot, err := filepath.EvalSymlinks(root.Local)
+	if err != nil {
+		return fmt.Errorf("无法解析数据目录 %s: %v", root.Local, err)
+	}
+	resolved, err := filepath.EvalSymlinks(localPath)
+	if err != nil {
+		return fmt.Errorf("无法解析路径 %s: %v", localPath, err)
+	}
+	if !isUnderDir(resolvedRoot, resolved) {
+		return fmt.Errorf("路径 %s 解析后不在数据目录 %s 下", localPath, root.Local)
+	}
+	return nil
+}
+
+// 为分集的每个内容文件找到合集中名称和大小都一致的文件
+func matchCollectionFile(collectionFiles []*transmissionrpc.TorrentFile, episodeFile *transmissionrpc.TorrentFile) *transmissionrpc.TorrentFile {
+	episodeFileName := getFileName(episodeFile.Name)
+	// 优先使用文件名完全相同的文件
+	for _, collectionFile := range collectionFiles {
+		if getFileName(collectionFile.Name) == episodeFileName && collectionFile.Length == episodeFile.Length && parentsCompatible(episodeFile.Name, collectionFile.Name) {
+			return collectionFile
+		}
+	}
+	for _, collectionFile := range collectionFiles {
+		if fileNamesMatch(episodeFile.Name, collectionFile.Name) && collectionFile.Length == episodeFile.Length {
+			return collectionFile
+		}
+	}
+	return nil
+}
+
+// 检查本地文件存在且大小与种子中记录的一致
+func checkLocalFile(localPath string, expectedSize int64) (os.FileInfo, error) {
+	info, err := os.Lstat(localPath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("不是普通文件: %s", localPath)
+	}
+	if info.Size() != expectedSize {
+		return nil, fmt.Errorf("文件大小不一致: %s (本地 %d, 种子 %d)", localPath, info.Size(), expectedSize)
+	}
+	return info, nil
+}
+
+// 为分集生成原地升级计划，任何检查不通过都会拒绝
+func planEpisodeLink(client *transmissionrpc.Client, groupName string, group DuplicateGroup, episode *transmissionrpc.Torrent, roots []DataRoot) (LinkPlan, error) {
+	plan := LinkPlan{GroupName: groupName, Collection: group.Collection, Episode: episode}
+	collection := group.Collection
+
+	if collection == nil || collection.ID == nil || episode == nil || episode.ID == nil {
+		return plan, fmt.Errorf("种子信息不完整")
+	}
+	if collection.PercentDone == nil || *collection.PercentDone < 1 {
+		return plan, fmt.Errorf("合集尚未下载完成")
+	}
+	if collection.DownloadDir == nil || episode.DownloadDir == nil {
+		return plan, fmt.Errorf("无法获取下载目录")
+	}
+
+	collectionFiles, err := getTorrentFiles(client, collection.ID)
+	if err != nil {
+		return plan, fmt.Errorf("获取合集文件列表失败: %v", err)
+	}
+	episodeFiles, err := getTorrentFiles(client, episode.ID)
+	if err != nil {
+		return plan, fmt.Errorf("获取分集文件列表失败: %v", err)
+	}
+
+	for _, episodeFile := range contentFiles(episodeFiles) {
+		collectionFile := matchCollectionFile(collectionFiles, episodeFile)
+		if collectionFile == nil {
+			return plan, fmt.Errorf("合集中没有名称和大小一致的文件: %s", episodeFile.Name)
+		}
+		if collectionFile.BytesCompleted != collectionFile.Length {
+			return plan, fmt.Errorf("合集文件尚未下载完成: %s", collectionFile.Name)
+		}
+
+		episodePath, episodeRoot, err := mapLocalPath(roots, path.Join(*episode.DownloadDir, episodeFile.Name))
+		if err != nil {
+			return plan, err
+		}
+		collectionPath, collectionRoot, err := mapLocalPath(roots, path.Join(*collection.DownloadDir, collectionFile.Name))
+		if err != nil {
+			return plan, err
+		}
+		if err := checkResolvedUnderRoot(episodeRoot, filepath.Dir(episodePath)); err != nil {
+			return plan, err
+		}
+		if err := checkResolvedUnderRoot(collectionRoot, collectionPath); err != nil {
+			return plan, err
+		}
+
+		collectionInfo, err := os.Stat(collectionPath)
+		if err != nil {
+			return plan, err
+		}
+		if collectionInfo.Size() != collectionFile.Length {
+			return plan, fmt.Errorf("文件大小不一致: %s (本地 %d, 种子 %d)", collectionPath, collectionInfo.Size(), collectionFile.Length)
+		}
+		// 已经是指向合集文件的链接时无需处理
+		if linkedInfo, err := os.Stat(episodePath); err == nil && os.SameFile(linkedInfo, collectionInfo) {
+			continue
+		}
+		if _, err := checkLocalFile(episodePath, episodeFile.Length); err != nil {
+			return plan, err
+		}
+
+		plan.Operations = append(plan.Operations, LinkOperation{
+			EpisodeFile:    episodePath,
+			CollectionFile: collectionPath,
+			Size:           episodeFile.Length,
+		})
+	}
+
+	if len(plan.Operations) == 0 {
+		return plan, fmt.Errorf("分集文件已全部链接到合集")
+	}
+	return plan, nil
+}
+
+// 符号链接使用相对路径，目录整体挂载到其他位置时仍然有效
+func symlinkTarget(op LinkOperation) string {
+	target, err := filepath.Rel(filepath.Dir(op.EpisodeFile), op.CollectionFile)
+	if err != nil {
+		return op.CollectionFile
+	}
+	return target
+}
+
+// 打印计划执行的文件系统操作
+func printLinkPlan(plan LinkPlan, linkType string) {
+	episodeID := *plan.Episode.ID
+	fmt.Printf("分集 ID: %d (%s):\n", episodeID, *plan.Episode.Name)
+	fmt.Printf("  停止种子 ID: %d\n", episodeID)
+	for _, op := range plan.Operations {
+		fmt.Printf("  mv %q %q\n", op.EpisodeFile, op.EpisodeFile+LINK_BACKUP_SUFFIX)
+		if linkType == LINK_HARDLINK {
+			fmt.Printf("  ln %q %q\n", op.CollectionFile, op.EpisodeFile)
+		} else {
+			fmt.Printf("  ln -s %q %q\n", symlinkTarget(op), op.EpisodeFile)
+		}
+	}
+	fmt.Printf("  校验种子 ID: %d（失败时恢复备份文件）\n", episodeID)
+	for _, op := range plan.Operations {
+		fmt.Printf("  rm %q\n", op.EpisodeFile+LINK_BACKUP_SUFFIX)
+	}
+	fmt.Printf("  恢复做种 ID: %d\n", episodeID)
+}
+
+// 创建链接
+func createLink(op LinkOperation, linkType string) error {
+	if linkType == LINK_HARDLINK {
+		return os.Link(op.CollectionFile, op.EpisodeFile)
+	}
+	return os.Symlink(symlinkTarget(op), op.EpisodeFile)
+}
+
+// 撤销已执行的链接操作，恢复备份的分集文件
+func rollbackLinks(operations []LinkOperation) error {
+	var failed []string
+	for i := len(operations) - 1; i >= 0; i-- {
+		op := operations[i]
+		backupPath := op.EpisodeFile + LINK_BACKUP_SUFFIX
+		if _, err := os.Lstat(backupPath); err != nil {
+			continue
+		}
+		if err := os.Remove(op.EpisodeFile); err != nil && !os.IsNotExist(err) {
+			failed = append(failed, op.EpisodeFile)
+			continue
+		}
+		if err := os.Rename(backupPath, op.EpisodeFile); err != nil {
+			failed = append(failed, backupPath)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("以下文件恢复失败，请手动处理: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// 等待种子校验完成，返回校验后的完成度
+func waitForVerify(client *transmissionrpc.Client, torrentID int64) (float64, error) {
+	deadline := time.Now().Add(LINK_VERIFY_TIMEOUT)
+	for time.Now().Before(deadline) {
+		time.Sleep(2 * time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+		torrents, err := client.TorrentGet(ctx, []string{"id", "status", "percentDone"}, []int64{torrentID})
+		cancel()
+		if err != nil {
+			return 0, err
+		}
+		if len(torrents) == 0 || torrents[0].Status == nil || torrents[0].PercentDone == nil {
+			return 0, fmt.Errorf("种子 ID: %d 已不存在", torrentID)
+		}
+		status := *torrents[0].Status
+		if status != transmissionrpc.TorrentStatusCheck && status != transmissionrpc.TorrentStatusCheckWait {
+			return *torrents[0].PercentDone, nil
+		}
+	}
+	return 0, fmt.Errorf("等待校验超时")
+}
+
+// 执行原地升级计划：停止分集、替换为链接、校验，校验失败时恢复原文件
+func executeLinkPlan(client *transmissionrpc.Client, plan LinkPlan, linkType string) error {
+	episodeID := *plan.Episode.ID
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+	err := client.TorrentStopIDs(ctx, []int64{episodeID})
+	cancel()
+	if err != nil {
+		return fmt.Errorf("停止分集失败: %v", err)
+	}
+
+	// 恢复原文件后重新校验并继续做种
+	restore := func(cause error) error {
+		if err := rollbackLinks(plan.Operations); err != nil {
+			return fmt.Errorf("%v；%v", cause, err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+		client.TorrentVerifyIDs(ctx, []int64{episodeID})
+		cancel()
+		if _, err := waitForVerify(client, episodeID); err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+			client.TorrentStartIDs(ctx, []int64{episodeID})
+			cancel()
+		}
+		return fmt.Errorf("%v，已恢复原文件", cause)
+	}
+
+	for _, op := range plan.Operations {
+		if err := os.Rename(op.EpisodeFile, op.EpisodeFile+LINK_BACKUP_SUFFIX); err != nil {
+			return restore(fmt.Errorf("备份文件失败: %v", err))
+		}
+		if err := createLink(op, linkType); err != nil {
+			return restore(fmt.Errorf("创建链接失败: %v", err))
+		}
+	}
+
+	ctx, cancel = context.WithTimeout(context.Background(), timeouts.Action)
+	err = client.TorrentVerifyIDs(ctx, []int64{episodeID})
+	cancel()
+	if err != nil {
+		return restore(fmt.Errorf("请求校验失败: %v", err))
+	}
+	percentDone, err := waitForVerify(client, episodeID)
+	if err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+		client.TorrentStopIDs(ctx, []int64{episodeID})
+		cancel()
+		return restore(fmt.Errorf("校验失败: %v", err))
+	}
+	if percentDone < 1 {
+		return restore(fmt.Errorf("校验后完成度为 %.2f%%", percentDone*100))
+	}
+
+	// 校验通过，删除重复数据并恢复做种
+	for _, op := range plan.Operations {
+		if err := os.Remove(op.EpisodeFile + LINK_BACKUP_SUFFIX); err != nil {
+			fmt.Printf("删除备份文件失败: %v\n", err)
+		}
+	}
+	ctx, cancel = context.WithTimeout(context.Background(), timeouts.Action)
+	err = client.TorrentStartIDs(ctx, []int64{episodeID})
+	cancel()
+	if err != nil {
+		return fmt.Errorf("链接已完成，但恢复做种失败: %v", err)
+	}
+	return nil
+}
+
+// 对完全包含在合集中的分集执行原地升级，试运行时只打印计划（成功数为计划处理的分集数）。
+// 按置信度从高到低处理，受本次处理数量和操作间隔的限制；收到中断信号后不再开始新的分集，
+// 正在替换的分集会完成校验或恢复原文件。替换成功的文件记录到操作历史，可以撤销
+func linkEpisodes(ctx context.Context, client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup, opts Options, history *HistoryWriter, throttle *ActionThrottle) (int, int, int) {
+	successCount, skippedCount, failedCount := 0, 0, 0
+
+	for _, groupName := range sortedGroupNames(duplicateGroups) {
+		group := duplicateGroups[groupName]
+		fmt.Printf("\n正在处理 \"%s\" 的 %d 个分集...\n", groupName, len(group.Episodes))
+		var plans []LinkPlan
+		var episodes []*transmissionrpc.Torrent
+		for _, episode := range group.Episodes {
+			plan, err := planEpisodeLink(client, groupName, group, episode, opts.DataRoots)
+			if err != nil {
+				if episode != nil && episode.ID != nil {
+					fmt.Printf("跳过分集 ID: %d: %v\n", *episode.ID, err)
+				}
+				skippedCount++
+				continue
+			}
+			plans = append(plans, plan)
+			episodes = append(episodes, episode)
+		}
+
+		if opts.DryRun {
+			for _, plan := range plans {
+				printLinkPlan(plan, opts.LinkType)
+				successCount++
+			}
+			continue
+		}
+
+		episodes = throttle.take(ctx, groupName, episodes)
+		for i, episode := range episodes {
+			if !throttle.wait(ctx) {
+				throttle.deferRest(groupName, episodes[i:], DEFERRED_INTERRUPTED)
+				break
+			}
+			plan := plans[i]
+			printLinkPlan(plan, opts.LinkType)
+			if err := executeLinkPlan(client, plan, opts.LinkType); err != nil {
+				fmt.Printf("分集 ID: %d 原地升级失败: %v\n", *episode.ID, err)
+				failedCount++
+				continue
+			}
+			fmt.Printf("分集 ID: %d 已替换为指向合集的链接并继续做种\n", *episode.ID)
+			record := newHistoryRecord(ACTION_LINK, groupName, episode)
+			record.LinkedFiles = plan.Operations
+			history.Record(record)
+			keptExport.succeed(groupName)
+			runNotices.succeed(groupName)
+			runNotices.reclaim(torrentBytes(episode))
+			successCount++
+		}
+	}
+
+	return successCount, skippedCount, failedCount
+}
+
+// 撤销原地升级：把仍指向合集文件的链接替换为合集文件的副本，分集恢复为独立的数据，需要相应的存储空间。
+// 分集文件已不是指向合集的链接（如已被替换或删除）时不处理该文件
+func restoreLinkedFiles(operations []LinkOperation) error {
+	var failed []string
+	for _, op := range operations {
+		episodeInfo, err := os.Stat(op.EpisodeFile)
+		if err != nil {
+			continue
+		}
+		collectionInfo, err := os.Stat(op.CollectionFile)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s（合集文件不可用: %v）", op.EpisodeFile, err))
+			continue
+		}
+		if !os.SameFile(episodeInfo, collectionInfo) {
+			continue
+		}
+		if err := copyOverLink(op, collectionInfo.Mode().Perm()); err != nil {
+			failed = append(failed, fmt.Sprintf("%s（%v）", op.EpisodeFile, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("以下文件恢复失败: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// 把合集文件复制到临时文件，完成后替换分集位置上的链接；失败时删除临时文件，链接保持不变
+func copyOverLink(op LinkOperation, perm os.FileMode) error {
+	tempPath := op.EpisodeFile + LINK_RESTORE_SUFFIX
+	source, err := os.Open(op.CollectionFile)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+	target, err := os.OpenFile(tempPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, perm)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(target, source)
+	if closeErr := target.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tempPath, op.EpisodeFile)
+	}
+	if err != nil {
+		os.Remove(tempPath)
+	}
+	return err
+}
+
+// 链接文件的大小之和，即撤销时需要的存储空间
+func linkedBytes(operations []LinkOperation) int64 {
+	var total int64
+	for _, op := range operations {
+		total += op.Size
+	}
+	return total
+}
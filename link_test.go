@@ -0,0 +1,90 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMapLocalPath(t *testing.T) {
+	roots := []DataRoot{{Remote: "/downloads", Local: filepath.FromSlash("/mnt/nas/downloads")}}
+	tests := []struct {
+		remote  string
+		want    string
+		wantErr bool
+	}{
+		{"/downloads/Show.S01/E01.mkv", filepath.FromSlash("/mnt/nas/downloads/Show.S01/E01.mkv"), false},
+		{"/downloads", filepath.FromSlash("/mnt/nas/downloads"), false},
+		{"/downloads-other/E01.mkv", "", true},
+		{"/downloads/../etc/passwd", "", true},
+	}
+	for _, tt := range tests {
+		got, _, err := mapLocalPath(roots, tt.remote)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("mapLocalPath(%q) = %q, %v, want %q, error %v", tt.remote, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestRestoreLinkedFiles(t *testing.T) {
+	tests := []struct {
+		name string
+		link func(collectionFile, episodeFile string) error
+	}{
+		{"符号链接", func(collectionFile, episodeFile string) error {
+			return os.Symlink(collectionFile, episodeFile)
+		}},
+		{"硬链接", os.Link},
+		{"独立的文件不处理", func(collectionFile, episodeFile string) error {
+			return os.WriteFile(episodeFile, []byte("episode data"), 0o644)
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			op := LinkOperation{
+				EpisodeFile:    filepath.Join(dir, "episode.mkv"),
+				CollectionFile: filepath.Join(dir, "collection.mkv"),
+			}
+			if err := os.WriteFile(op.CollectionFile, []byte("collection data"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.link(op.CollectionFile, op.EpisodeFile); err != nil {
+				t.Skipf("无法创建链接: %v", err)
+			}
+			before, _ := os.ReadFile(op.EpisodeFile)
+
+			if err := restoreLinkedFiles([]LinkOperation{op}); err != nil {
+				t.Fatalf("restoreLinkedFiles() error: %v", err)
+			}
+			episodeInfo, err := os.Lstat(op.EpisodeFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			collectionInfo, _ := os.Stat(op.CollectionFile)
+			if !episodeInfo.Mode().IsRegular() || os.SameFile(episodeInfo, collectionInfo) {
+				t.Errorf("分集文件仍是指向合集的链接")
+			}
+			if after, _ := os.ReadFile(op.EpisodeFile); string(after) != string(before) {
+				t.Errorf("分集文件内容 = %q, want %q", after, before)
+			}
+			if _, err := os.Stat(op.EpisodeFile + LINK_RESTORE_SUFFIX); !os.IsNotExist(err) {
+				t.Errorf("临时文件未删除")
+			}
+		})
+	}
+}
+
+func TestUndoableRecordsIncludeLinks(t *testing.T) {
+	records := []HistoryRecord{
+		{RunID: "run1", Action: ACTION_LINK, TorrentID: 1, LinkedFiles: []LinkOperation{{EpisodeFile: "/a", CollectionFile: "/b", Size: 10}}},
+		{RunID: "run1", Action: ACTION_PLANNED, TorrentID: 2},
+	}
+	runID, runRecords := lastUndoableRun(records)
+	if runID != "run1" || len(runRecords) != 1 || runRecords[0].Action != ACTION_LINK {
+		t.Errorf("lastUndoableRun() = %q, %+v", runID, runRecords)
+	}
+	if got := linkedBytes(runRecords[0].LinkedFiles); got != 10 {
+		t.Errorf("linkedBytes() = %d, want 10", got)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 请求的字段在超过该比例的种子中缺失时，认为服务器可能负载过高，返回了不完整的结果
+const SPARSE_FIELD_RATIO = 0.1
+
+// RPC字段名对应的 Torrent 结构体字段序号
+var torrentFieldIndex = func() map[string]int {
+	index := make(map[string]int)
+	torrentType := reflect.TypeOf(transmissionrpc.Torrent{})
+	for i := 0; i < torrentType.NumField(); i++ {
+		name, _, _ := strings.Cut(torrentType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			index[name] = i
+		}
+	}
+	return index
+}()
+
+// 种子是否缺少请求的字段（服务器没有返回时字段为nil）
+func torrentFieldMissing(torrent *transmissionrpc.Torrent, field string) bool {
+	i, ok := torrentFieldIndex[field]
+	if !ok {
+		return false
+	}
+	value := reflect.ValueOf(torrent).Elem().Field(i)
+	switch value.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+		return value.IsNil()
+	}
+	return false
+}
+
+// 返回的字段数量，用于在重复的种子中选择最完整的一个
+func torrentFieldCount(torrent *transmissionrpc.Torrent) int {
+	count := 0
+	for field := range torrentFieldIndex {
+		if !torrentFieldMissing(torrent, field) {
+			count++
+		}
+	}
+	return count
+}
+
+// 按ID和hash去掉重复的种子，保留字段最完整的一个，其余按原来的顺序，返回去掉的数量
+func dedupeTorrents(torrents []transmissionrpc.Torrent) ([]transmissionrpc.Torrent, int) {
+	var unique []transmissionrpc.Torrent
+	byID := make(map[int64]int)
+	byHash := make(map[string]int)
+	dropped := 0
+	for _, torrent := range torrents {
+		slot, found := -1, false
+		if torrent.ID != nil {
+			slot, found = byID[*torrent.ID]
+		}
+		if !found && torrent.HashString != nil {
+			slot, found = byHash[*torrent.HashString]
+		}
+		if !found {
+			slot = len(unique)
+			unique = append(unique, torrent)
+		} else {
+			dropped++
+			if torrentFieldCount(&torrent) > torrentFieldCount(&unique[slot]) {
+				unique[slot] = torrent
+			}
+		}
+		if torrent.ID != nil {
+			byID[*torrent.ID] = slot
+		}
+		if torrent.HashString != nil {
+			byHash[*torrent.HashString] = slot
+		}
+	}
+	return unique, dropped
+}
+
+// 找出缺失比例超过 SPARSE_FIELD_RATIO 的请求字段，返回缺失最多的字段及其缺失数量。
+// 全部种子都缺少的字段是服务器不支持的字段，不视为结果不完整
+func sparseField(torrents []transmissionrpc.Torrent, fields []string) (string, int) {
+	worst, worstMissing := "", 0
+	for _, field := range fields {
+		missing := 0
+		for i := range torrents {
+			if torrentFieldMissing(&torrents[i], field) {
+				missing++
+			}
+		}
+		if missing == len(torrents) || float64(missing) <= SPARSE_FIELD_RATIO*float64(len(torrents)) {
+			continue
+		}
+		if missing > worstMissing {
+			worst, worstMissing = field, missing
+		}
+	}
+	return worst, worstMissing
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 只有ID、hash和给定状态的种子，status 为nil时表示服务器没有返回该字段
+func listedTorrent(id int64, hash string, status *transmissionrpc.TorrentStatus) transmissionrpc.Torrent {
+	return transmissionrpc.Torrent{ID: &id, HashString: &hash, Status: status}
+}
+
+func TestDedupeTorrents(t *testing.T) {
+	seeding := transmissionrpc.TorrentStatusSeed
+	tests := []struct {
+		name     string
+		torrents []transmissionrpc.Torrent
+		ids      []int64
+		complete []bool // 保留的种子是否有状态字段
+		dropped  int
+	}{
+		{
+			name:     "没有重复",
+			torrents: []transmissionrpc.Torrent{listedTorrent(1, "a", &seeding), listedTorrent(2, "b", &seeding)},
+			ids:      []int64{1, 2},
+			complete: []bool{true, true},
+		},
+		{
+			name:     "重复的ID保留字段最完整的一个",
+			torrents: []transmissionrpc.Torrent{listedTorrent(1, "a", nil), listedTorrent(2, "b", &seeding), listedTorrent(1, "a", &seeding)},
+			ids:      []int64{1, 2},
+			complete: []bool{true, true},
+			dropped:  1,
+		},
+		{
+			name:     "字段一样完整时保留先出现的",
+			torrents: []transmissionrpc.Torrent{listedTorrent(1, "a", &seeding), listedTorrent(1, "a", nil), listedTorrent(1, "a", nil)},
+			ids:      []int64{1},
+			complete: []bool{true},
+			dropped:  2,
+		},
+		{
+			name:     "hash相同、ID不同的种子按hash去重",
+			torrents: []transmissionrpc.Torrent{listedTorrent(1, "a", nil), listedTorrent(5, "a", &seeding)},
+			ids:      []int64{5},
+			complete: []bool{true},
+			dropped:  1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			unique, dropped := dedupeTorrents(tt.torrents)
+			var ids []int64
+			var complete []bool
+			for i := range unique {
+				ids = append(ids, *unique[i].ID)
+				complete = append(complete, unique[i].Status != nil)
+			}
+			if !reflect.DeepEqual(ids, tt.ids) || !reflect.DeepEqual(complete, tt.complete) || dropped != tt.dropped {
+				t.Errorf("dedupeTorrents() = %v %v, 去掉 %d 个，应为 %v %v, 去掉 %d 个", ids, complete, dropped, tt.ids, tt.complete, tt.dropped)
+			}
+		})
+	}
+}
+
+func TestSparseField(t *testing.T) {
+	seeding := transmissionrpc.TorrentStatusSeed
+	list := func(missing int) []transmissionrpc.Torrent {
+		var torrents []transmissionrpc.Torrent
+		for i := 0; i < 20; i++ {
+			status := &seeding
+			if i < missing {
+				status = nil
+			}
+			torrents = append(torrents, listedTorrent(int64(i), "", status))
+		}
+		return torrents
+	}
+	tests := []struct {
+		name    string
+		missing int
+		field   string
+	}{
+		{"字段完整", 0, ""},
+		{"缺失比例不超过10%", 2, ""},
+		{"缺失比例超过10%", 3, "status"},
+		{"全部缺失视为服务器不支持", 20, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			field, missing := sparseField(list(tt.missing), []string{"id", "status", "labels"})
+			if field != tt.field {
+				t.Errorf("sparseField() = %q (%d)，应为 %q", field, missing, tt.field)
+			}
+		})
+	}
+}
+
+// 服务器返回重复的种子时，组内只有不重复的种子
+func TestScanDuplicateTorrents(t *testing.T) {
+	client, opts := connectFixture(t, "scan.json")
+	var duplicates []map[string]json.RawMessage
+	for _, torrent := range dumpReplay.Torrents {
+		if string(torrent["id"]) == "2" || string(torrent["id"]) == "3" {
+			duplicate := make(map[string]json.RawMessage)
+			for field, value := range torrent {
+				if field != "trackers" {
+					duplicate[field] = value
+				}
+			}
+			duplicates = append(duplicates, duplicate)
+		}
+	}
+	dumpReplay.Torrents = append(duplicates, dumpReplay.Torrents...)
+
+	result, err := scan(client, detectCapabilities(client), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Torrents) != 11 {
+		t.Errorf("种子列表有 %d 个种子，应为去重后的 11 个", len(result.Torrents))
+	}
+	group, ok := result.DuplicateGroups["Show.A.S01.1080p.WEB-DL"]
+	if !ok {
+		t.Fatal("没有找到需要处理的组")
+	}
+	var ids []int64
+	for _, episode := range group.Episodes {
+		ids = append(ids, *episode.ID)
+		if len(episode.Trackers) == 0 {
+			t.Errorf("分集 ID: %d 使用了缺少字段的重复项", *episode.ID)
+		}
+	}
+	if want := []int64{3, 2}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("分集 %v，应为 %v", ids, want)
+	}
+}
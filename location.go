@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// --relocate-episodes 的可选值
+const (
+	RELOCATE_NONE = "none" // 只在报告中提示，不修改位置
+	RELOCATE_SET  = "set"  // 只修改下载位置，不移动数据（目标位置已有数据，如硬链接）
+	RELOCATE_MOVE = "move" // 把数据移动到合集所在目录
+)
+
+// 下载位置与合集不同的分集
+func misplacedEpisodes(group DuplicateGroup) []*transmissionrpc.Torrent {
+	if group.Collection == nil || group.Collection.DownloadDir == nil {
+		return nil
+	}
+	collectionDir := path.Clean(*group.Collection.DownloadDir)
+	var misplaced []*transmissionrpc.Torrent
+	for _, episode := range group.Episodes {
+		if episode == nil || episode.ID == nil || episode.DownloadDir == nil {
+			continue
+		}
+		if path.Clean(*episode.DownloadDir) != collectionDir {
+			misplaced = append(misplaced, episode)
+		}
+	}
+	return misplaced
+}
+
+// 组内下载位置不一致的说明，一致时返回空。合集和分集位于不同目录通常说明导入流程有问题
+func describeLocationMismatch(group DuplicateGroup) string {
+	misplaced := misplacedEpisodes(group)
+	if len(misplaced) == 0 {
+		return ""
+	}
+	byDir := make(map[string][]string)
+	var dirs []string
+	for _, episode := range misplaced {
+		dir := path.Clean(*episode.DownloadDir)
+		if _, ok := byDir[dir]; !ok {
+			dirs = append(dirs, dir)
+		}
+		byDir[dir] = append(byDir[dir], fmt.Sprint(*episode.ID))
+	}
+	sort.Strings(dirs)
+	parts := make([]string, len(dirs))
+	for i, dir := range dirs {
+		parts[i] = fmt.Sprintf("分集 ID: %s 位于 %s", strings.Join(byDir[dir], ", "), dir)
+	}
+	return fmt.Sprintf("合集位于 %s，%s", path.Clean(*group.Collection.DownloadDir), strings.Join(parts, "；"))
+}
+
+// 按 --relocate-episodes 把下载位置与合集不同的分集移到合集所在目录，在执行操作前单独确认（reader 为空或指定
+// --yes 时不确认）；修改失败只显示错误，不影响之后的操作
+func relocateMisplacedEpisodes(ctx context.Context, reader *bufio.Reader, client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup, opts Options) {
+	if opts.RelocateEpisodes == RELOCATE_NONE {
+		return
+	}
+	type relocation struct {
+		Episode     *transmissionrpc.Torrent
+		Destination string
+	}
+	var relocations []relocation
+	for _, groupName := range sortedGroupNames(duplicateGroups) {
+		group := duplicateGroups[groupName]
+		for _, episode := range misplacedEpisodes(group) {
+			relocations = append(relocations, relocation{Episode: episode, Destination: path.Clean(*group.Collection.DownloadDir)})
+		}
+	}
+	if len(relocations) == 0 {
+		return
+	}
+
+	how := "只修改下载位置，不移动数据"
+	if opts.RelocateEpisodes == RELOCATE_MOVE {
+		how = "移动数据"
+	}
+	fmt.Printf("\n有 %d 个分集的下载位置与合集不同，将改为合集所在目录（%s）:\n", len(relocations), how)
+	for _, r := range relocations {
+		fmt.Printf("  ID: %d, %s → %s\n", *r.Episode.ID, *r.Episode.DownloadDir, r.Destination)
+	}
+	if opts.DryRun {
+		fmt.Println("试运行模式，不修改下载位置")
+		return
+	}
+	if reader != nil && !opts.Yes {
+		fmt.Print("是否修改这些分集的下载位置? (y/n) [默认: n]: ")
+		answer, _ := reader.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+			fmt.Println("不修改下载位置")
+			return
+		}
+	}
+
+	movedCount, failedCount := 0, 0
+	for _, r := range relocations {
+		if ctx.Err() != nil {
+			fmt.Println("已中断，不再修改其余分集的下载位置")
+			break
+		}
+		var err error
+		if opts.RelocateEpisodes == RELOCATE_MOVE {
+			err = moveCollection(ctx, client, *r.Episode.ID, r.Destination, opts.MoveTimeout)
+		} else {
+			setCtx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+			err = client.TorrentSetLocation(setCtx, *r.Episode.ID, r.Destination, false)
+			cancel()
+		}
+		if err != nil {
+			fmt.Printf("修改分集 ID: %d 的下载位置失败: %v\n", *r.Episode.ID, err)
+			failedCount++
+			continue
+		}
+		movedCount++
+	}
+	fmt.Printf("修改下载位置完成: 成功 %d 个, 失败 %d 个\n", movedCount, failedCount)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 元数据未完成的磁力链接：还没有文件信息，不能作为合集，也不能与其他种子比较文件
+type MagnetStub struct {
+	Torrent    *transmissionrpc.Torrent
+	Duplicates []*transmissionrpc.Torrent // 同名（同一分组）且已下载完成的种子
+}
+
+// 磁力链接添加后经过的天数，没有添加时间时为0
+func (m MagnetStub) ageDays(now time.Time) int {
+	if m.Torrent == nil || m.Torrent.AddedDate == nil {
+		return 0
+	}
+	return int(now.Sub(*m.Torrent.AddedDate).Hours() / 24)
+}
+
+// 查找元数据未完成的磁力链接，并按名称分组找出与其同名、已下载完成的种子
+func findMagnetStubs(torrents []transmissionrpc.Torrent, nameMap []NameAlias) []MagnetStub {
+	complete := make(map[string][]*transmissionrpc.Torrent)
+	var pending []*transmissionrpc.Torrent
+	for i := range torrents {
+		torrent := &torrents[i]
+		if torrent.ID == nil || torrent.Name == nil {
+			continue
+		}
+		if metadataPending(torrent) {
+			pending = append(pending, torrent)
+			continue
+		}
+		if torrent.PercentDone != nil && *torrent.PercentDone >= 1 {
+			key, _ := groupKey(nameMap, canonicalName(*torrent.Name))
+			complete[key] = append(complete[key], torrent)
+		}
+	}
+
+	stubs := make([]MagnetStub, 0, len(pending))
+	for _, torrent := range pending {
+		key, _ := groupKey(nameMap, canonicalName(*torrent.Name))
+		stubs = append(stubs, MagnetStub{Torrent: torrent, Duplicates: complete[key]})
+	}
+	sort.Slice(stubs, func(i, j int) bool {
+		return *stubs[i].Torrent.ID < *stubs[j].Torrent.ID
+	})
+	return stubs
+}
+
+// 可以删除的过期磁力链接：添加超过 days 天、且有同名的已下载完成种子
+func staleMagnetTargets(stubs []MagnetStub, days int, now time.Time) []*transmissionrpc.Torrent {
+	var targets []*transmissionrpc.Torrent
+	for _, stub := range stubs {
+		if len(stub.Duplicates) == 0 || stub.Torrent.AddedDate == nil || stub.ageDays(now) < days {
+			continue
+		}
+		targets = append(targets, stub.Torrent)
+	}
+	return targets
+}
+
+// 删除过期的磁力链接，只删除种子、不删除数据（同名的已完成种子可能使用相同的路径），返回成功删除的数量
+func removeStaleMagnets(ctx context.Context, client *transmissionrpc.Client, result *ScanResult, days int, dryRun bool, throttle *ActionThrottle) int {
+	targets := staleMagnetTargets(result.MagnetStubs, days, time.Now())
+	if len(targets) == 0 {
+		return 0
+	}
+	if dryRun {
+		fmt.Printf("\n试运行模式，不删除 %d 个过期的磁力链接\n", len(targets))
+		return 0
+	}
+	targets = throttle.take(ctx, "过期磁力链接", targets)
+	if len(targets) == 0 {
+		return 0
+	}
+
+	fmt.Printf("正在删除 %d 个过期的磁力链接...\n", len(targets))
+	removed := make(map[int64]bool)
+	attempted := 0
+	for i, torrent := range targets {
+		if !throttle.wait(ctx) {
+			throttle.deferRest("过期磁力链接", targets[i:], DEFERRED_INTERRUPTED)
+			break
+		}
+		attempted++
+		id := *torrent.ID
+		removeCtx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+		err := client.TorrentRemove(removeCtx, transmissionrpc.TorrentRemovePayload{
+			IDs:             []int64{id},
+			DeleteLocalData: false,
+		})
+		cancel()
+		if err != nil {
+			fmt.Printf("删除磁力链接失败 ID: %d: %v\n", id, err)
+			continue
+		}
+		removed[id] = true
+	}
+
+	var kept []MagnetStub
+	for _, stub := range result.MagnetStubs {
+		if !removed[*stub.Torrent.ID] {
+			kept = append(kept, stub)
+		}
+	}
+	result.MagnetStubs = kept
+
+	fmt.Printf("删除完成: 成功删除 %d 个磁力链接, 失败 %d 个\n", len(removed), attempted-len(removed))
+	return len(removed)
+}
+
+// 显示未完成的磁力链接：默认只显示与已下载完成的内容同名的，verbose 时显示全部
+func printMagnetStubs(w io.Writer, stubs []MagnetStub, verbose bool) {
+	var shown []MagnetStub
+	for _, stub := range stubs {
+		if verbose || len(stub.Duplicates) > 0 {
+			shown = append(shown, stub)
+		}
+	}
+	if len(shown) == 0 {
+		return
+	}
+	now := time.Now()
+	fmt.Fprintf(w, "\n===== 未完成磁力链接（%d 个，元数据未完成，不作为合集）=====\n", len(shown))
+	for i, stub := range shown {
+		torrent := stub.Torrent
+		line := fmt.Sprintf("  %d. ID: %d, %s, 元数据完成 %.0f%%", i+1, *torrent.ID, *torrent.Name, *torrent.MetadataPercentComplete*100)
+		if torrent.AddedDate != nil {
+			line += fmt.Sprintf(", 已添加 %d 天", stub.ageDays(now))
+		}
+		if len(stub.Duplicates) > 0 {
+			ids := make([]string, len(stub.Duplicates))
+			for j, duplicate := range stub.Duplicates {
+				ids[j] = torrentIDText(duplicate)
+			}
+			line += fmt.Sprintf(", 同名的已完成种子 ID: %s", strings.Join(ids, ", "))
+		}
+		fmt.Fprintln(w, line)
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scan.json 中的 ID 11 是元数据未完成、没有文件信息的磁力链接，与已完成的 Show.A 同名
+func TestMagnetStubFromFixture(t *testing.T) {
+	client, opts := connectFixture(t, "scan.json")
+	added := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	setReplayTorrent(t, 11, "addedDate", added.Unix())
+	result, err := scan(client, detectCapabilities(client), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, groups := range allGroupMaps(result) {
+		for name, group := range groups {
+			if group.Collection != nil && *group.Collection.ID == 11 {
+				t.Errorf("磁力链接不能作为组 %s 的合集", name)
+			}
+		}
+	}
+
+	if len(result.MagnetStubs) != 1 || *result.MagnetStubs[0].Torrent.ID != 11 {
+		t.Fatalf("未完成磁力链接 %d 个，应只有 ID 11", len(result.MagnetStubs))
+	}
+	if got := len(result.MagnetStubs[0].Duplicates); got != 3 {
+		t.Errorf("同名的已完成种子 %d 个，应为 3 个", got)
+	}
+	var out bytes.Buffer
+	printMagnetStubs(&out, result.MagnetStubs, false)
+	if !strings.Contains(out.String(), "未完成磁力链接（1 个") || !strings.Contains(out.String(), "ID: 11, Show.A.S01.1080p.WEB-DL") {
+		t.Errorf("未完成磁力链接中没有列出 ID 11:\n%s", out.String())
+	}
+
+	now := added.Add(10 * 24 * time.Hour)
+	tests := []struct {
+		days int
+		want int
+	}{
+		{7, 1},
+		{10, 1},
+		{11, 0},
+		{30, 0},
+	}
+	for _, tt := range tests {
+		if got := staleMagnetTargets(result.MagnetStubs, tt.days, now); len(got) != tt.want {
+			t.Errorf("添加 10 天的磁力链接，--remove-stale-magnets %d 选中 %d 个，应为 %d 个", tt.days, len(got), tt.want)
+		}
+	}
+}
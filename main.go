@@ -6,9 +6,11 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"regexp"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/hekmon/transmissionrpc/v2"
@@ -18,6 +20,8 @@ import (
 const (
 	// 重试次数和超时时间设置
 	MAX_RETRIES = 3
+	// 分批获取种子详情时每批的种子数量
+	TORRENT_CHUNK_SIZE = 500
 )
 
 // 定义一个结构体用于存储合集和分集的映射关系
@@ -25,262 +29,907 @@ type DuplicateGroup struct {
 	Collection      *transmissionrpc.Torrent   // 合集种子（较大的文件）
 	Episodes        []*transmissionrpc.Torrent // 分集种子（较小的文件）
 	HasFileOverlaps bool                       // 是否文件列表有重叠
-}
+	PartialEpisodes []PartialEpisode           // 只有部分内容包含在合集中的分集（不会被处理）
+	EpisodeActions  map[int64]string           // 按tracker策略确定的分集操作，为空时使用全局操作
+	EpisodePolicies map[int64]string           // 分集适用的tracker策略
+	GatedEpisodes   []GatedEpisode             // 被tracker策略暂缓的分集（不会被处理）
+	ActiveEpisodes  []ActiveEpisode            // 正在活跃上传而被保留的分集（不会被处理）
+	HandledEpisodes []*transmissionrpc.Torrent // 已经暂停、无需再处理的分集
+	EpisodeClasses  map[int64]string           // 分集与合集的tracker关系
+	TrackerClass    string                     // 组的tracker关系分类
+
+	UnregisteredEpisodes []UnregisteredTorrent // tracker报告已失效的分集
+
+	SameSizeDuplicate bool   // 同一tracker大小相同的重复种子，合集为保留的种子
+	Decision          string // 重复种子的保留决策说明
+
+	KeepLatestNote string // 未能应用 --keep-latest 的说明
+	PrivacyNote    string // 合集和分集私有属性不一致的说明
+	SwarmNote      string // 指定 --min-collection-seeders 时合集的做种情况
+
+	OldPacks      map[int64]bool // 作为分集的旧版合集（剧集标识是合集的真子集）
+	SeriesSeasons []int          // 全剧合集覆盖的季，其他组为空
+
+	EpisodeCopies map[int64]int64 // 与组内其他分集完全相同的副本: 副本ID → 第一个分集的ID
 
-// 用于识别剧集号的正则表达式
-var episodeRegex = regexp.MustCompile(`[Ss](\d+)[Ee](\d+)`)
+	AliasNames []string // 通过名称映射归入本组的种子名称
+	Note       string   // 用户为本组添加的备注
+	Origin     string   // 组的来源，深度扫描按内容匹配时为 ORIGIN_CONTENT
+
+	Evidence   GroupEvidence // 判断合集和分集关系的证据
+	Confidence float64       // 根据证据计算的置信度（0~1）
+
+	UploadEstimate UploadEstimate // 处理分集对上传量的影响（估算）
+
+	KeptEpisodes []*transmissionrpc.Torrent // 交互选择中手动保留的分集（不会被处理）
+
+	FileGrouped map[int64]string // 在 Transmission 中改过名、按文件名归入本组的种子: ID → 种子名称
+}
 
 func main() {
+	setupConsole()
 	reader := bufio.NewReader(os.Stdin)
 
-	// 提示用户输入连接参数
-	fmt.Println("请输入Transmission服务器连接参数：")
+	// 只扫描，保存计划或与已保存的计划比较
+	if len(os.Args) > 1 && os.Args[1] == "scan" {
+		opts := parseOptions(os.Args[2:])
+		defer diagBundle.write()
+		runScan(reader, opts)
+		return
+	}
 
-	// 输入服务器地址
-	fmt.Print("服务器地址 [默认: 127.0.0.1]: ")
-	serverAddressInput, _ := reader.ReadString('\n')
-	serverAddressInput = strings.TrimSpace(serverAddressInput)
-	serverAddress := "127.0.0.1"
-	if serverAddressInput != "" {
-		serverAddress = serverAddressInput
+	// 执行已保存的计划
+	if len(os.Args) > 1 && os.Args[1] == "apply" {
+		runApply(reader, os.Args[2:])
+		exitOnPostHookFailure()
+		return
 	}
 
-	// 输入端口
-	fmt.Print("端口 [默认: 9091]: ")
-	portInput, _ := reader.ReadString('\n')
-	portInput = strings.TrimSpace(portInput)
-	port := 9091
-	if portInput != "" {
-		portValue, err := strconv.Atoi(portInput)
-		if err == nil && portValue > 0 {
-			port = portValue
-		} else {
-			fmt.Println("端口输入无效，将使用默认值 9091")
-		}
+	// 显示指定种子的原始字段和识别结果
+	if len(os.Args) > 1 && os.Args[1] == "inspect" {
+		runInspect(reader, os.Args[2:])
+		return
 	}
 
-	// 是否使用HTTPS
-	fmt.Print("是否使用HTTPS (y/n) [默认: n]: ")
-	httpsInput, _ := reader.ReadString('\n')
-	httpsInput = strings.TrimSpace(httpsInput)
-	isHttps := false
-	if strings.ToLower(httpsInput) == "y" {
-		isHttps = true
+	// 撤销上一次操作
+	if len(os.Args) > 1 && os.Args[1] == "undo" {
+		runUndo(reader, parseOptions(os.Args[2:]))
+		return
 	}
 
-	// 输入用户名
-	fmt.Print("用户名 [默认: \"\"]: ")
-	username, _ := reader.ReadString('\n')
-	username = strings.TrimSpace(username)
+	// 输出shell补全脚本
+	if len(os.Args) > 1 && os.Args[1] == "completion" {
+		runCompletion(os.Args[2:])
+		return
+	}
 
-	// 输入密码
-	fmt.Print("密码 [默认: \"\"]: ")
-	password, _ := reader.ReadString('\n')
-	password = strings.TrimSpace(password)
+	// 管理误判记录
+	if len(os.Args) > 1 && os.Args[1] == "ignore" {
+		runIgnore(reader, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "ignores" {
+		runIgnores(os.Args[2:])
+		return
+	}
+
+	// 管理组的备注
+	if len(os.Args) > 1 && os.Args[1] == "notes" {
+		runNotes(os.Args[2:])
+		return
+	}
+
+	// 显示守护模式的趋势
+	if len(os.Args) > 1 && os.Args[1] == "trend" {
+		runTrend(os.Args[2:])
+		return
+	}
+
+	// 检查状态文件
+	if len(os.Args) > 1 && os.Args[1] == "doctor" {
+		runDoctor(reader, os.Args[2:])
+		return
+	}
+
+	opts := parseOptions(os.Args[1:])
+	// 先注册，在写入诊断包之后执行
+	defer exitOnPostHookFailure()
+	defer diagBundle.write()
+	if opts.FromDump != "" {
+		fmt.Printf("离线重放诊断包 %s：只分析，不连接服务器，不执行任何操作\n", opts.FromDump)
+	}
+	if opts.FromTorrents != "" {
+		fmt.Printf("离线分析种子备份目录 %s：只分析，不连接服务器，不执行任何操作；大小来自种子元数据\n", opts.FromTorrents)
+	}
+
+	// 测试剧集标识规则后退出
+	if opts.TestPattern != "" {
+		runTestPattern(opts.TestPattern)
+		return
+	}
+
+	// 统计模式：只按名称和大小统计后退出
+	if opts.StatsOnly {
+		runStats(reader, opts)
+		return
+	}
+
+	// 按审阅文件执行
+	if opts.ReviewIn != "" {
+		runReview(reader, opts)
+		return
+	}
+
+	// 紧凑格式和写入审阅文件只扫描和输出，不执行操作
+	if opts.Format == FORMAT_COMPACT || opts.ReviewOut != "" {
+		runScan(reader, opts)
+		return
+	}
+
+	// 耗时统计：按试运行完整执行一次，最后显示各阶段的耗时
+	if opts.Benchmark {
+		opts.DryRun = true
+		opts.Yes = true
+		runInteractive(reader, opts)
+		benchmark.print()
+		return
+	}
+
+	// 守护模式：按间隔循环扫描，不进行交互
+	if opts.Daemon {
+		runDaemon(opts)
+		return
+	}
+
+	runInteractive(reader, opts)
+}
+
+// 交互模式：提示输入参数，显示结果后确认执行
+func runInteractive(reader *bufio.Reader, opts Options) {
+	// 提示用户输入连接参数（已通过命令行指定时跳过）
+	params := opts.Connection
+	if !opts.ConnectionSet {
+		params = readConnectionParams(reader)
+		params.Proxy = opts.Connection.Proxy
+	}
 
 	// 输入种子名称筛选结尾
-	fmt.Print("种子名称筛选结尾（多个以;分隔，直接回车则不筛选）[例如: ADWeb;HHWEB]: ")
-	suffixesInput, _ := reader.ReadString('\n')
-	suffixesInput = strings.TrimSpace(suffixesInput)
-	var suffixFilters []string
-	if suffixesInput != "" {
-		suffixFilters = strings.Split(suffixesInput, ";")
-		// 移除可能的空白
-		for i, suffix := range suffixFilters {
-			suffixFilters[i] = strings.TrimSpace(suffix)
+	suffixFilters := opts.SuffixFilters
+	if !opts.SuffixSet {
+		fmt.Print("种子名称筛选结尾（多个以;分隔，直接回车则不筛选）[例如: ADWeb;HHWEB]: ")
+		suffixesInput, _ := reader.ReadString('\n')
+		suffixFilters = parseSuffixFilters(suffixesInput)
+	}
+
+	// 选择对分集执行的操作
+	action := opts.Action
+	if !opts.ActionSet {
+		if len(opts.DataRoots) > 0 {
+			fmt.Print("对分集执行的操作（pause=暂停, priority=调整带宽优先级, deselect=取消选择文件, idle-limit=空闲后停止, link=原地升级）[默认: pause]: ")
+		} else {
+			fmt.Print("对分集执行的操作（pause=暂停, priority=调整带宽优先级, deselect=取消选择文件, idle-limit=空闲后停止）[默认: pause]: ")
+		}
+		actionInput, _ := reader.ReadString('\n')
+		actionInput = strings.ToLower(strings.TrimSpace(actionInput))
+		if actionInput == ACTION_PRIORITY {
+			action = ACTION_PRIORITY
+		} else if actionInput == ACTION_DESELECT {
+			action = ACTION_DESELECT
+		} else if actionInput == ACTION_IDLE_LIMIT {
+			action = ACTION_IDLE_LIMIT
+		} else if actionInput == ACTION_LINK && len(opts.DataRoots) > 0 {
+			action = ACTION_LINK
+		} else if actionInput != "" && actionInput != ACTION_PAUSE {
+			fmt.Println("操作输入无效，将使用默认值 pause")
 		}
 	}
 
 	// 显示连接信息给用户确认
-	fmt.Println("将使用以下连接参数:")
-	fmt.Printf("服务器地址: %s\n", serverAddress)
-	fmt.Printf("端口: %d\n", port)
-	fmt.Printf("HTTPS: %t\n", isHttps)
-	fmt.Printf("用户名: %s\n", username)
-	if password != "" {
-		fmt.Printf("密码: ******\n")
-	} else {
-		fmt.Printf("密码: \n")
-	}
+	printConnectionParams(params)
 
 	if len(suffixFilters) > 0 {
 		fmt.Printf("种子名称筛选结尾: %s\n", strings.Join(suffixFilters, ", "))
 	} else {
 		fmt.Println("不进行种子名称筛选")
 	}
+	fmt.Printf("操作: %s\n", actionName(action))
+	printPresetSettings(os.Stdout, opts.Preset, opts.PresetSettings)
 
 	// 确认连接参数
-	fmt.Print("确认使用以上参数？(y/n) [默认: y]: ")
-	confirmInput, _ := reader.ReadString('\n')
-	confirmInput = strings.TrimSpace(confirmInput)
-	if confirmInput != "" && strings.ToLower(confirmInput) != "y" {
-		fmt.Println("已取消操作")
-		return
+	if !opts.Yes {
+		fmt.Print("确认使用以上参数？(y/n) [默认: y]: ")
+		confirmInput, _ := reader.ReadString('\n')
+		confirmInput = strings.TrimSpace(confirmInput)
+		if confirmInput != "" && strings.ToLower(confirmInput) != "y" {
+			fmt.Println("已取消操作")
+			return
+		}
 	}
+	printRuleSettings(os.Stdout, opts.Strictness, opts.Rules)
 
 	// 创建一个 Transmission 客户端
-	client, err := transmissionrpc.New(serverAddress, username, password, &transmissionrpc.AdvancedConfig{
-		Port:  uint16(port),
-		HTTPS: isHttps,
-	})
+	client, err := connect(params)
 	if err != nil {
-		log.Fatalf("无法连接到 Transmission 服务器: %v", err)
+		log.Fatalf("无法连接到 Transmission 服务器%s: %v", params.proxyHint(), err)
+	}
+
+	// 根据服务器RPC版本确定可请求的字段
+	capabilities := detectCapabilities(client)
+	capabilities.require(opts)
+
+	opts.Connection = params
+	opts.SuffixFilters = suffixFilters
+	opts.Action = action
+	result, err := scan(client, capabilities, opts)
+	if err == nil {
+		result, opts, err = rescanWithSuggestedSuffixes(reader, client, capabilities, result, opts)
+	}
+	if err != nil {
+		log.Fatalf("获取 torrent 列表失败%s: %v", params.proxyHint(), err)
+	}
+
+	var hasGroups bool
+	if opts.Top > 0 {
+		// 快速模式：只显示并处理得分最高的组，不显示完整报告
+		total := len(result.DuplicateGroups)
+		names := applyTop(result, opts.Top)
+		printTopGroups(os.Stdout, result, names, total)
+		hasGroups = len(names) > 0
+	} else {
+		hasGroups = printReport(os.Stdout, fetchReportFiles(client, result.DuplicateGroups), result, action, opts.Verbose)
+	}
+	if opts.ListArchivePacks {
+		printArchivePacks(os.Stdout, result.Skipped)
+	}
+	if result.Interrupted {
+		fmt.Println("\n分析已中断，不执行任何操作")
+		return
+	}
+	history := newHistoryWriter()
+	throttle := newActionThrottle(opts.MaxActions, opts.ActionDelay)
+	defer throttle.printDeferred()
+	ceiling := newDeleteCeiling(opts.MaxDeleteSize)
+	defer ceiling.printSummary()
+
+	// 删除已失效的种子，与重复分集的判断无关
+	if opts.RemoveUnregistered {
+		if targets := unregisteredTargets(result); len(targets) > 0 {
+			confirmed := opts.Yes || opts.DryRun
+			if !confirmed {
+				fmt.Printf("\n是否要删除 %d 个已失效种子及其数据（不可撤销）? (y/n): ", len(targets))
+				answer, _ := reader.ReadString('\n')
+				confirmed = strings.ToLower(strings.TrimSpace(answer)) == "y"
+			}
+			if confirmed {
+				ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+				removeUnregistered(ctx, client, result, opts.DryRun, throttle, ceiling)
+				stop()
+			}
+		}
+	}
+
+	// 删除与已下载完成的内容同名的过期磁力链接，不删除数据
+	if opts.RemoveStaleMagnets > 0 {
+		if targets := staleMagnetTargets(result.MagnetStubs, opts.RemoveStaleMagnets, time.Now()); len(targets) > 0 {
+			confirmed := opts.Yes || opts.DryRun
+			if !confirmed {
+				fmt.Printf("\n是否要删除 %d 个添加超过 %d 天的磁力链接（不删除数据）? (y/n): ", len(targets), opts.RemoveStaleMagnets)
+				answer, _ := reader.ReadString('\n')
+				confirmed = strings.ToLower(strings.TrimSpace(answer)) == "y"
+			}
+			if confirmed {
+				ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+				removeStaleMagnets(ctx, client, result, opts.RemoveStaleMagnets, opts.DryRun, throttle)
+				stop()
+			}
+		}
+	}
+
+	// 删除数据缺失的分集，不删除数据
+	if opts.RemoveMissingData {
+		if targets := missingDataTargets(result); len(targets) > 0 {
+			confirmed := opts.Yes || opts.DryRun
+			if !confirmed {
+				fmt.Printf("\n是否要删除 %d 个数据缺失分集（不删除数据，不可撤销）? (y/n): ", len(targets))
+				answer, _ := reader.ReadString('\n')
+				confirmed = strings.ToLower(strings.TrimSpace(answer)) == "y"
+			}
+			if confirmed {
+				ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+				removeMissingData(ctx, client, result, opts.DryRun, throttle)
+				stop()
+			}
+		}
+	}
+
+	// 两阶段暂停：为新识别的分集添加标签，只暂停标签已满宽限期的分集，不执行其他操作
+	if opts.TwoPhase {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		runTwoPhase(ctx, reader, client, result, opts, history, throttle, true)
+		stop()
+		return
+	}
+
+	// 重复的季合集只能在交互模式下手动选择处理
+	if opts.PackDuplicates {
+		pairs := findPackDuplicates(client, result.Torrents)
+		printPackDuplicates(pairs)
+		if len(pairs) > 0 && !opts.Yes && !opts.DryRun {
+			selectPackDuplicates(reader, client, pairs, history)
+		}
+	}
+	if !hasGroups {
+		return
+	}
+
+	// 让用户标记误判的组，以后不再显示
+	if len(result.DuplicateGroups) > 0 && !opts.Yes {
+		selectFalsePositives(reader, result)
+	}
+
+	// 需人工确认的组只在交互确认后才参与操作
+	if len(result.LowConfidenceGroups) > 0 && !opts.Yes && opts.Top == 0 {
+		fmt.Printf("\n是否同时处理需人工确认的 %d 组? (y/n) [默认: n]: ", len(result.LowConfidenceGroups))
+		answer, _ := reader.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(answer)) == "y" {
+			for name, group := range result.LowConfidenceGroups {
+				result.DuplicateGroups[name] = group
+			}
+		}
+	}
+	if len(result.DuplicateGroups) == 0 {
+		fmt.Println("操作已取消")
+		return
+	}
+
+	// 询问用户是否执行操作（试运行不会修改任何内容，无需确认）
+	if !opts.Yes && !opts.DryRun {
+		manifest := buildManifest(result.DuplicateGroups, action)
+		printManifestWithStatus(manifest, manifestStatuses(manifest))
+		if action == ACTION_LINK {
+			fmt.Print("\n是否要将分集数据替换为指向合集的链接? (y/n): ")
+		} else if action == ACTION_PRIORITY {
+			fmt.Print("\n是否要调整合集和分集的带宽优先级? (y/n): ")
+		} else if action == ACTION_DESELECT {
+			fmt.Print("\n是否要取消选择分集种子的文件? (y/n): ")
+		} else if action == ACTION_IDLE_LIMIT {
+			fmt.Printf("\n是否要为分集种子设置空闲 %d 分钟后停止? (y/n): ", opts.IdleMinutes)
+		} else if action == ACTION_BANDWIDTH_GROUP {
+			fmt.Printf("\n是否要把分集种子移入带宽组 \"%s\"? (y/n): ", opts.BandwidthGroup)
+		} else {
+			fmt.Print("\n是否要暂停分集种子? (y/n): ")
+		}
+		answer, _ := reader.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+
+		if strings.ToLower(answer) != "y" {
+			fmt.Println("操作已取消")
+			return
+		}
+	}
+
+	// 记录操作前的服务器状态，操作后对比
+	var before *SessionSnapshot
+	if !opts.DryRun {
+		before = sampleSessionBefore(client)
+	}
+
+	// 操作期间按 Ctrl+C 会在当前种子处理完后停止，已完成的操作记录在操作历史中
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	relocateMisplacedEpisodes(ctx, reader, client, result.DuplicateGroups, opts)
+	if !opts.DryRun {
+		history.RecordPlanned(buildManifest(result.DuplicateGroups, action))
+	}
+	applyAction(ctx, reader, client, result.DuplicateGroups, opts, history, throttle, ceiling)
+	if history.Count() > 0 {
+		fmt.Printf("已记录 %d 条操作历史，可使用 \"%s undo\" 撤销本次操作\n", history.Count(), os.Args[0])
 	}
+	printSessionImpact(ctx, client, before, !opts.NoStatsWait)
+}
+
+// 一次扫描的结果
+type ScanResult struct {
+	Torrents            []transmissionrpc.Torrent // 获取到的全部种子
+	DuplicateGroups     map[string]DuplicateGroup // 需要处理的合集和分集
+	SameSizeGroups      map[string]DuplicateGroup // 只有大小相同分集的合集（仅记录）
+	PartialGroups       map[string]DuplicateGroup // 只有部分包含分集的合集（仅记录）
+	OversizedGroups     map[string]DuplicateGroup // 分集大小之和超过合集的组（仅记录）
+	GatedGroups         map[string]DuplicateGroup // 分集全部被tracker策略暂缓的合集（仅记录）
+	ActiveGroups        map[string]DuplicateGroup // 分集全部正在活跃上传的合集（仅记录）
+	HandledGroups       map[string]DuplicateGroup // 分集已全部暂停、无需操作的合集（仅记录）
+	LowConfidenceGroups map[string]DuplicateGroup // 置信度低于阈值、需人工确认的组
+	SpeedLimits         SpeedLimits               // 扫描时会话的上传限速状态
+	Skipped             []SkipRecord              // 跳过的种子组及原因
+	ProcessedCount      int                       // 处理的种子组数量
+	SuppressedCount     int                       // 被标记为误判而忽略的分集数量
+	Unregistered        []UnregisteredTorrent     // 不属于任何组的已失效种子
+	SampledAt           time.Time                 // 获取种子列表的时间，用于计算扫描期间的平均上传速率
+
+	BelowMinEpisodesGroups map[string]DuplicateGroup // 可处理的分集少于 --min-episodes 的组（仅记录）
+
+	TrackerImpacts     []TrackerImpact // 各tracker本次会被停止做种的种子数量
+	TrackerImpactLimit float64         // --max-tracker-impact 的百分比，0 表示不限制
+
+	StatusExcluded map[int64]bool // 按 --exclude-status 不作为分集的种子
+
+	Interrupted bool // 分析被中断，结果只包含已完成的组
+	TotalGroups int  // 参与处理的种子组数量，中断时大于 ProcessedCount
+
+	NoSuffixMatch     bool               // 名称结尾筛选没有匹配任何种子
+	SuffixSuggestions []SuffixSuggestion // 没有匹配任何种子的名称结尾的近似结尾
+
+	MagnetStubs []MagnetStub // 元数据未完成的磁力链接
+}
+
+// 获取种子列表，按名称结尾筛选后查找合集和分集关系
+func scan(client *transmissionrpc.Client, capabilities ServerCapabilities, opts Options) (*ScanResult, error) {
+	suffixFilters := opts.SuffixFilters
 
 	// 获取所有 torrent
-	torrents, err := getWithRetry(client)
+	resetTorrentFilesCache()
+	showsList.reload()
+	torrents, err := getTorrentsChunked(client, capabilities.torrentFields())
 	if err != nil {
-		log.Fatalf("获取 torrent 列表失败: %v", err)
+		return nil, err
+	}
+	sampledAt := time.Now()
+	result := &ScanResult{
+		Torrents:            torrents,
+		SampledAt:           sampledAt,
+		DuplicateGroups:     make(map[string]DuplicateGroup),
+		SameSizeGroups:      make(map[string]DuplicateGroup),
+		PartialGroups:       make(map[string]DuplicateGroup),
+		OversizedGroups:     make(map[string]DuplicateGroup),
+		GatedGroups:         make(map[string]DuplicateGroup),
+		ActiveGroups:        make(map[string]DuplicateGroup),
+		HandledGroups:       make(map[string]DuplicateGroup),
+		LowConfidenceGroups: make(map[string]DuplicateGroup),
+
+		BelowMinEpisodesGroups: make(map[string]DuplicateGroup),
+	}
+
+	// 无名称的种子无法分组，记为跳过，不参与筛选
+	reasons := newReasonsWriter(opts.ReasonsOut)
+	defer reasons.Close()
+	named, nameless := splitNamelessTorrents(torrents)
+	for _, record := range nameless {
+		reasons.Write(record)
+	}
+	result.Skipped = nameless
+	if len(nameless) > 0 {
+		fmt.Printf("有 %d 个种子没有名称，已跳过\n", len(nameless))
 	}
 
 	// 筛选种子
 	var filteredTorrents []transmissionrpc.Torrent
 	if len(suffixFilters) > 0 {
 		// 按名称结尾筛选
-		for _, torrent := range torrents {
-			if torrent.Name != nil {
-				torrentName := *torrent.Name
-				for _, suffix := range suffixFilters {
-					if suffix != "" && strings.HasSuffix(torrentName, suffix) {
-						filteredTorrents = append(filteredTorrents, torrent)
-						break // 只要匹配一个后缀就添加
-					}
-				}
+		for _, torrent := range named {
+			if matchSuffix(canonicalName(*torrent.Name), suffixFilters) {
+				filteredTorrents = append(filteredTorrents, torrent)
 			}
 		}
 
+		// 没有匹配任何种子的结尾可能是多输入了标点或大小写不同，查找近似的结尾
+		result.SuffixSuggestions = suffixSuggestions(named, suffixFilters)
 		if len(filteredTorrents) == 0 {
 			fmt.Printf("未找到名称以 %s 结尾的种子\n", strings.Join(suffixFilters, ", "))
-			return
+			result.NoSuffixMatch = true
+			return result, nil
 		}
 
 		fmt.Printf("找到 %d 个名称以 %s 结尾的种子\n",
 			len(filteredTorrents), strings.Join(suffixFilters, ", "))
 	} else {
 		// 不筛选，使用所有种子
-		filteredTorrents = torrents
-		fmt.Printf("没有应用筛选，将处理所有 %d 个种子\n", len(torrents))
+		filteredTorrents = named
+		fmt.Printf("没有应用筛选，将处理所有 %d 个种子\n", len(named))
 	}
 
 	// 查找合集和分集关系
 	fmt.Println("开始查找合集和分集关系...")
-	duplicateGroups, dupGroupsWithOnlySameSize := findCollectionsAndEpisodes(client, filteredTorrents)
-
-	// 显示有分集但大小相同的合集信息（仅记录）
-	if len(dupGroupsWithOnlySameSize) > 0 {
-		fmt.Printf("\n找到 %d 组只有大小相同分集的合集(这些不会被暂停):\n", len(dupGroupsWithOnlySameSize))
-		for groupName, group := range dupGroupsWithOnlySameSize {
-			fmt.Printf("\n组名: %s\n", groupName)
-
-			// 显示合集信息
-			if group.Collection != nil && group.Collection.ID != nil && group.Collection.SizeWhenDone != nil {
-				collectionSize := (*group.Collection.SizeWhenDone).MB()
-				fmt.Printf("合集(不会被暂停): ID: %d, 大小: %.2f MB\n", *group.Collection.ID, collectionSize)
-			}
+	candidates, collectionOnly := collectionCandidates(named, filteredTorrents, opts)
+	if len(collectionOnly) > 0 {
+		fmt.Printf("另有 %d 个名称以 %s 结尾的种子作为合集候选\n", len(collectionOnly), strings.Join(opts.CollectionSuffixes, ", "))
+	}
+	// 分析期间按 Ctrl-C 停止分析，只报告已完成的组
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	suggestions := result.SuffixSuggestions
+	result = findCollectionsAndEpisodes(ctx, client, candidates, collectionOnly, opts, reasons)
+	result.SuffixSuggestions = suggestions
+	stop()
+	result.Skipped = append(nameless, result.Skipped...)
+	if result.Interrupted {
+		fmt.Printf("分析已中断，报告只包含已完成的 %d/%d 组\n", result.ProcessedCount, result.TotalGroups)
+	}
+	defer benchmark.start(BENCH_CHECKS)()
+	if opts.DeepScan && !result.Interrupted {
+		applyDeepScan(client, result, showsList.filterTorrents(candidates, opts.NameMap), collectionOnly, opts)
+	}
+	result.Torrents = torrents
+	result.SampledAt = sampledAt
+	result.SpeedLimits = detectSpeedLimits(client)
+	if ignores, err := loadIgnores(ignoresPath()); err != nil {
+		log.Printf("读取误判记录失败: %v", err)
+	} else {
+		result.SuppressedCount = applyIgnores(result, ignores)
+	}
+	if notes, err := loadNotes(notesPath()); err != nil {
+		log.Printf("读取备注失败: %v", err)
+	} else {
+		applyNotes(result, notes)
+	}
+	markUnregistered(result, filteredTorrents, opts.UnregisteredPatterns)
+	result.MagnetStubs = findMagnetStubs(filteredTorrents, opts.NameMap)
+	classifyTrackers(result)
+	applyPolicies(result, opts)
+	applyExtras(client, result, opts.IncludeExtras)
+	applyOldPacks(client, result, opts.OldPackAction)
+	applyKeepLatest(client, result, opts.KeepLatest)
+	applyCollectionSeeders(result, opts.MinCollectionSeeders)
+	applyCompleteCollection(result, opts.RequireCompleteCollection)
+	applyMissingData(result, sessionIncompleteDir(client))
+	applyPrivacyDefaults(result, opts)
+	applyAlreadyHandled(result, opts.Action)
+	if opts.KeepActiveUploaders {
+		applyActiveUploaders(client, result, result.SpeedLimits)
+	}
+	applyIdenticalEpisodes(client, result)
+	applyUploadEstimates(client, result, opts.MinWeeklyUploadToKeep)
+	applyMinEpisodes(result, opts.MinEpisodes)
+	demoteLowConfidence(result, opts.MinConfidence)
+	applyTrackerImpact(result, opts.Action, opts.MaxTrackerImpact)
+	diagBundle.recordScan(result, opts.Action)
+	return result, nil
+}
 
-			// 显示大小相同分集信息
-			if len(group.Episodes) > 0 {
-				fmt.Printf("包含 %d 个大小相同分集(大小与合集一致):\n", len(group.Episodes))
-				for i, episode := range group.Episodes {
-					if episode != nil && episode.ID != nil && episode.SizeWhenDone != nil {
-						episodeSize := (*episode.SizeWhenDone).MB()
-						fmt.Printf("  %d. ID: %d, 大小: %.2f MB\n", i+1, *episode.ID, episodeSize)
-					}
-				}
+// 对需要处理的组执行选定的操作，会删除数据的操作与同一次运行中的其他删除共用 ceiling 的额度
+func applyAction(ctx context.Context, reader *bufio.Reader, client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup, opts Options, history *HistoryWriter, throttle *ActionThrottle, ceiling *DeleteCeiling) int {
+	// 被做种限制自动停止的合集先重新开始，避免处理分集后没有种子做种
+	if opts.UnlimitCollection {
+		unlimitCollections(client, duplicateGroups, history, opts.DryRun)
+	}
+
+	// tracker策略可能为部分分集指定不同的操作
+	// 会删除数据的操作受 --max-delete-size 限制，超过上限的分集改为暂停；
+	// 删除分集数据前检查文件在其他种子中是否还有副本，没有副本且未确认的分集改为暂停
+	successCount := 0
+	keptExport.track(duplicateGroups)
+	runNotices.begin(opts.Connection)
+	for _, bucket := range splitGroupsByAction(duplicateGroups, opts.Action) {
+		groups := bucket.Groups
+		if deletesEpisodeData(bucket.Action) {
+			var unconfirmed, downgraded map[string]DuplicateGroup
+			if removesEpisodeTorrents(bucket.Action) {
+				groups, unconfirmed = confirmLastCopies(reader, client, groups, opts)
 			}
+			groups, downgraded = ceiling.split(groups)
+			downgraded = mergeEpisodeGroups(downgraded, unconfirmed)
+			if len(downgraded) > 0 {
+				successCount += applySingleAction(ctx, client, downgraded, downgradedAction(bucket.Action), opts, history, throttle)
+			}
+		}
+		if len(groups) > 0 {
+			successCount += applySingleAction(ctx, client, groups, bucket.Action, opts, history, throttle)
+		}
+	}
+	if !opts.Daemon {
+		// 守护模式在每轮统计中显示
+		printSessionRenegotiations(sessionRenegotiations.Load())
+	}
+	if !opts.DryRun {
+		keptExport.write(client)
+		runNotices.send()
+	}
 
-			// 显示文件重叠状态
-			fmt.Printf("文件列表重叠状态: %t\n", group.HasFileOverlaps)
+	// 操作完成后整理合集的存放位置，移动失败不影响上面的结果
+	if opts.CollectionDir != "" {
+		moveCollections(ctx, client, duplicateGroups, opts.CollectionDir, opts.DryRun, opts.MoveTimeout)
+	}
+	return successCount
+}
+
+// 对一组种子执行同一种操作
+func applySingleAction(ctx context.Context, client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup, action string, opts Options, history *HistoryWriter, throttle *ActionThrottle) int {
+	if action == ACTION_LINK {
+		// 原地升级：分集数据替换为指向合集文件的链接
+		successCount, skippedCount, failedCount := linkEpisodes(ctx, client, duplicateGroups, opts, history, throttle)
+		if opts.DryRun {
+			fmt.Printf("\n试运行完成: 计划处理 %d 个分集, 跳过 %d 个分集，未修改任何文件\n", successCount, skippedCount)
+			return 0
 		}
+		fmt.Printf("\n操作完成: 成功替换 %d 个分集, 跳过 %d 个分集, 失败 %d 个分集\n", successCount, skippedCount, failedCount)
+		runNotices.count(action, successCount, failedCount)
+		return successCount
+	}
+	if opts.DryRun {
+		fmt.Printf("\n试运行模式，不执行%s\n", actionName(action))
+		return 0
 	}
 
-	if len(duplicateGroups) == 0 {
-		fmt.Println("未找到需要处理的合集和对应分集的种子")
-		return
+	if action == ACTION_DESELECT {
+		// 取消选择分集的文件，单文件种子改为暂停
+		deselectedCount, pausedCount, failedCount := deselectEpisodes(ctx, client, duplicateGroups, history, throttle)
+		fmt.Printf("\n操作完成: 成功取消选择 %d 个分集, 单文件种子改为暂停 %d 个, 失败 %d 个分集\n", deselectedCount, pausedCount, failedCount)
+		runNotices.count(action, deselectedCount, failedCount)
+		runNotices.count(ACTION_PAUSE, pausedCount, 0)
+		return deselectedCount + pausedCount
 	}
 
-	// 显示找到的合集和分集信息
-	fmt.Printf("找到 %d 组需要处理的合集和对应分集:\n", len(duplicateGroups))
-	for groupName, group := range duplicateGroups {
-		fmt.Printf("\n组名: %s\n", groupName)
-
-		// 显示合集信息
-		if group.Collection != nil && group.Collection.ID != nil && group.Collection.SizeWhenDone != nil {
-			collectionSize := (*group.Collection.SizeWhenDone).MB()
-			fmt.Printf("合集(不会被暂停): ID: %d, 大小: %.2f MB\n", *group.Collection.ID, collectionSize)
-
-			// 显示合集的文件列表
-			collectionFiles, err := getTorrentFiles(client, group.Collection.ID)
-			if err == nil && len(collectionFiles) > 0 {
-				fmt.Println("  合集文件列表:")
-				for i, file := range collectionFiles {
-					if i < 5 { // 最多显示5个文件
-						fmt.Printf("    - %s\n", file.Name)
-					} else {
-						fmt.Printf("    - ... 以及 %d 个更多文件\n", len(collectionFiles)-5)
-						break
-					}
-				}
-			}
+	if action == ACTION_PRIORITY {
+		// 调整带宽优先级：合集设为高，分集设为低
+		successCount, skippedCount, failedCount := rebalancePriority(client, duplicateGroups, history)
+		fmt.Printf("\n操作完成: 成功调整 %d 个种子, 已是目标优先级跳过 %d 个, 失败 %d 个\n", successCount, skippedCount, failedCount)
+		runNotices.count(action, successCount, failedCount)
+		return successCount
+	}
+
+	if action == ACTION_OLD_PACK_DELETE || action == ACTION_DELETE {
+		// 删除旧版合集或分集，无法撤销
+		target := "分集"
+		if action == ACTION_OLD_PACK_DELETE {
+			target = "旧版合集"
 		}
+		successCount, failedCount := removeEpisodeTorrents(ctx, client, duplicateGroups, throttle, target)
+		fmt.Printf("\n操作完成: 成功删除 %d 个%s, 失败 %d 个\n", successCount, target, failedCount)
+		runNotices.count(action, successCount, failedCount)
+		return successCount
+	}
 
-		// 显示分集信息
-		fmt.Printf("包含 %d 个分集(将被暂停):\n", len(group.Episodes))
-		for i, episode := range group.Episodes {
-			if episode != nil && episode.ID != nil && episode.SizeWhenDone != nil {
-				episodeSize := (*episode.SizeWhenDone).MB()
-				fmt.Printf("  %d. ID: %d, 大小: %.2f MB\n", i+1, *episode.ID, episodeSize)
-
-				// 显示分集的文件列表
-				episodeFiles, err := getTorrentFiles(client, episode.ID)
-				if err == nil && len(episodeFiles) > 0 {
-					fmt.Println("    文件列表:")
-					for j, file := range episodeFiles {
-						if j < 3 { // 最多显示3个文件
-							fmt.Printf("      - %s\n", file.Name)
-						} else {
-							fmt.Printf("      - ... 以及 %d 个更多文件\n", len(episodeFiles)-3)
-							break
-						}
-					}
-				}
-			}
+	if action == ACTION_IDLE_LIMIT {
+		// 设置空闲时间限制，分集暂不停止，由 Transmission 在空闲后停止
+		appliedCount, stoppedCount, skippedCount, failedCount := idleLimitEpisodes(ctx, client, duplicateGroups, opts.IdleMinutes, history, throttle)
+		fmt.Printf("\n操作完成: 成功设置空闲时间限制 %d 个分集, 已停止跳过 %d 个, 已有相同限制跳过 %d 个, 失败 %d 个\n", appliedCount, stoppedCount, skippedCount, failedCount)
+		if appliedCount > 0 {
+			fmt.Printf("注意: 这些分集目前仍在做种，Transmission 将在分集空闲满 %d 分钟后停止，实际停止时间由服务器决定\n", opts.IdleMinutes)
 		}
+		runNotices.count(action, appliedCount, failedCount)
+		return appliedCount
+	}
 
-		// 显示文件重叠状态
-		fmt.Printf("文件列表重叠状态: %t\n", group.HasFileOverlaps)
+	if action == ACTION_BANDWIDTH_GROUP {
+		// 移入限速带宽组，分集继续低速做种
+		appliedCount, skippedCount, failedCount := bandwidthGroupEpisodes(ctx, client, duplicateGroups, opts.BandwidthGroup, opts.BandwidthGroupLimit, history, throttle)
+		fmt.Printf("\n操作完成: 成功移入带宽组 %d 个分集, 已在该组跳过 %d 个, 失败 %d 个\n", appliedCount, skippedCount, failedCount)
+		runNotices.count(action, appliedCount, failedCount)
+		return appliedCount
 	}
 
-	// 询问用户是否暂停这些种子
-	fmt.Print("\n是否要暂停分集种子? (y/n): ")
-	var answer string
-	fmt.Scanln(&answer)
+	if action == ACTION_LABEL {
+		// 为分集添加标签，不改变运行状态
+		successCount, skippedCount, failedCount := labelEpisodes(client, duplicateGroups, history)
+		fmt.Printf("\n操作完成: 成功添加标签 %d 个分集, 已有标签跳过 %d 个, 失败 %d 个\n", successCount, skippedCount, failedCount)
+		runNotices.count(action, successCount, failedCount)
+		return successCount
+	}
 
-	if strings.ToLower(answer) != "y" {
-		fmt.Println("操作已取消")
-		return
+	// 暂停分集种子，安全模式下校验结果并在失败过多时回滚
+	safety := newSafeMode(opts.SafeMode, opts.RollbackThreshold)
+	budget := newPauseBudget(client, opts.PauseBudget)
+	successCount, failedCount := pauseEpisodes(ctx, client, duplicateGroups, history, throttle, safety, budget)
+	target := "分集"
+	if action == ACTION_OLD_PACK_PAUSE {
+		target = "旧版合集"
+	}
+	fmt.Printf("\n操作完成: 成功暂停 %d 个%s, 失败 %d 个%s\n", successCount, target, failedCount, target)
+	runNotices.count(action, successCount, failedCount)
+	safety.printSummary()
+	return successCount
+}
+
+// 解析以分号分隔的名称结尾筛选
+func parseSuffixFilters(input string) []string {
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return nil
+	}
+	suffixFilters := strings.Split(input, ";")
+	// 移除可能的空白
+	for i, suffix := range suffixFilters {
+		suffixFilters[i] = strings.TrimSpace(suffix)
+	}
+	return suffixFilters
+}
+
+// Transmission服务器连接参数
+type ConnectionParams struct {
+	Address  string
+	Port     int
+	HTTPS    bool
+	Username string
+	Password string
+	Proxy    string // 代理地址，如 socks5://127.0.0.1:1080
+
+	UnixSocket string // RPC的 Unix socket 路径，指定后不通过TCP连接服务器地址和端口
+
+	Sources map[string]string // 各参数的来源（命令行参数、环境变量、netrc等），按参数名记录
+}
+
+// 提示用户输入连接参数
+func readConnectionParams(reader *bufio.Reader) ConnectionParams {
+	fmt.Println("请输入Transmission服务器连接参数：")
+
+	// 输入服务器地址
+	fmt.Print("服务器地址 [默认: 127.0.0.1]: ")
+	serverAddressInput, _ := reader.ReadString('\n')
+	serverAddressInput = strings.TrimSpace(serverAddressInput)
+	params := ConnectionParams{Address: "127.0.0.1", Port: 9091}
+	if serverAddressInput != "" {
+		params.Address = serverAddressInput
+	}
+
+	// 输入端口
+	fmt.Print("端口 [默认: 9091]: ")
+	portInput, _ := reader.ReadString('\n')
+	portInput = strings.TrimSpace(portInput)
+	if portInput != "" {
+		portValue, err := strconv.Atoi(portInput)
+		if err == nil && portValue > 0 {
+			params.Port = portValue
+		} else {
+			fmt.Println("端口输入无效，将使用默认值 9091")
+		}
+	}
+
+	// 是否使用HTTPS
+	fmt.Print("是否使用HTTPS (y/n) [默认: n]: ")
+	httpsInput, _ := reader.ReadString('\n')
+	httpsInput = strings.TrimSpace(httpsInput)
+	if strings.ToLower(httpsInput) == "y" {
+		params.HTTPS = true
+	}
+
+	// 输入用户名
+	fmt.Print("用户名 [默认: \"\"]: ")
+	username, _ := reader.ReadString('\n')
+	params.Username = strings.TrimSpace(username)
+
+	// 输入密码
+	fmt.Print("密码 [默认: \"\"]: ")
+	params.Password = strings.TrimSpace(readPassword(reader))
+
+	params.Sources = map[string]string{"host": SOURCE_PROMPT, "port": SOURCE_PROMPT, "https": SOURCE_PROMPT, "user": SOURCE_PROMPT, "password": SOURCE_PROMPT}
+	return params
+}
+
+// 显示连接参数给用户确认
+func printConnectionParams(params ConnectionParams) {
+	fmt.Println("将使用以下连接参数:")
+	if params.UnixSocket != "" {
+		fmt.Printf("Unix socket: %s\n", params.UnixSocket)
+	} else {
+		fmt.Printf("服务器地址: %s%s\n", params.Address, params.sourceHint("host"))
+		fmt.Printf("端口: %d%s\n", params.Port, params.sourceHint("port"))
+	}
+	fmt.Printf("HTTPS: %t%s\n", params.HTTPS, params.sourceHint("https"))
+	fmt.Printf("用户名: %s%s\n", params.Username, params.sourceHint("user"))
+	if params.Password != "" {
+		fmt.Printf("密码: ******%s\n", params.sourceHint("password"))
+	} else {
+		fmt.Printf("密码: %s\n", params.sourceHint("password"))
+	}
+	if params.Proxy != "" {
+		fmt.Printf("代理: %s\n", redactProxy(params.Proxy))
+	}
+}
+
+// 创建一个 Transmission 客户端
+func connect(params ConnectionParams) (*transmissionrpc.Client, error) {
+	client, err := newRPCClient(params)
+	if err != nil {
+		return client, err
+	}
+	// Unix socket 与代理不能同时使用，参数解析时已检查；重放诊断包时不连接服务器
+	if dumpReplay != nil {
+		if err := configureDumpReplay(client, dumpReplay); err != nil {
+			return nil, fmt.Errorf("设置诊断包重放失败: %v", err)
+		}
+	} else if params.UnixSocket != "" {
+		if err := checkUnixSocket(params.UnixSocket); err != nil {
+			return nil, err
+		}
+		if err := configureUnixSocket(client, params.UnixSocket); err != nil {
+			return nil, fmt.Errorf("设置 Unix socket 失败: %v", err)
+		}
+	} else if params.Proxy != "" {
+		if err := configureProxy(client, params.Proxy); err != nil {
+			return nil, fmt.Errorf("设置代理失败: %v", err)
+		}
+	}
+	if err := configureSessionTransport(client); err != nil {
+		return nil, fmt.Errorf("设置会话ID处理失败: %v", err)
+	}
+	if err := diagBundle.wrap(client); err != nil {
+		return nil, fmt.Errorf("设置诊断包记录失败: %v", err)
+	}
+	return client, nil
+}
+
+// 获取种子列表。服务器负载过高时可能返回重复或缺少字段的种子：去掉重复的种子，
+// 有字段在较多种子中缺失时重新获取，重试后仍不完整时使用缺失最少的一次结果
+func getTorrentsChunked(client *transmissionrpc.Client, fields []string) ([]transmissionrpc.Torrent, error) {
+	var best []transmissionrpc.Torrent
+	bestMissing := -1
+	for retry := 0; retry < MAX_RETRIES; retry++ {
+		torrents, err := fetchTorrentsChunked(client, fields)
+		if err != nil {
+			return nil, err
+		}
+		torrents, dropped := dedupeTorrents(torrents)
+		if dropped > 0 {
+			log.Printf("种子列表中有 %d 个重复的种子，已去重（保留字段最完整的一个）", dropped)
+		}
+		field, missing := sparseField(torrents, fields)
+		if bestMissing < 0 || missing < bestMissing {
+			best, bestMissing = torrents, missing
+		}
+		if missing == 0 {
+			break
+		}
+		log.Printf("警告: %d/%d 个种子缺少字段 %s，Transmission 可能负载过高", missing, len(torrents), field)
+		if retry < MAX_RETRIES-1 {
+			log.Printf("重新获取种子列表 (%d/%d)", retry+1, MAX_RETRIES-1)
+			time.Sleep(5 * time.Second)
+		}
+	}
+	return best, nil
+}
+
+// 分两步获取种子列表：先只获取全部ID，再按批次获取所需字段，避免种子数量很多时单次请求超时
+func fetchTorrentsChunked(client *transmissionrpc.Client, fields []string) ([]transmissionrpc.Torrent, error) {
+	done := benchmark.start(BENCH_LIST)
+	idTorrents, err := getWithRetry(client, []string{"id"}, nil, timeouts.List, "获取种子ID列表")
+	done()
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for _, torrent := range idTorrents {
+		if torrent.ID != nil {
+			ids = append(ids, *torrent.ID)
+		}
 	}
 
-	// 暂停合集和分集种子
-	successCount, failedCount := pauseEpisodes(client, duplicateGroups)
-	fmt.Printf("\n操作完成: 成功暂停 %d 个分集, 失败 %d 个分集\n", successCount, failedCount)
+	chunkCount := (len(ids) + TORRENT_CHUNK_SIZE - 1) / TORRENT_CHUNK_SIZE
+	torrents := make([]transmissionrpc.Torrent, 0, len(ids))
+	for chunk := 0; chunk < chunkCount; chunk++ {
+		start := chunk * TORRENT_CHUNK_SIZE
+		end := start + TORRENT_CHUNK_SIZE
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		// 单批失败时只重试这一批
+		done := benchmark.start(BENCH_LIST)
+		chunkTorrents, err := getWithRetry(client, fields, ids[start:end], timeouts.List, fmt.Sprintf("获取第 %d/%d 批种子", chunk+1, chunkCount))
+		done()
+		if err != nil {
+			return nil, err
+		}
+		torrents = append(torrents, chunkTorrents...)
+		if chunkCount > 1 {
+			fmt.Printf("已获取第 %d/%d 批种子 (%d/%d)\n", chunk+1, chunkCount, end, len(ids))
+		}
+	}
+	return torrents, nil
 }
 
-// 带重试的获取种子列表
-func getWithRetry(client *transmissionrpc.Client) ([]transmissionrpc.Torrent, error) {
+// 带重试的获取种子，ids为空时获取全部种子
+func getWithRetry(client *transmissionrpc.Client, fields []string, ids []int64, timeout time.Duration, description string) ([]transmissionrpc.Torrent, error) {
 	var torrents []transmissionrpc.Torrent
 	var err error
 
 	for retry := 0; retry < MAX_RETRIES; retry++ {
-		ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-		torrents, err = client.TorrentGetAll(ctx)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		torrents, err = client.TorrentGet(ctx, fields, ids)
 		cancel()
 
 		if err == nil {
 			return torrents, nil
 		}
 
-		log.Printf("获取种子列表失败，尝试重试 (%d/%d): %v", retry+1, MAX_RETRIES, err)
+		log.Printf("%s失败，尝试重试 (%d/%d): %v", description, retry+1, MAX_RETRIES, err)
 		time.Sleep(5 * time.Second)
 	}
 
@@ -288,187 +937,108 @@ func getWithRetry(client *transmissionrpc.Client) ([]transmissionrpc.Torrent, er
 }
 
 // 查找合集和分集关系
-func findCollectionsAndEpisodes(client *transmissionrpc.Client, torrents []transmissionrpc.Torrent) (map[string]DuplicateGroup, map[string]DuplicateGroup) {
-	// 按名称分组
+// collectionOnly 中的种子名称结尾不匹配筛选，只能作为合集，不会作为分集处理；
+// ctx 取消时返回已完成分析的组
+func findCollectionsAndEpisodes(ctx context.Context, client *transmissionrpc.Client, torrents []transmissionrpc.Torrent, collectionOnly map[int64]bool, opts Options, reasons *ReasonsWriter) *ScanResult {
+	// 按名称分组，名称映射中的别名归入同一组
+	grouped := benchmark.start(BENCH_GROUPING)
 	nameGroups := make(map[string][]transmissionrpc.Torrent)
+	aliasNames := make(map[string][]string)
 	for _, torrent := range torrents {
-		if torrent.Name != nil {
-			nameGroups[*torrent.Name] = append(nameGroups[*torrent.Name], torrent)
+		if !namelessTorrent(&torrent) {
+			key, aliased := groupKey(opts.NameMap, canonicalName(*torrent.Name))
+			if aliased {
+				aliasNames[key] = append(aliasNames[key], *torrent.Name)
+			}
+			nameGroups[key] = append(nameGroups[key], torrent)
 		}
 	}
-
-	// 查找合集和分集
+	// 名称中看不出剧名的单独种子可能在 Transmission 中改过名，按文件名归入对应的组
+	var fileGrouped map[string]map[int64]string
+	if !opts.NoRenameFallback {
+		fileGrouped = regroupRenamedTorrents(client, nameGroups)
+	}
+	// 指定 --shows-file 时只分析剧集清单中的组
+	notInShows := showsList.filter(nameGroups, collectionOnly)
+	// 全剧合集的分组扩大到剧名级别，各季的分集都可以归入
+	seriesSeasons := widenSeriesGroups(client, nameGroups)
+	grouped()
+	defer benchmark.start(BENCH_OVERLAP)()
+
+	// 分析各组，已完成的组按名称顺序合并到结果
+	completed, total, interrupted := analyzeGroups(ctx, client, nameGroups, collectionOnly, opts)
 	result := make(map[string]DuplicateGroup)
 	onlySameSizeResult := make(map[string]DuplicateGroup)
-	var processedCount, skippedCount, withoutEpisodesCount, sameSizeCount, onlySameSizeEpisodesCount, differentEpisodesCount int
-
-	for name, group := range nameGroups {
-		processedCount++
-		if len(group) > 1 {
-			// 检查所有种子大小是否相同
-			allSameSizes := true
-			var baseSize float64
-			if group[0].SizeWhenDone != nil {
-				baseSize = (*group[0].SizeWhenDone).Byte()
-			}
-
-			for i := 1; i < len(group); i++ {
-				if group[i].SizeWhenDone != nil {
-					currentSize := (*group[i].SizeWhenDone).Byte()
-					// 如果发现大小不同（允许1KB以内的误差），标记为不同
-					if abs(currentSize-baseSize) > 1024 {
-						allSameSizes = false
-						break
-					}
-				}
-			}
-
-			// 如果所有种子大小都相同，跳过这组种子
-			if allSameSizes {
-				fmt.Printf("跳过大小相同的种子组: %s (大小: %.2f MB)\n", name, baseSize/1024/1024)
-				sameSizeCount++
-				continue
-			}
-
-			// 排序：按大小从大到小排序（合集通常比分集大）
-			var sortedGroup []transmissionrpc.Torrent = make([]transmissionrpc.Torrent, len(group))
-			copy(sortedGroup, group)
-			for i := 0; i < len(sortedGroup); i++ {
-				for j := i + 1; j < len(sortedGroup); j++ {
-					if sortedGroup[i].SizeWhenDone != nil && sortedGroup[j].SizeWhenDone != nil {
-						sizeI := (*sortedGroup[i].SizeWhenDone).Byte()
-						sizeJ := (*sortedGroup[j].SizeWhenDone).Byte()
-						if sizeI < sizeJ {
-							sortedGroup[i], sortedGroup[j] = sortedGroup[j], sortedGroup[i]
-						}
-					}
-				}
-			}
+	partialResult := make(map[string]DuplicateGroup)
+	oversizedResult := make(map[string]DuplicateGroup)
+	variantResult := make(map[string]DuplicateGroup) // 版本差异或大小比例异常，需人工确认
+	targets := map[int]map[string]DuplicateGroup{
+		OUTCOME_DUPLICATE: result,
+		OUTCOME_SAME_SIZE: onlySameSizeResult,
+		OUTCOME_PARTIAL:   partialResult,
+		OUTCOME_OVERSIZED: oversizedResult,
+		OUTCOME_VARIANT:   variantResult,
+	}
+	skipped := notInShows
+	for _, record := range notInShows {
+		reasons.Write(record)
+	}
+	statusExcluded := make(map[int64]bool) // 按 --exclude-status 不作为分集的种子
+	for _, analysis := range completed {
+		// 记录跳过原因，同时逐条写入跳过原因文件
+		for _, record := range analysis.Skipped {
+			skipped = append(skipped, record)
+			reasons.Write(record)
+		}
+		for _, id := range analysis.StatusExcluded {
+			statusExcluded[id] = true
+		}
+		if target, ok := targets[analysis.Outcome]; ok {
+			target[analysis.Name] = analysis.Result
+		}
+	}
 
-			// 检查文件列表包含关系
-			if len(sortedGroup) >= 2 {
-				// 假设最大的是合集
-				collection := sortedGroup[0]
-				var episodes []*transmissionrpc.Torrent
-				var sameSizeEpisodes []*transmissionrpc.Torrent
-				hasFileOverlaps := false
-
-				// 获取合集的文件列表
-				collectionFiles, err := getTorrentFiles(client, collection.ID)
-				if err != nil {
-					log.Printf("获取种子 ID: %d 文件列表失败: %v", *collection.ID, err)
-					skippedCount++
-					continue
-				}
-
-				// 获取合集大小
-				var collectionSize float64
-				if collection.SizeWhenDone != nil {
-					collectionSize = (*collection.SizeWhenDone).Byte()
-				}
-
-				// 对每个可能的分集检查文件列表
-				for i := 1; i < len(sortedGroup); i++ {
-					episode := sortedGroup[i]
-					episodeFiles, err := getTorrentFiles(client, episode.ID)
-					if err != nil {
-						log.Printf("获取种子 ID: %d 文件列表失败: %v", *episode.ID, err)
-						continue
-					}
-
-					// 检查分集的大小
-					var episodeSize float64
-					if episode.SizeWhenDone != nil {
-						episodeSize = (*episode.SizeWhenDone).Byte()
-					}
-
-					// 检查分集文件是否实际上是合集的一部分
-					isActualEpisode, overlappingFiles := checkActualEpisodeOverlap(collectionFiles, episodeFiles)
-
-					if isActualEpisode {
-						hasFileOverlaps = true
-						episodeCopy := episode // 创建副本以避免引用问题
-
-						// 检查大小是否与合集相同
-						if abs(episodeSize-collectionSize) <= 1024 {
-							// 大小相同，不认为是需要处理的分集
-							sameSizeEpisodes = append(sameSizeEpisodes, &episodeCopy)
-						} else {
-							// 大小不同，是需要处理的分集
-							episodes = append(episodes, &episodeCopy)
-						}
-					} else if overlappingFiles > 0 {
-						// 有重叠但不是真正的分集关系（可能是不同剧集）
-						if collection.Name != nil && episode.Name != nil {
-							fmt.Printf("跳过可能是不同剧集的种子: %s 和 %s (有 %d 个重叠文件)\n",
-								*collection.Name, *episode.Name, overlappingFiles)
-						}
-						differentEpisodesCount++
-					}
-				}
-
-				// 创建合集副本用于结果
-				collectionCopy := collection
-
-				// 只有当存在文件重叠时继续
-				if hasFileOverlaps {
-					// 分成两种情况：有真正的分集 和 只有大小相同的"分集"
-					if len(episodes) > 0 {
-						// 有真正的分集（大小不同），加入需要处理的结果
-						result[name] = DuplicateGroup{
-							Collection:      &collectionCopy,
-							Episodes:        episodes,
-							HasFileOverlaps: hasFileOverlaps,
-						}
-					} else if len(sameSizeEpisodes) > 0 {
-						// 只有大小相同的"分集"，加入仅记录的结果
-						onlySameSizeResult[name] = DuplicateGroup{
-							Collection:      &collectionCopy,
-							Episodes:        sameSizeEpisodes,
-							HasFileOverlaps: hasFileOverlaps,
-						}
-						onlySameSizeEpisodesCount++
-					} else {
-						// 没有分集
-						if collection.Name != nil {
-							fmt.Printf("跳过没有分集的种子: %s\n", *collection.Name)
-						}
-						withoutEpisodesCount++
-					}
-				} else {
-					// 记录没有找到分集的种子
-					if collection.Name != nil {
-						fmt.Printf("跳过没有分集的种子: %s\n", *collection.Name)
-					}
-					withoutEpisodesCount++
-				}
+	// 记录通过别名分组的种子名称和全剧合集覆盖的季
+	for _, groups := range []map[string]DuplicateGroup{result, onlySameSizeResult, partialResult, oversizedResult, variantResult} {
+		for name, group := range groups {
+			if names, ok := aliasNames[name]; ok {
+				group.AliasNames = names
 			}
-		} else {
-			// 记录单种子的情况（不是名称重复的）
-			if group[0].Name != nil {
-				fmt.Printf("跳过单个种子: %s\n", *group[0].Name)
-			}
-			skippedCount++
+			group.FileGrouped = fileGrouped[name]
+			group.SeriesSeasons = seriesSeasons[name]
+			groups[name] = group
 		}
 	}
 
-	fmt.Printf("\n筛选统计：\n")
-	fmt.Printf("- 处理种子组数量: %d\n", processedCount)
-	fmt.Printf("- 跳过种子组数量: %d\n", skippedCount)
-	fmt.Printf("- 跳过大小相同的种子组数量: %d\n", sameSizeCount)
-	fmt.Printf("- 跳过不同剧集的种子组数量: %d\n", differentEpisodesCount)
-	fmt.Printf("- 没有找到分集的种子组数量: %d\n", withoutEpisodesCount)
-	fmt.Printf("- 只有大小相同分集的种子组数量: %d\n", onlySameSizeEpisodesCount)
-	fmt.Printf("- 符合条件的种子组数量: %d\n", len(result))
-
-	return result, onlySameSizeResult
+	return &ScanResult{
+		DuplicateGroups:     result,
+		SameSizeGroups:      onlySameSizeResult,
+		PartialGroups:       partialResult,
+		OversizedGroups:     oversizedResult,
+		GatedGroups:         make(map[string]DuplicateGroup),
+		ActiveGroups:        make(map[string]DuplicateGroup),
+		HandledGroups:       make(map[string]DuplicateGroup),
+		LowConfidenceGroups: variantResult,
+		Skipped:             skipped,
+		ProcessedCount:      len(completed),
+		StatusExcluded:      statusExcluded,
+		Interrupted:         interrupted,
+		TotalGroups:         total,
+
+		BelowMinEpisodesGroups: make(map[string]DuplicateGroup),
+	}
 }
 
 // 检查是否真正的分集关系并返回重叠文件数量
 func checkActualEpisodeOverlap(collectionFiles, episodeFiles []*transmissionrpc.TorrentFile) (bool, int) {
+	// 填充文件和空文件不是内容，混合种子两边都有时会被当作重叠文件
+	collectionFiles, _ = withoutPadding(collectionFiles)
+	episodeFiles, _ = withoutPadding(episodeFiles)
+
 	// 如果文件数量不对，可能不是分集与合集的关系
-	// 通常合集应该有更多的文件，或者至少等于分集文件数
-	if len(collectionFiles) < len(episodeFiles) {
+	// 通常合集应该有更多的主要文件，或者至少等于分集的主要文件数；
+	// 精简的合集可能不带样片和字幕，分集多出的附加文件在容差内时不排除
+	if !fileCountCompatible(collectionFiles, episodeFiles) {
 		return false, 0
 	}
 
@@ -509,51 +1079,36 @@ func checkActualEpisodeOverlap(collectionFiles, episodeFiles []*transmissionrpc.
 
 		// 如果没有交集，这些可能是不同的剧集，不是合集与分集的关系
 		if !hasIntersection {
-			// 记录有多少个重叠文件
-			for _, episodeFile := range episodeFiles {
-				for _, collectionFile := range collectionFiles {
-					// 根据文件名（去掉路径和剧集标识）来比较
-					episodeFileName := getFileName(episodeFile.Name)
-					collectionFileName := getFileName(collectionFile.Name)
-
-					if strings.Contains(episodeFileName, collectionFileName) ||
-						strings.Contains(collectionFileName, episodeFileName) {
-						matchCount++
-						break
-					}
-				}
-			}
+			// 记录有多少个重叠文件，根据文件名（去掉路径和剧集标识）来比较
+			matchCount = matchedCount(matchFiles(episodeFiles, collectionFiles, func(episodePath, collectionPath string) bool {
+				episodeFileName := getFileName(episodePath)
+				collectionFileName := getFileName(collectionPath)
+				return (strings.Contains(episodeFileName, collectionFileName) ||
+					strings.Contains(collectionFileName, episodeFileName)) &&
+					parentsCompatible(episodePath, collectionPath)
+			}))
 			return false, matchCount
 		}
 	}
 
-	// 常规文件对比
-	for _, episodeFile := range episodeFiles {
-		for _, collectionFile := range collectionFiles {
-			// 根据文件名（去掉路径）来比较
-			episodeFileName := getFileName(episodeFile.Name)
-			collectionFileName := getFileName(collectionFile.Name)
+	// 常规文件对比：根据文件名（去掉路径）来比较，检查是否为完全匹配或合集包含分集
+	matchCount = matchedCount(matchFiles(episodeFiles, collectionFiles, fileNamesMatch))
 
-			// 检查是否为完全匹配或合集包含分集
-			if episodeFileName == collectionFileName ||
-				strings.Contains(collectionFileName, episodeFileName) {
-				matchCount++
-				break
-			}
-		}
+	// 分集有视频文件时只按视频文件的重叠判断，字幕、图片的数量不影响结果
+	if ratio, ok := videoOverlap(collectionFiles, episodeFiles); ok {
+		return ratio >= videoOverlapThreshold, matchCount
+	}
+	// 没有视频文件的种子（如字幕包）不是视频合集的分集
+	if lacksVideo(collectionFiles, episodeFiles) {
+		return false, matchCount
 	}
 
-	// 如果50%以上的分集文件在合集中找到，则认为有重叠
-	return matchCount >= len(episodeFiles)/2, matchCount
-}
-
-// 提取文件名中的剧集标识（如S01E01）
-func extractEpisodeMarker(filename string) string {
-	matches := episodeRegex.FindStringSubmatch(filename)
-	if len(matches) >= 3 {
-		return matches[0] // 返回完整的匹配，如S01E01
+	// 双方都没有视频文件时，50%以上的分集文件在合集中找到则认为有重叠；样片、字幕等附加文件不计入
+	required := len(primaryFiles(episodeFiles))
+	if required == 0 {
+		required = len(episodeFiles)
 	}
-	return ""
+	return matchCount >= required/2, matchCount
 }
 
 // 计算绝对值
@@ -569,12 +1124,17 @@ func getTorrentFiles(client *transmissionrpc.Client, torrentID *int64) ([]*trans
 	if torrentID == nil {
 		return nil, fmt.Errorf("种子ID为空")
 	}
+	if files, ok := cachedTorrentFiles(*torrentID); ok {
+		return files, nil
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Files)
 	defer cancel()
 
 	// 获取种子详情，包含文件列表
+	done := benchmark.start(BENCH_FILES)
 	torrent, err := client.TorrentGet(ctx, []string{"files"}, []int64{*torrentID})
+	done()
 	if err != nil {
 		return nil, err
 	}
@@ -583,65 +1143,194 @@ func getTorrentFiles(client *transmissionrpc.Client, torrentID *int64) ([]*trans
 		return nil, fmt.Errorf("获取种子文件列表失败")
 	}
 
+	cacheTorrentFiles(*torrentID, torrent[0].Files)
 	return torrent[0].Files, nil
 }
 
-// 从完整路径中获取文件名
+// 本次扫描中已获取的种子文件列表
+func cachedTorrentFiles(torrentID int64) ([]*transmissionrpc.TorrentFile, bool) {
+	torrentFilesMutex.Lock()
+	defer torrentFilesMutex.Unlock()
+	files, ok := torrentFilesCache[torrentID]
+	return files, ok
+}
+
+// 记录获取到的文件列表，本次扫描中不再重复请求；检查重复的路径
+func cacheTorrentFiles(torrentID int64, files []*transmissionrpc.TorrentFile) {
+	warnDuplicatePaths(torrentID, files)
+	torrentFilesMutex.Lock()
+	torrentFilesCache[torrentID] = files
+	torrentFilesMutex.Unlock()
+}
+
+// 本次扫描中已获取的文件列表，避免报告、证据收集和深度扫描重复请求
+var torrentFilesCache = make(map[int64][]*transmissionrpc.TorrentFile)
+
+// 分组分析并行获取文件列表，读写缓存时加锁
+var torrentFilesMutex sync.Mutex
+
+// 每次扫描开始时清空文件列表缓存，种子ID在Transmission重启后可能变化
+func resetTorrentFilesCache() {
+	torrentFilesMutex.Lock()
+	defer torrentFilesMutex.Unlock()
+	torrentFilesCache = make(map[int64][]*transmissionrpc.TorrentFile)
+}
+
+// 获取合集的文件列表：请求失败时重试，仍失败时返回 SKIP_FILES_FAILED（下次扫描可能成功）；
+// 元数据未完成时返回 SKIP_METADATA_PENDING；种子确实没有文件信息时返回 SKIP_NO_FILES。
+// 获取到的文件列表写入缓存，证据收集、报告等之后的步骤不再重复请求
+func getCollectionFiles(client *transmissionrpc.Client, torrentID int64) ([]*transmissionrpc.TorrentFile, string, error) {
+	if files, ok := cachedTorrentFiles(torrentID); ok && len(files) > 0 {
+		return files, "", nil
+	}
+	done := benchmark.start(BENCH_FILES)
+	torrents, err := getWithRetry(client, []string{"id", "files", "metadataPercentComplete"}, []int64{torrentID}, timeouts.Files, fmt.Sprintf("获取种子 ID: %d 文件列表", torrentID))
+	done()
+	if err != nil {
+		return nil, SKIP_FILES_FAILED, err
+	}
+	if len(torrents) == 0 {
+		return nil, SKIP_NO_FILES, fmt.Errorf("种子已不存在")
+	}
+	if metadataPending(&torrents[0]) {
+		return nil, SKIP_METADATA_PENDING, fmt.Errorf("元数据未完成 (%.0f%%)", *torrents[0].MetadataPercentComplete*100)
+	}
+	if len(torrents[0].Files) == 0 {
+		return nil, SKIP_NO_FILES, fmt.Errorf("种子没有文件信息")
+	}
+	cacheTorrentFiles(torrentID, torrents[0].Files)
+	return torrents[0].Files, "", nil
+}
+
+// 种子的元数据是否未完成（磁力链接添加后尚未获取到种子信息）
+func metadataPending(torrent *transmissionrpc.Torrent) bool {
+	return torrent.MetadataPercentComplete != nil && *torrent.MetadataPercentComplete < 1
+}
+
+// 获取种子切片中每个种子的指针
+func torrentPointers(torrents []transmissionrpc.Torrent) []*transmissionrpc.Torrent {
+	pointers := make([]*transmissionrpc.Torrent, len(torrents))
+	for i := range torrents {
+		pointers[i] = &torrents[i]
+	}
+	return pointers
+}
+
+// 从完整路径中获取文件名，同时支持 / 和 \ 分隔符（Windows上创建的种子可能使用 \）
 func getFileName(path string) string {
-	parts := strings.Split(path, "/")
-	return parts[len(parts)-1]
+	return path[strings.LastIndexAny(path, "/\\")+1:]
 }
 
-// 只暂停分集种子，不暂停合集
-func pauseEpisodes(client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup) (int, int) {
+// 只暂停分集种子，不暂停合集；按置信度从高到低处理，受本次处理数量和操作间隔的限制
+func pauseEpisodes(ctx context.Context, client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup, history *HistoryWriter, throttle *ActionThrottle, safety *SafeMode, budget *PauseBudget) (int, int) {
 	successCount := 0
 	failedCount := 0
 
-	for groupName, group := range duplicateGroups {
-		// 只收集分集ID，不包括合集
-		var torrentIDs []int64
-
-		// 添加所有分集ID
+	for _, groupName := range sortedGroupNames(duplicateGroups) {
+		group := duplicateGroups[groupName]
+		// 只收集分集，不包括合集
+		var episodes []*transmissionrpc.Torrent
 		for _, episode := range group.Episodes {
 			if episode != nil && episode.ID != nil {
-				torrentIDs = append(torrentIDs, *episode.ID)
+				episodes = append(episodes, episode)
 			}
 		}
+		episodes = throttle.take(ctx, groupName, episodes)
+		episodes = budget.take(groupName, episodes, throttle)
+		if len(episodes) == 0 {
+			continue
+		}
+		safety.snapshot(episodes)
+
+		attempted, paused := pauseGroup(ctx, client, groupName, episodes, history, throttle)
+		unconfirmed, rolledBack := safety.verify(client, history, groupName, attempted, paused)
+		successCount += len(paused) - unconfirmed - rolledBack
+		failedCount += len(attempted) - len(paused) + unconfirmed
+		if len(paused)-unconfirmed-rolledBack > 0 {
+			keptExport.succeed(groupName)
+			runNotices.succeed(groupName)
+		}
+		budget.consume(len(paused) - unconfirmed - rolledBack)
+	}
+
+	return successCount, failedCount
+}
 
-		// 暂停这些分集
-		if len(torrentIDs) > 0 {
-			fmt.Printf("正在暂停 \"%s\" 的 %d 个分集...\n", groupName, len(torrentIDs))
-
-			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-			err := client.TorrentStopIDs(ctx, torrentIDs)
-			cancel()
-
-			if err == nil {
-				successCount += len(torrentIDs)
-				fmt.Printf("成功暂停 %d 个分集\n", len(torrentIDs))
-			} else {
-				failedCount += len(torrentIDs)
-				fmt.Printf("暂停分集失败: %v\n", err)
-
-				// 单独尝试暂停每个分集
-				for _, id := range torrentIDs {
-					ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-					err := client.TorrentStopIDs(ctx, []int64{id})
-					cancel()
-
-					if err == nil {
-						successCount++
-						failedCount--
-						fmt.Printf("成功暂停分集 ID: %d\n", id)
-					} else {
-						fmt.Printf("暂停分集 ID: %d 失败: %v\n", id, err)
-					}
-
-					time.Sleep(1 * time.Second)
-				}
+// 暂停一组分集，返回尝试暂停的分集和其中暂停成功的分集
+func pauseGroup(ctx context.Context, client *transmissionrpc.Client, groupName string, episodes []*transmissionrpc.Torrent, history *HistoryWriter, throttle *ActionThrottle) ([]*transmissionrpc.Torrent, []*transmissionrpc.Torrent) {
+	var paused []*transmissionrpc.Torrent
+
+	// 设置了操作间隔时逐个暂停
+	if throttle.spread() {
+		fmt.Printf("正在逐个暂停 \"%s\" 的 %d 个分集（间隔 %s）...\n", groupName, len(episodes), throttle.delay)
+		for i, episode := range episodes {
+			if !throttle.wait(ctx) {
+				throttle.deferRest(groupName, episodes[i:], DEFERRED_INTERRUPTED)
+				return episodes[:i], paused
+			}
+			if stopTorrent(client, *episode.ID, timeouts.Action) {
+				paused = append(paused, episode)
+				recordPause(history, groupName, episode)
 			}
 		}
+		return episodes, paused
 	}
 
-	return successCount, failedCount
+	torrentIDs := make([]int64, len(episodes))
+	for i, episode := range episodes {
+		torrentIDs[i] = *episode.ID
+	}
+
+	// 暂停这些分集
+	fmt.Printf("正在暂停 \"%s\" 的 %d 个分集...\n", groupName, len(torrentIDs))
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+	err := client.TorrentStopIDs(stopCtx, torrentIDs)
+	cancel()
+
+	if err == nil {
+		fmt.Printf("成功暂停 %d 个分集\n", len(torrentIDs))
+		for _, episode := range episodes {
+			recordPause(history, groupName, episode)
+		}
+		return episodes, episodes
+	}
+
+	if isSessionError(err) {
+		fmt.Printf("暂停分集失败: %v，从这批分集开始逐个继续\n", err)
+	} else {
+		fmt.Printf("暂停分集失败: %v\n", err)
+	}
+
+	// 单独尝试暂停每个分集
+	for _, episode := range episodes {
+		if stopTorrent(client, *episode.ID, timeouts.actionRetry()) {
+			paused = append(paused, episode)
+			recordPause(history, groupName, episode)
+		}
+		time.Sleep(1 * time.Second)
+	}
+	return episodes, paused
+}
+
+// 暂停单个种子并显示结果
+func stopTorrent(client *transmissionrpc.Client, id int64, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	err := client.TorrentStopIDs(ctx, []int64{id})
+	cancel()
+	if err != nil {
+		fmt.Printf("暂停分集 ID: %d 失败: %v\n", id, err)
+		return false
+	}
+	fmt.Printf("成功暂停分集 ID: %d\n", id)
+	return true
+}
+
+// 记录暂停操作，原本就已停止的种子不记录，避免撤销时被错误启动
+func recordPause(history *HistoryWriter, groupName string, episode *transmissionrpc.Torrent) {
+	if episode.Status != nil && *episode.Status == transmissionrpc.TorrentStatusStopped {
+		return
+	}
+	history.Record(newHistoryRecord(ACTION_PAUSE, groupName, episode))
+	windowPauses.add(episode)
 }
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+// 统计每个种子的文件列表被请求的次数
+type fileRequestCounter struct {
+	next   http.RoundTripper
+	mutex  sync.Mutex
+	counts map[int64]int
+}
+
+func (c *fileRequestCounter) RoundTrip(request *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	var rpcRequest struct {
+		Method    string `json:"method"`
+		Arguments struct {
+			Fields []string `json:"fields"`
+			IDs    []int64  `json:"ids"`
+		} `json:"arguments"`
+	}
+	json.Unmarshal(body, &rpcRequest)
+	if rpcRequest.Method == "torrent-get" {
+		for _, field := range rpcRequest.Arguments.Fields {
+			if field != "files" {
+				continue
+			}
+			c.mutex.Lock()
+			for _, id := range rpcRequest.Arguments.IDs {
+				c.counts[id]++
+			}
+			c.mutex.Unlock()
+		}
+	}
+	return c.next.RoundTrip(request)
+}
+
+func TestTorrentFilesFetchedOnce(t *testing.T) {
+	for _, parallel := range []string{"1", "8"} {
+		t.Run("parallel "+parallel, func(t *testing.T) {
+			client, opts := connectFixture(t, "scan.json", "--parallel", parallel)
+			httpClient, err := rpcHTTPClient(client)
+			if err != nil {
+				t.Fatal(err)
+			}
+			counter := &fileRequestCounter{next: httpClient.Transport, counts: make(map[int64]int)}
+			httpClient.Transport = counter
+
+			result, err := scan(client, detectCapabilities(client), opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(result.DuplicateGroups) == 0 {
+				t.Fatal("扫描没有找到需要处理的组")
+			}
+			// 报告再次使用分析时获取的文件列表
+			printReport(io.Discard, fetchReportFiles(client, result.DuplicateGroups), result, opts.Action, opts.Verbose)
+
+			for id, count := range counter.counts {
+				if count > 1 {
+					t.Errorf("种子 ID: %d 的文件列表被请求 %d 次，应只请求一次", id, count)
+				}
+			}
+			for _, group := range result.DuplicateGroups {
+				if counter.counts[*group.Collection.ID] != 1 {
+					t.Errorf("合集 ID: %d 的文件列表被请求 %d 次，应为一次", *group.Collection.ID, counter.counts[*group.Collection.ID])
+				}
+			}
+		})
+	}
+}
+
+func TestGetFileName(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"Show.S01E01.mkv", "Show.S01E01.mkv"},
+		{"Show.S01/Show.S01E01.mkv", "Show.S01E01.mkv"},
+		{`Show.S01\Show.S01E01.mkv`, "Show.S01E01.mkv"},
+		{`Show.S01\Season 1\Show.S01E01.mkv`, "Show.S01E01.mkv"},
+		{`Show.S01/Season 1\Show.S01E01.mkv`, "Show.S01E01.mkv"},
+		{`Show.S01\Season 1/Show.S01E01.mkv`, "Show.S01E01.mkv"},
+		{"Show.S01/", ""},
+	}
+	for _, tt := range tests {
+		if got := getFileName(tt.path); got != tt.want {
+			t.Errorf("getFileName(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 最终执行清单中显示的hash长度
+const MANIFEST_HASH_PREFIX = 8
+
+// 最终执行清单中的一个种子：确认前的最后检查，也写入计划文件作为实际执行内容的依据
+type ManifestEntry struct {
+	ID      int64  `json:"id"`
+	Hash    string `json:"hash"`
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	Tracker string `json:"tracker"` // 第一个tracker的主机名，没有tracker时为空
+	Group   string `json:"group"`
+	Action  string `json:"action"`
+}
+
+// 按已应用的选择、保护和策略列出将要被操作的每个分集，按操作、组名和ID排序；
+// 交互选择中手动保留的分集以 MANIFEST_KEPT 列在最后
+func buildManifest(duplicateGroups map[string]DuplicateGroup, defaultAction string) []ManifestEntry {
+	var entries []ManifestEntry
+	for _, name := range sortedGroupNames(duplicateGroups) {
+		group := duplicateGroups[name]
+		for _, episode := range group.Episodes {
+			if episode == nil || episode.ID == nil {
+				continue
+			}
+			entries = append(entries, newManifestEntry(name, episode, group.episodeAction(episode, defaultAction)))
+		}
+		for _, episode := range group.KeptEpisodes {
+			entries = append(entries, newManifestEntry(name, episode, MANIFEST_KEPT))
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if kept := entries[i].Action == MANIFEST_KEPT; kept != (entries[j].Action == MANIFEST_KEPT) {
+			return !kept
+		}
+		if entries[i].Action != entries[j].Action {
+			return entries[i].Action < entries[j].Action
+		}
+		if entries[i].Group != entries[j].Group {
+			return entries[i].Group < entries[j].Group
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+// 清单中的一个分集
+func newManifestEntry(groupName string, episode *transmissionrpc.Torrent, action string) ManifestEntry {
+	planTorrent := newPlanTorrent(episode)
+	entry := ManifestEntry{
+		ID:     planTorrent.ID,
+		Hash:   planTorrent.Hash,
+		Name:   planTorrent.Name,
+		Size:   planTorrent.Size,
+		Group:  groupName,
+		Action: action,
+	}
+	if len(planTorrent.Trackers) > 0 {
+		entry.Tracker = planTorrent.Trackers[0]
+	}
+	return entry
+}
+
+// 确认前显示最终执行清单：按操作分段，每个种子一行，只有ID、hash前缀、大小、tracker、名称和组
+func printManifest(entries []ManifestEntry) {
+	printManifestWithStatus(entries, nil)
+}
+
+// 显示最终执行清单，statuses 不为空时先显示与之前运行比较的各类数量，并在每个分集前标出比较结果
+func printManifestWithStatus(entries []ManifestEntry, statuses map[string]string) {
+	if len(entries) == 0 {
+		return
+	}
+	printRunDiffCounts(statuses)
+	fmt.Printf("\n===== 最终执行清单（%d 个种子）=====\n", len(entries))
+	for i, entry := range entries {
+		if i == 0 || entry.Action != entries[i-1].Action {
+			count := 0
+			var size int64
+			for _, other := range entries[i:] {
+				if other.Action != entry.Action {
+					break
+				}
+				count++
+				size += other.Size
+			}
+			fmt.Printf("%s（%d 个，%s）:\n", actionName(entry.Action), count, formatSize(size))
+		}
+		hash := entry.Hash
+		if len(hash) > MANIFEST_HASH_PREFIX {
+			hash = hash[:MANIFEST_HASH_PREFIX]
+		}
+		tracker := entry.Tracker
+		if tracker == "" {
+			tracker = "无tracker"
+		}
+		status := ""
+		if statuses != nil {
+			status = fmt.Sprintf("%-12s  ", statuses[entry.Hash])
+		}
+		// 名称和组名可能含中文，放在最后避免对齐问题
+		fmt.Printf("  %s%6d  %-8s  %11s  %-24s  %s  [组: %s]\n", status, entry.ID, hash, formatSize(entry.Size), tracker, entry.Name, entry.Group)
+	}
+}
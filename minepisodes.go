@@ -0,0 +1,16 @@
+package main
+
+// 按 --min-episodes 把可处理分集少于N个的组移到仅供参考部分。
+// 在tracker策略、保留最新分集、活跃上传等保护之后执行，只计算仍需处理的分集，
+// 已暂缓、已暂停或大小相同的分集不计入
+func applyMinEpisodes(result *ScanResult, minEpisodes int) {
+	if minEpisodes <= 1 {
+		return
+	}
+	for name, group := range result.DuplicateGroups {
+		if len(group.Episodes) < minEpisodes {
+			delete(result.DuplicateGroups, name)
+			result.BelowMinEpisodesGroups[name] = group
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 数据缺失的分集显示的策略名称：暂停这些分集没有意义，只能单独删除
+const MISSING_DATA_GATE_POLICY = "数据缺失分集"
+
+// 查询会话的未完成目录，查询失败、没有设置或与默认下载目录相同时返回空。
+// 未完成目录停用后，之前下载到其中的分集仍可能指向该目录，因此不要求当前启用
+func sessionIncompleteDir(client *transmissionrpc.Client) string {
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+	defer cancel()
+	session, err := client.SessionArgumentsGet(ctx, []string{"download-dir", "incomplete-dir"})
+	if err != nil {
+		log.Printf("查询未完成目录失败，只按本地错误判断数据缺失: %v", err)
+		return ""
+	}
+	if session.IncompleteDir == nil || strings.TrimSpace(*session.IncompleteDir) == "" {
+		return ""
+	}
+	incompleteDir := path.Clean(*session.IncompleteDir)
+	// 默认配置中两个目录相同，此时无法区分
+	if session.DownloadDir != nil && path.Clean(*session.DownloadDir) == incompleteDir {
+		return ""
+	}
+	return incompleteDir
+}
+
+// 目录是否是 base 或位于 base 之下
+func underDir(dir, base string) bool {
+	dir = path.Clean(dir)
+	return dir == base || strings.HasPrefix(dir, strings.TrimSuffix(base, "/")+"/")
+}
+
+// 分集数据缺失的原因：有本地错误（如找不到数据文件），或下载位置仍在未完成目录中，正常时返回空
+func missingDataReason(episode *transmissionrpc.Torrent, incompleteDir string) string {
+	if episode == nil {
+		return ""
+	}
+	if episode.Error != nil && *episode.Error == TR_STAT_LOCAL_ERROR {
+		message := ""
+		if episode.ErrorString != nil {
+			message = strings.TrimSpace(*episode.ErrorString)
+		}
+		return fmt.Sprintf("本地数据错误: %s", message)
+	}
+	if incompleteDir != "" && episode.DownloadDir != nil && underDir(*episode.DownloadDir, incompleteDir) {
+		return fmt.Sprintf("下载位置在未完成目录 %s 中", incompleteDir)
+	}
+	return ""
+}
+
+// 把需要处理的组中数据缺失的分集移出暂停流程，记为暂缓的分集；分集全部数据缺失的组移到仅供参考部分
+func applyMissingData(result *ScanResult, incompleteDir string) {
+	for name, group := range result.DuplicateGroups {
+		var kept []*transmissionrpc.Torrent
+		for _, episode := range group.Episodes {
+			reason := missingDataReason(episode, incompleteDir)
+			if reason == "" {
+				kept = append(kept, episode)
+				continue
+			}
+			group.GatedEpisodes = append(group.GatedEpisodes, GatedEpisode{
+				Episode: episode,
+				Policy:  MISSING_DATA_GATE_POLICY,
+				Reason:  reason,
+			})
+		}
+		if len(kept) == len(group.Episodes) {
+			continue
+		}
+		group.Episodes = kept
+		if len(kept) == 0 {
+			delete(result.DuplicateGroups, name)
+			result.GatedGroups[name] = group
+			continue
+		}
+		result.DuplicateGroups[name] = group
+	}
+}
+
+// 全部数据缺失的分集，按ID去重
+func missingDataTargets(result *ScanResult) []GatedEpisode {
+	seen := make(map[int64]bool)
+	var targets []GatedEpisode
+	for _, groups := range allGroupMaps(result) {
+		for _, name := range sortedGroupNames(groups) {
+			for _, gated := range groups[name].GatedEpisodes {
+				if gated.Policy != MISSING_DATA_GATE_POLICY || gated.Episode == nil || gated.Episode.ID == nil || seen[*gated.Episode.ID] {
+					continue
+				}
+				seen[*gated.Episode.ID] = true
+				targets = append(targets, gated)
+			}
+		}
+	}
+	return targets
+}
+
+// 删除数据缺失的分集，只删除种子、不删除数据（没有可保留的数据，目录中的残留文件可能属于其他种子），
+// 删除后从各组的暂缓分集中去掉，返回成功删除的数量
+func removeMissingData(ctx context.Context, client *transmissionrpc.Client, result *ScanResult, dryRun bool, throttle *ActionThrottle) int {
+	targets := missingDataTargets(result)
+	if len(targets) == 0 {
+		return 0
+	}
+	if dryRun {
+		fmt.Printf("\n试运行模式，不删除 %d 个数据缺失分集\n", len(targets))
+		return 0
+	}
+
+	torrents := make([]*transmissionrpc.Torrent, len(targets))
+	for i, target := range targets {
+		torrents[i] = target.Episode
+	}
+	torrents = throttle.take(ctx, MISSING_DATA_GATE_POLICY, torrents)
+	if len(torrents) == 0 {
+		return 0
+	}
+
+	fmt.Printf("正在删除 %d 个数据缺失分集...\n", len(torrents))
+	removed := make(map[int64]bool)
+	attempted := 0
+	for i, torrent := range torrents {
+		if !throttle.wait(ctx) {
+			throttle.deferRest(MISSING_DATA_GATE_POLICY, torrents[i:], DEFERRED_INTERRUPTED)
+			break
+		}
+		attempted++
+		id := *torrent.ID
+		removeCtx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+		err := client.TorrentRemove(removeCtx, transmissionrpc.TorrentRemovePayload{
+			IDs:             []int64{id},
+			DeleteLocalData: false,
+		})
+		cancel()
+		if err != nil {
+			fmt.Printf("删除数据缺失分集失败 ID: %d: %v\n", id, err)
+			continue
+		}
+		removed[id] = true
+	}
+
+	for _, groups := range allGroupMaps(result) {
+		for name, group := range groups {
+			var kept []GatedEpisode
+			for _, gated := range group.GatedEpisodes {
+				if gated.Episode != nil && gated.Episode.ID != nil && removed[*gated.Episode.ID] {
+					continue
+				}
+				kept = append(kept, gated)
+			}
+			if len(kept) != len(group.GatedEpisodes) {
+				group.GatedEpisodes = kept
+				groups[name] = group
+			}
+		}
+	}
+
+	fmt.Printf("删除完成: 成功删除 %d 个数据缺失分集, 失败 %d 个\n", len(removed), attempted-len(removed))
+	return len(removed)
+}
+
+// 汇总显示数据缺失的分集，便于用 --remove-missing-data 单独删除
+func printMissingData(w io.Writer, result *ScanResult) {
+	targets := missingDataTargets(result)
+	if len(targets) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n===== %s（%d 个，不参与暂停，可用 --remove-missing-data 删除）=====\n", MISSING_DATA_GATE_POLICY, len(targets))
+	for i, target := range targets {
+		torrent := target.Episode
+		line := fmt.Sprintf("  %d. ID: %d", i+1, *torrent.ID)
+		if torrent.Name != nil {
+			line += ", " + *torrent.Name
+		}
+		if torrent.PercentDone != nil {
+			line += fmt.Sprintf(", 完成 %.1f%%", *torrent.PercentDone*100)
+		}
+		fmt.Fprintf(w, "%s, %s\n", line, target.Reason)
+	}
+}
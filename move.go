@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 移动合集后检查是否完成的间隔
+const MOVE_POLL_INTERVAL = 2 * time.Second
+
+// 合集是否已在目标目录（或其子目录）下，路径为Transmission服务器上的路径
+func underDirectory(downloadDir, destination string) bool {
+	dir, dest := path.Clean(downloadDir), path.Clean(destination)
+	return dir == dest || strings.HasPrefix(dir, strings.TrimSuffix(dest, "/")+"/")
+}
+
+// 操作完成后把各组的合集移动到指定目录，已在该目录下的合集跳过；移动失败不影响已完成的操作
+func moveCollections(ctx context.Context, client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup, destination string, dryRun bool, moveTimeout time.Duration) {
+	movedCount, skippedCount, failedCount := 0, 0, 0
+	fmt.Printf("\n正在把合集移动到 %s ...\n", destination)
+
+	for _, groupName := range sortedGroupNames(duplicateGroups) {
+		collection := duplicateGroups[groupName].Collection
+		if collection == nil || collection.ID == nil {
+			continue
+		}
+		if collection.DownloadDir != nil && underDirectory(*collection.DownloadDir, destination) {
+			skippedCount++
+			continue
+		}
+		if dryRun {
+			fmt.Printf("试运行: 将移动合集 ID: %d (%s) 到 %s\n", *collection.ID, groupName, destination)
+			movedCount++
+			continue
+		}
+		if ctx.Err() != nil {
+			fmt.Println("已中断，不再移动其余合集")
+			break
+		}
+
+		if err := moveCollection(ctx, client, *collection.ID, destination, moveTimeout); err != nil {
+			fmt.Printf("移动合集 ID: %d (%s) 失败: %v\n", *collection.ID, groupName, err)
+			failedCount++
+			continue
+		}
+		fmt.Printf("成功移动合集 ID: %d (%s)\n", *collection.ID, groupName)
+		movedCount++
+	}
+
+	if dryRun {
+		fmt.Printf("试运行完成: 计划移动 %d 个合集, 已在目标目录跳过 %d 个\n", movedCount, skippedCount)
+		return
+	}
+	fmt.Printf("移动合集完成: 成功 %d 个, 已在目标目录跳过 %d 个, 失败 %d 个\n", movedCount, skippedCount, failedCount)
+}
+
+// 移动单个合集并等待Transmission完成移动，超时或种子报告错误时返回错误
+func moveCollection(ctx context.Context, client *transmissionrpc.Client, torrentID int64, destination string, moveTimeout time.Duration) error {
+	setCtx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+	err := client.TorrentSetLocation(setCtx, torrentID, destination, true)
+	cancel()
+	if err != nil {
+		return err
+	}
+
+	// 移动在服务器上异步进行，完成后种子的下载目录才会变为目标目录
+	deadline := time.Now().Add(moveTimeout)
+	for {
+		queryCtx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+		torrents, err := client.TorrentGet(queryCtx, []string{"id", "downloadDir", "errorString"}, []int64{torrentID})
+		cancel()
+		if err == nil {
+			if len(torrents) == 0 {
+				return fmt.Errorf("种子已不存在")
+			}
+			torrent := torrents[0]
+			if torrent.ErrorString != nil && *torrent.ErrorString != "" {
+				return fmt.Errorf("Transmission报告错误: %s", *torrent.ErrorString)
+			}
+			if torrent.DownloadDir != nil && path.Clean(*torrent.DownloadDir) == path.Clean(destination) {
+				return nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待 %s 后移动仍未完成，请稍后在Transmission中确认", moveTimeout)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("已中断，移动可能仍在服务器上进行")
+		case <-time.After(MOVE_POLL_INTERVAL):
+		}
+	}
+}
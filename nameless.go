@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 无名称的种子在报告和跳过原因文件中显示的组名
+const NAMELESS_GROUP = "(无名称)"
+
+// 种子是否没有名称：名称为空或只有空白（如刚添加、元数据还未获取的磁力链接），
+// 这些种子无法按名称分组
+func namelessTorrent(torrent *transmissionrpc.Torrent) bool {
+	return torrent.Name == nil || strings.TrimSpace(*torrent.Name) == ""
+}
+
+// 种子ID的显示文本，没有ID时显示为"?"
+func torrentIDText(torrent *transmissionrpc.Torrent) string {
+	if torrent == nil || torrent.ID == nil {
+		return "?"
+	}
+	return fmt.Sprint(*torrent.ID)
+}
+
+// 把无名称的种子从待分组的种子中分出，每个种子记录为一条跳过记录，
+// 在统计中计数并写入跳过原因文件，而不是在分组时静默丢弃
+func splitNamelessTorrents(torrents []transmissionrpc.Torrent) ([]transmissionrpc.Torrent, []SkipRecord) {
+	var named []transmissionrpc.Torrent
+	var skipped []SkipRecord
+	for i := range torrents {
+		torrent := &torrents[i]
+		if !namelessTorrent(torrent) {
+			named = append(named, *torrent)
+			continue
+		}
+		hash := "?"
+		if torrent.HashString != nil {
+			hash = *torrent.HashString
+		}
+		skipped = append(skipped, SkipRecord{
+			Reason:   SKIP_NO_NAME,
+			Name:     NAMELESS_GROUP,
+			Detail:   fmt.Sprintf("ID: %s, hash: %s", torrentIDText(torrent), hash),
+			Torrents: []*transmissionrpc.Torrent{torrent},
+		})
+	}
+	return named, skipped
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// 只有ID和hash的种子（如刚添加的磁力链接）记为跳过，报告中列出而不会出错
+func TestNamelessTorrentReport(t *testing.T) {
+	client, opts := connectFixture(t, "scan.json")
+	// 磁力链接 ID 11 也缺少 addedDate，补上后缺失字段的种子不超过 SPARSE_FIELD_RATIO，不会重新获取列表
+	setReplayTorrent(t, 11, "addedDate", 1704067200)
+	dumpReplay.Torrents = append(dumpReplay.Torrents, map[string]json.RawMessage{
+		"id":         json.RawMessage(`99`),
+		"hashString": json.RawMessage(`"0123456789abcdef0123456789abcdef01234567"`),
+	})
+	result, err := scan(client, detectCapabilities(client), opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var nameless []SkipRecord
+	for _, record := range result.Skipped {
+		if record.Reason == SKIP_NO_NAME {
+			nameless = append(nameless, record)
+		}
+	}
+	if len(nameless) != 1 || *nameless[0].Torrents[0].ID != 99 {
+		t.Fatalf("无名称的种子跳过记录 %d 条，应只有 ID 99", len(nameless))
+	}
+
+	for _, verbose := range []bool{false, true} {
+		var out bytes.Buffer
+		printReport(&out, fetchReportFiles(client, result.DuplicateGroups), result, opts.Action, verbose)
+		if !strings.Contains(out.String(), skipReasonLabels[SKIP_NO_NAME]) {
+			t.Errorf("verbose=%v 的报告中没有列出无名称的种子:\n%s", verbose, out.String())
+		}
+		if verbose && !strings.Contains(out.String(), "ID: 99, hash: 0123456789abcdef0123456789abcdef01234567") {
+			t.Errorf("详细报告中没有无名称种子的ID和hash:\n%s", out.String())
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// 一个别名及其对应的组名
+type NameAlias struct {
+	Alias     string
+	Canonical string // 每行的第一个名称作为组名
+	Line      int
+}
+
+// 读取并校验名称映射文件，每行以 = 分隔多个视为同一组的别名，# 开头的行为注释
+func loadNameMap(filePath string) ([]NameAlias, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var aliases []NameAlias
+	seen := make(map[string]NameAlias)
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		names := strings.Split(line, "=")
+		if len(names) < 2 {
+			return nil, fmt.Errorf("名称映射文件第 %d 行至少需要两个以 = 分隔的名称", lineNumber)
+		}
+		canonical := strings.TrimSpace(names[0])
+		for _, name := range names {
+			name = strings.TrimSpace(name)
+			if name == "" {
+				return nil, fmt.Errorf("名称映射文件第 %d 行含有空名称", lineNumber)
+			}
+			key := strings.ToLower(name)
+			if previous, ok := seen[key]; ok {
+				if previous.Line == lineNumber {
+					return nil, fmt.Errorf("名称映射文件第 %d 行重复的别名: %s", lineNumber, name)
+				}
+				return nil, fmt.Errorf("名称映射文件第 %d 行的别名 %s 与第 %d 行冲突", lineNumber, name, previous.Line)
+			}
+			alias := NameAlias{Alias: name, Canonical: canonical, Line: lineNumber}
+			seen[key] = alias
+			aliases = append(aliases, alias)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// 不同组的别名互相包含时，种子名称会同时匹配两组
+	for _, outer := range aliases {
+		for _, inner := range aliases {
+			if outer.Canonical == inner.Canonical || outer.Alias == inner.Alias {
+				continue
+			}
+			if strings.Contains(strings.ToLower(outer.Alias), strings.ToLower(inner.Alias)) {
+				return nil, fmt.Errorf("名称映射文件第 %d 行的别名 %s 包含第 %d 行的别名 %s，无法确定分组",
+					outer.Line, outer.Alias, inner.Line, inner.Alias)
+			}
+		}
+	}
+
+	// 较长的别名优先匹配
+	sort.SliceStable(aliases, func(i, j int) bool {
+		return len(aliases[i].Alias) > len(aliases[j].Alias)
+	})
+	return aliases, nil
+}
+
+// 获取种子名称的分组键，名称包含任一别名（不区分大小写）时使用对应的组名
+func groupKey(aliases []NameAlias, name string) (string, bool) {
+	lowerName := strings.ToLower(name)
+	for _, alias := range aliases {
+		if strings.Contains(lowerName, strings.ToLower(alias.Alias)) {
+			return alias.Canonical, true
+		}
+	}
+	return name, false
+}
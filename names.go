@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 默认的名称前缀规则：方括号/中文方括号中的站点或发布组标签、网站域名前缀
+var defaultNameTagPatterns = []string{
+	`^\[[^\]]*\]`,
+	`^【[^】]*】`,
+	`(?i)^(?:www\.)?[a-z0-9\-]+\.(?:com|net|org|cc|me|tv|io|xyz|club)\s*[-@_]`,
+}
+
+// 分组和筛选前从名称开头去掉的标签，可通过 --name-tag-pattern 替换
+var nameTagPatterns = mustCompileNameTagPatterns(defaultNameTagPatterns)
+
+// 编译名称前缀规则，规则无效时返回错误
+func compileNameTagPatterns(specs []string) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, spec := range specs {
+		pattern, err := regexp.Compile(spec)
+		if err != nil {
+			return nil, fmt.Errorf("无效的名称前缀规则 %q: %v", spec, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+func mustCompileNameTagPatterns(specs []string) []*regexp.Regexp {
+	patterns, err := compileNameTagPatterns(specs)
+	if err != nil {
+		panic(err)
+	}
+	return patterns
+}
+
+// 设置名称前缀规则
+func setNameTagPatterns(patterns []*regexp.Regexp) {
+	nameTagPatterns = patterns
+}
+
+// 用于分组和筛选的规范名称：去掉首尾空白和开头的标签（可叠加多个，如 "[A][B] Show"），
+// 种子的原始名称不变，仍用于显示和操作；去掉后为空时使用原始名称
+func canonicalName(name string) string {
+	canonical := strings.TrimSpace(name)
+	for stripped := true; stripped; {
+		stripped = false
+		for _, pattern := range nameTagPatterns {
+			location := pattern.FindStringIndex(canonical)
+			if location == nil || location[0] != 0 || location[1] == 0 {
+				continue
+			}
+			canonical = strings.TrimLeft(canonical[location[1]:], " ._-")
+			stripped = canonical != ""
+		}
+	}
+	if canonical == "" {
+		return strings.TrimSpace(name)
+	}
+	return canonical
+}
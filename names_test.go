@@ -0,0 +1,28 @@
+package main
+
+import "testing"
+
+func TestCanonicalName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Show.S01E01", "Show.S01E01"},
+		{"  Show.S01E01  ", "Show.S01E01"},
+		{"[Site] Show.S01E01", "Show.S01E01"},
+		{"【字幕组】Show.S01E01", "Show.S01E01"},
+		{"[A][B]【C】Show.S01E01", "Show.S01E01"},
+		{"【C】[A] - [B].Show.S01E01", "Show.S01E01"},
+		{"www.example.com - Show.S01E01", "Show.S01E01"},
+		{"[Site] www.example.com @ Show.S01E01", "Show.S01E01"},
+		{"Show.S01E01 [1080p]", "Show.S01E01 [1080p]"},
+		{"[A][B]", "[A][B]"},
+		{"  [A]【B】  ", "[A]【B】"},
+		{"[Site]", "[Site]"},
+	}
+	for _, tt := range tests {
+		if got := canonicalName(tt.name); got != tt.want {
+			t.Errorf("canonicalName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
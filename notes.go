@@ -0,0 +1,213 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 用户为组添加的备注，按合集hash保存，分集变化后仍然有效
+type GroupNote struct {
+	CollectionHash string    `json:"collection_hash"`
+	Group          string    `json:"group,omitempty"`
+	CollectionName string    `json:"collection_name,omitempty"`
+	Note           string    `json:"note"`
+	Time           time.Time `json:"time"`
+}
+
+// 备注文件格式
+type NotesFile struct {
+	Version int         `json:"version"`
+	Notes   []GroupNote `json:"notes"`
+}
+
+// 备注文件路径
+func notesPath() string {
+	return filepath.Join(stateDir(), "notes.json")
+}
+
+// 读取备注，文件不存在时返回空
+func loadNotes(path string) ([]GroupNote, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var file NotesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("备注文件格式错误: %v", err)
+	}
+	return file.Notes, nil
+}
+
+// 保存备注
+func saveNotes(path string, notes []GroupNote) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(NotesFile{Version: STATE_FILE_VERSION, Notes: notes}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// 添加或修改合集的备注，备注为空时删除
+func setNote(note GroupNote) error {
+	notes, err := loadNotes(notesPath())
+	if err != nil {
+		return err
+	}
+	var kept []GroupNote
+	for _, existing := range notes {
+		if existing.CollectionHash == note.CollectionHash {
+			// 未提供组名时沿用原来的
+			if note.Group == "" {
+				note.Group = existing.Group
+				note.CollectionName = existing.CollectionName
+			}
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	if note.Note != "" {
+		note.Time = time.Now()
+		kept = append(kept, note)
+	}
+	return saveNotes(notesPath(), kept)
+}
+
+// 为组的合集添加或修改备注
+func setGroupNote(groupName string, group DuplicateGroup, text string) error {
+	collection := group.Collection
+	if collection == nil || collection.HashString == nil {
+		return fmt.Errorf("合集缺少hash，无法记录")
+	}
+	note := GroupNote{
+		CollectionHash: *collection.HashString,
+		Group:          groupName,
+		Note:           text,
+	}
+	if collection.Name != nil {
+		note.CollectionName = *collection.Name
+	}
+	return setNote(note)
+}
+
+// 把已保存的备注附加到扫描结果中合集hash相同的组
+func applyNotes(result *ScanResult, notes []GroupNote) {
+	if len(notes) == 0 {
+		return
+	}
+	byHash := make(map[string]string)
+	for _, note := range notes {
+		byHash[note.CollectionHash] = note.Note
+	}
+	for _, groups := range allGroupMaps(result) {
+		for name, group := range groups {
+			if group.Collection == nil || group.Collection.HashString == nil {
+				continue
+			}
+			if note, ok := byHash[*group.Collection.HashString]; ok {
+				group.Note = note
+				groups[name] = group
+			}
+		}
+	}
+}
+
+// 显示组的备注
+func printGroupNote(w io.Writer, note string) {
+	if note != "" {
+		fmt.Fprintf(w, "备注: %s\n", note)
+	}
+}
+
+// 解析交互输入的 "note <编号> <备注>"，不是备注命令时返回 false
+func parseNoteCommand(input string) (int, string, bool, error) {
+	fields := strings.Fields(input)
+	if len(fields) == 0 || fields[0] != "note" {
+		return 0, "", false, nil
+	}
+	if len(fields) < 2 {
+		return 0, "", true, fmt.Errorf("用法: note <编号> <备注>（备注为空时删除）")
+	}
+	index, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, "", true, fmt.Errorf("无效的组编号: %s", fields[1])
+	}
+	return index, strings.Join(fields[2:], " "), true, nil
+}
+
+// notes 命令：列出、设置或删除组的备注
+func runNotes(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, "用法: delete-episode notes list|set <合集hash> <备注>|remove <序号|合集hash|组名>")
+		os.Exit(2)
+	}
+	notes, err := loadNotes(notesPath())
+	if err != nil {
+		log.Fatalf("读取备注失败: %v", err)
+	}
+
+	switch args[0] {
+	case "list":
+		if len(notes) == 0 {
+			fmt.Println("没有备注")
+			return
+		}
+		fmt.Printf("共 %d 条备注:\n", len(notes))
+		for i, note := range notes {
+			fmt.Printf("  %d. %s (%s)\n", i+1, note.Group, note.Time.Format("2006-01-02 15:04:05"))
+			fmt.Printf("     合集: %s %s\n", note.CollectionHash, note.CollectionName)
+			fmt.Printf("     备注: %s\n", note.Note)
+		}
+	case "set":
+		if len(args) < 3 {
+			fmt.Fprintln(os.Stderr, "用法: delete-episode notes set <合集hash> <备注>")
+			os.Exit(2)
+		}
+		note := GroupNote{
+			CollectionHash: strings.ToLower(args[1]),
+			Note:           strings.Join(args[2:], " "),
+		}
+		if err := setNote(note); err != nil {
+			log.Fatalf("保存备注失败: %v", err)
+		}
+		fmt.Printf("已保存合集 %s 的备注\n", note.CollectionHash)
+	case "remove":
+		if len(args) < 2 {
+			fmt.Fprintln(os.Stderr, "用法: delete-episode notes remove <序号|合集hash|组名>")
+			os.Exit(2)
+		}
+		target := args[1]
+		index, indexErr := strconv.Atoi(target)
+		var kept []GroupNote
+		for i, note := range notes {
+			if (indexErr == nil && i+1 == index) || (indexErr != nil && (note.Group == target || strings.EqualFold(note.CollectionHash, target))) {
+				continue
+			}
+			kept = append(kept, note)
+		}
+		removed := len(notes) - len(kept)
+		if removed == 0 {
+			fmt.Printf("未找到备注: %s\n", target)
+			os.Exit(1)
+		}
+		if err := saveNotes(notesPath(), kept); err != nil {
+			log.Fatalf("保存备注失败: %v", err)
+		}
+		fmt.Printf("已删除 %d 条备注\n", removed)
+	default:
+		fmt.Fprintf(os.Stderr, "未知的子命令: %s（可选: list, set, remove）\n", args[0])
+		os.Exit(2)
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 一次执行操作的结果，发送到各通知渠道
+type RunSummary struct {
+	Server    string    `json:"server"`
+	Time      time.Time `json:"time"`
+	Groups    []string  `json:"groups"`  // 至少有一个分集操作成功的组，按名称排序
+	Paused    int       `json:"paused"`  // 暂停的分集（含旧版合集）
+	Deleted   int       `json:"deleted"` // 删除的分集（含旧版合集）
+	Other     int       `json:"other"`   // 其他操作（优先级、取消选择、标签、原地升级）成功的分集
+	Failed    int       `json:"failed"`
+	Reclaimed int64     `json:"reclaimed_bytes"` // 删除数据和原地升级释放的空间（字节）
+
+	SessionRenegotiations int64 `json:"session_renegotiations"` // 执行操作期间重新协商会话ID的次数
+}
+
+// 通知渠道：每次执行操作后发送结果，发送失败只记录日志，不影响操作
+type Notifier interface {
+	Name() string
+	Notify(summary RunSummary) error
+}
+
+// 收集本次执行操作的结果并发送到通知渠道，未配置通知渠道时为nil，nil收集器不记录任何内容
+type RunNotices struct {
+	notifiers []Notifier
+	summary   RunSummary
+	groups    map[string]bool
+
+	renegotiationsBefore int64 // 开始时已重新协商会话ID的次数
+}
+
+// 本次运行的通知
+var runNotices *RunNotices
+
+// 配置通知渠道
+func enableNotifiers(notifiers ...Notifier) {
+	if len(notifiers) == 0 {
+		return
+	}
+	runNotices = &RunNotices{notifiers: notifiers}
+	runNotices.begin(ConnectionParams{})
+}
+
+// 开始收集一次执行操作的结果，守护模式每轮重新开始
+func (n *RunNotices) begin(params ConnectionParams) {
+	if n == nil {
+		return
+	}
+	server := params.UnixSocket
+	if server == "" {
+		server = fmt.Sprintf("%s:%d", params.Address, params.Port)
+	}
+	n.summary = RunSummary{Server: server}
+	n.groups = make(map[string]bool)
+	n.renegotiationsBefore = sessionRenegotiations.Load()
+}
+
+// 记录一种操作成功和失败的数量
+func (n *RunNotices) count(action string, successCount, failedCount int) {
+	if n == nil {
+		return
+	}
+	switch {
+	case pausesEpisode(action):
+		n.summary.Paused += successCount
+	case action == ACTION_DELETE || action == ACTION_OLD_PACK_DELETE:
+		n.summary.Deleted += successCount
+	default:
+		n.summary.Other += successCount
+	}
+	n.summary.Failed += failedCount
+}
+
+// 标记组内有分集操作成功
+func (n *RunNotices) succeed(groupName string) {
+	if n == nil {
+		return
+	}
+	n.groups[groupName] = true
+}
+
+// 记录释放的空间
+func (n *RunNotices) reclaim(bytes int64) {
+	if n == nil {
+		return
+	}
+	n.summary.Reclaimed += bytes
+}
+
+// 发送本次执行操作的结果，没有任何操作成功或失败时不发送
+func (n *RunNotices) send() {
+	if n == nil {
+		return
+	}
+	summary := n.summary
+	if summary.Paused+summary.Deleted+summary.Other+summary.Failed == 0 {
+		return
+	}
+	summary.Time = time.Now()
+	summary.SessionRenegotiations = sessionRenegotiations.Load() - n.renegotiationsBefore
+	for name := range n.groups {
+		summary.Groups = append(summary.Groups, name)
+	}
+	sort.Strings(summary.Groups)
+	for _, notifier := range n.notifiers {
+		if err := notifier.Notify(summary); err != nil {
+			log.Printf("发送%s通知失败: %v", notifier.Name(), err)
+		}
+	}
+}
+
+// 种子的大小（字节），未知时为0
+func torrentBytes(torrent *transmissionrpc.Torrent) int64 {
+	if torrent == nil || torrent.SizeWhenDone == nil {
+		return 0
+	}
+	return int64((*torrent.SizeWhenDone).Byte())
+}
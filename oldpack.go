@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 旧版合集的操作，与普通分集分开执行
+const (
+	ACTION_OLD_PACK_PAUSE  = "old-pack-pause"  // 暂停旧版合集
+	ACTION_OLD_PACK_DELETE = "old-pack-delete" // 删除旧版合集及其数据
+)
+
+// --old-pack-action 的可选值
+const (
+	OLD_PACK_PAUSE  = "pause"
+	OLD_PACK_DELETE = "delete"
+	OLD_PACK_SKIP   = "skip"
+)
+
+// 旧版合集显示的策略名称
+const OLD_PACK_POLICY = "旧版合集"
+
+// 分集的操作是否为暂停
+func pausesEpisode(action string) bool {
+	return action == ACTION_PAUSE || action == ACTION_OLD_PACK_PAUSE
+}
+
+// 内容文件中的剧集标识集合
+func episodeMarkerSet(files []*transmissionrpc.TorrentFile) map[string]bool {
+	markers := make(map[string]bool)
+	for _, file := range contentFiles(files) {
+		if marker := extractEpisodeMarker(getFileName(file.Name)); marker != "" {
+			markers[marker] = true
+		}
+	}
+	return markers
+}
+
+// a 是否为 b 的真子集
+func strictSubset(a, b map[string]bool) bool {
+	if len(a) >= len(b) {
+		return false
+	}
+	for marker := range a {
+		if !b[marker] {
+			return false
+		}
+	}
+	return true
+}
+
+// 找出组内的旧版合集：连载中的剧每周发布新的合集，上周的合集会被识别为本周合集的分集。
+// 分集包含至少两个剧集标识且是合集剧集标识的真子集时视为旧版合集，按 --old-pack-action 处理，
+// 使用单独的操作，不与普通分集一起执行
+func applyOldPacks(client *transmissionrpc.Client, result *ScanResult, oldPackAction string) {
+	for name, group := range result.DuplicateGroups {
+		collectionFiles, err := getTorrentFiles(client, group.Collection.ID)
+		if err != nil {
+			continue
+		}
+		collectionMarkers := episodeMarkerSet(collectionFiles)
+		if len(collectionMarkers) < 2 {
+			continue
+		}
+
+		var remaining []*transmissionrpc.Torrent
+		for _, episode := range group.Episodes {
+			if episode == nil || episode.ID == nil {
+				remaining = append(remaining, episode)
+				continue
+			}
+			files, err := getTorrentFiles(client, episode.ID)
+			if err != nil {
+				remaining = append(remaining, episode)
+				continue
+			}
+			markers := episodeMarkerSet(files)
+			if len(markers) < 2 || !strictSubset(markers, collectionMarkers) {
+				remaining = append(remaining, episode)
+				continue
+			}
+
+			policy := fmt.Sprintf("%s（%d 集，合集 %d 集）", OLD_PACK_POLICY, len(markers), len(collectionMarkers))
+			if oldPackAction == OLD_PACK_SKIP {
+				group.GatedEpisodes = append(group.GatedEpisodes, GatedEpisode{
+					Episode: episode,
+					Policy:  policy,
+					Reason:  "--old-pack-action skip",
+				})
+				continue
+			}
+			if group.EpisodeActions == nil {
+				group.EpisodeActions = make(map[int64]string)
+			}
+			if group.EpisodePolicies == nil {
+				group.EpisodePolicies = make(map[int64]string)
+			}
+			if group.OldPacks == nil {
+				group.OldPacks = make(map[int64]bool)
+			}
+			action := ACTION_OLD_PACK_PAUSE
+			if oldPackAction == OLD_PACK_DELETE {
+				action = ACTION_OLD_PACK_DELETE
+			}
+			group.EpisodeActions[*episode.ID] = action
+			group.EpisodePolicies[*episode.ID] = policy
+			group.OldPacks[*episode.ID] = true
+			remaining = append(remaining, episode)
+		}
+
+		group.Episodes = remaining
+		if len(remaining) == 0 {
+			// 只有旧版合集且指定跳过，移到仅供参考的部分
+			delete(result.DuplicateGroups, name)
+			result.GatedGroups[name] = group
+			continue
+		}
+		result.DuplicateGroups[name] = group
+	}
+}
+
+// 种子数据的位置
+func torrentDataPath(torrent *transmissionrpc.Torrent) string {
+	if torrent == nil || torrent.DownloadDir == nil || torrent.Name == nil {
+		return ""
+	}
+	return filepath.Clean(filepath.Join(*torrent.DownloadDir, *torrent.Name))
+}
+
+// 删除分集种子时是否同时删除其数据：数据与合集位于同一位置时只删除种子
+func deletesEpisodeFiles(group DuplicateGroup, episode *transmissionrpc.Torrent) bool {
+	dataPath := torrentDataPath(episode)
+	return dataPath != "" && dataPath != torrentDataPath(group.Collection)
+}
+
+// 删除分集种子（旧版合集或审阅文件中指定删除的分集），数据与合集位于同一位置时只删除种子、保留数据，
+// target 为输出中分集的名称，返回成功和失败的数量
+func removeEpisodeTorrents(ctx context.Context, client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup, throttle *ActionThrottle, target string) (int, int) {
+	successCount, failedCount := 0, 0
+	for _, groupName := range sortedGroupNames(duplicateGroups) {
+		group := duplicateGroups[groupName]
+		packs := throttle.take(ctx, groupName, group.Episodes)
+		for i, pack := range packs {
+			if !throttle.wait(ctx) {
+				throttle.deferRest(groupName, packs[i:], DEFERRED_INTERRUPTED)
+				break
+			}
+			deleteData := deletesEpisodeFiles(group, pack)
+			removeCtx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+			err := client.TorrentRemove(removeCtx, transmissionrpc.TorrentRemovePayload{
+				IDs:             []int64{*pack.ID},
+				DeleteLocalData: deleteData,
+			})
+			cancel()
+			if err != nil {
+				fmt.Printf("删除%s失败 ID: %d: %v\n", target, *pack.ID, err)
+				failedCount++
+				continue
+			}
+			if deleteData {
+				fmt.Printf("已删除%s及其数据 ID: %d\n", target, *pack.ID)
+				runNotices.reclaim(torrentBytes(pack))
+			} else {
+				fmt.Printf("已删除%s ID: %d（数据与合集位于同一位置，已保留）\n", target, *pack.ID)
+			}
+			keptExport.succeed(groupName)
+			runNotices.succeed(groupName)
+			successCount++
+		}
+	}
+	return successCount, failedCount
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 每集一个文件的合集，episodes 为剧集序号
+func packFiles(episodes ...int) []testFile {
+	files := make([]testFile, len(episodes))
+	for i, episode := range episodes {
+		files[i] = testFile{fmt.Sprintf("Show.S01/Show.S01E%02d.mkv", episode), 1000}
+	}
+	return files
+}
+
+// 上周的 4 集合集包含在本周的 6 集合集中，识别为旧版合集，与普通分集分开执行
+func TestApplyOldPacks(t *testing.T) {
+	t.Cleanup(resetTorrentFilesCache)
+	for _, oldPackAction := range []string{OLD_PACK_PAUSE, OLD_PACK_DELETE} {
+		t.Run(oldPackAction, func(t *testing.T) {
+			files := map[int64][]testFile{1: packFiles(1, 2, 3, 4, 5, 6), 2: packFiles(1, 2, 3, 4), 3: packFiles(5)}
+			for id, list := range files {
+				cacheTorrentFiles(id, torrentFiles(list...))
+			}
+			collection := testTorrent(1, "Show.S01", files[1]...)
+			oldPack := testTorrent(2, "Show.S01", files[2]...)
+			single := testTorrent(3, "Show.S01", files[3]...)
+			result := &ScanResult{
+				DuplicateGroups: map[string]DuplicateGroup{"Show.S01": {Collection: collection, Episodes: []*transmissionrpc.Torrent{oldPack, single}}},
+				GatedGroups:     make(map[string]DuplicateGroup),
+			}
+			applyOldPacks(nil, result, oldPackAction)
+
+			group := result.DuplicateGroups["Show.S01"]
+			if !group.OldPacks[2] || group.OldPacks[3] {
+				t.Fatalf("旧版合集 %v，应只有 ID 2", group.OldPacks)
+			}
+			if got, want := group.EpisodePolicies[2], "旧版合集（4 集，合集 6 集）"; got != want {
+				t.Errorf("策略 %q，应为 %q", got, want)
+			}
+
+			wantOldPack := ACTION_OLD_PACK_PAUSE
+			if oldPackAction == OLD_PACK_DELETE {
+				wantOldPack = ACTION_OLD_PACK_DELETE
+			}
+			for _, action := range []string{ACTION_PAUSE, ACTION_LINK} {
+				for _, bucket := range splitGroupsByAction(result.DuplicateGroups, action) {
+					ids := splitIDs(bucket.Groups)["Show.S01"]
+					if len(ids) != 1 {
+						t.Errorf("全局操作 %s: 操作 %s 中有分集 %v，旧版合集不能与普通分集混在一起", action, bucket.Action, ids)
+						continue
+					}
+					want := action
+					if ids[0] == 2 {
+						want = wantOldPack
+					}
+					if bucket.Action != want {
+						t.Errorf("全局操作 %s: ID %d 的操作为 %s，应为 %s", action, ids[0], bucket.Action, want)
+					}
+				}
+			}
+		})
+	}
+}
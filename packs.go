@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 两个合集的剧集覆盖重合度达到该值时视为重复合集
+const PACK_OVERLAP_THRESHOLD = 0.9
+
+// 从种子名称中识别剧名和季，如 Show.Name.S01.1080p 或 Show Name Season 1
+var packSeasonRegex = regexp.MustCompile(`(?i)^(.+?)[ ._\-\[]+(?:s(\d{1,2})|season[ ._]?(\d{1,2}))(?:[ ._\-\]]|$)`)
+
+// 一个完整的季合集及其包含的剧集标识
+type SeasonPack struct {
+	Torrent *transmissionrpc.Torrent
+	Markers map[string]bool
+}
+
+// 剧集覆盖重合的两个合集
+type PackPair struct {
+	Key     string // 剧名和季，如 "show name S01"
+	First   SeasonPack
+	Second  SeasonPack
+	Overlap float64 // 两者剧集标识的交集占并集的比例
+}
+
+// 合集的分组键：剧名（统一大小写和分隔符）加季，名称中包含单集标识或无法识别季时返回空
+func packKey(name string) string {
+	if episodeRegex.MatchString(name) {
+		return ""
+	}
+	matches := packSeasonRegex.FindStringSubmatch(canonicalName(name))
+	if matches == nil {
+		return ""
+	}
+	season := matches[2]
+	if season == "" {
+		season = matches[3]
+	}
+	title := strings.ToLower(strings.NewReplacer(".", " ", "_", " ", "-", " ").Replace(matches[1]))
+	return fmt.Sprintf("%s S%s", strings.Join(strings.Fields(title), " "), padNumber(season))
+}
+
+// 查找重复的季合集：按剧名和季分组，比较各合集内容文件的剧集标识覆盖范围，与合集-分集的判断完全独立
+func findPackDuplicates(client *transmissionrpc.Client, torrents []transmissionrpc.Torrent) []PackPair {
+	candidates := make(map[string][]*transmissionrpc.Torrent)
+	for i := range torrents {
+		if torrents[i].Name == nil || torrents[i].ID == nil {
+			continue
+		}
+		if key := packKey(*torrents[i].Name); key != "" {
+			candidates[key] = append(candidates[key], &torrents[i])
+		}
+	}
+
+	var pairs []PackPair
+	for key, group := range candidates {
+		if len(group) < 2 {
+			continue
+		}
+
+		// 只有包含至少两个不同剧集标识的种子才视为合集
+		var packs []SeasonPack
+		for _, torrent := range group {
+			files, err := getTorrentFiles(client, torrent.ID)
+			if err != nil {
+				log.Printf("获取种子 ID: %d 文件列表失败: %v", *torrent.ID, err)
+				continue
+			}
+			markers := make(map[string]bool)
+			for _, file := range contentFiles(files) {
+				if marker := extractEpisodeMarker(getFileName(file.Name)); marker != "" {
+					markers[marker] = true
+				}
+			}
+			if len(markers) >= 2 {
+				packs = append(packs, SeasonPack{Torrent: torrent, Markers: markers})
+			}
+		}
+
+		for i := 0; i < len(packs); i++ {
+			for j := i + 1; j < len(packs); j++ {
+				if overlap := markerOverlap(packs[i].Markers, packs[j].Markers); overlap >= PACK_OVERLAP_THRESHOLD {
+					pairs = append(pairs, PackPair{Key: key, First: packs[i], Second: packs[j], Overlap: overlap})
+				}
+			}
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Key != pairs[j].Key {
+			return pairs[i].Key < pairs[j].Key
+		}
+		return *pairs[i].First.Torrent.ID < *pairs[j].First.Torrent.ID
+	})
+	return pairs
+}
+
+// 两组剧集标识的交集占并集的比例
+func markerOverlap(a, b map[string]bool) float64 {
+	common := 0
+	for marker := range a {
+		if b[marker] {
+			common++
+		}
+	}
+	union := len(a) + len(b) - common
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+// 显示重复的季合集
+func printPackDuplicates(pairs []PackPair) {
+	fmt.Printf("\n===== 重复的季合集（%d 对，仅供参考，不会自动处理）=====\n", len(pairs))
+	if len(pairs) == 0 {
+		fmt.Println("无")
+		return
+	}
+	for i, pair := range pairs {
+		fmt.Printf("\n%d. %s，剧集覆盖重合 %.0f%%\n", i+1, pair.Key, pair.Overlap*100)
+		for _, pack := range []SeasonPack{pair.First, pair.Second} {
+			fmt.Printf("  ID: %d, %s, 大小: %s, 剧集: %d 个\n", *pack.Torrent.ID, *pack.Torrent.Name, formatSize(torrentBytes(pack.Torrent)), len(pack.Markers))
+		}
+		if second := torrentBytes(pair.Second.Torrent); second > 0 {
+			fmt.Printf("  大小比例: %.2f\n", float64(torrentBytes(pair.First.Torrent))/float64(second))
+		}
+	}
+}
+
+// 交互选择要暂停的重复合集，只能在交互模式下手动选择，不会自动处理
+func selectPackDuplicates(reader *bufio.Reader, client *transmissionrpc.Client, pairs []PackPair, history *HistoryWriter) {
+	candidates := make(map[int64]PackPair)
+	for _, pair := range pairs {
+		candidates[*pair.First.Torrent.ID] = pair
+		candidates[*pair.Second.Torrent.ID] = pair
+	}
+
+	fmt.Print("\n输入要暂停的合集ID（多个以,分隔，直接回车跳过）: ")
+	input, _ := reader.ReadString('\n')
+	input = strings.TrimSpace(input)
+	if input == "" {
+		return
+	}
+
+	for _, field := range strings.Split(input, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64)
+		pair, ok := candidates[id]
+		if err != nil || !ok {
+			fmt.Printf("无效的合集ID: %s\n", strings.TrimSpace(field))
+			continue
+		}
+		torrent := pair.First.Torrent
+		if *torrent.ID != id {
+			torrent = pair.Second.Torrent
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+		err = client.TorrentStopIDs(ctx, []int64{id})
+		cancel()
+		if err != nil {
+			fmt.Printf("暂停合集失败 ID: %d: %v\n", id, err)
+			continue
+		}
+		recordPause(history, pair.Key, torrent)
+		fmt.Printf("成功暂停合集 ID: %d\n", id)
+	}
+}
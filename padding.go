@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 默认的填充文件规则：BitComet 的填充文件和 BitTorrent v2 混合种子的 .pad 目录
+var defaultPaddingPatterns = []string{`_____padding_file.*`, `(^|/)\.pad/`}
+
+// 当前生效的填充文件规则，匹配文件在种子中的完整路径
+var paddingPatterns = mustCompilePaddingPatterns(defaultPaddingPatterns)
+
+// 编译填充文件规则
+func compilePaddingPatterns(specs []string) ([]*regexp.Regexp, error) {
+	var patterns []*regexp.Regexp
+	for _, spec := range specs {
+		pattern, err := regexp.Compile(spec)
+		if err != nil {
+			return nil, fmt.Errorf("填充文件规则 %s 的正则无效: %v", spec, err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+func mustCompilePaddingPatterns(specs []string) []*regexp.Regexp {
+	patterns, err := compilePaddingPatterns(specs)
+	if err != nil {
+		panic(err)
+	}
+	return patterns
+}
+
+// 设置填充文件规则，替换默认规则
+func setPaddingPatterns(patterns []*regexp.Regexp) {
+	paddingPatterns = patterns
+}
+
+// 判断是否为填充文件：匹配填充文件规则或大小为0。这些文件不是内容，
+// 比较文件重叠和计算大小时都不计入
+func isPaddingFile(file *transmissionrpc.TorrentFile) bool {
+	if file.Length == 0 {
+		return true
+	}
+	for _, pattern := range paddingPatterns {
+		if pattern.MatchString(file.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// 去掉填充文件，返回剩余的文件和去掉的数量
+func withoutPadding(files []*transmissionrpc.TorrentFile) ([]*transmissionrpc.TorrentFile, int) {
+	var result []*transmissionrpc.TorrentFile
+	excluded := 0
+	for _, file := range files {
+		if isPaddingFile(file) {
+			excluded++
+			continue
+		}
+		result = append(result, file)
+	}
+	return result, excluded
+}
+
+// 文件列表中填充文件的大小之和（字节）
+func paddingBytes(files []*transmissionrpc.TorrentFile) int64 {
+	var total int64
+	for _, file := range files {
+		if isPaddingFile(file) {
+			total += file.Length
+		}
+	}
+	return total
+}
+
+// 种子去掉填充文件后的大小（字节），padding 为各种子填充文件的大小，没有记录的种子按没有填充文件计算
+func contentBytes(torrent *transmissionrpc.Torrent, padding map[int64]int64) int64 {
+	if torrent == nil || torrent.ID == nil {
+		return torrentBytes(torrent)
+	}
+	return torrentBytes(torrent) - padding[*torrent.ID]
+}
+
+// 组内种子去掉填充文件后的大小（字节），填充文件的大小来自收集证据时的文件列表，
+// 用于可释放空间，不受之后是否获取过文件列表影响
+func (g DuplicateGroup) contentBytes(torrent *transmissionrpc.Torrent) int64 {
+	return contentBytes(torrent, g.Evidence.PaddingBytes)
+}
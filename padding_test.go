@@ -0,0 +1,59 @@
+package main
+
+import "testing"
+
+// hybrid.json: 合集 ID 1 和分集 ID 2、3 都是带填充文件的混合种子，
+// 分集的填充文件计入大小时分集之和会超过合集
+func TestHybridPaddingFixture(t *testing.T) {
+	const name = "Show.H.S01.1080p.WEB"
+	const mb = 1024 * 1024
+	_, result, _ := scanFixture(t, "hybrid.json")
+	group, ok := result.DuplicateGroups[name]
+	if !ok {
+		t.Fatalf("没有找到需要处理的组，分集大小之和超过合集的组: %v", sortedGroupNames(result.OversizedGroups))
+	}
+	if got := splitIDs(result.DuplicateGroups)[name]; len(got) != 2 {
+		t.Fatalf("分集 %v，应为 ID 2 和 3", got)
+	}
+	if !group.Evidence.SizeConsistent {
+		t.Error("去掉填充文件后分集大小之和不超过合集")
+	}
+	if group.Evidence.PaddingFiles != 4 {
+		t.Errorf("排除填充文件 %d 个，应为合集 2 个、分集各 1 个", group.Evidence.PaddingFiles)
+	}
+	wantPadding := map[int64]int64{1: 5 * mb, 2: 900 * mb, 3: 900 * mb}
+	for id, want := range wantPadding {
+		if got := group.Evidence.PaddingBytes[id]; got != want {
+			t.Errorf("ID %d 的填充文件 %d 字节，应为 %d 字节", id, got, want)
+		}
+	}
+
+	// 可释放空间只取决于收集证据时的文件列表，与之后是否获取过文件列表无关
+	want := int64((601 + 602) * mb)
+	if got := groupReclaimBytes(group); got != want {
+		t.Errorf("可释放 %d 字节，应为 %d 字节", got, want)
+	}
+	resetTorrentFilesCache()
+	if got := groupReclaimBytes(group); got != want {
+		t.Errorf("清空文件列表缓存后可释放 %d 字节，应为 %d 字节", got, want)
+	}
+}
+
+func TestPaddingBytes(t *testing.T) {
+	files := torrentFiles(
+		testFile{"Show/E01.mkv", 1000},
+		testFile{"Show/.pad/0", 24},
+		testFile{"Show/_____padding_file_1_", 8},
+		testFile{"Show/empty.txt", 0},
+	)
+	if got := paddingBytes(files); got != 32 {
+		t.Errorf("paddingBytes() = %d, want 32", got)
+	}
+	torrent := testTorrent(1, "Show", testFile{"Show/E01.mkv", 1032})
+	if got := contentBytes(torrent, map[int64]int64{1: 32}); got != 1000 {
+		t.Errorf("contentBytes() = %d, want 1000", got)
+	}
+	if got := contentBytes(torrent, nil); got != 1032 {
+		t.Errorf("没有记录填充文件时 contentBytes() = %d, want 1032", got)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 用于识别剧集号的正则表达式
+var episodeRegex = regexp.MustCompile(`[Ss](\d+)[Ee](\d+)`)
+
+// 一条剧集标识规则，自定义规则通过命名分组 season/episode/date 提取标识
+type EpisodePattern struct {
+	Name    string
+	Regex   *regexp.Regexp
+	BuiltIn bool
+}
+
+// 内置的剧集标识规则
+var builtinEpisodePatterns = []EpisodePattern{
+	{Name: "SxxEyy", Regex: episodeRegex, BuiltIn: true},
+}
+
+// 当前生效的剧集标识规则，内置规则在前，自定义规则按指定顺序在后
+var episodePatterns = builtinEpisodePatterns
+
+// 解析 "名称=正则" 格式的自定义剧集标识规则
+func parseEpisodePattern(spec string) (EpisodePattern, error) {
+	name, expr, found := strings.Cut(spec, "=")
+	name = strings.TrimSpace(name)
+	if !found || name == "" || expr == "" {
+		return EpisodePattern{}, fmt.Errorf("剧集标识规则格式应为 名称=正则: %s", spec)
+	}
+
+	regex, err := regexp.Compile(expr)
+	if err != nil {
+		return EpisodePattern{}, fmt.Errorf("剧集标识规则 %s 的正则无效: %v", name, err)
+	}
+
+	hasEpisode, hasDate := false, false
+	for _, group := range regex.SubexpNames() {
+		switch group {
+		case "episode":
+			hasEpisode = true
+		case "date":
+			hasDate = true
+		}
+	}
+	if !hasEpisode && !hasDate {
+		return EpisodePattern{}, fmt.Errorf("剧集标识规则 %s 缺少命名分组 (?P<episode>...) 或 (?P<date>...)", name)
+	}
+
+	return EpisodePattern{Name: name, Regex: regex}, nil
+}
+
+// 设置自定义剧集标识规则，每次都从内置规则重新生成，重复解析参数时不会累积
+func setEpisodePatterns(patterns []EpisodePattern) {
+	episodePatterns = append(append([]EpisodePattern{}, builtinEpisodePatterns...), patterns...)
+}
+
+// 用单条规则匹配文件名，返回剧集标识
+func (p EpisodePattern) match(filename string) string {
+	matches := p.Regex.FindStringSubmatch(filename)
+	if matches == nil {
+		return ""
+	}
+	if p.BuiltIn {
+		if len(matches) >= 3 {
+			// 统一为 S01E01 的格式，如 s1e5 与 S01E05 视为相同
+			return fmt.Sprintf("S%sE%s", padNumber(matches[1]), padNumber(matches[2]))
+		}
+		return ""
+	}
+
+	var season, episode, date string
+	for i, group := range p.Regex.SubexpNames() {
+		switch group {
+		case "season":
+			season = matches[i]
+		case "episode":
+			episode = matches[i]
+		case "date":
+			date = matches[i]
+		}
+	}
+
+	// 日期类标识统一分隔符，如 2024.03.01 与 2024-03-01 视为相同
+	if date != "" {
+		return "D" + strings.NewReplacer(".", "-", "_", "-", " ", "-").Replace(date)
+	}
+	if episode == "" {
+		return ""
+	}
+	if season != "" {
+		return fmt.Sprintf("S%sE%s", padNumber(season), padNumber(episode))
+	}
+	return fmt.Sprintf("%s:E%s", p.Name, padNumber(episode))
+}
+
+// 数字补齐两位，非数字原样返回
+func padNumber(value string) string {
+	number, err := strconv.Atoi(value)
+	if err != nil {
+		return value
+	}
+	return fmt.Sprintf("%02d", number)
+}
+
+// 提取文件名中的剧集标识（如S01E01），依次尝试所有规则
+func extractEpisodeMarker(filename string) string {
+	marker, _ := matchEpisodeMarker(filename)
+	return marker
+}
+
+// 提取剧集标识并返回匹配的规则名称
+func matchEpisodeMarker(filename string) (string, string) {
+	for _, pattern := range episodePatterns {
+		if marker := pattern.match(filename); marker != "" {
+			return marker, pattern.Name
+		}
+	}
+	return "", ""
+}
+
+// 测试模式：显示每条规则对文件名的匹配结果
+func runTestPattern(filename string) {
+	fmt.Printf("文件名: %s\n", filename)
+	for _, pattern := range episodePatterns {
+		kind := "自定义"
+		if pattern.BuiltIn {
+			kind = "内置"
+		}
+		if marker := pattern.match(filename); marker != "" {
+			fmt.Printf("  [%s] %s: 匹配，标识: %s\n", kind, pattern.Name, marker)
+		} else {
+			fmt.Printf("  [%s] %s: 不匹配\n", kind, pattern.Name)
+		}
+	}
+
+	marker, name := matchEpisodeMarker(filename)
+	if marker == "" {
+		fmt.Println("结果: 未识别到剧集标识")
+		return
+	}
+	fmt.Printf("结果: 使用规则 %s，剧集标识 %s\n", name, marker)
+}
@@ -0,0 +1,55 @@
+package main
+
+import "testing"
+
+func TestExtractEpisodeMarker(t *testing.T) {
+	t.Cleanup(func() { setEpisodePatterns(nil) })
+	var patterns []EpisodePattern
+	for _, spec := range []string{
+		`part=(?i)part[ ._]?(?P<episode>\d+)`,
+		`date=(?P<date>\d{4}[.-]\d{2}[.-]\d{2})`,
+		`sxe=(?P<season>\d+)x(?P<episode>\d+)`,
+	} {
+		pattern, err := parseEpisodePattern(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	setEpisodePatterns(patterns)
+
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"Show.S01E05.1080p.mkv", "S01E05"},
+		{"Show.s1e5.1080p.mkv", "S01E05"},
+		{"Show.S1E5.mkv", "S01E05"},
+		{"Show.s01e105.mkv", "S01E105"},
+		{"Show.1x05.mkv", "S01E05"},
+		{"Show.Part.2.mkv", "part:E02"},
+		{"Race.2024.03.01.mkv", "D2024-03-01"},
+		{"Race.2024-03-01.mkv", "D2024-03-01"},
+		{"Show.1080p.mkv", ""},
+	}
+	for _, tt := range tests {
+		if got := extractEpisodeMarker(tt.filename); got != tt.want {
+			t.Errorf("extractEpisodeMarker(%q) = %q, want %q", tt.filename, got, tt.want)
+		}
+	}
+}
+
+// 重复解析参数时自定义规则不会累积
+func TestSetEpisodePatternsReplaces(t *testing.T) {
+	t.Cleanup(func() { parseOptions(nil) })
+	args := []string{"--episode-pattern", `part=(?P<episode>\d+)`}
+	parseOptions(args)
+	parseOptions(args)
+	if len(episodePatterns) != 2 || !episodePatterns[0].BuiltIn || episodePatterns[1].Name != "part" {
+		t.Errorf("两次解析后有 %d 条规则，应为内置规则和 part", len(episodePatterns))
+	}
+	parseOptions(nil)
+	if len(episodePatterns) != len(builtinEpisodePatterns) {
+		t.Errorf("没有自定义规则时有 %d 条规则，应只有内置规则", len(episodePatterns))
+	}
+}
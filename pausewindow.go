@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 守护模式的暂停时段：时段内暂停分集，时段结束时重新开始本工具在时段内暂停的分集
+type PauseWindow struct {
+	Start    int // 开始时间，从0点起的分钟数
+	End      int // 结束时间，早于开始时间时跨过0点
+	Location *time.Location
+}
+
+// 解析暂停时段，如 01:00-07:00 或跨过0点的 22:00-06:00；tz 为空时使用本地时区
+func parsePauseWindow(value, tz string) (*PauseWindow, error) {
+	startText, endText, ok := strings.Cut(strings.TrimSpace(value), "-")
+	if !ok {
+		return nil, fmt.Errorf("无效的暂停时段: %s（示例: 01:00-07:00）", value)
+	}
+	start, err := time.Parse("15:04", strings.TrimSpace(startText))
+	if err != nil {
+		return nil, fmt.Errorf("无效的暂停时段开始时间: %s", startText)
+	}
+	end, err := time.Parse("15:04", strings.TrimSpace(endText))
+	if err != nil {
+		return nil, fmt.Errorf("无效的暂停时段结束时间: %s", endText)
+	}
+	window := &PauseWindow{
+		Start:    start.Hour()*60 + start.Minute(),
+		End:      end.Hour()*60 + end.Minute(),
+		Location: time.Local,
+	}
+	if window.Start == window.End {
+		return nil, fmt.Errorf("暂停时段的开始和结束时间不能相同: %s", value)
+	}
+	if tz != "" {
+		location, err := time.LoadLocation(tz)
+		if err != nil {
+			return nil, fmt.Errorf("无效的时区: %s: %v", tz, err)
+		}
+		window.Location = location
+	}
+	return window, nil
+}
+
+// 时间在时段内的分钟数
+func (w *PauseWindow) minuteOf(t time.Time) int {
+	local := t.In(w.Location)
+	return local.Hour()*60 + local.Minute()
+}
+
+// 时间是否在暂停时段内
+func (w *PauseWindow) contains(t time.Time) bool {
+	minute := w.minuteOf(t)
+	if w.Start < w.End {
+		return minute >= w.Start && minute < w.End
+	}
+	return minute >= w.Start || minute < w.End
+}
+
+// 到下一次时段开始或结束的时间，守护模式按此缩短等待，使暂停和重新开始发生在时段边界
+func (w *PauseWindow) untilNextBoundary(now time.Time) time.Duration {
+	local := now.In(w.Location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, w.Location)
+	var next time.Time
+	for _, day := range []int{0, 1} {
+		for _, minute := range []int{w.Start, w.End} {
+			boundary := midnight.AddDate(0, 0, day).Add(time.Duration(minute) * time.Minute)
+			if boundary.After(local) && (next.IsZero() || boundary.Before(next)) {
+				next = boundary
+			}
+		}
+	}
+	return next.Sub(local)
+}
+
+func (w *PauseWindow) describe() string {
+	return fmt.Sprintf("%02d:%02d-%02d:%02d（%s）", w.Start/60, w.Start%60, w.End/60, w.End%60, w.Location)
+}
+
+// 暂停时段内本工具暂停的分集hash，跨轮次保存在状态目录中；
+// 只记录暂停前正在运行的分集，用户自己暂停的种子不会被重新开始
+type WindowPauses struct {
+	path   string
+	hashes map[string]bool
+}
+
+// 守护模式的暂停时段记录，未指定 --pause-window 时为nil，nil记录不记录任何内容
+var windowPauses *WindowPauses
+
+// 暂停时段记录文件路径
+func windowPausesPath() string {
+	return filepath.Join(stateDir(), "pause-window.json")
+}
+
+// 开启暂停时段记录，读取上次运行保存的记录
+func enableWindowPauses() {
+	windowPauses = &WindowPauses{path: windowPausesPath(), hashes: make(map[string]bool)}
+	data, err := os.ReadFile(windowPauses.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("读取暂停时段记录失败: %v", err)
+		}
+		return
+	}
+	var hashes []string
+	if err := json.Unmarshal(data, &hashes); err != nil {
+		log.Printf("读取暂停时段记录失败: %v", err)
+		return
+	}
+	for _, hash := range hashes {
+		windowPauses.hashes[hash] = true
+	}
+}
+
+// 记录本工具暂停的分集
+func (w *WindowPauses) add(torrent *transmissionrpc.Torrent) {
+	if w == nil || torrent.HashString == nil {
+		return
+	}
+	w.hashes[*torrent.HashString] = true
+}
+
+// 保存记录，写入失败只打印警告
+func (w *WindowPauses) save() {
+	if w == nil {
+		return
+	}
+	hashes := make([]string, 0, len(w.hashes))
+	for hash := range w.hashes {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	data, err := json.Marshal(hashes)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(w.path), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(w.path, data, 0o644)
+	}
+	if err != nil {
+		log.Printf("保存暂停时段记录失败: %v", err)
+	}
+}
+
+// 时段结束后重新开始记录中仍处于停止状态的分集，已不存在或已被重新开始的分集直接从记录中去掉，
+// 重新开始失败的分集保留在记录中，下一轮再试；返回重新开始的数量
+func (w *WindowPauses) resume(ctx context.Context, client *transmissionrpc.Client, dryRun bool) int {
+	if w == nil || len(w.hashes) == 0 {
+		return 0
+	}
+	hashes := make([]string, 0, len(w.hashes))
+	for hash := range w.hashes {
+		hashes = append(hashes, hash)
+	}
+	sort.Strings(hashes)
+	queryCtx, cancel := context.WithTimeout(ctx, timeouts.Query)
+	torrents, err := client.TorrentGetHashes(queryCtx, []string{"id", "name", "hashString", "status"}, hashes)
+	cancel()
+	if err != nil {
+		log.Printf("查询暂停时段内暂停的分集失败，下一轮再试: %v", err)
+		return 0
+	}
+
+	fmt.Printf("\n暂停时段已结束，重新开始时段内暂停的 %d 个分集\n", len(hashes))
+	remaining := make(map[string]bool)
+	resumed := 0
+	for i := range torrents {
+		torrent := &torrents[i]
+		if torrent.ID == nil || torrent.HashString == nil || !alreadyStopped(torrent) {
+			continue
+		}
+		if dryRun {
+			fmt.Printf("试运行: 将重新开始分集 ID: %d\n", *torrent.ID)
+			remaining[*torrent.HashString] = true
+			continue
+		}
+		startCtx, cancel := context.WithTimeout(ctx, timeouts.Action)
+		err := client.TorrentStartIDs(startCtx, []int64{*torrent.ID})
+		cancel()
+		if err != nil {
+			fmt.Printf("重新开始分集 ID: %d 失败: %v\n", *torrent.ID, err)
+			remaining[*torrent.HashString] = true
+			continue
+		}
+		fmt.Printf("已重新开始分集 ID: %d\n", *torrent.ID)
+		resumed++
+	}
+	w.hashes = remaining
+	w.save()
+	return resumed
+}
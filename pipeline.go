@@ -0,0 +1,491 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// --parallel 的默认值：同时分析的种子组数量
+const DEFAULT_PARALLEL = 4
+
+// 分组分析的结论，决定组放入扫描结果的哪一部分
+const (
+	OUTCOME_NONE      = iota // 没有可报告的组（已跳过或没有分集）
+	OUTCOME_DUPLICATE        // 需要处理
+	OUTCOME_SAME_SIZE        // 只有大小相同的分集
+	OUTCOME_PARTIAL          // 只有部分包含的分集
+	OUTCOME_OVERSIZED        // 分集大小之和超过合集
+	OUTCOME_VARIANT          // 版本差异或合集与分集的大小比例异常，需人工确认
+)
+
+// 分集候选及其文件列表
+type memberFiles struct {
+	Torrent transmissionrpc.Torrent
+	Files   []*transmissionrpc.TorrentFile
+}
+
+// 一个名称分组在分析流水线中的状态，依次经过分组、获取文件、分类、评分和判定阶段；
+// Done 表示已经得出结论，之后的阶段直接传递
+type groupAnalysis struct {
+	Index int // 在按名称排序的分组中的位置，合并结果时按此排序
+	Name  string
+	Group []transmissionrpc.Torrent // 元数据已完成的种子
+	Done  bool
+
+	// 分组阶段
+	Sorted            []transmissionrpc.Torrent // 按大小从大到小排序，第一个为合集
+	SameSizeDuplicate *DuplicateGroup           // --same-size-action pause 时误重复添加的种子
+
+	// 获取文件阶段
+	CollectionFiles []*transmissionrpc.TorrentFile
+	Members         []memberFiles
+
+	// 分类阶段
+	Episodes         []*transmissionrpc.Torrent
+	SameSizeEpisodes []*transmissionrpc.Torrent
+	PartialEpisodes  []PartialEpisode
+	ActionableFiles  [][]*transmissionrpc.TorrentFile // 与 Episodes 一一对应的文件列表
+	HasFileOverlaps  bool
+
+	// 评分阶段
+	Evidence   GroupEvidence
+	Confidence float64
+
+	// 判定阶段
+	Outcome        int
+	Result         DuplicateGroup
+	Skipped        []SkipRecord
+	StatusExcluded []int64 // 按 --exclude-status 不作为分集的种子
+}
+
+// 记录跳过原因
+func (a *groupAnalysis) skip(record SkipRecord) {
+	a.Skipped = append(a.Skipped, record)
+}
+
+// 结束分析，组内剩余的种子记为指定原因跳过
+func (a *groupAnalysis) finish(record *SkipRecord) {
+	if record != nil {
+		a.skip(*record)
+	}
+	a.Done = true
+}
+
+// 运行流水线的一个阶段：parallel 个协程从 in 读取，work 处理未完成的组后发送到下一阶段；
+// work 返回 false 表示该组因取消未处理完，不再继续传递。取消后仍读完 in，上一阶段不会阻塞
+func runStage(ctx context.Context, parallel int, in <-chan *groupAnalysis, work func(*groupAnalysis) bool) <-chan *groupAnalysis {
+	out := make(chan *groupAnalysis)
+	var wg sync.WaitGroup
+	for i := 0; i < parallel; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range in {
+				if ctx.Err() != nil {
+					continue
+				}
+				if !a.Done && !work(a) {
+					continue
+				}
+				select {
+				case out <- a:
+				case <-ctx.Done():
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// 分组阶段：排除元数据未完成的种子，检查大小是否全部相同，按大小排序。不请求服务器
+func prepareGroup(a *groupAnalysis, collectionOnly map[int64]bool, opts Options) {
+	// 元数据未完成的种子（如磁力链接）还没有文件信息，推迟到下次扫描
+	var ready []transmissionrpc.Torrent
+	for i := range a.Group {
+		if metadataPending(&a.Group[i]) {
+			a.skip(SkipRecord{
+				Reason:   SKIP_METADATA_PENDING,
+				Name:     a.Name,
+				Detail:   fmt.Sprintf("ID: %s 元数据完成 %.0f%%，下次扫描时重新检查", torrentIDText(&a.Group[i]), *a.Group[i].MetadataPercentComplete*100),
+				Torrents: []*transmissionrpc.Torrent{&a.Group[i]},
+			})
+			continue
+		}
+		ready = append(ready, a.Group[i])
+	}
+	a.Group = ready
+	if len(a.Group) < 2 {
+		a.finish(nil)
+		return
+	}
+
+	// 检查所有种子大小是否相同
+	allSameSizes := true
+	var baseSize float64
+	if a.Group[0].SizeWhenDone != nil {
+		baseSize = (*a.Group[0].SizeWhenDone).Byte()
+	}
+	for i := 1; i < len(a.Group); i++ {
+		if a.Group[i].SizeWhenDone != nil {
+			// 如果发现大小不同（允许1KB以内的误差），标记为不同
+			if abs((*a.Group[i].SizeWhenDone).Byte()-baseSize) > 1024 {
+				allSameSizes = false
+				break
+			}
+		}
+	}
+
+	// 如果所有种子大小都相同，跳过这组种子
+	if allSameSizes {
+		// 同一tracker误重复添加的种子，保留上传量较高的一个
+		if opts.SameSizeAction == SAME_SIZE_PAUSE {
+			var duplicates []*transmissionrpc.Torrent
+			for i := range a.Group {
+				if !collectionOnly[*a.Group[i].ID] {
+					duplicates = append(duplicates, &a.Group[i])
+				}
+			}
+			if len(duplicates) > 1 && shareTracker(duplicates) {
+				keeper, victims, decision := selectSameSizeKeeper(duplicates)
+				// 保留的种子相当于合集，不受状态限制；被处理的种子按状态筛选
+				var eligible []*transmissionrpc.Torrent
+				for _, victim := range victims {
+					if excludedByStatus(victim, opts.ExcludeStatuses) {
+						a.StatusExcluded = append(a.StatusExcluded, *victim.ID)
+						continue
+					}
+					eligible = append(eligible, victim)
+				}
+				if len(eligible) > 0 {
+					a.SameSizeDuplicate = &DuplicateGroup{
+						Collection:        keeper,
+						Episodes:          eligible,
+						SameSizeDuplicate: true,
+						Decision:          decision,
+					}
+					return
+				}
+			}
+		}
+		a.finish(&SkipRecord{
+			Reason:   SKIP_SAME_SIZE,
+			Name:     a.Name,
+			Detail:   fmt.Sprintf("大小: %s", formatSize(int64(baseSize))),
+			Torrents: torrentPointers(a.Group),
+		})
+		return
+	}
+
+	// 排序：按大小从大到小排序（合集通常比分集大）
+	a.Sorted = make([]transmissionrpc.Torrent, len(a.Group))
+	copy(a.Sorted, a.Group)
+	for i := 0; i < len(a.Sorted); i++ {
+		for j := i + 1; j < len(a.Sorted); j++ {
+			if a.Sorted[i].SizeWhenDone != nil && a.Sorted[j].SizeWhenDone != nil {
+				if (*a.Sorted[i].SizeWhenDone).Byte() < (*a.Sorted[j].SizeWhenDone).Byte() {
+					a.Sorted[i], a.Sorted[j] = a.Sorted[j], a.Sorted[i]
+				}
+			}
+		}
+	}
+}
+
+// 获取文件阶段：获取合集（假设最大的种子是合集）和各分集候选的文件列表。
+// 每获取一个分集前检查是否已取消，已取消时返回 false
+func fetchGroupFiles(ctx context.Context, client *transmissionrpc.Client, a *groupAnalysis, collectionOnly map[int64]bool, opts Options) bool {
+	if a.SameSizeDuplicate != nil {
+		return true
+	}
+	var collection transmissionrpc.Torrent
+	var collectionFiles []*transmissionrpc.TorrentFile
+	for {
+		collection = a.Sorted[0]
+
+		// 获取合集的文件列表，临时失败时重试
+		files, reason, err := getCollectionFiles(client, *collection.ID)
+		if err != nil {
+			log.Printf("获取种子 ID: %d 文件列表失败: %v", *collection.ID, err)
+			a.finish(&SkipRecord{Reason: reason, Name: a.Name, Detail: err.Error(), Torrents: []*transmissionrpc.Torrent{&collection}})
+			return true
+		}
+		if !archivePack(files) {
+			collectionFiles = files
+			break
+		}
+		// 压缩包种子不作为合集，由下一个最大的种子尝试
+		a.skip(archivePackRecord(a.Name, collection, files))
+		a.Sorted = a.Sorted[1:]
+		a.Group = withoutTorrent(a.Group, *collection.ID)
+		if len(a.Sorted) < 2 {
+			a.finish(nil)
+			return true
+		}
+	}
+
+	// 只有包含多个剧集的种子才可能是合集，否则最大的种子可能只是较大的分集（合集可能被筛选条件排除）
+	if !collectionEligible(collectionFiles) {
+		a.finish(&SkipRecord{
+			Reason:   SKIP_NO_COLLECTION,
+			Name:     a.Name,
+			Detail:   fmt.Sprintf("最大的种子 ID: %d 的内容文件中剧集标识少于2个且文件少于 %d 个，不是合集", *collection.ID, COLLECTION_MIN_FILES),
+			Torrents: torrentPointers(a.Group),
+		})
+		return true
+	}
+	a.CollectionFiles = collectionFiles
+
+	for _, episode := range a.Sorted[1:] {
+		if ctx.Err() != nil {
+			return false
+		}
+		if collectionOnly[*episode.ID] {
+			continue
+		}
+		// 只有状态符合要求的种子才作为分集，合集不受此限制
+		if excludedByStatus(&episode, opts.ExcludeStatuses) {
+			a.StatusExcluded = append(a.StatusExcluded, *episode.ID)
+			continue
+		}
+		episodeFiles, err := getTorrentFiles(client, episode.ID)
+		if err != nil {
+			log.Printf("获取种子 ID: %d 文件列表失败: %v", *episode.ID, err)
+			continue
+		}
+		if archivePack(episodeFiles) {
+			a.skip(archivePackRecord(a.Name, episode, episodeFiles))
+			continue
+		}
+		a.Members = append(a.Members, memberFiles{Torrent: episode, Files: episodeFiles})
+	}
+	return true
+}
+
+// 分类阶段：按分辨率、文件重叠、包含关系和大小把分集候选分为分集、大小相同的分集和部分包含的分集。不请求服务器
+func classifyMembers(a *groupAnalysis, opts Options) {
+	if a.SameSizeDuplicate != nil {
+		return
+	}
+	collection := a.Sorted[0]
+	// 合集的分辨率和编码
+	collectionQuality := torrentQuality(&collection, a.CollectionFiles)
+	collectionCuts := torrentCutTokens(&collection, a.CollectionFiles)
+	var collectionSize float64
+	if collection.SizeWhenDone != nil {
+		collectionSize = (*collection.SizeWhenDone).Byte()
+	}
+
+	for _, member := range a.Members {
+		episode := member.Torrent // 创建副本以避免引用问题
+		var episodeSize float64
+		if episode.SizeWhenDone != nil {
+			episodeSize = (*episode.SizeWhenDone).Byte()
+		}
+
+		// 不同分辨率或编码的种子不是重复种子
+		if !opts.AllowCrossQuality {
+			if episodeQuality := torrentQuality(&episode, member.Files); !collectionQuality.compatible(episodeQuality) {
+				a.skip(SkipRecord{
+					Reason: SKIP_QUALITY_MISMATCH,
+					Name:   a.Name,
+					Detail: fmt.Sprintf("分辨率/编码不同，已跳过: 合集 ID: %d (%s), 分集 ID: %d (%s)",
+						*collection.ID, collectionQuality.describe(), *episode.ID, episodeQuality.describe()),
+					Torrents: []*transmissionrpc.Torrent{&episode},
+				})
+				continue
+			}
+		}
+
+		// 剪辑版本不同（如无修正版合集和电视播出版分集）时内容不同，即使文件名相近也不处理
+		if !opts.AllowCrossCut {
+			if episodeCuts := torrentCutTokens(&episode, member.Files); !cutTokensAgree(collectionCuts, episodeCuts) {
+				a.skip(SkipRecord{
+					Reason: SKIP_CUT_MISMATCH,
+					Name:   a.Name,
+					Detail: fmt.Sprintf("版本不同，已跳过: 合集 ID: %d (%s), 分集 ID: %d (%s)",
+						*collection.ID, describeCutTokens(collectionCuts), *episode.ID, describeCutTokens(episodeCuts)),
+					Torrents: []*transmissionrpc.Torrent{&episode},
+				})
+				continue
+			}
+		}
+
+		// 检查分集文件是否实际上是合集的一部分
+		isActualEpisode, overlappingFiles := checkActualEpisodeOverlap(a.CollectionFiles, member.Files)
+		if !isActualEpisode {
+			if lacksVideo(a.CollectionFiles, member.Files) {
+				a.skip(SkipRecord{
+					Reason:   SKIP_NO_VIDEO,
+					Name:     a.Name,
+					Detail:   fmt.Sprintf("ID: %d 没有视频文件，合集 ID: %d 有 %d 个", *episode.ID, *collection.ID, videoFileCount(a.CollectionFiles)),
+					Torrents: []*transmissionrpc.Torrent{&episode},
+				})
+			} else if overlappingFiles > 0 {
+				// 有重叠但不是真正的分集关系（可能是不同剧集）
+				a.skip(SkipRecord{
+					Reason:   SKIP_DIFFERENT_EPISODES,
+					Name:     a.Name,
+					Detail:   fmt.Sprintf("ID: %d 和 ID: %d 有 %d 个重叠文件", *collection.ID, *episode.ID, overlappingFiles),
+					Torrents: []*transmissionrpc.Torrent{&episode},
+				})
+			}
+			continue
+		}
+		a.HasFileOverlaps = true
+
+		// 严格模式下分集的内容文件必须全部包含在合集中
+		if opts.RequireFullContainment {
+			if uncovered := uncoveredEpisodeFiles(a.CollectionFiles, member.Files); len(uncovered) > 0 {
+				a.PartialEpisodes = append(a.PartialEpisodes, PartialEpisode{
+					Episode:        &episode,
+					UncoveredFiles: uncovered,
+				})
+				continue
+			}
+		}
+
+		// 检查大小是否与合集相同
+		if abs(episodeSize-collectionSize) <= sizeTolerance {
+			// 大小相同，不认为是需要处理的分集
+			a.SameSizeEpisodes = append(a.SameSizeEpisodes, &episode)
+		} else {
+			// 大小不同，是需要处理的分集
+			a.Episodes = append(a.Episodes, &episode)
+			a.ActionableFiles = append(a.ActionableFiles, member.Files)
+		}
+	}
+}
+
+// 评分阶段：收集需要处理的组的证据并计算置信度
+func scoreGroup(client *transmissionrpc.Client, a *groupAnalysis) {
+	if a.SameSizeDuplicate != nil {
+		a.Evidence, a.Confidence = fetchGroupEvidence(client, a.SameSizeDuplicate.Collection, a.SameSizeDuplicate.Episodes)
+		return
+	}
+	if a.HasFileOverlaps && len(a.Episodes) > 0 {
+		collection := a.Sorted[0]
+		a.Evidence = collectGroupEvidence(&collection, a.CollectionFiles, a.Episodes, a.ActionableFiles)
+		a.Confidence = confidenceScore(a.Evidence)
+	}
+}
+
+// 判定阶段：决定组放入扫描结果的哪一部分。不请求服务器
+func decideGroup(a *groupAnalysis, opts Options) {
+	if a.Done {
+		return
+	}
+	a.Done = true
+	if a.SameSizeDuplicate != nil {
+		a.Result = *a.SameSizeDuplicate
+		a.Result.Evidence, a.Result.Confidence = a.Evidence, a.Confidence
+		a.Outcome = OUTCOME_DUPLICATE
+		return
+	}
+
+	// 只有当存在文件重叠时继续
+	if !a.HasFileOverlaps {
+		// 记录没有找到分集的种子
+		a.skip(SkipRecord{Reason: SKIP_NO_EPISODES, Name: a.Name, Torrents: torrentPointers(a.Group)})
+		return
+	}
+	// 创建合集副本用于结果
+	collection := a.Sorted[0]
+	group := DuplicateGroup{
+		Collection:      &collection,
+		HasFileOverlaps: a.HasFileOverlaps,
+		PartialEpisodes: a.PartialEpisodes,
+	}
+	// 分成两种情况：有真正的分集 和 只有大小相同的"分集"
+	switch {
+	case len(a.Episodes) > 0:
+		// 有真正的分集（大小不同），加入需要处理的结果
+		group.Episodes = a.Episodes
+		group.Evidence, group.Confidence = a.Evidence, a.Confidence
+		// 分集大小之和超过合集时可能是不同版本被误判，移到仅记录的结果
+		if !a.Evidence.SizeConsistent && !opts.SkipSizeCheck {
+			a.Outcome = OUTCOME_OVERSIZED
+		} else if a.Evidence.VariantMismatch || a.Evidence.RatioExceeded {
+			// 版本标识不同且文件大小不完全相同，或合集远大于分集（可能只是通用文件名相同），需人工确认
+			a.Outcome = OUTCOME_VARIANT
+		} else {
+			a.Outcome = OUTCOME_DUPLICATE
+		}
+	case len(a.SameSizeEpisodes) > 0:
+		// 只有大小相同的"分集"，加入仅记录的结果
+		group.Episodes = a.SameSizeEpisodes
+		a.Outcome = OUTCOME_SAME_SIZE
+	case len(a.PartialEpisodes) > 0:
+		// 只有部分包含的分集，加入仅记录的结果
+		a.Outcome = OUTCOME_PARTIAL
+	default:
+		// 没有分集
+		a.skip(SkipRecord{Reason: SKIP_NO_EPISODES, Name: a.Name, Torrents: torrentPointers(a.Group)})
+		return
+	}
+	a.Result = group
+}
+
+// 按流水线分析各名称分组：分组阶段按名称顺序产生组，获取文件、分类和评分阶段各由 parallel 个协程处理，
+// 判定阶段逐个收集。ctx 取消后不再开始新的组，已进入流水线但未完成的组被丢弃，
+// 返回已完成的组（按名称顺序）、参与处理的组数量和是否被中断
+func analyzeGroups(ctx context.Context, client *transmissionrpc.Client, nameGroups map[string][]transmissionrpc.Torrent, collectionOnly map[int64]bool, opts Options) ([]*groupAnalysis, int, bool) {
+	names := make([]string, 0, len(nameGroups))
+	for name, group := range nameGroups {
+		// 只作为合集候选的种子（名称结尾不匹配筛选）组成的组不参与处理
+		if !onlyCollectionCandidates(group, collectionOnly) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	parallel := opts.Parallel
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	prepared := make(chan *groupAnalysis)
+	go func() {
+		defer close(prepared)
+		for i, name := range names {
+			a := &groupAnalysis{Index: i, Name: name, Group: nameGroups[name]}
+			if len(a.Group) > 1 {
+				prepareGroup(a, collectionOnly, opts)
+			} else {
+				// 记录单种子的情况（不是名称重复的）
+				a.finish(&SkipRecord{Reason: SKIP_SINGLE, Name: name, Torrents: torrentPointers(a.Group)})
+			}
+			select {
+			case prepared <- a:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	fetched := runStage(ctx, parallel, prepared, func(a *groupAnalysis) bool {
+		return fetchGroupFiles(ctx, client, a, collectionOnly, opts)
+	})
+	classified := runStage(ctx, parallel, fetched, func(a *groupAnalysis) bool {
+		classifyMembers(a, opts)
+		return true
+	})
+	scored := runStage(ctx, parallel, classified, func(a *groupAnalysis) bool {
+		scoreGroup(client, a)
+		return true
+	})
+
+	var completed []*groupAnalysis
+	for a := range scored {
+		decideGroup(a, opts)
+		completed = append(completed, a)
+	}
+	sort.Slice(completed, func(i, j int) bool { return completed[i].Index < completed[j].Index })
+	return completed, len(names), len(completed) < len(names)
+}
@@ -0,0 +1,529 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 计划文件格式版本
+const PLAN_VERSION = 1
+
+// 扫描后保存的操作计划，供 apply 命令执行
+type Plan struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Action    string    `json:"action"`
+	Suffixes  []string  `json:"suffixes,omitempty"` // 扫描时的名称结尾筛选
+
+	CollectionSuffixes []string `json:"collection_suffixes,omitempty"` // 扫描时合集的名称结尾筛选
+
+	Groups []PlanGroup `json:"groups"`
+
+	Manifest []ManifestEntry `json:"manifest"` // 最终执行清单：将要被操作的每个分集
+
+	Benchmark []StageTiming `json:"benchmark,omitempty"` // 指定 --benchmark 时各阶段的耗时
+
+	SizeSource string `json:"size_source,omitempty"` // 大小的来源，离线分析种子备份时为 metadata，为空时为服务器的 sizeWhenDone
+}
+
+// 计划中的一组
+type PlanGroup struct {
+	Name       string        `json:"name"`
+	Confidence float64       `json:"confidence"`
+	Collection PlanTorrent   `json:"collection"`
+	Episodes   []PlanTorrent `json:"episodes"`
+
+	UploadEstimate UploadEstimate `json:"upload_estimate"` // 处理分集对上传量的影响（估算）
+}
+
+// 计划中的一个种子，按hash识别
+type PlanTorrent struct {
+	ID     int64  `json:"id"`
+	Hash   string `json:"hash"`
+	Name   string `json:"name"`
+	Size   int64  `json:"size"`
+	Action string `json:"action,omitempty"` // 分集的操作
+
+	CopyOf   int64    `json:"copy_of,omitempty"`  // 与组内该ID的分集完全相同的副本，可释放大小不重复计算
+	Trackers []string `json:"trackers,omitempty"` // tracker主机名
+	Labels   []string `json:"labels,omitempty"`
+}
+
+// 重新扫描与已保存计划的差异
+type PlanDiff struct {
+	Added   []PlanGroup       `json:"added"`   // 新出现的组
+	Removed []PlanGroupRemove `json:"removed"` // 消失的组
+	Changed []PlanGroupChange `json:"changed"` // 有变化的组
+}
+
+// 消失的组及原因
+type PlanGroupRemove struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// 有变化的组及变化内容
+type PlanGroupChange struct {
+	Name    string   `json:"name"`
+	Changes []string `json:"changes"`
+}
+
+// 根据种子信息创建计划中的种子
+func newPlanTorrent(torrent *transmissionrpc.Torrent) PlanTorrent {
+	planTorrent := PlanTorrent{}
+	if torrent.ID != nil {
+		planTorrent.ID = *torrent.ID
+	}
+	if torrent.HashString != nil {
+		planTorrent.Hash = *torrent.HashString
+	}
+	if torrent.Name != nil {
+		planTorrent.Name = *torrent.Name
+	}
+	if torrent.SizeWhenDone != nil {
+		planTorrent.Size = int64((*torrent.SizeWhenDone).Byte())
+	}
+	planTorrent.Trackers = trackerHosts(torrent)
+	planTorrent.Labels = torrent.Labels
+	return planTorrent
+}
+
+// 根据扫描结果中需要处理的组创建计划
+func buildPlan(result *ScanResult, opts Options) Plan {
+	plan := Plan{
+		Version:   PLAN_VERSION,
+		CreatedAt: time.Now(),
+		Action:    opts.Action,
+		Suffixes:  opts.SuffixFilters,
+
+		CollectionSuffixes: opts.CollectionSuffixes,
+	}
+	if opts.FromTorrents != "" {
+		plan.SizeSource = PLAN_SIZE_METADATA
+	}
+	for _, name := range sortedGroupNames(result.DuplicateGroups) {
+		group := result.DuplicateGroups[name]
+		if group.Collection == nil {
+			continue
+		}
+		planGroup := PlanGroup{
+			Name:       name,
+			Confidence: group.Confidence,
+			Collection: newPlanTorrent(group.Collection),
+
+			UploadEstimate: group.UploadEstimate,
+		}
+		for _, episode := range group.Episodes {
+			if episode == nil || episode.ID == nil {
+				continue
+			}
+			planEpisode := newPlanTorrent(episode)
+			planEpisode.Action = group.episodeAction(episode, opts.Action)
+			planEpisode.CopyOf, _ = group.copyOf(episode)
+			planGroup.Episodes = append(planGroup.Episodes, planEpisode)
+		}
+		plan.Groups = append(plan.Groups, planGroup)
+	}
+	plan.Manifest = buildManifest(result.DuplicateGroups, opts.Action)
+	return plan
+}
+
+// 读取计划文件
+func loadPlan(path string) (Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Plan{}, err
+	}
+	var plan Plan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return Plan{}, fmt.Errorf("计划文件格式错误: %v", err)
+	}
+	if plan.Version != PLAN_VERSION {
+		return Plan{}, fmt.Errorf("不支持的计划文件版本: %d", plan.Version)
+	}
+	return plan, nil
+}
+
+// 保存计划文件
+func savePlan(path string, plan Plan) error {
+	data, err := json.MarshalIndent(plan, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// 去掉计划中的大小，比较时不再报告大小变化
+func withoutPlanSizes(plan Plan) Plan {
+	groups := make([]PlanGroup, len(plan.Groups))
+	for i, group := range plan.Groups {
+		group.Collection.Size = 0
+		episodes := make([]PlanTorrent, len(group.Episodes))
+		for j, episode := range group.Episodes {
+			episode.Size = 0
+			episodes[j] = episode
+		}
+		group.Episodes = episodes
+		groups[i] = group
+	}
+	plan.Groups = groups
+	return plan
+}
+
+// 比较已保存的计划和重新扫描得到的计划，torrents 为当前全部种子，用于判断消失的组的原因
+func diffPlans(old, current Plan, torrents []transmissionrpc.Torrent) PlanDiff {
+	diff := PlanDiff{Added: []PlanGroup{}, Removed: []PlanGroupRemove{}, Changed: []PlanGroupChange{}}
+	byHash := make(map[string]*transmissionrpc.Torrent)
+	for i := range torrents {
+		if torrents[i].HashString != nil {
+			byHash[*torrents[i].HashString] = &torrents[i]
+		}
+	}
+	currentGroups := make(map[string]PlanGroup)
+	for _, group := range current.Groups {
+		currentGroups[group.Name] = group
+	}
+	oldGroups := make(map[string]PlanGroup)
+	for _, group := range old.Groups {
+		oldGroups[group.Name] = group
+	}
+
+	for _, group := range current.Groups {
+		if _, ok := oldGroups[group.Name]; !ok {
+			diff.Added = append(diff.Added, group)
+		}
+	}
+	for _, oldGroup := range old.Groups {
+		group, ok := currentGroups[oldGroup.Name]
+		if !ok {
+			diff.Removed = append(diff.Removed, PlanGroupRemove{Name: oldGroup.Name, Reason: removedReason(oldGroup, byHash)})
+			continue
+		}
+		if changes := groupChanges(oldGroup, group); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, PlanGroupChange{Name: oldGroup.Name, Changes: changes})
+		}
+	}
+	return diff
+}
+
+// 判断计划中的组消失的原因
+func removedReason(group PlanGroup, byHash map[string]*transmissionrpc.Torrent) string {
+	if _, ok := byHash[group.Collection.Hash]; !ok {
+		return "合集已删除"
+	}
+	existing, stopped := 0, 0
+	for _, episode := range group.Episodes {
+		torrent, ok := byHash[episode.Hash]
+		if !ok {
+			continue
+		}
+		existing++
+		if torrent.Status != nil && *torrent.Status == transmissionrpc.TorrentStatusStopped {
+			stopped++
+		}
+	}
+	switch {
+	case existing == 0:
+		return "分集已删除"
+	case stopped == existing:
+		return "分集已暂停"
+	default:
+		return "不再符合处理条件"
+	}
+}
+
+// 比较同名组的合集、大小和分集组成
+func groupChanges(old, current PlanGroup) []string {
+	var changes []string
+	if old.Collection.Hash != current.Collection.Hash {
+		changes = append(changes, fmt.Sprintf("合集变为 ID: %d", current.Collection.ID))
+	} else if old.Collection.Size != current.Collection.Size {
+		changes = append(changes, fmt.Sprintf("合集大小 %s → %s", formatSize(old.Collection.Size), formatSize(current.Collection.Size)))
+	}
+
+	oldEpisodes := make(map[string]PlanTorrent)
+	for _, episode := range old.Episodes {
+		oldEpisodes[episode.Hash] = episode
+	}
+	currentEpisodes := make(map[string]PlanTorrent)
+	for _, episode := range current.Episodes {
+		currentEpisodes[episode.Hash] = episode
+		oldEpisode, ok := oldEpisodes[episode.Hash]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("新增分集 ID: %d", episode.ID))
+			continue
+		}
+		if oldEpisode.Size != episode.Size {
+			changes = append(changes, fmt.Sprintf("分集 ID: %d 大小 %s → %s", episode.ID, formatSize(oldEpisode.Size), formatSize(episode.Size)))
+		}
+		if oldEpisode.Action != episode.Action {
+			changes = append(changes, fmt.Sprintf("分集 ID: %d 操作 %s → %s", episode.ID, oldEpisode.Action, episode.Action))
+		}
+	}
+	for _, episode := range old.Episodes {
+		if _, ok := currentEpisodes[episode.Hash]; !ok {
+			changes = append(changes, fmt.Sprintf("移除分集 ID: %d", episode.ID))
+		}
+	}
+	return changes
+}
+
+// 差异是否为空
+func (d PlanDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// 显示计划差异
+func (d PlanDiff) print() {
+	fmt.Printf("\n===== 与计划的差异（新增 %d 组, 消失 %d 组, 变化 %d 组）=====\n", len(d.Added), len(d.Removed), len(d.Changed))
+	if d.empty() {
+		fmt.Println("无")
+		return
+	}
+	for _, group := range d.Added {
+		fmt.Printf("+ %s（%d 个分集）\n", group.Name, len(group.Episodes))
+	}
+	for _, removed := range d.Removed {
+		fmt.Printf("- %s（%s）\n", removed.Name, removed.Reason)
+	}
+	for _, changed := range d.Changed {
+		fmt.Printf("~ %s: %s\n", changed.Name, strings.Join(changed.Changes, "; "))
+	}
+}
+
+// 保存计划差异（JSON）
+func savePlanDiff(path string, diff PlanDiff) error {
+	data, err := json.MarshalIndent(diff, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// 连接服务器并扫描，未通过命令行指定连接参数时提示输入
+func connectAndScan(reader *bufio.Reader, opts Options) (*transmissionrpc.Client, *ScanResult, Options) {
+	params := opts.Connection
+	if !opts.ConnectionSet {
+		params = readConnectionParams(reader)
+		params.Proxy = opts.Connection.Proxy
+	}
+	opts.Connection = params
+
+	client, err := connect(params)
+	if err != nil {
+		log.Fatalf("无法连接到 Transmission 服务器%s: %v", params.proxyHint(), err)
+	}
+	capabilities := detectCapabilities(client)
+	capabilities.require(opts)
+	result, err := scan(client, capabilities, opts)
+	if err == nil {
+		result, opts, err = rescanWithSuggestedSuffixes(reader, client, capabilities, result, opts)
+	}
+	if err != nil {
+		log.Fatalf("获取 torrent 列表失败%s: %v", params.proxyHint(), err)
+	}
+	return client, result, opts
+}
+
+// scan 命令：扫描并显示报告，保存计划，与已保存的计划比较
+func runScan(reader *bufio.Reader, opts Options) {
+	if opts.StatsOnly {
+		runStats(reader, opts)
+		return
+	}
+	var compactOut io.Writer
+	if opts.Format == FORMAT_COMPACT {
+		compactOut = redirectChatter()
+	}
+	client, result, opts := connectAndScan(reader, opts)
+	plan := buildPlan(result, opts)
+	if compactOut != nil {
+		if err := writeCompact(compactOut, plan); err != nil {
+			log.Fatalf("输出失败: %v", err)
+		}
+	} else {
+		printReport(os.Stdout, fetchReportFiles(client, result.DuplicateGroups), result, opts.Action, opts.Verbose)
+		if opts.ListArchivePacks {
+			printArchivePacks(os.Stdout, result.Skipped)
+		}
+	}
+	plan.Benchmark = benchmark.timings()
+	benchmark.print()
+	if result.Interrupted {
+		fmt.Println("\n分析已中断，不保存计划、计划差异和审阅文件")
+		return
+	}
+
+	if opts.DiffPlan != "" {
+		old, err := loadPlan(opts.DiffPlan)
+		if err != nil {
+			log.Fatalf("读取计划文件失败: %v", err)
+		}
+		diff := diffPlans(old, plan, result.Torrents)
+		diff.print()
+		if opts.DiffOut != "" {
+			if err := savePlanDiff(opts.DiffOut, diff); err != nil {
+				log.Fatalf("保存计划差异失败: %v", err)
+			}
+			fmt.Printf("计划差异已保存到 %s\n", opts.DiffOut)
+		}
+	}
+	if opts.ReviewOut != "" {
+		review := buildReview(result)
+		if err := saveReview(opts.ReviewOut, review); err != nil {
+			log.Fatalf("保存审阅文件失败: %v", err)
+		}
+		fmt.Printf("\n审阅文件已保存到 %s（%d 组），修改后使用 \"%s --review-in %s\" 执行\n", opts.ReviewOut, len(review.Groups), os.Args[0], opts.ReviewOut)
+	}
+	if opts.PlanOut != "" {
+		if err := savePlan(opts.PlanOut, plan); err != nil {
+			log.Fatalf("保存计划失败: %v", err)
+		}
+		fmt.Printf("\n计划已保存到 %s（%d 组），使用 \"%s apply %s\" 执行\n", opts.PlanOut, len(plan.Groups), os.Args[0], opts.PlanOut)
+	}
+}
+
+// apply 命令：执行已保存的计划，执行前重新扫描，计划与当前状态不一致时需 --force
+func runApply(reader *bufio.Reader, args []string) {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, "用法: delete-episode apply <计划文件> [参数]")
+		os.Exit(2)
+	}
+	plan, err := loadPlan(args[0])
+	if err != nil {
+		log.Fatalf("读取计划文件失败: %v", err)
+	}
+	opts := parseOptions(args[1:])
+	// 未指定时使用计划中的操作和筛选，保证重新扫描的结果可以与计划比较
+	if !opts.ActionSet {
+		opts.Action = plan.Action
+	}
+	if !opts.SuffixSet {
+		opts.SuffixFilters = plan.Suffixes
+	}
+	if len(opts.CollectionSuffixes) == 0 {
+		opts.CollectionSuffixes = plan.CollectionSuffixes
+	}
+
+	client, result, opts := connectAndScan(reader, opts)
+	if result.Interrupted {
+		fmt.Println("分析已中断，未执行计划")
+		os.Exit(1)
+	}
+
+	// 按hash查询计划中种子的当前状态，已完成和已不存在的分集不再执行，
+	// 重复执行同一计划或中断后再次执行时不会被当作新的成功或错误
+	torrents, err := lookupPlanTorrents(client, plan)
+	if err != nil {
+		log.Fatalf("查询计划中种子的状态失败%s: %v", opts.Connection.proxyHint(), err)
+	}
+	classification := classifyPlan(plan, torrents, opts.Action)
+	classification.print()
+
+	// 只比较双方仍需执行的部分，已完成的分集不会被视为计划不一致
+	current := classifyPlan(buildPlan(result, opts), result.Torrents, opts.Action).Pending
+	pending := classification.Pending
+	if plan.SizeSource == PLAN_SIZE_METADATA {
+		// 离线生成的计划中的大小来自种子元数据，与服务器的 sizeWhenDone 可能不同（如有未选择的文件）
+		fmt.Println("计划由 --from-torrents 离线生成，大小来自种子元数据，比较时忽略大小变化")
+		pending, current = withoutPlanSizes(pending), withoutPlanSizes(current)
+	}
+	diff := diffPlans(pending, current, result.Torrents)
+	diff.print()
+	if !diff.empty() {
+		if !opts.Force {
+			fmt.Println("\n计划与当前状态不一致，请重新扫描生成计划，或使用 --force 按原计划执行（已不存在的种子会被跳过）")
+			os.Exit(1)
+		}
+		fmt.Println("\n警告: 计划与当前状态不一致，已指定 --force，按原计划执行")
+	}
+
+	groups := planGroups(classification.Pending, torrents)
+	if len(groups) == 0 {
+		fmt.Println("计划中没有需要执行的组")
+		classification.printSummary(0)
+		return
+	}
+	episodeCount := 0
+	for _, group := range groups {
+		episodeCount += len(group.Episodes)
+	}
+	fmt.Printf("\n将执行计划中的 %d 组（%d 个分集）\n", len(groups), episodeCount)
+	if !opts.Yes && !opts.DryRun {
+		manifest := buildManifest(groups, opts.Action)
+		printManifestWithStatus(manifest, manifestStatuses(manifest))
+		fmt.Print("是否执行? (y/n): ")
+		answer, _ := reader.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+			fmt.Println("操作已取消")
+			return
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	history := newHistoryWriter()
+	throttle := newActionThrottle(opts.MaxActions, opts.ActionDelay)
+	relocateMisplacedEpisodes(ctx, reader, client, groups, opts)
+	if !opts.DryRun {
+		history.RecordPlanned(buildManifest(groups, opts.Action))
+	}
+	ceiling := newDeleteCeiling(opts.MaxDeleteSize)
+	successCount := applyAction(ctx, reader, client, groups, opts, history, throttle, ceiling)
+	throttle.printDeferred()
+	ceiling.printSummary()
+	classification.printSummary(successCount)
+	if history.Count() > 0 {
+		fmt.Printf("已记录 %d 条操作历史，可使用 \"%s undo\" 撤销本次操作\n", history.Count(), os.Args[0])
+	}
+}
+
+// 按hash把计划中的组对应到当前的种子，已不存在的种子被跳过
+func planGroups(plan Plan, torrents []transmissionrpc.Torrent) map[string]DuplicateGroup {
+	byHash := make(map[string]*transmissionrpc.Torrent)
+	for i := range torrents {
+		if torrents[i].HashString != nil {
+			byHash[*torrents[i].HashString] = &torrents[i]
+		}
+	}
+
+	groups := make(map[string]DuplicateGroup)
+	for _, planGroup := range plan.Groups {
+		collection, ok := byHash[planGroup.Collection.Hash]
+		if !ok {
+			fmt.Printf("合集已不存在，跳过组: %s\n", planGroup.Name)
+			continue
+		}
+		group := DuplicateGroup{
+			Collection:     collection,
+			Confidence:     planGroup.Confidence,
+			EpisodeActions: make(map[int64]string),
+		}
+		for _, planEpisode := range planGroup.Episodes {
+			episode, ok := byHash[planEpisode.Hash]
+			if !ok || episode.ID == nil {
+				fmt.Printf("分集已不存在，跳过: %s\n", planEpisode.Name)
+				continue
+			}
+			group.Episodes = append(group.Episodes, episode)
+			if planEpisode.Action != "" {
+				group.EpisodeActions[*episode.ID] = planEpisode.Action
+			}
+		}
+		if len(group.Episodes) > 0 {
+			groups[planGroup.Name] = group
+		}
+	}
+	return groups
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// tracker策略中表示跳过、不处理分集的操作
+const ACTION_SKIP = "skip"
+
+// 一条tracker策略，按tracker主机名模式匹配
+type TrackerPolicy struct {
+	Tracker     string  `json:"tracker"`       // 主机名模式，支持通配符，如 *.example.org，"*" 匹配其他所有tracker
+	MinSeedTime string  `json:"min_seed_time"` // 最短做种时间，如 336h
+	MinRatio    float64 `json:"min_ratio"`     // 最低分享率
+	Action      string  `json:"action"`        // pause、priority 或 skip，为空时使用全局操作
+
+	minSeedTime time.Duration
+}
+
+// 策略文件格式
+type PolicyFile struct {
+	Policies []TrackerPolicy `json:"policies"`
+}
+
+// 多个tracker策略合并后的最严格要求
+type EffectivePolicy struct {
+	MinSeedTime time.Duration
+	MinRatio    float64
+	Action      string
+	Names       []string // 生效的策略名称（tracker模式）
+}
+
+// 被tracker策略暂缓的分集
+type GatedEpisode struct {
+	Episode *transmissionrpc.Torrent
+	Policy  string // 生效的策略
+	Reason  string // 暂缓原因
+}
+
+// 读取并校验策略文件
+func loadPolicies(filePath string) ([]TrackerPolicy, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	var file PolicyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("策略文件格式错误: %v", err)
+	}
+
+	for i := range file.Policies {
+		policy := &file.Policies[i]
+		policy.Tracker = strings.ToLower(strings.TrimSpace(policy.Tracker))
+		if policy.Tracker == "" {
+			return nil, fmt.Errorf("第 %d 条策略缺少 tracker", i+1)
+		}
+		if _, err := path.Match(policy.Tracker, ""); err != nil {
+			return nil, fmt.Errorf("第 %d 条策略的 tracker 模式无效: %s", i+1, policy.Tracker)
+		}
+		if policy.MinSeedTime != "" {
+			policy.minSeedTime, err = time.ParseDuration(policy.MinSeedTime)
+			if err != nil {
+				return nil, fmt.Errorf("第 %d 条策略的 min_seed_time 无效: %v", i+1, err)
+			}
+		}
+		switch policy.Action {
+		case "", ACTION_PAUSE, ACTION_PRIORITY, ACTION_SKIP:
+		default:
+			return nil, fmt.Errorf("第 %d 条策略的 action 无效: %s", i+1, policy.Action)
+		}
+	}
+	return file.Policies, nil
+}
+
+// 获取种子的tracker主机名
+func trackerHosts(torrent *transmissionrpc.Torrent) []string {
+	var hosts []string
+	seen := make(map[string]bool)
+	for _, tracker := range torrent.Trackers {
+		if tracker == nil {
+			continue
+		}
+		announceURL, err := url.Parse(tracker.Announce)
+		if err != nil || announceURL.Hostname() == "" {
+			continue
+		}
+		host := strings.ToLower(announceURL.Hostname())
+		if !seen[host] {
+			seen[host] = true
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
+
+// 判断主机名是否匹配策略模式
+func (p TrackerPolicy) matches(host string) bool {
+	matched, _ := path.Match(p.Tracker, host)
+	return matched
+}
+
+// 为种子的每个tracker找到第一条匹配的策略，并合并为最严格的要求
+func effectivePolicy(policies []TrackerPolicy, torrent *transmissionrpc.Torrent, defaultAction string) (EffectivePolicy, bool) {
+	hosts := trackerHosts(torrent)
+	if len(hosts) == 0 {
+		hosts = []string{""} // 没有tracker时只匹配 "*"
+	}
+
+	effective := EffectivePolicy{}
+	found := false
+	for _, host := range hosts {
+		for _, policy := range policies {
+			if !policy.matches(host) {
+				continue
+			}
+			found = true
+			if policy.minSeedTime > effective.MinSeedTime {
+				effective.MinSeedTime = policy.minSeedTime
+			}
+			if policy.MinRatio > effective.MinRatio {
+				effective.MinRatio = policy.MinRatio
+			}
+			action := policy.Action
+			if action == "" {
+				action = defaultAction
+			}
+			if actionStrictness(action) > actionStrictness(effective.Action) {
+				effective.Action = action
+			}
+			effective.Names = append(effective.Names, policy.Tracker)
+			break
+		}
+	}
+	if effective.Action == "" {
+		effective.Action = defaultAction
+	}
+	return effective, found
+}
+
+// 操作的严格程度，越严格对做种的影响越小
+func actionStrictness(action string) int {
+	switch action {
+	case ACTION_SKIP:
+		return 3
+	case ACTION_PRIORITY:
+		return 2
+	case ACTION_PAUSE:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// 检查分集是否满足策略要求，不满足时返回原因
+func (p EffectivePolicy) gateReason(episode *transmissionrpc.Torrent) string {
+	if p.Action == ACTION_SKIP {
+		return "策略要求跳过"
+	}
+	if p.MinSeedTime > 0 {
+		var seeding time.Duration
+		if episode.SecondsSeeding != nil {
+			seeding = *episode.SecondsSeeding
+		}
+		if seeding < p.MinSeedTime {
+			return fmt.Sprintf("做种时间 %s 未达到 %s", formatDuration(seeding), formatDuration(p.MinSeedTime))
+		}
+	}
+	if p.MinRatio > 0 {
+		var ratio float64
+		if episode.UploadRatio != nil {
+			ratio = *episode.UploadRatio
+		}
+		if ratio < p.MinRatio {
+			return fmt.Sprintf("分享率 %.2f 未达到 %.2f", ratio, p.MinRatio)
+		}
+	}
+	return ""
+}
+
+// 策略描述，用于报告
+func (p EffectivePolicy) describe() string {
+	return strings.Join(p.Names, " + ")
+}
+
+// 格式化时长，按天和小时显示
+func formatDuration(d time.Duration) string {
+	days := int(d.Hours()) / 24
+	hours := int(d.Hours()) % 24
+	if days > 0 {
+		return fmt.Sprintf("%d天%d小时", days, hours)
+	}
+	return fmt.Sprintf("%d小时", hours)
+}
+
+// 对需要处理的组应用tracker策略：不满足要求的分集被暂缓，其余分集记录各自的操作；
+// 按tracker关系配置了操作的分集直接使用配置的操作，配置为 policy 时使用tracker策略
+func applyPolicies(result *ScanResult, opts Options) {
+	policies, defaultAction := opts.Policies, opts.Action
+	if len(policies) == 0 && opts.SameTrackerAction == "" && opts.CrossTrackerAction == "" {
+		return
+	}
+
+	for name, group := range result.DuplicateGroups {
+		var kept []*transmissionrpc.Torrent
+		group.EpisodeActions = make(map[int64]string)
+		group.EpisodePolicies = make(map[int64]string)
+
+		for _, episode := range group.Episodes {
+			if episode == nil || episode.ID == nil {
+				continue
+			}
+			class := group.EpisodeClasses[*episode.ID]
+			switch classAction := opts.classAction(class); classAction {
+			case "", CLASS_ACTION_POLICY:
+				// 使用tracker策略
+			case ACTION_SKIP:
+				group.GatedEpisodes = append(group.GatedEpisodes, GatedEpisode{
+					Episode: episode,
+					Policy:  trackerClassName(class),
+					Reason:  "按tracker关系配置为跳过",
+				})
+				continue
+			default:
+				kept = append(kept, episode)
+				group.EpisodeActions[*episode.ID] = classAction
+				group.EpisodePolicies[*episode.ID] = trackerClassName(class)
+				continue
+			}
+			policy, found := effectivePolicy(policies, episode, defaultAction)
+			if !found {
+				kept = append(kept, episode)
+				group.EpisodeActions[*episode.ID] = defaultAction
+				continue
+			}
+			if reason := policy.gateReason(episode); reason != "" {
+				group.GatedEpisodes = append(group.GatedEpisodes, GatedEpisode{
+					Episode: episode,
+					Policy:  policy.describe(),
+					Reason:  reason,
+				})
+				continue
+			}
+			kept = append(kept, episode)
+			group.EpisodeActions[*episode.ID] = policy.Action
+			group.EpisodePolicies[*episode.ID] = policy.describe()
+		}
+
+		group.Episodes = kept
+		if len(kept) == 0 {
+			// 全部分集都被策略暂缓，移到仅供参考的部分
+			delete(result.DuplicateGroups, name)
+			result.GatedGroups[name] = group
+			continue
+		}
+		result.DuplicateGroups[name] = group
+	}
+}
+
+// 分集的操作，没有策略指定时使用全局操作
+func (g DuplicateGroup) episodeAction(episode *transmissionrpc.Torrent, defaultAction string) string {
+	if episode != nil && episode.ID != nil {
+		if action, ok := g.EpisodeActions[*episode.ID]; ok {
+			return action
+		}
+	}
+	return defaultAction
+}
+
+// 一种操作及其对应的组
+type ActionBucket struct {
+	Action string
+	Groups map[string]DuplicateGroup
+}
+
+// 按分集的操作拆分组，没有策略时所有组使用全局操作
+func splitGroupsByAction(duplicateGroups map[string]DuplicateGroup, defaultAction string) []ActionBucket {
+	buckets := make(map[string]map[string]DuplicateGroup)
+	for name, group := range duplicateGroups {
+		byAction := make(map[string][]*transmissionrpc.Torrent)
+		for _, episode := range group.Episodes {
+			action := group.episodeAction(episode, defaultAction)
+			byAction[action] = append(byAction[action], episode)
+		}
+		for action, episodes := range byAction {
+			if buckets[action] == nil {
+				buckets[action] = make(map[string]DuplicateGroup)
+			}
+			subGroup := group
+			subGroup.Episodes = episodes
+			buckets[action][name] = subGroup
+		}
+	}
+
+	var result []ActionBucket
+	for action, groups := range buckets {
+		result = append(result, ActionBucket{Action: action, Groups: groups})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Action < result[j].Action
+	})
+	return result
+}
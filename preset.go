@@ -0,0 +1,108 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// 预设名称
+const (
+	PRESET_SAFE       = "safe"
+	PRESET_AGGRESSIVE = "aggressive"
+)
+
+// 预设中的一个参数
+type PresetFlag struct {
+	Name  string
+	Value string
+}
+
+// 预设只是一组普通参数的取值：safe 只处理每个分集都完全包含在合集中、与合集在同一tracker、
+// 合集完整且有其他做种者的组，并且只暂停不删除；aggressive 放宽这些检查，旧版合集直接删除
+var presets = map[string][]PresetFlag{
+	PRESET_SAFE: {
+		{"require-full-containment", "true"},
+		{"cross-tracker-action", ACTION_SKIP},
+		{"require-complete-collection", "true"},
+		{"min-collection-seeders", "1"},
+		{"action", ACTION_PAUSE},
+		{"old-pack-action", OLD_PACK_PAUSE},
+		{"same-size-action", SAME_SIZE_SKIP},
+		{"allow-delete-private", "false"},
+		{"remove-unregistered", "false"},
+	},
+	PRESET_AGGRESSIVE: {
+		{"require-full-containment", "false"},
+		{"cross-tracker-action", ACTION_PAUSE},
+		{"require-complete-collection", "false"},
+		{"min-collection-seeders", "0"},
+		{"old-pack-action", OLD_PACK_DELETE},
+		{"same-size-action", SAME_SIZE_PAUSE},
+	},
+}
+
+// 预设中一个参数的生效值
+type PresetSetting struct {
+	Name       string
+	Value      string
+	Overridden bool // 命令行另外指定了该参数，使用命令行的值
+}
+
+// 可选的预设名称
+func presetNames() []string {
+	names := make([]string, 0, len(presets))
+	for name := range presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 应用预设：命令行没有指定的参数设为预设的值，已指定的保留命令行的值。
+// 返回预设中各参数的生效值
+func applyPreset(fs *flag.FlagSet, name string) ([]PresetSetting, error) {
+	bundle, ok := presets[name]
+	if !ok {
+		return nil, fmt.Errorf("无效的预设: %s（可选: %s）", name, strings.Join(presetNames(), ", "))
+	}
+	return applyFlagBundle(fs, "预设 "+name, bundle)
+}
+
+// 把一组参数设为指定的值，已指定的参数（命令行或之前应用的预设）保留原来的值，返回各参数的生效值；
+// label 用于错误信息，如"预设 safe"
+func applyFlagBundle(fs *flag.FlagSet, label string, bundle []PresetFlag) ([]PresetSetting, error) {
+	explicit := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	settings := make([]PresetSetting, 0, len(bundle))
+	for _, preset := range bundle {
+		if explicit[preset.Name] {
+			settings = append(settings, PresetSetting{Name: preset.Name, Value: fs.Lookup(preset.Name).Value.String(), Overridden: true})
+			continue
+		}
+		if err := fs.Set(preset.Name, preset.Value); err != nil {
+			return nil, fmt.Errorf("%s 的参数 --%s 无效: %v", label, preset.Name, err)
+		}
+		settings = append(settings, PresetSetting{Name: preset.Name, Value: preset.Value})
+	}
+	return settings, nil
+}
+
+// 显示预设名称和预设中各参数的生效值，命令行覆盖的参数单独标出
+func printPresetSettings(w io.Writer, name string, settings []PresetSetting) {
+	if name == "" {
+		return
+	}
+	fmt.Fprintf(w, "预设: %s\n", name)
+	for _, setting := range settings {
+		line := fmt.Sprintf("  --%s=%s", setting.Name, setting.Value)
+		if setting.Overridden {
+			line += "（命令行指定）"
+		}
+		fmt.Fprintln(w, line)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// Transmission 带宽优先级取值
+const (
+	PRIORITY_LOW    int64 = -1
+	PRIORITY_NORMAL int64 = 0
+	PRIORITY_HIGH   int64 = 1
+)
+
+// 带宽优先级的中文名称
+func priorityName(priority int64) string {
+	switch priority {
+	case PRIORITY_LOW:
+		return "低"
+	case PRIORITY_HIGH:
+		return "高"
+	default:
+		return "普通"
+	}
+}
+
+// 获取种子当前的带宽优先级，未知时视为普通
+func currentPriority(torrent *transmissionrpc.Torrent) int64 {
+	if torrent == nil || torrent.BandwidthPriority == nil {
+		return PRIORITY_NORMAL
+	}
+	return *torrent.BandwidthPriority
+}
+
+// 描述种子带宽优先级的变化，如 "优先级: 普通 -> 低"
+func describePriorityChange(torrent *transmissionrpc.Torrent, target int64) string {
+	current := currentPriority(torrent)
+	if current == target {
+		return fmt.Sprintf("优先级: 已是%s，跳过", priorityName(target))
+	}
+	return fmt.Sprintf("优先级: %s -> %s", priorityName(current), priorityName(target))
+}
+
+// 按组调整带宽优先级：合集设为高，分集设为低，已是目标优先级的种子跳过
+func rebalancePriority(client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup, history *HistoryWriter) (int, int, int) {
+	successCount := 0
+	skippedCount := 0
+	failedCount := 0
+
+	for groupName, group := range duplicateGroups {
+		// 合集设为高优先级
+		var collections []*transmissionrpc.Torrent
+		if group.Collection != nil && group.Collection.ID != nil {
+			collections = append(collections, group.Collection)
+		}
+
+		// 分集设为低优先级
+		var episodes []*transmissionrpc.Torrent
+		for _, episode := range group.Episodes {
+			if episode != nil && episode.ID != nil {
+				episodes = append(episodes, episode)
+			}
+		}
+
+		for _, target := range []struct {
+			priority int64
+			torrents []*transmissionrpc.Torrent
+		}{
+			{PRIORITY_HIGH, collections},
+			{PRIORITY_LOW, episodes},
+		} {
+			success, skipped, failed := setPriority(client, groupName, target.torrents, target.priority, history)
+			if target.priority == PRIORITY_LOW && success > 0 {
+				keptExport.succeed(groupName)
+				runNotices.succeed(groupName)
+			}
+			successCount += success
+			skippedCount += skipped
+			failedCount += failed
+		}
+	}
+
+	return successCount, skippedCount, failedCount
+}
+
+// 将一组种子设为指定带宽优先级，并记录原优先级以便撤销
+func setPriority(client *transmissionrpc.Client, groupName string, torrents []*transmissionrpc.Torrent, priority int64, history *HistoryWriter) (int, int, int) {
+	var pending []*transmissionrpc.Torrent
+	var torrentIDs []int64
+	skippedCount := 0
+
+	for _, torrent := range torrents {
+		if currentPriority(torrent) == priority {
+			skippedCount++
+			continue
+		}
+		pending = append(pending, torrent)
+		torrentIDs = append(torrentIDs, *torrent.ID)
+	}
+
+	if len(torrentIDs) == 0 {
+		return 0, skippedCount, 0
+	}
+
+	fmt.Printf("正在将 \"%s\" 的 %d 个种子设为%s优先级...\n", groupName, len(torrentIDs), priorityName(priority))
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+	err := client.TorrentSet(ctx, transmissionrpc.TorrentSetPayload{
+		IDs:               torrentIDs,
+		BandwidthPriority: &priority,
+	})
+	cancel()
+
+	if err != nil {
+		fmt.Printf("设置优先级失败: %v\n", err)
+		return 0, skippedCount, len(torrentIDs)
+	}
+
+	for _, torrent := range pending {
+		record := newHistoryRecord(ACTION_PRIORITY, groupName, torrent)
+		previous := currentPriority(torrent)
+		record.PrevBandwidthPriority = &previous
+		history.Record(record)
+	}
+	fmt.Printf("成功设置 %d 个种子为%s优先级\n", len(torrentIDs), priorityName(priority))
+
+	return len(torrentIDs), skippedCount, 0
+}
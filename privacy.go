@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 私有种子默认只暂停时显示的策略名称
+const PRIVATE_PAUSE_POLICY = "私有种子默认只暂停"
+
+// 会删除分集数据的操作，私有种子默认不执行
+func deletesEpisodeData(action string) bool {
+	return action == ACTION_LINK || action == ACTION_OLD_PACK_DELETE || action == ACTION_DELETE
+}
+
+// 种子是否为私有种子
+func isPrivate(torrent *transmissionrpc.Torrent) bool {
+	return torrent != nil && torrent.IsPrivate != nil && *torrent.IsPrivate
+}
+
+// 种子私有或公开的中文名称，服务器未返回时为空
+func privacyName(torrent *transmissionrpc.Torrent) string {
+	if torrent == nil || torrent.IsPrivate == nil {
+		return ""
+	}
+	if *torrent.IsPrivate {
+		return "私有"
+	}
+	return "公开"
+}
+
+// 私有分集的操作会删除数据时改为暂停（除非指定了 --allow-delete-private），
+// 并为合集和分集私有属性不一致的组添加说明
+func applyPrivacyDefaults(result *ScanResult, opts Options) {
+	for name, group := range result.DuplicateGroups {
+		group.PrivacyNote = describePrivacyMix(group)
+
+		if !opts.AllowDeletePrivate {
+			for _, episode := range group.Episodes {
+				if episode == nil || episode.ID == nil || !isPrivate(episode) {
+					continue
+				}
+				action := group.episodeAction(episode, opts.Action)
+				if !deletesEpisodeData(action) {
+					continue
+				}
+				if group.EpisodeActions == nil {
+					group.EpisodeActions = make(map[int64]string)
+				}
+				if group.EpisodePolicies == nil {
+					group.EpisodePolicies = make(map[int64]string)
+				}
+				// 旧版合集改为暂停时仍与普通分集分开执行
+				if action == ACTION_OLD_PACK_DELETE {
+					group.EpisodeActions[*episode.ID] = ACTION_OLD_PACK_PAUSE
+				} else {
+					group.EpisodeActions[*episode.ID] = ACTION_PAUSE
+				}
+				group.EpisodePolicies[*episode.ID] = PRIVATE_PAUSE_POLICY
+			}
+		}
+		result.DuplicateGroups[name] = group
+	}
+}
+
+// 合集和分集私有属性不一致时的说明，一致时返回空
+func describePrivacyMix(group DuplicateGroup) string {
+	privateCount, publicCount := 0, 0
+	for _, episode := range group.Episodes {
+		if episode == nil || episode.IsPrivate == nil {
+			continue
+		}
+		if *episode.IsPrivate {
+			privateCount++
+		} else {
+			publicCount++
+		}
+	}
+
+	collection := privacyName(group.Collection)
+	switch {
+	case collection == "" || privateCount+publicCount == 0:
+		return ""
+	case isPrivate(group.Collection) && publicCount > 0:
+		return fmt.Sprintf("合集为私有种子，%d 个分集为公开种子，%d 个为私有种子", publicCount, privateCount)
+	case !isPrivate(group.Collection) && privateCount > 0:
+		return fmt.Sprintf("合集为公开种子，%d 个分集为私有种子，%d 个为公开种子", privateCount, publicCount)
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 用于识别分辨率和编码的正则表达式
+var (
+	resolutionRegex = regexp.MustCompile(`(?i)\b(480|576|720|1080|1440|2160|4320)[pi]\b|\b(4k|uhd)\b`)
+	codecRegex      = regexp.MustCompile(`(?i)\b(x\.?264|h\.?264|avc|x\.?265|h\.?265|hevc|av1)\b`)
+)
+
+// 种子的分辨率和编码，未识别时为空
+type QualityTokens struct {
+	Resolution string
+	Codec      string
+}
+
+// 从名称中提取分辨率，4K/UHD 统一为 2160p
+func extractResolution(name string) string {
+	matches := resolutionRegex.FindStringSubmatch(name)
+	if matches == nil {
+		return ""
+	}
+	if matches[1] == "" {
+		return "2160p"
+	}
+	return strings.ToLower(matches[0])
+}
+
+// 从名称中提取编码，同一编码的不同写法统一，如 x265、HEVC 都视为 H.265
+func extractCodec(name string) string {
+	match := strings.ToLower(codecRegex.FindString(name))
+	match = strings.ReplaceAll(match, ".", "")
+	switch match {
+	case "":
+		return ""
+	case "x264", "h264", "avc":
+		return "H.264"
+	case "x265", "h265", "hevc":
+		return "H.265"
+	default:
+		return strings.ToUpper(match)
+	}
+}
+
+// 获取种子的分辨率和编码：优先使用种子名称，名称中没有时使用内容文件名
+func torrentQuality(torrent *transmissionrpc.Torrent, files []*transmissionrpc.TorrentFile) QualityTokens {
+	quality := QualityTokens{}
+	if torrent != nil && torrent.Name != nil {
+		quality.Resolution = extractResolution(*torrent.Name)
+		quality.Codec = extractCodec(*torrent.Name)
+	}
+	for _, file := range contentFiles(files) {
+		if quality.Resolution != "" && quality.Codec != "" {
+			break
+		}
+		fileName := getFileName(file.Name)
+		if quality.Resolution == "" {
+			quality.Resolution = extractResolution(fileName)
+		}
+		if quality.Codec == "" {
+			quality.Codec = extractCodec(fileName)
+		}
+	}
+	return quality
+}
+
+// 两者都识别到且不同时视为不兼容，任一方未识别时不作判断
+func (q QualityTokens) compatible(other QualityTokens) bool {
+	if q.Resolution != "" && other.Resolution != "" && q.Resolution != other.Resolution {
+		return false
+	}
+	if q.Codec != "" && other.Codec != "" && q.Codec != other.Codec {
+		return false
+	}
+	return true
+}
+
+// 分辨率和编码描述，如 "2160p, H.265"
+func (q QualityTokens) describe() string {
+	resolution, codec := q.Resolution, q.Codec
+	if resolution == "" {
+		resolution = "未知分辨率"
+	}
+	if codec == "" {
+		codec = "未知编码"
+	}
+	return fmt.Sprintf("%s, %s", resolution, codec)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 一周的秒数，用于按平均上传速率估算每周上传量
+const SECONDS_PER_WEEK = 7 * 24 * 3600
+
+// 扫描期间短于该值时两次采样的间隔太短，改用当前上传速率估算
+const UPLOAD_ESTIMATE_MIN_WINDOW = 5 * time.Second
+
+// 处理一组分集对上传量的影响（估算）
+type UploadEstimate struct {
+	UploadedEver     int64   `json:"uploaded_ever"`      // 分集的累计上传量之和（字节）
+	AverageRate      float64 `json:"average_rate"`       // 扫描期间分集的平均上传速率之和（B/s）
+	WeeklyUploadLoss float64 `json:"weekly_upload_loss"` // 处理后预计每周少上传的量（字节）
+	Reclaimable      float64 `json:"reclaimable"`        // 分集大小之和（字节）
+}
+
+// 描述上传影响，明确标注为估算
+func (e UploadEstimate) describe() string {
+	return fmt.Sprintf("分集累计上传 %s, 扫描期间平均 %s, 处理后预计每周少上传 %s, 分集共 %s（估算，按扫描期间的速率推算）",
+		formatSize(e.UploadedEver), formatRate(int64(e.AverageRate)), formatSize(int64(e.WeeklyUploadLoss)), formatSize(int64(e.Reclaimable)))
+}
+
+// 重新获取需要处理的分集的累计上传量，与获取种子列表时的值比较得到扫描期间的平均上传速率（B/s）；
+// 扫描时间太短或重新获取失败时使用当前上传速率
+func scanUploadRates(client *transmissionrpc.Client, result *ScanResult) map[int64]float64 {
+	rates := make(map[int64]float64)
+	before := make(map[int64]int64)
+	var episodeIDs []int64
+	for _, group := range result.DuplicateGroups {
+		for _, episode := range group.Episodes {
+			if episode == nil || episode.ID == nil {
+				continue
+			}
+			episodeIDs = append(episodeIDs, *episode.ID)
+			if episode.UploadedEver != nil {
+				before[*episode.ID] = *episode.UploadedEver
+			}
+			if episode.RateUpload != nil {
+				rates[*episode.ID] = float64(*episode.RateUpload)
+			}
+		}
+	}
+
+	elapsed := time.Since(result.SampledAt)
+	if len(episodeIDs) == 0 || elapsed < UPLOAD_ESTIMATE_MIN_WINDOW {
+		return rates
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+	defer cancel()
+	current, err := client.TorrentGet(ctx, []string{"id", "uploadedEver"}, episodeIDs)
+	if err != nil {
+		log.Printf("重新获取分集上传量失败，将使用当前上传速率估算上传影响: %v", err)
+		return rates
+	}
+	for _, torrent := range current {
+		if torrent.ID == nil || torrent.UploadedEver == nil {
+			continue
+		}
+		previous, ok := before[*torrent.ID]
+		if !ok {
+			continue
+		}
+		var delta int64
+		if *torrent.UploadedEver > previous {
+			delta = *torrent.UploadedEver - previous
+		}
+		rates[*torrent.ID] = float64(delta) / elapsed.Seconds()
+	}
+	return rates
+}
+
+// 计算一组分集的上传影响，相同分集的副本只计算一次大小
+func estimateUpload(group DuplicateGroup, rates map[int64]float64) UploadEstimate {
+	estimate := UploadEstimate{}
+	for _, episode := range group.Episodes {
+		if episode == nil || episode.ID == nil {
+			continue
+		}
+		if episode.UploadedEver != nil {
+			estimate.UploadedEver += *episode.UploadedEver
+		}
+		if _, isCopy := group.copyOf(episode); !isCopy {
+			estimate.Reclaimable += float64(group.contentBytes(episode))
+		}
+		estimate.AverageRate += rates[*episode.ID]
+	}
+	estimate.WeeklyUploadLoss = estimate.AverageRate * SECONDS_PER_WEEK
+	return estimate
+}
+
+// 为需要处理的组估算上传影响；指定每周上传量下限（GB）时保留预计每周上传量达到下限的分集
+func applyUploadEstimates(client *transmissionrpc.Client, result *ScanResult, minWeeklyUploadGB float64) {
+	rates := scanUploadRates(client, result)
+	minWeeklyUpload := minWeeklyUploadGB * 1024 * 1024 * 1024
+
+	for name, group := range result.DuplicateGroups {
+		if minWeeklyUpload > 0 {
+			var kept []*transmissionrpc.Torrent
+			for _, episode := range group.Episodes {
+				if episode == nil || episode.ID == nil {
+					continue
+				}
+				weekly := rates[*episode.ID] * SECONDS_PER_WEEK
+				if weekly < minWeeklyUpload {
+					kept = append(kept, episode)
+					continue
+				}
+				group.ActiveEpisodes = append(group.ActiveEpisodes, ActiveEpisode{
+					Episode: episode,
+					Reason:  fmt.Sprintf("预计每周上传 %s（估算），达到保留下限 %g GB", formatSize(int64(weekly)), minWeeklyUploadGB),
+				})
+			}
+			group.Episodes = kept
+			if len(kept) == 0 {
+				// 全部分集都预计有较多上传，移到仅供参考的部分
+				delete(result.DuplicateGroups, name)
+				result.ActiveGroups[name] = group
+				continue
+			}
+		}
+		group.UploadEstimate = estimateUpload(group, rates)
+		result.DuplicateGroups[name] = group
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 跳过原因文件中的一行，每个被跳过的种子一行
+type ReasonRecord struct {
+	RunID     string    `json:"run_id"`
+	Time      time.Time `json:"time"`
+	Group     string    `json:"group"`
+	TorrentID int64     `json:"torrent_id,omitempty"`
+	Hash      string    `json:"hash,omitempty"`
+	Name      string    `json:"name,omitempty"`
+	Reason    string    `json:"reason"` // 跳过原因代码，与 SKIP_* 常量一致
+	Detail    string    `json:"detail,omitempty"`
+}
+
+// 逐条追加写入跳过原因，不经过缓冲，中断时已写入的记录不会丢失
+type ReasonsWriter struct {
+	runID string
+	file  *os.File
+}
+
+// 打开跳过原因文件（追加写入），路径为空时返回nil，nil写入器不记录任何内容
+func newReasonsWriter(path string) *ReasonsWriter {
+	if path == "" {
+		return nil
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		log.Printf("打开跳过原因文件失败，将不记录跳过原因: %v", err)
+		return nil
+	}
+	return &ReasonsWriter{
+		runID: time.Now().Format("20060102-150405"),
+		file:  file,
+	}
+}
+
+// 写入一条跳过记录涉及的每个种子，写入失败只打印警告
+func (w *ReasonsWriter) Write(record SkipRecord) {
+	if w == nil {
+		return
+	}
+	torrents := record.Torrents
+	if len(torrents) == 0 {
+		torrents = []*transmissionrpc.Torrent{{}}
+	}
+	for _, torrent := range torrents {
+		line := ReasonRecord{
+			RunID:  w.runID,
+			Time:   time.Now(),
+			Group:  record.Name,
+			Reason: record.Reason,
+			Detail: record.Detail,
+		}
+		if torrent.ID != nil {
+			line.TorrentID = *torrent.ID
+		}
+		if torrent.HashString != nil {
+			line.Hash = *torrent.HashString
+		}
+		if torrent.Name != nil {
+			line.Name = *torrent.Name
+		}
+
+		data, err := json.Marshal(line)
+		if err != nil {
+			log.Printf("写入跳过原因失败: %v", err)
+			continue
+		}
+		if _, err := w.file.Write(append(data, '\n')); err != nil {
+			log.Printf("写入跳过原因失败: %v", err)
+			return
+		}
+	}
+}
+
+// 关闭跳过原因文件
+func (w *ReasonsWriter) Close() {
+	if w == nil {
+		return
+	}
+	if err := w.file.Close(); err != nil {
+		log.Printf("关闭跳过原因文件失败: %v", err)
+	}
+}
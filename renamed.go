@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 按文件名分组时每批获取文件列表的种子数量，文件列表比其他字段大得多
+const RENAMED_FILES_CHUNK_SIZE = 100
+
+// 剧名和季组成的分组键，如 "show s01"；无法识别剧名或季时返回空
+func showSeasonKey(show string, season int) string {
+	if show == "" || season < 0 {
+		return ""
+	}
+	return fmt.Sprintf("%s s%02d", show, season)
+}
+
+// 种子名称的剧名和季分组键，如 Show.S01.1080p-ADWeb 为 "show s01"
+func nameShowSeasonKey(name string) string {
+	matches := packSeasonRegex.FindStringSubmatch(canonicalName(name))
+	if matches == nil {
+		return ""
+	}
+	return showSeasonKey(seriesShowKey(name), atoi(matches[2]+matches[3]))
+}
+
+// 最大视频文件名的剧名和季分组键，如 Show.S01E03.1080p.mkv 为 "show s01"；没有视频文件或无法识别时返回空
+func fileShowSeasonKey(files []*transmissionrpc.TorrentFile) string {
+	var largest *transmissionrpc.TorrentFile
+	for _, file := range contentFiles(files) {
+		if fileClass(file.Name) == FILE_CLASS_VIDEO && (largest == nil || file.Length > largest.Length) {
+			largest = file
+		}
+	}
+	if largest == nil {
+		return ""
+	}
+	name := getFileName(largest.Name)
+	if season := markerSeason(extractEpisodeMarker(name)); season >= 0 {
+		return showSeasonKey(seriesShowKey(name), season)
+	}
+	return nameShowSeasonKey(name)
+}
+
+// 批量获取种子的文件列表并写入缓存，已缓存的种子不再获取
+func prefetchTorrentFiles(client *transmissionrpc.Client, ids []int64) error {
+	var missing []int64
+	for _, id := range ids {
+		if _, ok := cachedTorrentFiles(id); !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	for start := 0; start < len(missing); start += RENAMED_FILES_CHUNK_SIZE {
+		end := min(start+RENAMED_FILES_CHUNK_SIZE, len(missing))
+		done := benchmark.start(BENCH_FILES)
+		torrents, err := getWithRetry(client, []string{"id", "files"}, missing[start:end], timeouts.Files, "获取单独种子的文件列表")
+		done()
+		if err != nil {
+			return err
+		}
+		for _, torrent := range torrents {
+			if torrent.ID != nil && torrent.Files != nil {
+				cacheTorrentFiles(*torrent.ID, torrent.Files)
+			}
+		}
+	}
+	return nil
+}
+
+// 为在 Transmission 中改过名的种子按文件名分组：名称分组中只有自己、名称中识别不出剧名和季的种子，
+// 如果最大视频文件名的剧名和季恰好与另一个名称分组的剧名和季相同，就并入那个组。
+// 名称中有剧名的种子（如单集种子 Show.S01E03）不获取文件列表，按名称分组不变。
+// 返回各组按文件名并入的种子: 组名 → 种子ID → 种子名称
+func regroupRenamedTorrents(client *transmissionrpc.Client, nameGroups map[string][]transmissionrpc.Torrent) map[string]map[int64]string {
+	keys := make([]string, 0, len(nameGroups))
+	for key := range nameGroups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	targets := make(map[string][]string) // 剧名和季 → 名称分组
+	var singles []string
+	var ids []int64
+	for _, key := range keys {
+		if showSeason := nameShowSeasonKey(key); showSeason != "" {
+			targets[showSeason] = append(targets[showSeason], key)
+		}
+		group := nameGroups[key]
+		if len(group) == 1 && group[0].ID != nil && !metadataPending(&group[0]) && seriesShowKey(*group[0].Name) == "" {
+			singles = append(singles, key)
+			ids = append(ids, *group[0].ID)
+		}
+	}
+	if len(singles) == 0 || len(targets) == 0 {
+		return nil
+	}
+	if err := prefetchTorrentFiles(client, ids); err != nil {
+		log.Printf("获取单独种子的文件列表失败，不按文件名分组: %v", err)
+		return nil
+	}
+
+	regrouped := make(map[string]map[int64]string)
+	for _, key := range singles {
+		torrent := nameGroups[key][0]
+		files, err := getTorrentFiles(client, torrent.ID)
+		if err != nil {
+			continue
+		}
+		fileKey := fileShowSeasonKey(files)
+		if fileKey == "" {
+			continue
+		}
+		// 同一剧名和季有多个组（如不同分辨率）时无法确定归属，不合并
+		var candidates []string
+		for _, target := range targets[fileKey] {
+			if target != key && nameGroups[target] != nil {
+				candidates = append(candidates, target)
+			}
+		}
+		if len(candidates) != 1 {
+			continue
+		}
+		target := candidates[0]
+		nameGroups[target] = append(nameGroups[target], torrent)
+		delete(nameGroups, key)
+		if regrouped[target] == nil {
+			regrouped[target] = make(map[int64]string)
+		}
+		regrouped[target][*torrent.ID] = *torrent.Name
+	}
+	return regrouped
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// renamed.json: ID 1 为 Show.R 第一季的合集，ID 2 改名为 My Favourite Episode，文件为 Show.R.S01E02，
+// ID 3 改过名但文件属于另一部剧
+func TestRegroupRenamedFixture(t *testing.T) {
+	const name = "Show.R.S01.1080p.WEB"
+	_, result, _ := scanFixture(t, "renamed.json")
+	group, ok := result.DuplicateGroups[name]
+	if !ok {
+		t.Fatalf("改名的分集没有并入合集的组，需要处理的组: %v", sortedGroupNames(result.DuplicateGroups))
+	}
+	if got := splitIDs(result.DuplicateGroups)[name]; !reflect.DeepEqual(got, []int64{2}) {
+		t.Errorf("分集 %v，应为改名的 ID 2", got)
+	}
+	if got := group.FileGrouped[2]; got != "My Favourite Episode" {
+		t.Errorf("按文件名归组的种子 %v，应记录 ID 2 的名称", group.FileGrouped)
+	}
+	for _, groups := range allGroupMaps(result) {
+		for groupName, other := range groups {
+			if _, ok := other.FileGrouped[3]; ok {
+				t.Errorf("文件属于另一部剧的 ID 3 被并入组 %s", groupName)
+			}
+		}
+	}
+	if _, ok := cachedTorrentFiles(2); !ok {
+		t.Error("批量获取的文件列表应写入缓存")
+	}
+}
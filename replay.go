@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 重放诊断包时的RPC数据，未指定 --from-dump 时为nil
+var dumpReplay *DiagRPC
+
+// 开启诊断包重放
+func enableDumpReplay(rpc DiagRPC) {
+	dumpReplay = &rpc
+}
+
+// 让客户端的RPC请求由诊断包中的数据回答，不连接任何服务器
+func configureDumpReplay(client *transmissionrpc.Client, rpc *DiagRPC) error {
+	httpClient, err := rpcHTTPClient(client)
+	if err != nil {
+		return err
+	}
+	httpClient.Transport = &replayTransport{rpc: rpc}
+	return nil
+}
+
+// 按诊断包回答RPC请求的HTTP层：torrent-get 按请求的ID和字段返回记录的种子，
+// 其他只读方法返回记录的内容，修改类的方法返回错误
+type replayTransport struct {
+	rpc *DiagRPC
+}
+
+func (t *replayTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	var rpcRequest diagRequest
+	if request.Body != nil {
+		err := json.NewDecoder(request.Body).Decode(&rpcRequest)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	answer := map[string]interface{}{"result": "success"}
+	switch {
+	case rpcRequest.Method == "torrent-get":
+		answer["arguments"] = t.torrentGet(rpcRequest.Arguments)
+	case diagRecordedMethods[rpcRequest.Method]:
+		arguments, ok := t.rpc.Methods[rpcRequest.Method]
+		if !ok {
+			arguments = json.RawMessage("{}")
+		}
+		answer["arguments"] = arguments
+	default:
+		answer["result"] = fmt.Sprintf("离线重放不执行 %s", rpcRequest.Method)
+	}
+	if rpcRequest.Tag != nil {
+		answer["tag"] = rpcRequest.Tag
+	}
+	data, err := json.Marshal(answer)
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Status:     "200 OK",
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     http.Header{"Content-Type": []string{"application/json"}, SESSION_ID_HEADER: []string{"replay"}},
+		Body:       io.NopCloser(bytes.NewReader(data)),
+		Request:    request,
+	}, nil
+}
+
+// 按请求的ID（数字、hash或 recently-active）和字段返回记录的种子
+func (t *replayTransport) torrentGet(raw json.RawMessage) map[string]interface{} {
+	var arguments struct {
+		Fields []string        `json:"fields"`
+		IDs    json.RawMessage `json:"ids"`
+	}
+	json.Unmarshal(raw, &arguments)
+
+	all := len(arguments.IDs) == 0 || strings.Trim(string(arguments.IDs), `"`) == "recently-active"
+	wanted := make(map[string]bool)
+	if !all {
+		var ids []interface{}
+		json.Unmarshal(arguments.IDs, &ids)
+		for _, id := range ids {
+			switch typed := id.(type) {
+			case float64:
+				wanted[strconv.FormatInt(int64(typed), 10)] = true
+			case string:
+				wanted[strings.ToLower(typed)] = true
+			}
+		}
+	}
+
+	torrents := []map[string]json.RawMessage{}
+	for _, torrent := range t.rpc.Torrents {
+		if !all {
+			var hash string
+			json.Unmarshal(torrent["hashString"], &hash)
+			if !wanted[string(torrent["id"])] && !wanted[strings.ToLower(hash)] {
+				continue
+			}
+		}
+		selected := make(map[string]json.RawMessage)
+		for _, field := range arguments.Fields {
+			if value, ok := torrent[field]; ok {
+				selected[field] = value
+			}
+		}
+		torrents = append(torrents, selected)
+	}
+	return map[string]interface{}{"torrents": torrents, "removed": []int64{}}
+}
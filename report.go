@@ -0,0 +1,595 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 跳过原因
+const (
+	SKIP_SINGLE             = "single"             // 单个种子，没有同名种子
+	SKIP_SAME_SIZE          = "same_size"          // 同组种子大小全部相同
+	SKIP_FILES_FAILED       = "files_failed"       // 获取合集文件列表失败（重试后仍失败，下次扫描可能成功）
+	SKIP_METADATA_PENDING   = "metadata_pending"   // 元数据未完成，推迟到下次扫描
+	SKIP_NO_FILES           = "no_files"           // 合集没有文件信息
+	SKIP_DIFFERENT_EPISODES = "different_episodes" // 文件有重叠但剧集标识不同
+	SKIP_NO_EPISODES        = "no_episodes"        // 没有找到分集
+	SKIP_NO_COLLECTION      = "no_collection"      // 最大的种子不是合集（合集可能被筛选条件排除）
+	SKIP_QUALITY_MISMATCH   = "quality_mismatch"   // 分辨率或编码不同
+	SKIP_NO_NAME            = "no_name"            // 种子没有名称，无法分组
+
+	SKIP_NO_VIDEO = "no_video" // 合集有视频文件而分集没有，如同名的字幕包
+)
+
+// 跳过原因按固定顺序显示
+var skipReasonOrder = []string{
+	SKIP_SINGLE,
+	SKIP_SAME_SIZE,
+	SKIP_DIFFERENT_EPISODES,
+	SKIP_NO_VIDEO,
+	SKIP_QUALITY_MISMATCH,
+	SKIP_CUT_MISMATCH,
+	SKIP_ARCHIVE_PACK,
+	SKIP_NO_EPISODES,
+	SKIP_NO_COLLECTION,
+	SKIP_METADATA_PENDING,
+	SKIP_FILES_FAILED,
+	SKIP_NO_FILES,
+	SKIP_NO_NAME,
+	SKIP_NOT_IN_SHOWS,
+}
+
+// 跳过原因的中文描述
+var skipReasonLabels = map[string]string{
+	SKIP_SINGLE:             "单个种子",
+	SKIP_SAME_SIZE:          "大小相同的种子组",
+	SKIP_FILES_FAILED:       "获取文件列表失败的种子组（重试后仍失败）",
+	SKIP_METADATA_PENDING:   "元数据未完成的种子（下次扫描时重新检查）",
+	SKIP_NO_FILES:           "合集没有文件信息的种子组",
+	SKIP_DIFFERENT_EPISODES: "可能是不同剧集的种子",
+	SKIP_NO_EPISODES:        "没有分集的种子组",
+	SKIP_NO_COLLECTION:      "未找到合集（可能被筛选条件排除）",
+	SKIP_QUALITY_MISMATCH:   "分辨率/编码不同的种子",
+	SKIP_NO_NAME:            "无名称的种子（如元数据未完成的磁力链接）",
+	SKIP_NOT_IN_SHOWS:       "不在剧集清单中的种子组",
+	SKIP_NO_VIDEO:           "没有视频文件的种子（不是视频合集的分集）",
+	SKIP_CUT_MISMATCH:       "剪辑版本不同的种子（如无修正版和播出版）",
+	SKIP_ARCHIVE_PACK:       "压缩包种子（RAR/ZIP 等分卷，不作为合集）",
+}
+
+// 一条跳过记录
+type SkipRecord struct {
+	Reason   string
+	Name     string
+	Detail   string
+	Torrents []*transmissionrpc.Torrent // 被跳过的种子，写入跳过原因文件
+}
+
+// 报告中显示的文件列表，按种子ID记录；渲染报告前由 fetchReportFiles 获取，没有记录的种子不显示文件列表
+type ReportFiles map[int64][]*transmissionrpc.TorrentFile
+
+// 获取需要处理的组中合集和分集的文件列表，获取失败的种子不显示文件列表
+func fetchReportFiles(client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup) ReportFiles {
+	files := make(ReportFiles)
+	fetch := func(torrent *transmissionrpc.Torrent) {
+		if torrent == nil || torrent.ID == nil {
+			return
+		}
+		if torrentFiles, err := getTorrentFiles(client, torrent.ID); err == nil {
+			files[*torrent.ID] = torrentFiles
+		}
+	}
+	for _, groupName := range sortedGroupNames(duplicateGroups) {
+		group := duplicateGroups[groupName]
+		fetch(group.Collection)
+		for _, episode := range group.Episodes {
+			if _, isCopy := group.copyOf(episode); !isCopy {
+				fetch(episode)
+			}
+		}
+	}
+	return files
+}
+
+// 按固定顺序把报告写入 w：需要处理的组、仅供参考的组、跳过原因统计，没有需要处理的组时返回false。
+// 报告的各部分都只写入传入的 w，不直接写标准输出，也不请求服务器；文件列表由调用方预先获取
+func printReport(w io.Writer, files ReportFiles, result *ScanResult, action string, verbose bool) bool {
+	defer benchmark.start(BENCH_REPORT)()
+	printSpeedLimitNotice(w, result.SpeedLimits)
+	printActionableGroups(w, files, result.DuplicateGroups, action, verbose)
+	printInformationalGroups(w, result)
+	printUnregisteredTorrents(w, result.Unregistered)
+	printMagnetStubs(w, result.MagnetStubs, verbose)
+	printMissingData(w, result)
+	printSkipSummary(w, result, verbose)
+	printTrackerImpact(w, result.TrackerImpacts, result.TrackerImpactLimit)
+
+	if len(result.DuplicateGroups) == 0 {
+		if len(result.LowConfidenceGroups) > 0 {
+			fmt.Fprintf(w, "\n没有置信度达到要求的组，有 %d 组需人工确认\n", len(result.LowConfidenceGroups))
+			return true
+		}
+		fmt.Fprintln(w, "\n未找到需要处理的合集和对应分集的种子")
+		return false
+	}
+
+	episodeCount := 0
+	for _, group := range result.DuplicateGroups {
+		episodeCount += len(group.Episodes)
+	}
+	fmt.Fprintf(w, "\n只有第一部分的 %d 组（%d 个分集）会参与以下操作，其余部分仅供参考\n", len(result.DuplicateGroups), episodeCount)
+	return true
+}
+
+// 第一部分：需要处理的合集和分集
+func printActionableGroups(w io.Writer, files ReportFiles, duplicateGroups map[string]DuplicateGroup, action string, verbose bool) {
+	fmt.Fprintf(w, "\n===== 一、需要处理的合集和分集（%d 组）=====\n", len(duplicateGroups))
+	if len(duplicateGroups) == 0 {
+		fmt.Fprintln(w, "无")
+		return
+	}
+
+	// 置信度高的组排在前面
+	for _, groupName := range sortedGroupNames(duplicateGroups) {
+		group := duplicateGroups[groupName]
+		fmt.Fprintf(w, "\n组名: %s\n", groupName)
+		fmt.Fprintf(w, "置信度: %.2f（%s）\n", group.Confidence, group.Evidence.describe())
+		fmt.Fprintf(w, "tracker关系: %s\n", trackerClassName(group.TrackerClass))
+		if group.Origin != "" {
+			fmt.Fprintf(w, "来源: %s\n", originName(group.Origin))
+		}
+		fmt.Fprintf(w, "上传影响: %s\n", group.UploadEstimate.describe())
+		printAliasNames(w, group.AliasNames)
+		printFileGrouped(w, group.FileGrouped)
+		printGroupNote(w, group.Note)
+		if group.SameSizeDuplicate {
+			fmt.Fprintf(w, "同一tracker的重复种子: %s\n", group.Decision)
+		}
+		if group.KeepLatestNote != "" {
+			fmt.Fprintf(w, "注意: %s\n", group.KeepLatestNote)
+		}
+		if group.PrivacyNote != "" {
+			fmt.Fprintf(w, "私有/公开混合: %s\n", group.PrivacyNote)
+		}
+		if mismatch := describeLocationMismatch(group); mismatch != "" {
+			fmt.Fprintf(w, "下载位置不一致: %s\n", mismatch)
+		}
+		printSwarmNote(w, group.SwarmNote)
+
+		// 显示合集信息
+		if group.Collection != nil && group.Collection.ID != nil && group.Collection.SizeWhenDone != nil {
+			collectionSize := formatSize(torrentBytes(group.Collection))
+			line := fmt.Sprintf("合集(%s): ID: %d, 大小: %s", describeCollectionAction(action, group.Collection), *group.Collection.ID, collectionSize)
+			if privacy := privacyName(group.Collection); privacy != "" {
+				line += ", " + privacy
+			}
+			line += ", " + describeTrackersAndLabels(group.Collection, verbose)
+			fmt.Fprintln(w, line)
+			if note := describeSeedLimitStop(group.Collection); note != "" {
+				fmt.Fprintf(w, "  注意: %s\n", note)
+			}
+
+			// 显示合集的文件列表
+			if collectionFiles := files[*group.Collection.ID]; len(collectionFiles) > 0 {
+				fmt.Fprintln(w, "  合集文件列表:")
+				for i, file := range collectionFiles {
+					if i < 5 { // 最多显示5个文件
+						fmt.Fprintf(w, "    - %s\n", displayPath(group.Collection, file.Name))
+					} else {
+						fmt.Fprintf(w, "    - ... 以及 %d 个更多文件\n", len(collectionFiles)-5)
+						break
+					}
+				}
+			}
+		}
+
+		// 显示分集信息
+		fmt.Fprintf(w, "包含 %d 个分集(%s):\n", len(group.Episodes), describeEpisodesAction(action))
+		number := 0
+		for _, episode := range group.Episodes {
+			if _, isCopy := group.copyOf(episode); isCopy {
+				// 副本合并到第一个分集的行中显示
+				continue
+			}
+			if episode != nil && episode.ID != nil && episode.SizeWhenDone != nil {
+				number++
+				episodeSize := formatSize(torrentBytes(episode))
+				episodeAction := group.episodeAction(episode, action)
+				line := fmt.Sprintf("  %d. ID: %d, 大小: %s", number, *episode.ID, episodeSize)
+				if copies := group.copiesOf(episode); len(copies) > 0 {
+					line += ", " + describeCopies(episode, copies)
+				}
+				if privacy := privacyName(episode); privacy != "" {
+					line += ", " + privacy
+				}
+				if _, ok := group.FileGrouped[*episode.ID]; ok {
+					line += ", 按文件名归组"
+				}
+				line += ", " + describeTrackersAndLabels(episode, verbose)
+				if episodeAction == ACTION_PRIORITY {
+					line += ", " + describePriorityChange(episode, PRIORITY_LOW)
+				}
+				if limit := describeTorrentUploadLimit(episode); limit != "" {
+					line += ", " + limit
+				}
+				// 显示生效的tracker策略
+				if policy := group.EpisodePolicies[*episode.ID]; policy != "" {
+					line += fmt.Sprintf(", 策略: %s(%s)", policy, describeEpisodesAction(episodeAction))
+				}
+				fmt.Fprintln(w, line)
+
+				// 显示分集的文件列表
+				if episodeFiles := files[*episode.ID]; len(episodeFiles) > 0 {
+					fmt.Fprintln(w, "    文件列表:")
+					for j, file := range episodeFiles {
+						if j < 3 { // 最多显示3个文件
+							fmt.Fprintf(w, "      - %s\n", file.Name)
+						} else {
+							fmt.Fprintf(w, "      - ... 以及 %d 个更多文件\n", len(episodeFiles)-3)
+							break
+						}
+					}
+				}
+			}
+		}
+
+		printSeriesCoverage(w, group)
+		printPartialEpisodes(w, group.PartialEpisodes)
+		printGatedEpisodes(w, group.GatedEpisodes)
+		printActiveEpisodes(w, group.ActiveEpisodes)
+		printHandledEpisodes(w, group.HandledEpisodes)
+		printUnregisteredEpisodes(w, group.UnregisteredEpisodes)
+
+		// 显示文件重叠状态
+		fmt.Fprintf(w, "文件列表重叠状态: %t\n", group.HasFileOverlaps)
+	}
+}
+
+// 第二部分：仅供参考的组（不会被处理）
+func printInformationalGroups(w io.Writer, result *ScanResult) {
+	dupGroupsWithOnlySameSize := result.SameSizeGroups
+	partialGroups := result.PartialGroups
+	gatedGroups := result.GatedGroups
+	activeGroups := result.ActiveGroups
+	handledGroups := result.HandledGroups
+	lowConfidenceGroups := result.LowConfidenceGroups
+	oversizedGroups := result.OversizedGroups
+	belowMinGroups := result.BelowMinEpisodesGroups
+	total := len(dupGroupsWithOnlySameSize) + len(partialGroups) + len(gatedGroups) + len(activeGroups) + len(lowConfidenceGroups) + len(oversizedGroups) + len(handledGroups) + len(belowMinGroups)
+	fmt.Fprintf(w, "\n===== 二、仅供参考（%d 组，不会被处理）=====\n", total)
+	if total == 0 {
+		fmt.Fprintln(w, "无")
+		return
+	}
+
+	if len(lowConfidenceGroups) > 0 {
+		fmt.Fprintf(w, "\n--- 需人工确认（%d 组，置信度低于阈值、版本差异或大小比例异常，不参与非交互操作）---\n", len(lowConfidenceGroups))
+	}
+	for _, groupName := range sortedGroupNames(lowConfidenceGroups) {
+		group := lowConfidenceGroups[groupName]
+		fmt.Fprintf(w, "\n组名: %s\n", groupName)
+		fmt.Fprintf(w, "置信度: %.2f（%s）\n", group.Confidence, group.Evidence.describe())
+		printAliasNames(w, group.AliasNames)
+		printFileGrouped(w, group.FileGrouped)
+		printGroupNote(w, group.Note)
+		if group.Collection != nil && group.Collection.ID != nil && group.Collection.SizeWhenDone != nil {
+			collectionSize := formatSize(torrentBytes(group.Collection))
+			fmt.Fprintf(w, "合集(不会被暂停): ID: %d, 大小: %s\n", *group.Collection.ID, collectionSize)
+		}
+		fmt.Fprintf(w, "包含 %d 个分集:\n", len(group.Episodes))
+		for i, episode := range group.Episodes {
+			if episode != nil && episode.ID != nil && episode.SizeWhenDone != nil {
+				fmt.Fprintf(w, "  %d. ID: %d, 大小: %s\n", i+1, *episode.ID, formatSize(torrentBytes(episode)))
+			}
+		}
+		printUnregisteredEpisodes(w, group.UnregisteredEpisodes)
+	}
+
+	if len(dupGroupsWithOnlySameSize) > 0 {
+		fmt.Fprintf(w, "\n--- 只有大小相同分集的合集（%d 组，可能是辅种）---\n", len(dupGroupsWithOnlySameSize))
+	}
+
+	for _, groupName := range sortedGroupNames(dupGroupsWithOnlySameSize) {
+		group := dupGroupsWithOnlySameSize[groupName]
+		fmt.Fprintf(w, "\n组名: %s\n", groupName)
+		printAliasNames(w, group.AliasNames)
+		printFileGrouped(w, group.FileGrouped)
+		printGroupNote(w, group.Note)
+
+		// 显示合集信息
+		if group.Collection != nil && group.Collection.ID != nil && group.Collection.SizeWhenDone != nil {
+			collectionSize := formatSize(torrentBytes(group.Collection))
+			fmt.Fprintf(w, "合集(不会被暂停): ID: %d, 大小: %s\n", *group.Collection.ID, collectionSize)
+		}
+
+		// 显示大小相同分集信息
+		if len(group.Episodes) > 0 {
+			fmt.Fprintf(w, "包含 %d 个大小相同分集(大小与合集一致):\n", len(group.Episodes))
+			for i, episode := range group.Episodes {
+				if episode != nil && episode.ID != nil && episode.SizeWhenDone != nil {
+					episodeSize := formatSize(torrentBytes(episode))
+					fmt.Fprintf(w, "  %d. ID: %d, 大小: %s\n", i+1, *episode.ID, episodeSize)
+				}
+			}
+		}
+		printPartialEpisodes(w, group.PartialEpisodes)
+		printUnregisteredEpisodes(w, group.UnregisteredEpisodes)
+
+		// 显示文件重叠状态
+		fmt.Fprintf(w, "文件列表重叠状态: %t\n", group.HasFileOverlaps)
+	}
+
+	if len(partialGroups) > 0 {
+		fmt.Fprintf(w, "\n--- 分集只有部分内容包含在合集中（%d 组，部分包含）---\n", len(partialGroups))
+	}
+	for _, groupName := range sortedGroupNames(partialGroups) {
+		group := partialGroups[groupName]
+		fmt.Fprintf(w, "\n组名: %s\n", groupName)
+		printAliasNames(w, group.AliasNames)
+		printFileGrouped(w, group.FileGrouped)
+		printGroupNote(w, group.Note)
+		if group.Collection != nil && group.Collection.ID != nil && group.Collection.SizeWhenDone != nil {
+			collectionSize := formatSize(torrentBytes(group.Collection))
+			fmt.Fprintf(w, "合集(不会被暂停): ID: %d, 大小: %s\n", *group.Collection.ID, collectionSize)
+		}
+		printPartialEpisodes(w, group.PartialEpisodes)
+	}
+
+	if len(oversizedGroups) > 0 {
+		fmt.Fprintf(w, "\n--- 分集大小之和超过合集（%d 组，可能是不同版本）---\n", len(oversizedGroups))
+	}
+	for _, groupName := range sortedGroupNames(oversizedGroups) {
+		group := oversizedGroups[groupName]
+		fmt.Fprintf(w, "\n组名: %s\n", groupName)
+		printAliasNames(w, group.AliasNames)
+		printFileGrouped(w, group.FileGrouped)
+		printGroupNote(w, group.Note)
+		collectionSize, episodesSize := sizeSums(group.Collection, group.Episodes, group.Evidence.PaddingBytes)
+		if group.Collection != nil && group.Collection.ID != nil {
+			fmt.Fprintf(w, "合集(不会被暂停): ID: %d, 大小: %s\n", *group.Collection.ID, formatSize(int64(collectionSize)))
+		}
+		fmt.Fprintf(w, "%d 个分集大小之和: %s，超过合集 %s:\n", len(group.Episodes), formatSize(int64(episodesSize)), formatSize(int64(episodesSize-collectionSize)))
+		for i, episode := range group.Episodes {
+			if episode != nil && episode.ID != nil && episode.SizeWhenDone != nil {
+				fmt.Fprintf(w, "  %d. ID: %d, 大小: %s\n", i+1, *episode.ID, formatSize(torrentBytes(episode)))
+			}
+		}
+		printPartialEpisodes(w, group.PartialEpisodes)
+		printUnregisteredEpisodes(w, group.UnregisteredEpisodes)
+	}
+
+	if len(gatedGroups) > 0 {
+		fmt.Fprintf(w, "\n--- 分集全部被策略暂缓（%d 组，tracker策略、保留最新分集或合集做种人数不足）---\n", len(gatedGroups))
+	}
+	for _, groupName := range sortedGroupNames(gatedGroups) {
+		group := gatedGroups[groupName]
+		fmt.Fprintf(w, "\n组名: %s\n", groupName)
+		printAliasNames(w, group.AliasNames)
+		printFileGrouped(w, group.FileGrouped)
+		printGroupNote(w, group.Note)
+		printSwarmNote(w, group.SwarmNote)
+		if group.Collection != nil && group.Collection.ID != nil && group.Collection.SizeWhenDone != nil {
+			collectionSize := formatSize(torrentBytes(group.Collection))
+			fmt.Fprintf(w, "合集(不会被暂停): ID: %d, 大小: %s\n", *group.Collection.ID, collectionSize)
+		}
+		printGatedEpisodes(w, group.GatedEpisodes)
+		printPartialEpisodes(w, group.PartialEpisodes)
+		printUnregisteredEpisodes(w, group.UnregisteredEpisodes)
+	}
+
+	if len(activeGroups) > 0 {
+		fmt.Fprintf(w, "\n--- 分集全部正在活跃上传（%d 组）---\n", len(activeGroups))
+	}
+	for _, groupName := range sortedGroupNames(activeGroups) {
+		group := activeGroups[groupName]
+		fmt.Fprintf(w, "\n组名: %s\n", groupName)
+		printAliasNames(w, group.AliasNames)
+		printFileGrouped(w, group.FileGrouped)
+		printGroupNote(w, group.Note)
+		if group.Collection != nil && group.Collection.ID != nil && group.Collection.SizeWhenDone != nil {
+			collectionSize := formatSize(torrentBytes(group.Collection))
+			fmt.Fprintf(w, "合集(不会被暂停): ID: %d, 大小: %s\n", *group.Collection.ID, collectionSize)
+		}
+		printActiveEpisodes(w, group.ActiveEpisodes)
+		printGatedEpisodes(w, group.GatedEpisodes)
+		printPartialEpisodes(w, group.PartialEpisodes)
+		printUnregisteredEpisodes(w, group.UnregisteredEpisodes)
+	}
+
+	if len(handledGroups) > 0 {
+		fmt.Fprintf(w, "\n--- 已处理（%d 组，分集已全部暂停，无需操作）---\n", len(handledGroups))
+	}
+	for _, groupName := range sortedGroupNames(handledGroups) {
+		group := handledGroups[groupName]
+		fmt.Fprintf(w, "\n组名: %s\n", groupName)
+		printAliasNames(w, group.AliasNames)
+		printFileGrouped(w, group.FileGrouped)
+		printGroupNote(w, group.Note)
+		if group.Collection != nil && group.Collection.ID != nil && group.Collection.SizeWhenDone != nil {
+			collectionSize := formatSize(torrentBytes(group.Collection))
+			fmt.Fprintf(w, "合集(不会被暂停): ID: %d, 大小: %s\n", *group.Collection.ID, collectionSize)
+		}
+		printHandledEpisodes(w, group.HandledEpisodes)
+		printGatedEpisodes(w, group.GatedEpisodes)
+		printUnregisteredEpisodes(w, group.UnregisteredEpisodes)
+	}
+
+	if len(belowMinGroups) > 0 {
+		fmt.Fprintf(w, "\n--- 低于最小分集数（%d 组，可处理的分集数量不足，不会被处理）---\n", len(belowMinGroups))
+	}
+	for _, groupName := range sortedGroupNames(belowMinGroups) {
+		group := belowMinGroups[groupName]
+		fmt.Fprintf(w, "\n组名: %s\n", groupName)
+		printAliasNames(w, group.AliasNames)
+		printFileGrouped(w, group.FileGrouped)
+		printGroupNote(w, group.Note)
+		if group.Collection != nil && group.Collection.ID != nil && group.Collection.SizeWhenDone != nil {
+			collectionSize := formatSize(torrentBytes(group.Collection))
+			fmt.Fprintf(w, "合集(不会被暂停): ID: %d, 大小: %s\n", *group.Collection.ID, collectionSize)
+		}
+		fmt.Fprintf(w, "可处理 %d 个分集:\n", len(group.Episodes))
+		for i, episode := range group.Episodes {
+			if episode != nil && episode.ID != nil && episode.SizeWhenDone != nil {
+				fmt.Fprintf(w, "  %d. ID: %d, 大小: %s\n", i+1, *episode.ID, formatSize(torrentBytes(episode)))
+			}
+		}
+		printHandledEpisodes(w, group.HandledEpisodes)
+		printGatedEpisodes(w, group.GatedEpisodes)
+		printActiveEpisodes(w, group.ActiveEpisodes)
+		printUnregisteredEpisodes(w, group.UnregisteredEpisodes)
+	}
+}
+
+// 显示通过名称映射归入同一组的种子名称
+func printAliasNames(w io.Writer, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "通过名称映射分组:")
+	for _, name := range names {
+		fmt.Fprintf(w, "  - %s\n", name)
+	}
+}
+
+// 显示按文件名归入本组的种子，按ID排列
+func printFileGrouped(w io.Writer, torrents map[int64]string) {
+	if len(torrents) == 0 {
+		return
+	}
+	ids := make([]int64, 0, len(torrents))
+	for id := range torrents {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	fmt.Fprintln(w, "按文件名归组（名称与文件不一致，可能在 Transmission 中改过名）:")
+	for _, id := range ids {
+		fmt.Fprintf(w, "  - ID: %d, %s\n", id, torrents[id])
+	}
+}
+
+// 会话处于限速状态时提示上传速率不代表真实能力
+func printSpeedLimitNotice(w io.Writer, limits SpeedLimits) {
+	if limits.Active() {
+		fmt.Fprintf(w, "\n注意: 当前处于限速模式（%s），上传速率不代表真实能力\n", limits.describe())
+	}
+}
+
+// 显示正在活跃上传而被保留的分集
+func printActiveEpisodes(w io.Writer, activeEpisodes []ActiveEpisode) {
+	if len(activeEpisodes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "正在活跃上传 %d 个分集(不会被处理):\n", len(activeEpisodes))
+	for i, active := range activeEpisodes {
+		episode := active.Episode
+		if episode == nil || episode.ID == nil || episode.SizeWhenDone == nil {
+			continue
+		}
+		line := fmt.Sprintf("  %d. ID: %d, 大小: %s, %s", i+1, *episode.ID, formatSize(torrentBytes(episode)), active.Reason)
+		if limit := describeTorrentUploadLimit(episode); limit != "" {
+			line += ", " + limit
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// 显示被tracker策略暂缓的分集及原因
+func printGatedEpisodes(w io.Writer, gatedEpisodes []GatedEpisode) {
+	if len(gatedEpisodes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "被策略暂缓 %d 个分集(不会被处理):\n", len(gatedEpisodes))
+	for i, gated := range gatedEpisodes {
+		episode := gated.Episode
+		if episode == nil || episode.ID == nil || episode.SizeWhenDone == nil {
+			continue
+		}
+		fmt.Fprintf(w, "  %d. ID: %d, 大小: %s, 策略: %s, %s\n", i+1, *episode.ID, formatSize(torrentBytes(episode)), gated.Policy, gated.Reason)
+	}
+}
+
+// 显示部分包含的分集及合集中找不到的文件
+func printPartialEpisodes(w io.Writer, partialEpisodes []PartialEpisode) {
+	if len(partialEpisodes) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "部分包含 %d 个分集(含有合集中没有的文件，不会被处理):\n", len(partialEpisodes))
+	for i, partial := range partialEpisodes {
+		episode := partial.Episode
+		if episode == nil || episode.ID == nil || episode.SizeWhenDone == nil {
+			continue
+		}
+		fmt.Fprintf(w, "  %d. ID: %d, 大小: %s\n", i+1, *episode.ID, formatSize(torrentBytes(episode)))
+		fmt.Fprintln(w, "    合集中找不到的文件:")
+		for _, file := range partial.UncoveredFiles {
+			fmt.Fprintf(w, "      - %s\n", displayPath(episode, file))
+		}
+	}
+}
+
+// 第三部分：跳过的种子组，默认只显示各原因的数量，详细模式下列出全部
+func printSkipSummary(w io.Writer, result *ScanResult, verbose bool) {
+	byReason := make(map[string][]SkipRecord)
+	for _, record := range result.Skipped {
+		byReason[record.Reason] = append(byReason[record.Reason], record)
+	}
+
+	fmt.Fprintf(w, "\n===== 三、跳过的种子组（%d 条）=====\n", len(result.Skipped))
+	fmt.Fprintf(w, "- 处理种子组数量: %d\n", result.ProcessedCount)
+	if result.Interrupted {
+		fmt.Fprintf(w, "- 分析已中断，未完成的种子组数量: %d\n", result.TotalGroups-result.ProcessedCount)
+	}
+	fmt.Fprintf(w, "- 符合条件的种子组数量: %d\n", len(result.DuplicateGroups))
+	fmt.Fprintf(w, "- 只有大小相同分集的种子组数量: %d\n", len(result.SameSizeGroups))
+	fmt.Fprintf(w, "- 只有部分包含分集的种子组数量: %d\n", len(result.PartialGroups))
+	fmt.Fprintf(w, "- 分集大小之和超过合集的种子组数量: %d\n", len(result.OversizedGroups))
+	fmt.Fprintf(w, "- 分集全部被策略暂缓的种子组数量: %d\n", len(result.GatedGroups))
+	fmt.Fprintf(w, "- 分集全部正在活跃上传的种子组数量: %d\n", len(result.ActiveGroups))
+	fmt.Fprintf(w, "- 分集已全部暂停的种子组数量: %d\n", len(result.HandledGroups))
+	fmt.Fprintf(w, "- 需人工确认的种子组数量: %d\n", len(result.LowConfidenceGroups))
+	fmt.Fprintf(w, "- 低于最小分集数的种子组数量: %d\n", len(result.BelowMinEpisodesGroups))
+	fmt.Fprintf(w, "- 已标记为误判而忽略的分集数量: %d\n", result.SuppressedCount)
+	fmt.Fprintf(w, "- 按状态排除、未作为分集的种子数量: %d\n", len(result.StatusExcluded))
+	fmt.Fprintf(w, "- 不属于任何组的已失效种子数量: %d\n", len(result.Unregistered))
+	fmt.Fprintf(w, "- %s数量: %d\n", MISSING_DATA_GATE_POLICY, len(missingDataTargets(result)))
+	for _, reason := range skipReasonOrder {
+		records := byReason[reason]
+		fmt.Fprintf(w, "- 跳过%s: %d\n", skipReasonLabels[reason], len(records))
+		if !verbose {
+			continue
+		}
+		for _, record := range records {
+			if record.Detail != "" {
+				fmt.Fprintf(w, "    %s (%s)\n", record.Name, record.Detail)
+			} else {
+				fmt.Fprintf(w, "    %s\n", record.Name)
+			}
+		}
+	}
+	if !verbose && len(result.Skipped) > 0 {
+		fmt.Fprintln(w, "（使用 --verbose 显示全部跳过的种子）")
+	}
+}
+
+// 种子的tracker和标签，便于人工检查辅种：默认只显示第一个tracker的主机名，详细模式显示全部，
+// 没有tracker（只用DHT）时显示为"无tracker"
+func describeTrackersAndLabels(torrent *transmissionrpc.Torrent, verbose bool) string {
+	hosts := trackerHosts(torrent)
+	var line string
+	switch {
+	case len(hosts) == 0:
+		line = "无tracker"
+	case verbose || len(hosts) == 1:
+		line = "tracker=" + strings.Join(hosts, ",")
+	default:
+		line = fmt.Sprintf("tracker=%s(+%d)", hosts[0], len(hosts)-1)
+	}
+	if len(torrent.Labels) > 0 {
+		line += fmt.Sprintf(", labels=[%s]", strings.Join(torrent.Labels, ","))
+	}
+	return line
+}
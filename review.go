@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 审阅文件中只用于审阅的分集操作
+const (
+	ACTION_DELETE = "delete" // 删除分集种子及其数据
+	ACTION_LABEL  = "label"  // 为分集添加标签，不改变运行状态
+)
+
+// 审阅文件中组的操作
+const (
+	REVIEW_SKIP         = "skip"
+	REVIEW_PAUSE        = "pause"
+	REVIEW_DELETE       = "delete"
+	REVIEW_LABEL_ACTION = "label"
+)
+
+// label 操作添加的标签
+const REVIEW_LABEL = "duplicate-episode"
+
+// 审阅文件格式版本
+const REVIEW_VERSION = 1
+
+// 审阅文件：每个需要处理的组一项，可以修改组的操作和保留个别分集
+type ReviewFile struct {
+	Version int           `json:"version"`
+	Groups  []ReviewGroup `json:"groups"`
+}
+
+// 审阅文件中的一组
+type ReviewGroup struct {
+	Name       string          `json:"name"`
+	Action     string          `json:"action"`
+	Collection ReviewTorrent   `json:"collection"`
+	Episodes   []ReviewEpisode `json:"episodes"`
+}
+
+// 审阅文件中的种子，按hash匹配，ID和名称仅供参考
+type ReviewTorrent struct {
+	Hash string `json:"hash"`
+	ID   int64  `json:"id"`
+	Name string `json:"name"`
+}
+
+// 审阅文件中的分集，keep 为 true 时不执行组的操作
+type ReviewEpisode struct {
+	ReviewTorrent
+	Keep bool `json:"keep"`
+}
+
+// 组的操作对应的分集操作
+func reviewEpisodeAction(action string) string {
+	switch action {
+	case REVIEW_DELETE:
+		return ACTION_DELETE
+	case REVIEW_LABEL_ACTION:
+		return ACTION_LABEL
+	}
+	return ACTION_PAUSE
+}
+
+// 根据扫描结果中需要处理的组创建审阅文件，组的操作默认为 pause
+func buildReview(result *ScanResult) ReviewFile {
+	review := ReviewFile{Version: REVIEW_VERSION}
+	for _, name := range sortedGroupNames(result.DuplicateGroups) {
+		group := result.DuplicateGroups[name]
+		if group.Collection == nil {
+			continue
+		}
+		collection := newPlanTorrent(group.Collection)
+		reviewGroup := ReviewGroup{
+			Name:       name,
+			Action:     REVIEW_PAUSE,
+			Collection: ReviewTorrent{Hash: collection.Hash, ID: collection.ID, Name: collection.Name},
+		}
+		for _, episode := range group.Episodes {
+			if episode == nil || episode.ID == nil {
+				continue
+			}
+			planEpisode := newPlanTorrent(episode)
+			reviewGroup.Episodes = append(reviewGroup.Episodes, ReviewEpisode{
+				ReviewTorrent: ReviewTorrent{Hash: planEpisode.Hash, ID: planEpisode.ID, Name: planEpisode.Name},
+			})
+		}
+		review.Groups = append(review.Groups, reviewGroup)
+	}
+	return review
+}
+
+// 以YAML保存审阅文件
+func saveReview(path string, review ReviewFile) error {
+	var b strings.Builder
+	b.WriteString("# delete-episode 审阅文件，修改后使用 --review-in 执行\n")
+	fmt.Fprintf(&b, "# 组的 action: %s（不处理）、%s（暂停分集）、%s（删除分集种子及数据，无法撤销）、%s（为分集添加标签 %s）\n",
+		REVIEW_SKIP, REVIEW_PAUSE, REVIEW_DELETE, REVIEW_LABEL_ACTION, REVIEW_LABEL)
+	b.WriteString("# 分集的 keep: true 表示保留该分集，不执行组的操作\n")
+	b.WriteString("# 种子按 hash 匹配，请勿修改 hash；id 和 name 仅供参考\n")
+	fmt.Fprintf(&b, "version: %d\n", review.Version)
+	if len(review.Groups) == 0 {
+		b.WriteString("groups: []\n")
+	} else {
+		b.WriteString("groups:\n")
+	}
+	for _, group := range review.Groups {
+		fmt.Fprintf(&b, "  - name: %s\n", strconv.Quote(group.Name))
+		fmt.Fprintf(&b, "    action: %s\n", group.Action)
+		b.WriteString("    collection:\n")
+		fmt.Fprintf(&b, "      hash: %s\n", strconv.Quote(group.Collection.Hash))
+		fmt.Fprintf(&b, "      id: %d\n", group.Collection.ID)
+		fmt.Fprintf(&b, "      name: %s\n", strconv.Quote(group.Collection.Name))
+		b.WriteString("    episodes:\n")
+		for _, episode := range group.Episodes {
+			fmt.Fprintf(&b, "      - hash: %s\n", strconv.Quote(episode.Hash))
+			fmt.Fprintf(&b, "        id: %d\n", episode.ID)
+			fmt.Fprintf(&b, "        name: %s\n", strconv.Quote(episode.Name))
+			fmt.Fprintf(&b, "        keep: %t\n", episode.Keep)
+		}
+	}
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
+
+// 读取并校验审阅文件
+func loadReview(path string) (ReviewFile, error) {
+	var review ReviewFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return review, err
+	}
+	value, err := parseYAMLSubset(string(data))
+	if err != nil {
+		return review, err
+	}
+	// 解析结果只包含映射、列表和标量，经JSON转换为结构体
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return review, err
+	}
+	if err := json.Unmarshal(encoded, &review); err != nil {
+		return review, fmt.Errorf("格式不正确: %v", err)
+	}
+	if review.Version != REVIEW_VERSION {
+		return review, fmt.Errorf("不支持的审阅文件版本: %d", review.Version)
+	}
+	for i, group := range review.Groups {
+		switch group.Action {
+		case REVIEW_SKIP, REVIEW_PAUSE, REVIEW_DELETE, REVIEW_LABEL_ACTION:
+		default:
+			return review, fmt.Errorf("第 %d 组 (%s) 的 action 无效: %s（可选: %s, %s, %s, %s）",
+				i+1, group.Name, group.Action, REVIEW_SKIP, REVIEW_PAUSE, REVIEW_DELETE, REVIEW_LABEL_ACTION)
+		}
+		if group.Collection.Hash == "" {
+			return review, fmt.Errorf("第 %d 组 (%s) 缺少合集的 hash", i+1, group.Name)
+		}
+		for j, episode := range group.Episodes {
+			if episode.Hash == "" {
+				return review, fmt.Errorf("第 %d 组 (%s) 的第 %d 个分集缺少 hash", i+1, group.Name, j+1)
+			}
+		}
+	}
+	return review, nil
+}
+
+// 为分集添加标签，已有该标签的分集跳过；原来的标签记录到操作历史以便撤销
+func labelEpisodes(client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup, history *HistoryWriter) (int, int, int) {
+	successCount, skippedCount, failedCount := 0, 0, 0
+	for _, groupName := range sortedGroupNames(duplicateGroups) {
+		for _, episode := range duplicateGroups[groupName].Episodes {
+			if episode == nil || episode.ID == nil {
+				continue
+			}
+			labels := append([]string{}, episode.Labels...)
+			if slices.Contains(labels, REVIEW_LABEL) {
+				skippedCount++
+				continue
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+			err := client.TorrentSet(ctx, transmissionrpc.TorrentSetPayload{
+				IDs:    []int64{*episode.ID},
+				Labels: append(labels, REVIEW_LABEL),
+			})
+			cancel()
+			if err != nil {
+				fmt.Printf("添加标签失败 ID: %d: %v\n", *episode.ID, err)
+				failedCount++
+				continue
+			}
+			record := newHistoryRecord(ACTION_LABEL, groupName, episode)
+			record.PrevLabels = episode.Labels
+			history.Record(record)
+			fmt.Printf("已添加标签 %s ID: %d\n", REVIEW_LABEL, *episode.ID)
+			keptExport.succeed(groupName)
+			runNotices.succeed(groupName)
+			successCount++
+		}
+	}
+	return successCount, skippedCount, failedCount
+}
+
+// 按hash把审阅文件中的组对应到当前的种子。合集或分集的hash已不匹配任何种子时拒绝执行该项，
+// 返回需要执行的组
+func reviewGroups(review ReviewFile, torrents []transmissionrpc.Torrent, labelsSupported bool) map[string]DuplicateGroup {
+	byHash := make(map[string]*transmissionrpc.Torrent)
+	for i := range torrents {
+		if torrents[i].HashString != nil {
+			byHash[*torrents[i].HashString] = &torrents[i]
+		}
+	}
+
+	groups := make(map[string]DuplicateGroup)
+	refused, skipped, kept := 0, 0, 0
+	for _, reviewGroup := range review.Groups {
+		if reviewGroup.Action == REVIEW_SKIP {
+			skipped++
+			continue
+		}
+		if reviewGroup.Action == REVIEW_LABEL_ACTION && !labelsSupported {
+			fmt.Printf("拒绝: 服务器不支持种子标签，无法执行组 %s 的 label\n", reviewGroup.Name)
+			refused++
+			continue
+		}
+		collection, ok := byHash[reviewGroup.Collection.Hash]
+		if !ok || collection.ID == nil {
+			fmt.Printf("拒绝: 合集的 hash 已不匹配任何种子，跳过组 %s\n", reviewGroup.Name)
+			refused++
+			continue
+		}
+		group := DuplicateGroup{
+			Collection:     collection,
+			EpisodeActions: make(map[int64]string),
+		}
+		for _, reviewEpisode := range reviewGroup.Episodes {
+			if reviewEpisode.Keep {
+				kept++
+				continue
+			}
+			episode, ok := byHash[reviewEpisode.Hash]
+			if !ok || episode.ID == nil {
+				fmt.Printf("拒绝: 分集的 hash 已不匹配任何种子，跳过 %s (%s)\n", reviewEpisode.Name, reviewEpisode.Hash)
+				refused++
+				continue
+			}
+			if *episode.ID != reviewEpisode.ID {
+				fmt.Printf("注意: 分集 %s 的ID已由 %d 变为 %d，按 hash 执行\n", reviewEpisode.Name, reviewEpisode.ID, *episode.ID)
+			}
+			group.Episodes = append(group.Episodes, episode)
+			group.EpisodeActions[*episode.ID] = reviewEpisodeAction(reviewGroup.Action)
+		}
+		if len(group.Episodes) > 0 {
+			groups[reviewGroup.Name] = group
+		}
+	}
+	fmt.Printf("\n审阅文件: 执行 %d 组, 跳过 %d 组, 保留 %d 个分集, 拒绝 %d 项\n", len(groups), skipped, kept, refused)
+	return groups
+}
+
+// 按hash查询审阅文件中全部合集和分集的当前状态
+func lookupReviewTorrents(client *transmissionrpc.Client, capabilities ServerCapabilities, review ReviewFile) ([]transmissionrpc.Torrent, error) {
+	var hashes []string
+	for _, group := range review.Groups {
+		hashes = append(hashes, group.Collection.Hash)
+		for _, episode := range group.Episodes {
+			hashes = append(hashes, episode.Hash)
+		}
+	}
+	if len(hashes) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+	defer cancel()
+	return client.TorrentGetHashes(ctx, capabilities.torrentFields(), hashes)
+}
+
+// 按审阅文件执行：只执行文件中的操作，不重新扫描
+func runReview(reader *bufio.Reader, opts Options) {
+	review, err := loadReview(opts.ReviewIn)
+	if err != nil {
+		log.Fatalf("读取审阅文件失败: %v", err)
+	}
+
+	params := opts.Connection
+	if !opts.ConnectionSet {
+		params = readConnectionParams(reader)
+		params.Proxy = opts.Connection.Proxy
+	}
+	opts.Connection = params
+	printConnectionParams(params)
+	client, err := connect(params)
+	if err != nil {
+		log.Fatalf("无法连接到 Transmission 服务器%s: %v", params.proxyHint(), err)
+	}
+	capabilities := detectCapabilities(client)
+	torrents, err := lookupReviewTorrents(client, capabilities, review)
+	if err != nil {
+		log.Fatalf("查询审阅文件中种子的状态失败%s: %v", params.proxyHint(), err)
+	}
+
+	groups := reviewGroups(review, torrents, capabilities.Labels)
+	// 私有分集的删除与扫描时一样默认改为暂停
+	result := &ScanResult{DuplicateGroups: groups}
+	applyPrivacyDefaults(result, opts)
+	if len(groups) == 0 {
+		fmt.Println("审阅文件中没有需要执行的组")
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, group := range groups {
+		for _, episode := range group.Episodes {
+			counts[group.episodeAction(episode, ACTION_PAUSE)]++
+		}
+	}
+	for _, action := range []string{ACTION_PAUSE, ACTION_DELETE, ACTION_LABEL} {
+		if counts[action] > 0 {
+			fmt.Printf("- %s: %d 个分集\n", actionName(action), counts[action])
+		}
+	}
+	if !opts.Yes && !opts.DryRun {
+		printManifest(buildManifest(groups, ACTION_PAUSE))
+		fmt.Print("是否执行? (y/n): ")
+		answer, _ := reader.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+			fmt.Println("操作已取消")
+			return
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	history := newHistoryWriter()
+	throttle := newActionThrottle(opts.MaxActions, opts.ActionDelay)
+	ceiling := newDeleteCeiling(opts.MaxDeleteSize)
+	applyAction(ctx, reader, client, groups, opts, history, throttle, ceiling)
+	throttle.printDeferred()
+	ceiling.printSummary()
+	if history.Count() > 0 {
+		fmt.Printf("已记录 %d 条操作历史，可使用 \"%s undo\" 撤销本次操作（删除无法撤销）\n", history.Count(), os.Args[0])
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 各功能所需的最低RPC版本
+const (
+	RPC_VERSION_LABELS = 16 // Transmission 3.00 起支持种子标签
+
+	RPC_VERSION_BANDWIDTH_GROUPS = 17 // Transmission 4.0 起支持带宽组
+)
+
+// 获取种子列表时始终请求的字段，Transmission 2.x 均支持
+var baseTorrentFields = []string{
+	"id",
+	"name",
+	"hashString",
+	"sizeWhenDone",
+	"status",
+	"bandwidthPriority",
+	"uploadedEver",
+	"uploadRatio",
+	"secondsSeeding",
+	"trackers",
+	"percentDone",
+	"downloadDir",
+	"rateUpload",
+	"uploadLimit",
+	"uploadLimited",
+	"doneDate",
+	"addedDate",
+	"isPrivate",
+	"error",
+	"errorString",
+	"trackerStats",
+	"peersConnected",
+	"webseeds",
+	"isFinished",
+	"seedRatioMode",
+	"seedRatioLimit",
+	"seedIdleMode",
+	"seedIdleLimit",
+	"metadataPercentComplete",
+}
+
+// 服务器支持的功能，根据RPC版本判断
+type ServerCapabilities struct {
+	RPCVersion int64
+	Labels     bool
+
+	BandwidthGroups bool
+}
+
+// 查询服务器RPC版本并确定可用功能，查询失败时按最低版本处理
+func detectCapabilities(client *transmissionrpc.Client) ServerCapabilities {
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+	defer cancel()
+
+	_, serverVersion, _, err := client.RPCVersion(ctx)
+	if err != nil {
+		log.Printf("查询服务器RPC版本失败，将按旧版本处理: %v", err)
+		serverVersion = 0
+	}
+	capabilities := newServerCapabilities(serverVersion)
+	capabilities.printWarnings()
+	return capabilities
+}
+
+// 根据RPC版本构建服务器功能
+func newServerCapabilities(rpcVersion int64) ServerCapabilities {
+	return ServerCapabilities{
+		RPCVersion: rpcVersion,
+		Labels:     rpcVersion >= RPC_VERSION_LABELS,
+
+		BandwidthGroups: rpcVersion >= RPC_VERSION_BANDWIDTH_GROUPS,
+	}
+}
+
+// 检查参数需要的功能服务器是否支持，不支持时退出
+func (c ServerCapabilities) require(opts Options) {
+	if opts.TwoPhase && !c.Labels {
+		log.Fatalf("服务器不支持种子标签（需要RPC版本 %d 以上），无法使用 --two-phase", RPC_VERSION_LABELS)
+	}
+	if opts.Action == ACTION_BANDWIDTH_GROUP && !c.BandwidthGroups {
+		log.Fatalf("服务器不支持带宽组（需要RPC版本 %d 以上，即 Transmission 4.0 以上），无法使用 --action %s", RPC_VERSION_BANDWIDTH_GROUPS, ACTION_BANDWIDTH_GROUP)
+	}
+}
+
+// 打印旧版本服务器不支持的功能
+func (c ServerCapabilities) printWarnings() {
+	if c.RPCVersion > 0 {
+		fmt.Printf("Transmission RPC 版本: %d\n", c.RPCVersion)
+	}
+	if !c.Labels {
+		fmt.Printf("警告: 服务器RPC版本低于 %d，不支持种子标签，标签相关功能已禁用\n", RPC_VERSION_LABELS)
+	}
+}
+
+// 获取种子列表时请求的字段，只包含服务器支持的字段
+func (c ServerCapabilities) torrentFields() []string {
+	fields := append([]string{}, baseTorrentFields...)
+	if c.Labels {
+		fields = append(fields, "labels")
+	}
+	return fields
+}
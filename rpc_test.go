@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+func TestNewServerCapabilities(t *testing.T) {
+	tests := []struct {
+		rpcVersion      int64
+		labels          bool
+		bandwidthGroups bool
+	}{
+		{0, false, false}, // 查询版本失败
+		{14, false, false},
+		{15, false, false},
+		{16, true, false},
+		{17, true, true},
+	}
+	for _, tt := range tests {
+		capabilities := newServerCapabilities(tt.rpcVersion)
+		if capabilities.Labels != tt.labels || capabilities.BandwidthGroups != tt.bandwidthGroups {
+			t.Errorf("RPC版本 %d: Labels=%t BandwidthGroups=%t，应为 %t %t",
+				tt.rpcVersion, capabilities.Labels, capabilities.BandwidthGroups, tt.labels, tt.bandwidthGroups)
+		}
+		if got := slices.Contains(capabilities.torrentFields(), "labels"); got != tt.labels {
+			t.Errorf("RPC版本 %d: 请求字段包含 labels=%t，应为 %t", tt.rpcVersion, got, tt.labels)
+		}
+	}
+}
+
+// 模拟旧版本服务器：请求不支持的字段时返回错误
+type oldServerTransport struct {
+	next        http.RoundTripper
+	unsupported string
+}
+
+func (o *oldServerTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		return nil, err
+	}
+	request.Body = io.NopCloser(bytes.NewReader(body))
+	var rpcRequest diagRequest
+	json.Unmarshal(body, &rpcRequest)
+	var arguments struct {
+		Fields []string `json:"fields"`
+	}
+	json.Unmarshal(rpcRequest.Arguments, &arguments)
+	if rpcRequest.Method == "torrent-get" && slices.Contains(arguments.Fields, o.unsupported) {
+		data, _ := json.Marshal(map[string]interface{}{"result": "invalid argument", "tag": rpcRequest.Tag})
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Content-Type": []string{"application/json"}},
+			Body:       io.NopCloser(bytes.NewReader(data)),
+			Request:    request,
+		}, nil
+	}
+	return o.next.RoundTrip(request)
+}
+
+func TestScanOldServer(t *testing.T) {
+	client, opts := connectFixture(t, "scan.json")
+	dumpReplay.Methods["session-get"] = json.RawMessage(`{"rpc-version": 14, "rpc-version-minimum": 1, "version": "2.94 (d8e60ee44f)"}`)
+	httpClient, err := rpcHTTPClient(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpClient.Transport = &oldServerTransport{next: httpClient.Transport, unsupported: "labels"}
+
+	capabilities := detectCapabilities(client)
+	if capabilities.Labels {
+		t.Fatal("RPC版本 14 不应支持标签")
+	}
+	result, err := scan(client, capabilities, opts)
+	if err != nil {
+		t.Fatalf("旧版本服务器上扫描失败: %v", err)
+	}
+	if len(result.DuplicateGroups) == 0 {
+		t.Error("旧版本服务器上没有找到需要处理的组")
+	}
+	for _, torrent := range result.Torrents {
+		if torrent.Labels != nil {
+			t.Errorf("种子 ID: %d 不应有标签", *torrent.ID)
+		}
+	}
+}
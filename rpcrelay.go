@@ -0,0 +1,144 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 服务器的RPC路径，与 transmissionrpc 的默认值相同
+const RPC_PATH = "/transmission/rpc"
+
+// 一次HTTP请求的超时时间，与 transmissionrpc 的默认值相同
+const RPC_HTTP_TIMEOUT = 30 * time.Second
+
+// 一个客户端的RPC连接。transmissionrpc 不能指定HTTP客户端，它的请求发到本机回环地址上的中转，
+// 由本程序自己的 http.Client 转发到服务器；代理、Unix socket、会话ID处理、诊断包的记录和重放
+// 都通过替换这个客户端的 Transport 设置，rawRPC 也直接使用它
+type rpcConnection struct {
+	endpoint   string // 服务器的RPC地址
+	user       string
+	password   string
+	httpClient *http.Client
+	relayPath  string // 中转只接受的路径，含随机令牌，避免本机的其他程序借用中转访问服务器
+}
+
+// 各客户端对应的RPC连接
+var (
+	rpcConnections      = make(map[*transmissionrpc.Client]*rpcConnection)
+	rpcConnectionsMutex sync.Mutex
+)
+
+// 创建经本机中转连接服务器的客户端，中转在程序退出前一直运行
+func newRPCClient(params ConnectionParams) (*transmissionrpc.Client, error) {
+	scheme := "http"
+	if params.HTTPS {
+		scheme = "https"
+	}
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("生成中转令牌失败: %v", err)
+	}
+	conn := &rpcConnection{
+		endpoint:   fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(params.Address, strconv.Itoa(params.Port)), RPC_PATH),
+		user:       params.Username,
+		password:   params.Password,
+		httpClient: &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone(), Timeout: RPC_HTTP_TIMEOUT},
+		relayPath:  "/" + hex.EncodeToString(token) + RPC_PATH,
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("启动本机中转失败: %v", err)
+	}
+	server := &http.Server{Handler: conn, ReadHeaderTimeout: RPC_HTTP_TIMEOUT}
+	go server.Serve(listener)
+
+	client, err := transmissionrpc.New("127.0.0.1", params.Username, params.Password, &transmissionrpc.AdvancedConfig{
+		Port:   uint16(listener.Addr().(*net.TCPAddr).Port),
+		RPCURI: conn.relayPath,
+	})
+	if err != nil {
+		server.Close()
+		return nil, err
+	}
+	rpcConnectionsMutex.Lock()
+	rpcConnections[client] = conn
+	rpcConnectionsMutex.Unlock()
+	return client, nil
+}
+
+// 客户端实际使用的HTTP客户端，设置代理、Unix socket 等时替换其 Transport
+func rpcHTTPClient(client *transmissionrpc.Client) (*http.Client, error) {
+	conn, err := rpcConnectionOf(client)
+	if err != nil {
+		return nil, err
+	}
+	return conn.httpClient, nil
+}
+
+// 客户端对应的RPC连接，客户端不是由 connect 创建时返回错误
+func rpcConnectionOf(client *transmissionrpc.Client) (*rpcConnection, error) {
+	rpcConnectionsMutex.Lock()
+	defer rpcConnectionsMutex.Unlock()
+	conn, ok := rpcConnections[client]
+	if !ok {
+		return nil, fmt.Errorf("客户端没有对应的RPC连接")
+	}
+	return conn, nil
+}
+
+// 把 transmissionrpc 的请求转发到服务器。连接失败等错误按RPC失败返回，
+// transmissionrpc 的错误中保留原始的错误信息（如 Unix socket 不存在、会话ID反复无效）
+func (c *rpcConnection) ServeHTTP(w http.ResponseWriter, request *http.Request) {
+	if request.URL.Path != c.relayPath {
+		http.NotFound(w, request)
+		return
+	}
+	body, err := io.ReadAll(request.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	outgoing, err := http.NewRequestWithContext(request.Context(), request.Method, c.endpoint, bytes.NewReader(body))
+	if err != nil {
+		c.relayError(w, body, err)
+		return
+	}
+	outgoing.Header = request.Header.Clone()
+	response, err := c.httpClient.Do(outgoing)
+	if err != nil {
+		c.relayError(w, body, err)
+		return
+	}
+	defer response.Body.Close()
+	for name, values := range response.Header {
+		w.Header()[name] = values
+	}
+	w.WriteHeader(response.StatusCode)
+	io.Copy(w, response.Body)
+}
+
+// 按RPC失败回答请求，result 为错误信息；transmissionrpc 要求回答带有请求的tag
+func (c *rpcConnection) relayError(w http.ResponseWriter, body []byte, err error) {
+	var rpcRequest struct {
+		Tag *int `json:"tag"`
+	}
+	json.Unmarshal(body, &rpcRequest)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"result": err.Error(),
+		"tag":    rpcRequest.Tag,
+	})
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// 模拟的 Transmission 服务器：要求基本认证和会话ID，按请求的方法回答
+func fakeTransmission(t *testing.T, user, password string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, request *http.Request) {
+		if request.URL.Path != RPC_PATH {
+			http.NotFound(w, request)
+			return
+		}
+		if requestUser, requestPassword, ok := request.BasicAuth(); !ok || requestUser != user || requestPassword != password {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if request.Header.Get(SESSION_ID_HEADER) != "fake-session" {
+			w.Header().Set(SESSION_ID_HEADER, "fake-session")
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		var rpcRequest struct {
+			Method string `json:"method"`
+			Tag    *int   `json:"tag"`
+		}
+		if err := json.NewDecoder(request.Body).Decode(&rpcRequest); err != nil {
+			t.Errorf("无效的请求: %v", err)
+		}
+		answer := map[string]interface{}{"result": "success", "tag": rpcRequest.Tag}
+		switch rpcRequest.Method {
+		case "session-get":
+			answer["arguments"] = map[string]interface{}{"download-dir": "/downloads"}
+		case "group-get":
+			answer["arguments"] = map[string]interface{}{"group": []map[string]interface{}{{"name": "slow"}}}
+		default:
+			answer["result"] = "unknown method " + rpcRequest.Method
+		}
+		json.NewEncoder(w).Encode(answer)
+	}))
+}
+
+// 测试服务器的连接参数
+func fakeParams(t *testing.T, server *httptest.Server, user, password string) ConnectionParams {
+	host, portText, err := net.SplitHostPort(strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, _ := strconv.Atoi(portText)
+	return ConnectionParams{Address: host, Port: port, Username: user, Password: password}
+}
+
+func TestRPCRelay(t *testing.T) {
+	server := fakeTransmission(t, "admin", "secret")
+	defer server.Close()
+	client, err := connect(fakeParams(t, server, "admin", "secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := client.SessionArgumentsGet(context.Background(), []string{"download-dir"})
+	if err != nil {
+		t.Fatalf("经中转的请求失败: %v", err)
+	}
+	if session.DownloadDir == nil || *session.DownloadDir != "/downloads" {
+		t.Errorf("download-dir = %v, want /downloads", session.DownloadDir)
+	}
+
+	var groups struct {
+		Group []struct {
+			Name string `json:"name"`
+		} `json:"group"`
+	}
+	if err := rawRPC(context.Background(), client, "group-get", map[string]interface{}{}, &groups); err != nil {
+		t.Fatalf("rawRPC 失败: %v", err)
+	}
+	if len(groups.Group) != 1 || groups.Group[0].Name != "slow" {
+		t.Errorf("group-get = %+v", groups)
+	}
+}
+
+func TestRPCRelayRejectsOtherPaths(t *testing.T) {
+	server := fakeTransmission(t, "", "")
+	defer server.Close()
+	client, err := connect(fakeParams(t, server, "", ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := rpcConnectionOf(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := httptest.NewRecorder()
+	conn.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, RPC_PATH, strings.NewReader("{}")))
+	if recorder.Code != http.StatusNotFound {
+		t.Errorf("不含令牌的路径返回 %d, want %d", recorder.Code, http.StatusNotFound)
+	}
+}
+
+// 连接失败时 transmissionrpc 的错误中保留原始的错误信息
+func TestRPCRelayError(t *testing.T) {
+	server := fakeTransmission(t, "", "")
+	params := fakeParams(t, server, "", "")
+	server.Close()
+	client, err := connect(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.SessionArgumentsGet(context.Background(), []string{"download-dir"})
+	if err == nil {
+		t.Fatal("服务器已关闭，请求应失败")
+	}
+	if endpoint := net.JoinHostPort(params.Address, strconv.Itoa(params.Port)); !strings.Contains(err.Error(), endpoint) {
+		t.Errorf("错误 %q 中没有服务器地址 %s", err, endpoint)
+	}
+}
+
+func TestIsSessionError(t *testing.T) {
+	relayed := "http request ok but payload does not indicate success: " + (&SessionRenegotiationError{Attempts: 6}).Error()
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&SessionRenegotiationError{Attempts: 6}, true},
+		{errors.New(relayed), true},
+		{errors.New("connection refused"), false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := isSessionError(tt.err); got != tt.want {
+			t.Errorf("isSessionError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// 执行前写入操作历史的清单记录：只用于与下次运行比较，不是实际操作，不能撤销
+const ACTION_PLANNED = "planned"
+
+// 清单中的分集与之前运行的比较结果
+const (
+	RUN_DIFF_NEW    = "NEW"          // 之前的清单中没有
+	RUN_DIFF_REPEAT = "REPEAT"       // 之前的清单中有，但没有执行成功（失败、被限速推迟或中断）
+	RUN_DIFF_DONE   = "ALREADY-DONE" // 之前已执行成功，之后又恢复了（如被手动重新开始）
+)
+
+// 一个种子在操作历史中的记录
+type HashHistory struct {
+	Planned bool            // 曾出现在确认执行的清单中
+	Done    map[string]bool // 执行成功且未撤销、未回滚的操作
+}
+
+// 按hash索引操作历史：已撤销的运行和安全模式回滚的暂停不计为执行成功
+func indexHistoryByHash(records []HistoryRecord) map[string]*HashHistory {
+	undone := make(map[string]bool)
+	rolledBack := make(map[string]bool)
+	for _, record := range records {
+		switch record.Action {
+		case ACTION_UNDO:
+			undone[record.UndoneRunID] = true
+		case ACTION_ROLLBACK:
+			rolledBack[record.RunID+"/"+historyKey(record)] = true
+		}
+	}
+
+	index := make(map[string]*HashHistory)
+	for _, record := range records {
+		if record.Hash == "" || record.Action == ACTION_UNDO || record.Action == ACTION_ROLLBACK {
+			continue
+		}
+		entry := index[record.Hash]
+		if entry == nil {
+			entry = &HashHistory{Done: make(map[string]bool)}
+			index[record.Hash] = entry
+		}
+		if record.Action == ACTION_PLANNED {
+			entry.Planned = true
+			continue
+		}
+		if undone[record.RunID] || (record.Action == ACTION_PAUSE && rolledBack[record.RunID+"/"+historyKey(record)]) {
+			continue
+		}
+		entry.Done[historyAction(record.Action)] = true
+	}
+	return index
+}
+
+// 比较时使用的操作：暂停旧版合集与暂停分集记录为同一种操作
+func historyAction(action string) string {
+	if pausesEpisode(action) {
+		return ACTION_PAUSE
+	}
+	return action
+}
+
+// 把清单中的分集分为新增、重复和已完成三类，按hash返回；手动保留的分集不参与比较
+func classifyManifest(entries []ManifestEntry, index map[string]*HashHistory) map[string]string {
+	statuses := make(map[string]string)
+	for _, entry := range entries {
+		if entry.Action == MANIFEST_KEPT || entry.Hash == "" {
+			continue
+		}
+		history := index[entry.Hash]
+		switch {
+		case history != nil && history.Done[historyAction(entry.Action)]:
+			statuses[entry.Hash] = RUN_DIFF_DONE
+		case history != nil && history.Planned:
+			statuses[entry.Hash] = RUN_DIFF_REPEAT
+		default:
+			statuses[entry.Hash] = RUN_DIFF_NEW
+		}
+	}
+	return statuses
+}
+
+// 读取操作历史并与清单比较，读取失败时只打印警告，不标记比较结果
+func manifestStatuses(entries []ManifestEntry) map[string]string {
+	records, err := loadHistory(historyPath())
+	if err != nil {
+		log.Printf("读取操作历史失败，不与之前的运行比较: %v", err)
+		return nil
+	}
+	return classifyManifest(entries, indexHistoryByHash(records))
+}
+
+// 显示与之前运行比较的各类数量
+func printRunDiffCounts(statuses map[string]string) {
+	if len(statuses) == 0 {
+		return
+	}
+	counts := make(map[string]int)
+	for _, status := range statuses {
+		counts[status]++
+	}
+	fmt.Printf("\n与之前的运行比较: 新增 (%s) %d 个, 重复 (%s，之前失败或未执行) %d 个, 已完成 (%s，之前已执行成功) %d 个\n",
+		RUN_DIFF_NEW, counts[RUN_DIFF_NEW], RUN_DIFF_REPEAT, counts[RUN_DIFF_REPEAT], RUN_DIFF_DONE, counts[RUN_DIFF_DONE])
+}
+
+// 把确认执行的清单写入操作历史，下次运行时据此区分重复的分集；不计入本次运行可撤销的记录数
+func (w *HistoryWriter) RecordPlanned(entries []ManifestEntry) {
+	for _, entry := range entries {
+		if entry.Action == MANIFEST_KEPT || entry.Hash == "" {
+			continue
+		}
+		record := HistoryRecord{
+			RunID:         w.runID,
+			Time:          time.Now(),
+			Action:        ACTION_PLANNED,
+			Group:         entry.Group,
+			TorrentID:     entry.ID,
+			Hash:          entry.Hash,
+			Name:          entry.Name,
+			PlannedAction: entry.Action,
+		}
+		if err := appendHistory(w.path, record); err != nil {
+			log.Printf("写入操作历史失败: %v", err)
+			return
+		}
+	}
+}
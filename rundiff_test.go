@@ -0,0 +1,103 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestClassifyManifest(t *testing.T) {
+	planned := func(runID, hash, action string) HistoryRecord {
+		return HistoryRecord{RunID: runID, Action: ACTION_PLANNED, Hash: hash, PlannedAction: action}
+	}
+	done := func(runID, hash, action string) HistoryRecord {
+		return HistoryRecord{RunID: runID, Action: action, Hash: hash}
+	}
+	tests := []struct {
+		name    string
+		history []HistoryRecord
+		entries []ManifestEntry
+		want    map[string]string
+	}{
+		{
+			name:    "没有历史",
+			entries: []ManifestEntry{{Hash: "a", Action: ACTION_PAUSE}},
+			want:    map[string]string{"a": RUN_DIFF_NEW},
+		},
+		{
+			name:    "之前计划但没有执行成功",
+			history: []HistoryRecord{planned("run1", "a", ACTION_PAUSE)},
+			entries: []ManifestEntry{{Hash: "a", Action: ACTION_PAUSE}},
+			want:    map[string]string{"a": RUN_DIFF_REPEAT},
+		},
+		{
+			name:    "之前已执行成功",
+			history: []HistoryRecord{planned("run1", "a", ACTION_PAUSE), done("run1", "a", ACTION_PAUSE)},
+			entries: []ManifestEntry{{Hash: "a", Action: ACTION_PAUSE}},
+			want:    map[string]string{"a": RUN_DIFF_DONE},
+		},
+		{
+			name:    "暂停旧版合集与暂停分集视为同一种操作",
+			history: []HistoryRecord{done("run1", "a", ACTION_OLD_PACK_PAUSE)},
+			entries: []ManifestEntry{{Hash: "a", Action: ACTION_PAUSE}},
+			want:    map[string]string{"a": RUN_DIFF_DONE},
+		},
+		{
+			name:    "之前执行的是其他操作",
+			history: []HistoryRecord{planned("run1", "a", ACTION_PRIORITY), done("run1", "a", ACTION_PRIORITY)},
+			entries: []ManifestEntry{{Hash: "a", Action: ACTION_PAUSE}},
+			want:    map[string]string{"a": RUN_DIFF_REPEAT},
+		},
+		{
+			name: "已撤销的运行不计为执行成功",
+			history: []HistoryRecord{
+				planned("run1", "a", ACTION_PAUSE), done("run1", "a", ACTION_PAUSE),
+				{RunID: "run2", Action: ACTION_UNDO, UndoneRunID: "run1"},
+			},
+			entries: []ManifestEntry{{Hash: "a", Action: ACTION_PAUSE}},
+			want:    map[string]string{"a": RUN_DIFF_REPEAT},
+		},
+		{
+			name: "安全模式回滚的暂停不计为执行成功",
+			history: []HistoryRecord{
+				planned("run1", "a", ACTION_PAUSE), done("run1", "a", ACTION_PAUSE),
+				{RunID: "run1", Action: ACTION_ROLLBACK, Hash: "a"},
+			},
+			entries: []ManifestEntry{{Hash: "a", Action: ACTION_PAUSE}},
+			want:    map[string]string{"a": RUN_DIFF_REPEAT},
+		},
+		{
+			name:    "手动保留和没有hash的分集不参与比较",
+			history: []HistoryRecord{planned("run1", "a", ACTION_PAUSE)},
+			entries: []ManifestEntry{{Hash: "a", Action: MANIFEST_KEPT}, {ID: 3, Action: ACTION_PAUSE}, {Hash: "b", Action: ACTION_PAUSE}},
+			want:    map[string]string{"b": RUN_DIFF_NEW},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := classifyManifest(tt.entries, indexHistoryByHash(tt.history))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("classifyManifest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// 写入清单后，下次运行按操作历史区分三类分集
+func TestManifestStatusesFromHistory(t *testing.T) {
+	t.Setenv("DELETE_EPISODE_STATE_DIR", t.TempDir())
+	entries := []ManifestEntry{
+		{ID: 1, Hash: "failed", Group: "G", Action: ACTION_PAUSE},
+		{ID: 2, Hash: "paused", Group: "G", Action: ACTION_PAUSE},
+	}
+	history := newHistoryWriter()
+	history.RecordPlanned(entries)
+	if err := appendHistory(historyPath(), HistoryRecord{RunID: history.runID, Action: ACTION_PAUSE, Hash: "paused"}); err != nil {
+		t.Fatal(err)
+	}
+
+	next := append(entries, ManifestEntry{ID: 3, Hash: "new", Group: "G", Action: ACTION_PAUSE})
+	want := map[string]string{"failed": RUN_DIFF_REPEAT, "paused": RUN_DIFF_DONE, "new": RUN_DIFF_NEW}
+	if got := manifestStatuses(next); !reflect.DeepEqual(got, want) {
+		t.Errorf("manifestStatuses() = %v, want %v", got, want)
+	}
+}
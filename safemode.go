@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 回滚操作的历史记录类型，撤销时跳过已回滚的暂停记录
+const ACTION_ROLLBACK = "rollback"
+
+// 暂停后等待Transmission更新状态再校验
+const SAFE_MODE_VERIFY_DELAY = 1 * time.Second
+
+// 安全模式：暂停一组分集后重新获取状态校验结果，
+// 未能暂停的比例超过阈值时恢复本次暂停的分集，使该组保持操作前的状态
+type SafeMode struct {
+	enabled      bool
+	threshold    float64 // 组内未能暂停的比例超过该值时回滚，0 表示有任何失败就回滚
+	prevStatus   map[int64]transmissionrpc.TorrentStatus
+	RolledBack   int      // 回滚恢复运行的分集数量
+	FailedGroups []string // 被回滚的组
+}
+
+// 创建安全模式，未启用时所有方法都不做任何事
+func newSafeMode(enabled bool, threshold float64) *SafeMode {
+	return &SafeMode{
+		enabled:    enabled,
+		threshold:  threshold,
+		prevStatus: make(map[int64]transmissionrpc.TorrentStatus),
+	}
+}
+
+// 记录操作前的状态，回滚时只恢复原本未停止的种子
+func (s *SafeMode) snapshot(episodes []*transmissionrpc.Torrent) {
+	if !s.enabled {
+		return
+	}
+	for _, episode := range episodes {
+		if episode.ID != nil && episode.Status != nil {
+			s.prevStatus[*episode.ID] = *episode.Status
+		}
+	}
+}
+
+// 校验一组分集是否都已暂停，attempted 为本次尝试暂停的分集，paused 为其中暂停请求成功的分集。
+// 返回暂停请求成功但实际未停止的数量，以及回滚恢复运行的数量
+func (s *SafeMode) verify(client *transmissionrpc.Client, history *HistoryWriter, groupName string, attempted, paused []*transmissionrpc.Torrent) (int, int) {
+	if !s.enabled || len(attempted) == 0 {
+		return 0, 0
+	}
+
+	time.Sleep(SAFE_MODE_VERIFY_DELAY)
+	ids := make([]int64, len(attempted))
+	for i, episode := range attempted {
+		ids[i] = *episode.ID
+	}
+	stopped, err := fetchStopped(client, ids)
+	if err != nil {
+		fmt.Printf("安全模式: 无法获取 \"%s\" 的分集状态，跳过校验: %v\n", groupName, err)
+		return 0, 0
+	}
+
+	var notStopped []int64
+	for _, id := range ids {
+		if !stopped[id] {
+			notStopped = append(notStopped, id)
+		}
+	}
+	unconfirmed := 0
+	for _, episode := range paused {
+		if !stopped[*episode.ID] {
+			unconfirmed++
+		}
+	}
+	if len(notStopped) == 0 {
+		return 0, 0
+	}
+
+	failureRate := float64(len(notStopped)) / float64(len(ids))
+	if failureRate <= s.threshold {
+		fmt.Printf("安全模式: \"%s\" 有 %d 个分集未能暂停 (ID: %s)，未超过回滚阈值\n", groupName, len(notStopped), joinIDs(notStopped))
+		return unconfirmed, 0
+	}
+
+	fmt.Printf("安全模式: \"%s\" 有 %d/%d 个分集未能暂停，超过回滚阈值 %.0f%%，正在恢复本次暂停的分集...\n",
+		groupName, len(notStopped), len(ids), s.threshold*100)
+	rolledBack := s.rollback(client, history, groupName, paused, stopped)
+	s.RolledBack += rolledBack
+	s.FailedGroups = append(s.FailedGroups, groupName)
+	return unconfirmed, rolledBack
+}
+
+// 恢复本次暂停的分集，操作前已停止的种子保持停止
+func (s *SafeMode) rollback(client *transmissionrpc.Client, history *HistoryWriter, groupName string, paused []*transmissionrpc.Torrent, stopped map[int64]bool) int {
+	rolledBack := 0
+	for _, episode := range paused {
+		id := *episode.ID
+		if !stopped[id] {
+			continue
+		}
+		if status, ok := s.prevStatus[id]; ok && status == transmissionrpc.TorrentStatusStopped {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeouts.actionRetry())
+		err := client.TorrentStartIDs(ctx, []int64{id})
+		cancel()
+		if err != nil {
+			fmt.Printf("回滚失败，分集 ID: %d 仍处于暂停状态: %v\n", id, err)
+			continue
+		}
+		fmt.Printf("已恢复分集 ID: %d\n", id)
+		history.Record(newHistoryRecord(ACTION_ROLLBACK, groupName, episode))
+		rolledBack++
+	}
+	return rolledBack
+}
+
+// 显示安全模式的回滚结果
+func (s *SafeMode) printSummary() {
+	if !s.enabled || len(s.FailedGroups) == 0 {
+		return
+	}
+	sort.Strings(s.FailedGroups)
+	fmt.Printf("安全模式: %d 个组暂停失败并已回滚，恢复运行 %d 个分集:\n", len(s.FailedGroups), s.RolledBack)
+	for _, groupName := range s.FailedGroups {
+		fmt.Printf("  - %s\n", groupName)
+	}
+}
+
+// 重新获取种子状态，返回已停止的种子ID
+func fetchStopped(client *transmissionrpc.Client, ids []int64) (map[int64]bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+	defer cancel()
+	torrents, err := client.TorrentGet(ctx, []string{"id", "status"}, ids)
+	if err != nil {
+		return nil, err
+	}
+	stopped := make(map[int64]bool)
+	for _, torrent := range torrents {
+		if torrent.ID != nil && torrent.Status != nil && *torrent.Status == transmissionrpc.TorrentStatusStopped {
+			stopped[*torrent.ID] = true
+		}
+	}
+	return stopped, nil
+}
+
+// 把种子ID拼接为逗号分隔的字符串
+func joinIDs(ids []int64) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprint(id)
+	}
+	return strings.Join(parts, ", ")
+}
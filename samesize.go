@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 大小相同的种子组的处理方式
+const (
+	SAME_SIZE_SKIP  = "skip"  // 只记录，不处理（默认，通常是辅种）
+	SAME_SIZE_PAUSE = "pause" // 同一tracker的重复种子保留上传量较高的一个，处理其余的
+)
+
+// 判断同组种子是否都来自同一tracker（误重复添加），没有tracker或tracker不同时视为辅种
+func shareTracker(torrents []*transmissionrpc.Torrent) bool {
+	if len(torrents) < 2 {
+		return false
+	}
+	common := make(map[string]bool)
+	for _, host := range trackerHosts(torrents[0]) {
+		common[host] = true
+	}
+	for _, torrent := range torrents[1:] {
+		hosts := make(map[string]bool)
+		for _, host := range trackerHosts(torrent) {
+			if common[host] {
+				hosts[host] = true
+			}
+		}
+		common = hosts
+	}
+	return len(common) > 0
+}
+
+// 种子的累计上传量，未知时视为0
+func uploadedEver(torrent *transmissionrpc.Torrent) int64 {
+	if torrent.UploadedEver == nil {
+		return 0
+	}
+	return *torrent.UploadedEver
+}
+
+// 种子的完成时间，未知时视为最早
+func doneDate(torrent *transmissionrpc.Torrent) time.Time {
+	if torrent.DoneDate == nil {
+		return time.Time{}
+	}
+	return *torrent.DoneDate
+}
+
+// 从大小相同的重复种子中选出保留的一个：上传量最高的保留，上传量相同时保留完成时间较早的；
+// 返回保留的种子、需要处理的种子和决策说明
+func selectSameSizeKeeper(torrents []*transmissionrpc.Torrent) (*transmissionrpc.Torrent, []*transmissionrpc.Torrent, string) {
+	sorted := append([]*transmissionrpc.Torrent{}, torrents...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		uploadedI, uploadedJ := uploadedEver(sorted[i]), uploadedEver(sorted[j])
+		if uploadedI != uploadedJ {
+			return uploadedI > uploadedJ
+		}
+		return doneDate(sorted[i]).Before(doneDate(sorted[j]))
+	})
+
+	keeper, victims := sorted[0], sorted[1:]
+	var victimIDs []string
+	tiedUpload := false
+	for _, victim := range victims {
+		if victim.ID != nil {
+			victimIDs = append(victimIDs, fmt.Sprintf("ID %d", *victim.ID))
+		}
+		if uploadedEver(victim) == uploadedEver(keeper) {
+			tiedUpload = true
+		}
+	}
+
+	var keeperID int64
+	if keeper.ID != nil {
+		keeperID = *keeper.ID
+	}
+	reason := "上传量较高"
+	if tiedUpload {
+		reason = "上传量相同时完成较早"
+	}
+	decision := fmt.Sprintf("保留%s的 ID %d, 暂停 %s", reason, keeperID, strings.Join(victimIDs, ", "))
+	return keeper, victims, decision
+}
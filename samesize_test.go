@@ -0,0 +1,107 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 上传量和完成时间已知的种子
+func seededTorrent(id, uploaded int64, done time.Time) *transmissionrpc.Torrent {
+	torrent := testTorrent(id, "Show.S01", testFile{"Show.S01/Show.S01E01.mkv", 1000})
+	torrent.UploadedEver = &uploaded
+	torrent.DoneDate = &done
+	return torrent
+}
+
+func TestSelectSameSizeKeeper(t *testing.T) {
+	older := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	newer := older.AddDate(0, 1, 0)
+	tests := []struct {
+		name     string
+		torrents []*transmissionrpc.Torrent
+		keeper   int64
+		victims  []int64
+		decision string
+	}{
+		{
+			name:     "上传量不同时保留上传量较高的",
+			torrents: []*transmissionrpc.Torrent{seededTorrent(456, 100, older), seededTorrent(123, 500, newer)},
+			keeper:   123,
+			victims:  []int64{456},
+			decision: "保留上传量较高的 ID 123, 暂停 ID 456",
+		},
+		{
+			name:     "上传量相同时保留完成较早的",
+			torrents: []*transmissionrpc.Torrent{seededTorrent(2, 300, newer), seededTorrent(1, 300, older)},
+			keeper:   1,
+			victims:  []int64{2},
+			decision: "保留上传量相同时完成较早的 ID 1, 暂停 ID 2",
+		},
+		{
+			name:     "多个重复种子按上传量排列",
+			torrents: []*transmissionrpc.Torrent{seededTorrent(1, 10, older), seededTorrent(2, 30, older), seededTorrent(3, 20, newer)},
+			keeper:   2,
+			victims:  []int64{3, 1},
+			decision: "保留上传量较高的 ID 2, 暂停 ID 3, ID 1",
+		},
+		{
+			name:     "上传量和完成时间都相同时保留先出现的",
+			torrents: []*transmissionrpc.Torrent{seededTorrent(7, 0, older), seededTorrent(8, 0, older)},
+			keeper:   7,
+			victims:  []int64{8},
+			decision: "保留上传量相同时完成较早的 ID 7, 暂停 ID 8",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keeper, victims, decision := selectSameSizeKeeper(tt.torrents)
+			if *keeper.ID != tt.keeper {
+				t.Errorf("保留 ID %d，应为 ID %d", *keeper.ID, tt.keeper)
+			}
+			var victimIDs []int64
+			for _, victim := range victims {
+				victimIDs = append(victimIDs, *victim.ID)
+			}
+			if len(victimIDs) != len(tt.victims) {
+				t.Fatalf("处理 %v，应为 %v", victimIDs, tt.victims)
+			}
+			for i := range victimIDs {
+				if victimIDs[i] != tt.victims[i] {
+					t.Fatalf("处理 %v，应为 %v", victimIDs, tt.victims)
+				}
+			}
+			if decision != tt.decision {
+				t.Errorf("决策说明 %q，应为 %q", decision, tt.decision)
+			}
+		})
+	}
+}
+
+func TestShareTracker(t *testing.T) {
+	withTrackers := func(id int64, announces ...string) *transmissionrpc.Torrent {
+		torrent := testTorrent(id, "Show.S01")
+		for _, announce := range announces {
+			torrent.Trackers = append(torrent.Trackers, &transmissionrpc.Tracker{Announce: announce})
+		}
+		return torrent
+	}
+	tests := []struct {
+		name     string
+		torrents []*transmissionrpc.Torrent
+		want     bool
+	}{
+		{"同一tracker", []*transmissionrpc.Torrent{withTrackers(1, "https://a.example/announce"), withTrackers(2, "https://a.example/announce?passkey=x")}, true},
+		{"不同tracker（辅种）", []*transmissionrpc.Torrent{withTrackers(1, "https://a.example/announce"), withTrackers(2, "https://b.example/announce")}, false},
+		{"没有tracker", []*transmissionrpc.Torrent{withTrackers(1), withTrackers(2)}, false},
+		{"只有一个种子", []*transmissionrpc.Torrent{withTrackers(1, "https://a.example/announce")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := shareTracker(tt.torrents); got != tt.want {
+				t.Errorf("shareTracker() = %t，应为 %t", got, tt.want)
+			}
+		})
+	}
+}
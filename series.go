@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 可能是全剧合集的名称：季范围（如 S01-S05、Season 1-5）或 Complete Series、全集
+var seriesNameRegex = regexp.MustCompile(`(?i)s\d{1,2}[ ._]?[-~–][ ._]?s?\d{1,2}(?:[ ._\-\]]|$)|season[ ._]?\d{1,2}[ ._]?[-~–][ ._]?\d{1,2}|complete[ ._\-]?series|全集`)
+
+// 剧名：名称中第一个季、剧集标识或 Complete 之前的部分
+var seriesTitleRegex = regexp.MustCompile(`(?i)^(.+?)[ ._\-\[]+(?:s\d{1,2}|season[ ._]?\d{1,2}|complete)`)
+
+// 剧集标识中的季，如 S02E03
+var markerSeasonRegex = regexp.MustCompile(`^S(\d+)E`)
+
+// 全剧合集：内容文件的剧集标识跨越多季
+type SeriesPack struct {
+	Key     string // 合集所在的名称分组
+	Show    string // 剧名分组键
+	Seasons []int  // 覆盖的季
+	Size    float64
+}
+
+// 剧名分组键，忽略大小写和分隔符；无法识别剧名时返回空
+func seriesShowKey(name string) string {
+	matches := seriesTitleRegex.FindStringSubmatch(canonicalName(name))
+	if matches == nil {
+		return ""
+	}
+	title := strings.ToLower(strings.NewReplacer(".", " ", "_", " ", "-", " ").Replace(matches[1]))
+	return strings.Join(strings.Fields(title), " ")
+}
+
+// 剧集标识的季，没有季时返回 -1
+func markerSeason(marker string) int {
+	matches := markerSeasonRegex.FindStringSubmatch(marker)
+	if matches == nil {
+		return -1
+	}
+	return atoi(matches[1])
+}
+
+// 内容文件覆盖的季，从小到大
+func seasonsInFiles(files []*transmissionrpc.TorrentFile) []int {
+	seen := make(map[int]bool)
+	var seasons []int
+	for marker := range episodeMarkerSet(files) {
+		if season := markerSeason(marker); season >= 0 && !seen[season] {
+			seen[season] = true
+			seasons = append(seasons, season)
+		}
+	}
+	sort.Ints(seasons)
+	return seasons
+}
+
+// 季覆盖范围，连续时显示为 S01–S05，否则逐个列出
+func formatSeasonCoverage(seasons []int) string {
+	if len(seasons) == 0 {
+		return ""
+	}
+	contiguous := true
+	for i := 1; i < len(seasons); i++ {
+		if seasons[i] != seasons[i-1]+1 {
+			contiguous = false
+			break
+		}
+	}
+	if contiguous && len(seasons) > 1 {
+		return fmt.Sprintf("S%02d–S%02d", seasons[0], seasons[len(seasons)-1])
+	}
+	parts := make([]string, len(seasons))
+	for i, season := range seasons {
+		parts[i] = fmt.Sprintf("S%02d", season)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// 把全剧合集的分组扩大到剧名级别：名称像全剧合集且内容文件跨越多季的种子，
+// 同一剧名下其他各季的名称分组并入它所在的组，使各季的分集都可以作为它的分集。
+// 剧名不同的组不会合并；同一剧有多个全剧合集时只使用最大的一个。返回各全剧合集分组覆盖的季
+func widenSeriesGroups(client *transmissionrpc.Client, nameGroups map[string][]transmissionrpc.Torrent) map[string][]int {
+	keys := make([]string, 0, len(nameGroups))
+	for key := range nameGroups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	packs := make(map[string]SeriesPack)
+	for _, key := range keys {
+		for i := range nameGroups[key] {
+			torrent := &nameGroups[key][i]
+			if torrent.ID == nil || torrent.Name == nil || metadataPending(torrent) || !seriesNameRegex.MatchString(canonicalName(*torrent.Name)) {
+				continue
+			}
+			show := seriesShowKey(*torrent.Name)
+			if show == "" {
+				continue
+			}
+			files, err := getTorrentFiles(client, torrent.ID)
+			if err != nil {
+				continue
+			}
+			seasons := seasonsInFiles(files)
+			if len(seasons) < 2 {
+				continue
+			}
+			var size float64
+			if torrent.SizeWhenDone != nil {
+				size = (*torrent.SizeWhenDone).Byte()
+			}
+			if existing, ok := packs[show]; !ok || size > existing.Size {
+				packs[show] = SeriesPack{Key: key, Show: show, Seasons: seasons, Size: size}
+			}
+		}
+	}
+
+	coverage := make(map[string][]int)
+	for _, pack := range packs {
+		coverage[pack.Key] = pack.Seasons
+		for _, key := range keys {
+			if key == pack.Key || nameGroups[key] == nil || seriesShowKey(key) != pack.Show {
+				continue
+			}
+			nameGroups[pack.Key] = append(nameGroups[pack.Key], nameGroups[key]...)
+			delete(nameGroups, key)
+		}
+	}
+	return coverage
+}
+
+// 显示全剧合集覆盖的季，并按季列出分集
+func printSeriesCoverage(w io.Writer, group DuplicateGroup) {
+	if len(group.SeriesSeasons) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "全剧合集: 覆盖 %s\n", formatSeasonCoverage(group.SeriesSeasons))
+
+	bySeason := make(map[int][]string)
+	var seasons []int
+	for _, episode := range group.Episodes {
+		if episode == nil || episode.ID == nil || episode.Name == nil {
+			continue
+		}
+		season := -1
+		if matches := multiEpisodeRegex.FindStringSubmatch(*episode.Name); matches != nil {
+			season = atoi(matches[1])
+		} else if matches := packSeasonRegex.FindStringSubmatch(canonicalName(*episode.Name)); matches != nil {
+			season = atoi(matches[2] + matches[3])
+		}
+		if _, ok := bySeason[season]; !ok {
+			seasons = append(seasons, season)
+		}
+		bySeason[season] = append(bySeason[season], fmt.Sprint(*episode.ID))
+	}
+	sort.Ints(seasons)
+	for _, season := range seasons {
+		label := "未知季"
+		if season >= 0 {
+			label = fmt.Sprintf("S%02d", season)
+		}
+		fmt.Fprintf(w, "  %s: %d 个分集 (ID: %s)\n", label, len(bySeason[season]), strings.Join(bySeason[season], ", "))
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// series.json: ID 1 为 S01–S05 的全剧合集，ID 2 为 S02 的季合集，ID 3 为 S04E02 的单集，
+// ID 4 为剧名不同（Show.E.UK）但文件名与全剧合集中相同的种子
+func TestSeriesPackFixture(t *testing.T) {
+	_, result, _ := scanFixture(t, "series.json")
+	const packName = "Show.E.Complete.Series.S01-S05.1080p.WEB"
+	group, ok := result.DuplicateGroups[packName]
+	if !ok {
+		t.Fatalf("没有找到全剧合集的组，需要处理的组: %v", sortedGroupNames(result.DuplicateGroups))
+	}
+	if *group.Collection.ID != 1 {
+		t.Errorf("合集 ID: %d，应为全剧合集 ID 1", *group.Collection.ID)
+	}
+	if got := splitIDs(result.DuplicateGroups)[packName]; !reflect.DeepEqual(got, []int64{2, 3}) {
+		t.Errorf("分集 %v，应为各季的 ID 2 和 3", got)
+	}
+	if want := []int{1, 2, 3, 4, 5}; !reflect.DeepEqual(group.SeriesSeasons, want) {
+		t.Errorf("覆盖的季 %v，应为 %v", group.SeriesSeasons, want)
+	}
+
+	var out bytes.Buffer
+	printSeriesCoverage(&out, group)
+	for _, line := range []string{"全剧合集: 覆盖 S01–S05", "S02: 1 个分集 (ID: 2)", "S04: 1 个分集 (ID: 3)"} {
+		if !strings.Contains(out.String(), line) {
+			t.Errorf("全剧合集的说明中没有 %q:\n%s", line, out.String())
+		}
+	}
+
+	// 剧名不同的种子不会并入全剧合集的组
+	for _, groups := range allGroupMaps(result) {
+		for name, group := range groups {
+			for _, episode := range append(group.Episodes, group.Collection) {
+				if episode != nil && *episode.ID == 4 {
+					t.Errorf("剧名不同的 ID 4 被并入组 %s", name)
+				}
+			}
+		}
+	}
+}
+
+func TestSeriesShowKey(t *testing.T) {
+	tests := map[string]string{
+		"Show.E.Complete.Series.S01-S05.1080p.WEB": "show e",
+		"Show.E.S02.1080p.WEB":                     "show e",
+		"[Group] Show E - Season 1-5":              "show e",
+		"Show.E.UK.S02.1080p.WEB":                  "show e uk",
+		"Movie.X.2020.1080p.BluRay":                "",
+	}
+	for name, want := range tests {
+		if got := seriesShowKey(name); got != want {
+			t.Errorf("seriesShowKey(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
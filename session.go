@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 操作后等待多久再统计，让上传速度反映暂停后的状态
+const SESSION_STATS_WAIT = 30 * time.Second
+
+// 服务器整体状态，用于对比操作前后的影响
+type SessionSnapshot struct {
+	ActiveTorrents int64
+	UploadSpeed    int64   // 总上传速度（字节/秒）
+	FreeSpace      float64 // 默认下载目录的剩余空间（字节），查询失败时为-1
+}
+
+// 查询活跃种子数量、总上传速度和默认下载目录的剩余空间
+func sampleSession(client *transmissionrpc.Client) (SessionSnapshot, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+	defer cancel()
+
+	stats, err := client.SessionStats(ctx)
+	if err != nil {
+		return SessionSnapshot{}, err
+	}
+	snapshot := SessionSnapshot{
+		ActiveTorrents: stats.ActiveTorrentCount,
+		UploadSpeed:    stats.UploadSpeed,
+		FreeSpace:      -1,
+	}
+
+	// 剩余空间查询失败不影响其他统计
+	session, err := client.SessionArgumentsGet(ctx, []string{"download-dir"})
+	if err != nil || session.DownloadDir == nil {
+		log.Printf("查询默认下载目录失败，不统计剩余空间: %v", err)
+		return snapshot, nil
+	}
+	freeSpace, err := client.FreeSpace(ctx, *session.DownloadDir)
+	if err != nil {
+		log.Printf("查询剩余空间失败: %v", err)
+		return snapshot, nil
+	}
+	snapshot.FreeSpace = freeSpace.Byte()
+	return snapshot, nil
+}
+
+// 操作前记录服务器状态，查询失败时返回nil，不进行对比
+func sampleSessionBefore(client *transmissionrpc.Client) *SessionSnapshot {
+	snapshot, err := sampleSession(client)
+	if err != nil {
+		log.Printf("查询服务器状态失败，不进行操作前后对比: %v", err)
+		return nil
+	}
+	return &snapshot
+}
+
+// 操作后（默认等待一段时间）再次查询服务器状态并显示变化，等待期间收到中断信号时不再对比
+func printSessionImpact(ctx context.Context, client *transmissionrpc.Client, before *SessionSnapshot, wait bool) {
+	if before == nil {
+		return
+	}
+	if wait {
+		fmt.Printf("\n等待 %s 后统计服务器状态变化（可使用 --no-stats-wait 跳过等待）...\n", SESSION_STATS_WAIT)
+		select {
+		case <-ctx.Done():
+			fmt.Println("已中断，不统计服务器状态变化")
+			return
+		case <-time.After(SESSION_STATS_WAIT):
+		}
+	}
+
+	after, err := sampleSession(client)
+	if err != nil {
+		log.Printf("查询服务器状态失败: %v", err)
+		return
+	}
+
+	fmt.Println("\n服务器状态变化（操作前 → 操作后）:")
+	fmt.Printf("- 活跃种子: %d → %d (%+d)\n", before.ActiveTorrents, after.ActiveTorrents, after.ActiveTorrents-before.ActiveTorrents)
+	fmt.Printf("- 总上传速度: %s → %s (%s/s)\n", formatRate(before.UploadSpeed), formatRate(after.UploadSpeed), formatSizeDelta(after.UploadSpeed-before.UploadSpeed))
+	if before.FreeSpace >= 0 && after.FreeSpace >= 0 {
+		fmt.Printf("- 剩余空间: %s → %s (%s)\n", formatSize(int64(before.FreeSpace)), formatSize(int64(after.FreeSpace)), formatSizeDelta(int64(after.FreeSpace-before.FreeSpace)))
+	}
+	if !wait {
+		fmt.Println("（未等待，上传速度可能尚未反映操作后的状态）")
+	}
+}
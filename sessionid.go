@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// Transmission 防CSRF的会话ID头，服务器返回409时在响应中给出新的会话ID
+const SESSION_ID_HEADER = "X-Transmission-Session-Id"
+
+// 一次请求最多重新协商会话ID的次数，超过时放弃该请求
+const SESSION_MAX_RENEGOTIATIONS = 5
+
+// 连续重新协商之间的等待时间上限（每次翻倍，从1秒开始）
+const SESSION_MAX_BACKOFF = 8 * time.Second
+
+// 本次运行中重新协商会话ID的次数，不含第一次请求时的正常协商
+var sessionRenegotiations atomic.Int64
+
+// 请求因会话ID反复无效（409）而失败，与其他请求错误区分
+type SessionRenegotiationError struct {
+	Attempts int
+}
+
+func (e *SessionRenegotiationError) Error() string {
+	return fmt.Sprintf("连续 %d 次会话ID无效（HTTP 409），可能是反向代理丢弃了 %s 头", e.Attempts, SESSION_ID_HEADER)
+}
+
+// 错误是否由会话ID反复无效造成。经本机中转的请求只保留错误信息，按信息中的会话ID头名称判断
+func isSessionError(err error) bool {
+	var sessionErr *SessionRenegotiationError
+	return errors.As(err, &sessionErr) || (err != nil && strings.Contains(err.Error(), SESSION_ID_HEADER))
+}
+
+// 在HTTP层处理会话ID：transmissionrpc 只在409后重试一次，反向代理偶尔丢弃会话ID头时整批操作都会失败。
+// 这里记录最新的会话ID并附加到每个请求，收到409时使用新的会话ID重发同一个请求，
+// 正在进行的操作从失败的那个请求继续，而不是重新开始
+type sessionTransport struct {
+	base      http.RoundTripper
+	mu        sync.Mutex
+	sessionID string
+}
+
+// 当前的会话ID
+func (t *sessionTransport) current() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.sessionID
+}
+
+// 记录新的会话ID，返回是否为第一次协商
+func (t *sessionTransport) update(sessionID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	first := t.sessionID == ""
+	if sessionID != "" {
+		t.sessionID = sessionID
+	}
+	return first
+}
+
+func (t *sessionTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	// transmissionrpc 通过管道写入请求内容，无法重新读取，先读出以便重发
+	var body []byte
+	if request.Body != nil {
+		var err error
+		body, err = io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		retry := request.Clone(request.Context())
+		retry.Body = io.NopCloser(bytes.NewReader(body))
+		retry.ContentLength = int64(len(body))
+		if sessionID := t.current(); sessionID != "" {
+			retry.Header.Set(SESSION_ID_HEADER, sessionID)
+		}
+		response, err := t.base.RoundTrip(retry)
+		if err != nil || response.StatusCode != http.StatusConflict {
+			return response, err
+		}
+		io.Copy(io.Discard, response.Body)
+		response.Body.Close()
+
+		if !t.update(response.Header.Get(SESSION_ID_HEADER)) {
+			sessionRenegotiations.Add(1)
+		}
+		if attempt > SESSION_MAX_RENEGOTIATIONS {
+			return nil, &SessionRenegotiationError{Attempts: attempt}
+		}
+		// 第一次使用新的会话ID立即重发，之后逐渐延长等待
+		if attempt > 1 {
+			select {
+			case <-request.Context().Done():
+				return nil, request.Context().Err()
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, SESSION_MAX_BACKOFF)
+		}
+	}
+}
+
+// 为RPC连接的HTTP客户端加上会话ID处理，在设置代理或 Unix socket 之后调用
+func configureSessionTransport(client *transmissionrpc.Client) error {
+	httpClient, err := rpcHTTPClient(client)
+	if err != nil {
+		return err
+	}
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	httpClient.Transport = &sessionTransport{base: base}
+	return nil
+}
+
+// 显示本次运行中重新协商会话ID的次数，没有时不显示
+func printSessionRenegotiations(count int64) {
+	if count > 0 {
+		fmt.Printf("会话ID重新协商 %d 次（频繁出现时请检查反向代理是否转发了 %s 头）\n", count, SESSION_ID_HEADER)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 不在 --shows-file 剧集清单中的组
+const SKIP_NOT_IN_SHOWS = "not_in_shows"
+
+// 剧集清单：只分析组名与清单中的条目匹配的组，未指定 --shows-file 时为nil，nil清单匹配全部组
+type ShowsList struct {
+	path     string
+	patterns []string // 规范化后的条目，支持 * ? [...] 通配符
+}
+
+// 本次运行的剧集清单
+var showsList *ShowsList
+
+// 规范化名称用于比较：小写，. 和 _ 视为空格，合并连续空白
+func normalizeShowName(name string) string {
+	name = strings.ToLower(strings.NewReplacer(".", " ", "_", " ").Replace(name))
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// 读取剧集清单，每行一个剧名，# 开头的行为注释；没有任何条目时返回错误
+func loadShowsFile(filePath string) ([]string, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pattern := normalizeShowName(line)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("剧集清单第 %d 行的通配符无效: %s", lineNumber, line)
+		}
+		patterns = append(patterns, pattern)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(patterns) == 0 {
+		return nil, fmt.Errorf("剧集清单中没有任何剧名")
+	}
+	return patterns, nil
+}
+
+// 读取剧集清单并开启筛选，文件不存在或没有条目时返回错误
+func enableShowsList(filePath string) error {
+	patterns, err := loadShowsFile(filePath)
+	if err != nil {
+		return err
+	}
+	showsList = &ShowsList{path: filePath, patterns: patterns}
+	return nil
+}
+
+// 每次扫描前重新读取剧集清单，守护模式中修改清单后下一轮生效；读取失败时继续使用上次的清单
+func (l *ShowsList) reload() {
+	if l == nil {
+		return
+	}
+	patterns, err := loadShowsFile(l.path)
+	if err != nil {
+		log.Printf("重新读取剧集清单失败，继续使用上次的 %d 个剧名: %v", len(l.patterns), err)
+		return
+	}
+	l.patterns = patterns
+}
+
+// 组名是否与清单中的条目匹配：条目与完整的组名匹配，或与组名中的剧名（季或剧集标识之前的部分）匹配
+func (l *ShowsList) matches(key string) bool {
+	if l == nil {
+		return true
+	}
+	name := normalizeShowName(key)
+	show := seriesShowKey(key)
+	for _, pattern := range l.patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+		if show != "" {
+			if matched, _ := path.Match(pattern, show); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// 从名称分组中去掉不在清单中的组，返回这些组的跳过记录；只作为合集候选的组本来就不参与处理，不记录
+func (l *ShowsList) filter(nameGroups map[string][]transmissionrpc.Torrent, collectionOnly map[int64]bool) []SkipRecord {
+	if l == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(nameGroups))
+	for key := range nameGroups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var skipped []SkipRecord
+	for _, key := range keys {
+		if l.matches(key) {
+			continue
+		}
+		if !onlyCollectionCandidates(nameGroups[key], collectionOnly) {
+			skipped = append(skipped, SkipRecord{Reason: SKIP_NOT_IN_SHOWS, Name: key, Torrents: torrentPointers(nameGroups[key])})
+		}
+		delete(nameGroups, key)
+	}
+	return skipped
+}
+
+// 只保留组名在清单中的种子，用于深度扫描
+func (l *ShowsList) filterTorrents(torrents []transmissionrpc.Torrent, nameMap []NameAlias) []transmissionrpc.Torrent {
+	if l == nil {
+		return torrents
+	}
+	var kept []transmissionrpc.Torrent
+	for _, torrent := range torrents {
+		if namelessTorrent(&torrent) {
+			continue
+		}
+		if key, _ := groupKey(nameMap, canonicalName(*torrent.Name)); l.matches(key) {
+			kept = append(kept, torrent)
+		}
+	}
+	return kept
+}
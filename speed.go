@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+const (
+	ACTIVE_UPLOAD_MIN_RATE = 10 * 1024        // 上传速率达到该值（B/s）的分集视为正在活跃上传
+	ACTIVE_REPOLL_WINDOW   = 30 * time.Second // 限速时重新获取累计上传量的间隔
+)
+
+// 会话的上传限速状态
+type SpeedLimits struct {
+	AltSpeedEnabled     bool  // 备用速度（乌龟模式）是否开启
+	AltSpeedUp          int64 // 备用上传速度限制（KB/s）
+	SpeedLimitUpEnabled bool  // 全局上传限速是否开启
+	SpeedLimitUp        int64 // 全局上传速度限制（KB/s）
+}
+
+// 被保留的正在活跃上传的分集
+type ActiveEpisode struct {
+	Episode *transmissionrpc.Torrent
+	Reason  string
+}
+
+// 查询会话的上传限速状态，查询失败时按未限速处理
+func detectSpeedLimits(client *transmissionrpc.Client) SpeedLimits {
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+	defer cancel()
+
+	session, err := client.SessionArgumentsGet(ctx, []string{
+		"alt-speed-enabled",
+		"alt-speed-up",
+		"speed-limit-up-enabled",
+		"speed-limit-up",
+	})
+	if err != nil {
+		log.Printf("查询会话限速状态失败，将按未限速处理: %v", err)
+		return SpeedLimits{}
+	}
+
+	limits := SpeedLimits{}
+	if session.AltSpeedEnabled != nil {
+		limits.AltSpeedEnabled = *session.AltSpeedEnabled
+	}
+	if session.AltSpeedUp != nil {
+		limits.AltSpeedUp = *session.AltSpeedUp
+	}
+	if session.SpeedLimitUpEnabled != nil {
+		limits.SpeedLimitUpEnabled = *session.SpeedLimitUpEnabled
+	}
+	if session.SpeedLimitUp != nil {
+		limits.SpeedLimitUp = *session.SpeedLimitUp
+	}
+	return limits
+}
+
+// 会话是否处于上传限速状态
+func (l SpeedLimits) Active() bool {
+	return l.AltSpeedEnabled || l.SpeedLimitUpEnabled
+}
+
+// 限速状态描述，如 "乌龟模式 上传 50 KB/s"
+func (l SpeedLimits) describe() string {
+	if l.AltSpeedEnabled {
+		return fmt.Sprintf("乌龟模式 上传 %d KB/s", l.AltSpeedUp)
+	}
+	if l.SpeedLimitUpEnabled {
+		return fmt.Sprintf("全局上传限速 %d KB/s", l.SpeedLimitUp)
+	}
+	return "未限速"
+}
+
+// 种子是否设置了单独的上传限速
+func torrentUploadLimited(torrent *transmissionrpc.Torrent) bool {
+	return torrent.UploadLimited != nil && *torrent.UploadLimited
+}
+
+// 描述种子的单独上传限速，未限速时返回空
+func describeTorrentUploadLimit(torrent *transmissionrpc.Torrent) string {
+	if !torrentUploadLimited(torrent) || torrent.UploadLimit == nil {
+		return ""
+	}
+	return fmt.Sprintf("单种上传限速: %d KB/s", *torrent.UploadLimit)
+}
+
+// 格式化上传速率
+func formatRate(bytesPerSecond int64) string {
+	return formatSize(bytesPerSecond) + "/s"
+}
+
+// 保留正在活跃上传的分集：未限速时按当前上传速率判断；
+// 会话或种子限速时瞬时速率不可靠，改为间隔一段时间重新获取累计上传量，按平均速率判断
+func applyActiveUploaders(client *transmissionrpc.Client, result *ScanResult, limits SpeedLimits) {
+	// 需要重新获取累计上传量的分集
+	var repollIDs []int64
+	for _, group := range result.DuplicateGroups {
+		for _, episode := range group.Episodes {
+			if episode == nil || episode.ID == nil {
+				continue
+			}
+			if limits.Active() || torrentUploadLimited(episode) {
+				repollIDs = append(repollIDs, *episode.ID)
+			}
+		}
+	}
+
+	averageRates := make(map[int64]int64)
+	if len(repollIDs) > 0 {
+		fmt.Printf("检测到上传限速，等待 %s 后根据累计上传量判断活跃分集...\n", ACTIVE_REPOLL_WINDOW)
+		rates, err := averageUploadRates(client, result.Torrents, repollIDs)
+		if err != nil {
+			log.Printf("重新获取上传量失败，将使用当前上传速率判断: %v", err)
+		} else {
+			averageRates = rates
+		}
+	}
+
+	for name, group := range result.DuplicateGroups {
+		var kept []*transmissionrpc.Torrent
+		for _, episode := range group.Episodes {
+			if episode == nil || episode.ID == nil {
+				continue
+			}
+			var reason string
+			if rate, ok := averageRates[*episode.ID]; ok {
+				if rate >= ACTIVE_UPLOAD_MIN_RATE {
+					reason = fmt.Sprintf("%s内平均上传 %s", ACTIVE_REPOLL_WINDOW, formatRate(rate))
+				}
+			} else if episode.RateUpload != nil && *episode.RateUpload >= ACTIVE_UPLOAD_MIN_RATE {
+				reason = fmt.Sprintf("当前上传 %s", formatRate(*episode.RateUpload))
+			}
+			if reason == "" {
+				kept = append(kept, episode)
+				continue
+			}
+			group.ActiveEpisodes = append(group.ActiveEpisodes, ActiveEpisode{Episode: episode, Reason: reason})
+		}
+
+		group.Episodes = kept
+		if len(kept) == 0 {
+			// 全部分集都在活跃上传，移到仅供参考的部分
+			delete(result.DuplicateGroups, name)
+			result.ActiveGroups[name] = group
+			continue
+		}
+		result.DuplicateGroups[name] = group
+	}
+}
+
+// 间隔一段时间重新获取累计上传量，计算各种子的平均上传速率（B/s）
+func averageUploadRates(client *transmissionrpc.Client, torrents []transmissionrpc.Torrent, torrentIDs []int64) (map[int64]int64, error) {
+	before := make(map[int64]int64)
+	for _, torrent := range torrents {
+		if torrent.ID != nil && torrent.UploadedEver != nil {
+			before[*torrent.ID] = *torrent.UploadedEver
+		}
+	}
+
+	time.Sleep(ACTIVE_REPOLL_WINDOW)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+	defer cancel()
+	current, err := client.TorrentGet(ctx, []string{"id", "uploadedEver"}, torrentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make(map[int64]int64)
+	seconds := int64(ACTIVE_REPOLL_WINDOW / time.Second)
+	for _, torrent := range current {
+		if torrent.ID == nil || torrent.UploadedEver == nil {
+			continue
+		}
+		previous, ok := before[*torrent.ID]
+		if !ok {
+			continue
+		}
+		var delta int64
+		if *torrent.UploadedEver > previous {
+			delta = *torrent.UploadedEver - previous
+		}
+		rates[*torrent.ID] = delta / seconds
+	}
+	return rates, nil
+}
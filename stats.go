@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 统计模式下列出的可释放空间最多的组数
+const STATS_TOP_GROUPS = 10
+
+// 只按名称和大小统计的一组种子
+type StatsGroup struct {
+	Name       string
+	Collection *transmissionrpc.Torrent // 组内最大的种子，作为合集候选
+	Episodes   []*transmissionrpc.Torrent
+	SameSize   bool    // 组内种子大小全部相同
+	Reclaim    float64 // 分集大小之和（字节），即可释放空间的上限
+}
+
+// 统计模式：只获取种子列表，按名称分组并分析大小，不获取文件列表，不执行任何操作
+func runStats(reader *bufio.Reader, opts Options) {
+	params := opts.Connection
+	if !opts.ConnectionSet {
+		params = readConnectionParams(reader)
+		params.Proxy = opts.Connection.Proxy
+	}
+	suffixFilters := opts.SuffixFilters
+	if !opts.SuffixSet {
+		fmt.Print("种子名称筛选结尾（多个以;分隔，直接回车则不筛选）[例如: ADWeb;HHWEB]: ")
+		suffixesInput, _ := reader.ReadString('\n')
+		suffixFilters = parseSuffixFilters(suffixesInput)
+	}
+	opts.SuffixFilters = suffixFilters
+	printConnectionParams(params)
+
+	client, err := connect(params)
+	if err != nil {
+		log.Fatalf("无法连接到 Transmission 服务器%s: %v", params.proxyHint(), err)
+	}
+	torrents, err := getTorrentsChunked(client, detectCapabilities(client).torrentFields())
+	if err != nil {
+		log.Fatalf("获取 torrent 列表失败%s: %v", params.proxyHint(), err)
+	}
+
+	filteredTorrents := torrents
+	if len(suffixFilters) > 0 {
+		filteredTorrents = nil
+		for _, torrent := range torrents {
+			if torrent.Name != nil && matchSuffix(canonicalName(*torrent.Name), suffixFilters) {
+				filteredTorrents = append(filteredTorrents, torrent)
+			}
+		}
+	}
+	candidates, collectionOnly := collectionCandidates(torrents, filteredTorrents, opts)
+	printStats(statsGroups(candidates, collectionOnly, opts), len(torrents), len(filteredTorrents))
+}
+
+// 按名称分组，每组最大的种子作为合集候选，其余作为分集候选
+func statsGroups(torrents []transmissionrpc.Torrent, collectionOnly map[int64]bool, opts Options) []StatsGroup {
+	nameGroups := make(map[string][]*transmissionrpc.Torrent)
+	for i := range torrents {
+		torrent := &torrents[i]
+		if torrent.Name == nil || torrent.ID == nil || torrent.SizeWhenDone == nil {
+			continue
+		}
+		key, _ := groupKey(opts.NameMap, canonicalName(*torrent.Name))
+		nameGroups[key] = append(nameGroups[key], torrent)
+	}
+
+	var groups []StatsGroup
+	for name, members := range nameGroups {
+		if len(members) < 2 || !showsList.matches(name) {
+			continue
+		}
+		sort.SliceStable(members, func(i, j int) bool {
+			return (*members[i].SizeWhenDone).Byte() > (*members[j].SizeWhenDone).Byte()
+		})
+		group := StatsGroup{Name: name, Collection: members[0], SameSize: true}
+		collectionSize := (*members[0].SizeWhenDone).Byte()
+		for _, torrent := range members[1:] {
+			// 只能作为合集的种子不会被当作分集处理
+			if collectionOnly[*torrent.ID] {
+				continue
+			}
+			size := (*torrent.SizeWhenDone).Byte()
+			if size != collectionSize {
+				group.SameSize = false
+			}
+			group.Episodes = append(group.Episodes, torrent)
+			group.Reclaim += size
+		}
+		if len(group.Episodes) > 0 {
+			groups = append(groups, group)
+		}
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Reclaim != groups[j].Reclaim {
+			return groups[i].Reclaim > groups[j].Reclaim
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// 显示统计结果
+func printStats(groups []StatsGroup, total, filtered int) {
+	fmt.Println("\n===== 统计（未获取文件列表，未进行任何文件级校验）=====")
+	fmt.Printf("种子总数: %d，参与统计: %d\n", total, filtered)
+	if len(groups) == 0 {
+		fmt.Println("没有名称相同的种子组")
+		return
+	}
+
+	episodeCount, sameSizeCount := 0, 0
+	var reclaim float64
+	var collectionSizes []float64
+	for _, group := range groups {
+		episodeCount += len(group.Episodes)
+		reclaim += group.Reclaim
+		if group.SameSize {
+			sameSizeCount++
+		}
+		collectionSizes = append(collectionSizes, (*group.Collection.SizeWhenDone).Byte())
+	}
+	sort.Float64s(collectionSizes)
+
+	fmt.Printf("名称相同的组: %d（其中大小全部相同 %d 组）\n", len(groups), sameSizeCount)
+	fmt.Printf("合集候选: %d，分集候选: %d\n", len(groups), episodeCount)
+	fmt.Printf("合集候选大小: 最小 %s, 中位数 %s, 最大 %s\n",
+		formatSize(int64(collectionSizes[0])), formatSize(int64(collectionSizes[len(collectionSizes)/2])), formatSize(int64(collectionSizes[len(collectionSizes)-1])))
+	fmt.Printf("可释放空间上限: %s（全部分集候选的大小之和）\n", formatSize(int64(reclaim)))
+
+	top := groups
+	if len(top) > STATS_TOP_GROUPS {
+		top = top[:STATS_TOP_GROUPS]
+	}
+	fmt.Printf("\n可释放空间最多的 %d 组:\n", len(top))
+	for i, group := range top {
+		line := fmt.Sprintf("  %d. %s: 合集候选 %s, %d 个分集候选共 %s",
+			i+1, group.Name, formatSize(torrentBytes(group.Collection)), len(group.Episodes), formatSize(int64(group.Reclaim)))
+		if group.SameSize {
+			line += "（大小全部相同）"
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Println("\n以上结果只按名称和大小估算，未核对文件列表和重叠关系，实际可处理的分集可能更少。")
+	fmt.Println("去掉 --stats-only 重新运行以进行完整扫描")
+}
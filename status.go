@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// --exclude-status 不排除任何状态，恢复不按状态筛选分集的行为
+const STATUS_NONE = "none"
+
+// 默认只把正在做种的种子作为分集
+const DEFAULT_EXCLUDE_STATUS = "stopped,check-wait,checking,download-wait,downloading,seed-wait"
+
+// --exclude-status 的可选值对应的种子状态
+var torrentStatusNames = map[string]transmissionrpc.TorrentStatus{
+	"stopped":       transmissionrpc.TorrentStatusStopped,
+	"check-wait":    transmissionrpc.TorrentStatusCheckWait,
+	"checking":      transmissionrpc.TorrentStatusCheck,
+	"download-wait": transmissionrpc.TorrentStatusDownloadWait,
+	"downloading":   transmissionrpc.TorrentStatusDownload,
+	"seed-wait":     transmissionrpc.TorrentStatusSeedWait,
+	"seeding":       transmissionrpc.TorrentStatusSeed,
+}
+
+// 种子状态的可选值，按名称排序，用于补全和错误提示
+func torrentStatusChoices() []string {
+	choices := []string{STATUS_NONE}
+	for name := range torrentStatusNames {
+		choices = append(choices, name)
+	}
+	sort.Strings(choices[1:])
+	return choices
+}
+
+// 解析以逗号分隔的种子状态，none 表示不排除任何状态
+func parseStatusSet(value string) (map[transmissionrpc.TorrentStatus]bool, error) {
+	statuses := make(map[transmissionrpc.TorrentStatus]bool)
+	for _, name := range strings.Split(value, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || name == STATUS_NONE {
+			continue
+		}
+		status, ok := torrentStatusNames[name]
+		if !ok {
+			return nil, fmt.Errorf("无效的种子状态: %s（可选: %s）", name, strings.Join(torrentStatusChoices(), ", "))
+		}
+		statuses[status] = true
+	}
+	return statuses, nil
+}
+
+// 种子是否因状态不作为分集，状态未知时不排除
+func excludedByStatus(torrent *transmissionrpc.Torrent, excluded map[transmissionrpc.TorrentStatus]bool) bool {
+	return torrent.Status != nil && excluded[*torrent.Status]
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// 判定严格度的级别数，--strictness 可选 1~5，0 表示不使用
+const STRICTNESS_LEVELS = 5
+
+// 级别 3 的取值与各参数的默认值相同
+const STRICTNESS_DEFAULT = 3
+
+// 一条判定规则及其在各严格度下的取值
+type StrictnessRule struct {
+	Name   string                    // 参数名
+	Label  string                    // 报告中显示的规则说明
+	Values [STRICTNESS_LEVELS]string // 严格度 1~5 对应的参数值
+}
+
+// 判定规则与严格度的对应表，也是"当前判定规则"中列出的规则。
+// 每条规则必须给出全部级别的取值，新增的阈值加入此表后才会在启动时显示
+var strictnessRules = []StrictnessRule{
+	{"video-overlap", "视频文件重叠比例下限", [STRICTNESS_LEVELS]string{"30%", "40%", "50%", "75%", "100%"}},
+	{"require-full-containment", "分集内容必须全部包含在合集中", [STRICTNESS_LEVELS]string{"false", "false", "true", "true", "true"}},
+	{"extra-file-tolerance", "分集可多出的附加文件数量", [STRICTNESS_LEVELS]string{"10", "8", "5", "3", "0"}},
+	{"skip-size-check", "不检查分集大小之和", [STRICTNESS_LEVELS]string{"true", "false", "false", "false", "false"}},
+	{"size-tolerance", "比较大小时允许的误差", [STRICTNESS_LEVELS]string{"16KiB", "4KiB", "1KiB", "1KiB", "0"}},
+	{"max-size-ratio", "合集与分集大小比例上限（0 不检查）", [STRICTNESS_LEVELS]string{"0", "50", "30", "20", "10"}},
+	{"min-confidence", "置信度下限（0 不限制）", [STRICTNESS_LEVELS]string{"0", "0", "0", "0.6", "0.8"}},
+	{"require-parent-match", "文件上级目录的剧名必须一致", [STRICTNESS_LEVELS]string{"false", "false", "false", "true", "true"}},
+	{"allow-cross-quality", "允许不同分辨率/编码", [STRICTNESS_LEVELS]string{"true", "false", "false", "false", "false"}},
+	{"allow-cross-cut", "允许不同剪辑版本", [STRICTNESS_LEVELS]string{"true", "false", "false", "false", "false"}},
+	{"require-complete-collection", "合集必须完整且校验无误", [STRICTNESS_LEVELS]string{"false", "false", "false", "true", "true"}},
+	{"min-collection-seeders", "合集的其他做种者下限", [STRICTNESS_LEVELS]string{"0", "0", "0", "0", "1"}},
+	{"min-episodes", "组内可处理的分集数量下限", [STRICTNESS_LEVELS]string{"1", "1", "1", "1", "2"}},
+	{"deep-scan-min-percent", "深度扫描的内容重叠百分比下限", [STRICTNESS_LEVELS]string{"75", "80", "90", "95", "100"}},
+}
+
+// 只显示来源、不由严格度调整的判定规则
+var displayedRules = []struct {
+	Name  string
+	Label string
+}{
+	{"episode-pattern", "自定义剧集标识规则"},
+	{"padding-pattern", "填充文件规则"},
+	{"same-size-action", "大小相同的种子组"},
+}
+
+// 严格度对应的一组参数，与预设相同的方式应用
+func strictnessFlags(level int) []PresetFlag {
+	bundle := make([]PresetFlag, 0, len(strictnessRules))
+	for _, rule := range strictnessRules {
+		bundle = append(bundle, PresetFlag{rule.Name, rule.Values[level-1]})
+	}
+	return bundle
+}
+
+// 参数生效值的来源
+const (
+	RULE_SOURCE_DEFAULT = "默认"
+	RULE_SOURCE_FLAG    = "命令行"
+	RULE_SOURCE_DUMP    = "诊断包"
+)
+
+// 一条判定规则的生效值和来源
+type RuleSetting struct {
+	Label  string
+	Name   string
+	Value  string
+	Source string
+}
+
+// 汇总判定规则的生效值和来源：sources 中没有的参数为默认值
+func ruleSettings(fs *flag.FlagSet, sources map[string]string) []RuleSetting {
+	var settings []RuleSetting
+	add := func(name, label string) {
+		source, ok := sources[name]
+		if !ok {
+			source = RULE_SOURCE_DEFAULT
+		}
+		value := fs.Lookup(name).Value.String()
+		if value == "" {
+			value = "内置规则"
+		}
+		settings = append(settings, RuleSetting{Label: label, Name: name, Value: value, Source: source})
+	}
+	for _, rule := range strictnessRules {
+		add(rule.Name, rule.Label)
+	}
+	for _, rule := range displayedRules {
+		add(rule.Name, rule.Label)
+	}
+	return settings
+}
+
+// 预设和严格度设置的参数的来源，命令行覆盖的参数不记录
+func bundleSources(sources map[string]string, source string, settings []PresetSetting) {
+	for _, setting := range settings {
+		if !setting.Overridden {
+			sources[setting.Name] = source
+		}
+	}
+}
+
+// 严格度的来源说明
+func strictnessSource(level int) string {
+	return "严格度 " + strconv.Itoa(level)
+}
+
+// 显示"当前判定规则"：每条规则的生效值和来源
+func printRuleSettings(w io.Writer, strictness int, settings []RuleSetting) {
+	if strictness > 0 {
+		fmt.Fprintf(w, "当前判定规则（严格度 %d）:\n", strictness)
+	} else {
+		fmt.Fprintln(w, "当前判定规则:")
+	}
+	for _, setting := range settings {
+		fmt.Fprintf(w, "  %s: %s（--%s，%s）\n", setting.Label, setting.Value, setting.Name, setting.Source)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import "testing"
+
+// 识别参数中不属于判定阈值、不由严格度调整的参数。新增的识别参数必须加入严格度对应表、
+// displayedRules 或此列表，避免新阈值遗漏在 --strictness 之外
+var nonThresholdFlags = map[string]bool{
+	"test-pattern":              true,
+	"preset":                    true,
+	"strictness":                true,
+	"no-rename-fallback":        true,
+	"cut-token":                 true,
+	"policy-file":               true,
+	"same-tracker-action":       true,
+	"cross-tracker-action":      true,
+	"keep-active-uploaders":     true,
+	"min-weekly-upload-to-keep": true,
+	"keep-latest":               true,
+	"old-pack-action":           true,
+	"include-extras":            true,
+	"unregistered-message":      true,
+	"pack-duplicates":           true,
+}
+
+func TestStrictnessRulesCoverThresholds(t *testing.T) {
+	covered := make(map[string]bool)
+	for _, rule := range strictnessRules {
+		covered[rule.Name] = true
+	}
+	for _, rule := range displayedRules {
+		covered[rule.Name] = true
+	}
+	for _, group := range flagGroups {
+		if group.Title != "识别" {
+			continue
+		}
+		for _, name := range group.Names {
+			if !covered[name] && !nonThresholdFlags[name] {
+				t.Errorf("识别参数 --%s 既不在严格度对应表中，也没有标记为非阈值参数", name)
+			}
+		}
+	}
+}
+
+func TestStrictnessRuleValues(t *testing.T) {
+	fs := newFlagSet(&Options{}, &rawFlags{})
+	seen := make(map[string]bool)
+	for _, rule := range strictnessRules {
+		if seen[rule.Name] {
+			t.Errorf("规则 --%s 重复", rule.Name)
+		}
+		seen[rule.Name] = true
+		if fs.Lookup(rule.Name) == nil {
+			t.Errorf("规则 --%s 不是有效的参数", rule.Name)
+			continue
+		}
+		for level, value := range rule.Values {
+			if value == "" {
+				t.Errorf("规则 --%s 缺少严格度 %d 的取值", rule.Name, level+1)
+			}
+		}
+	}
+	for _, rule := range displayedRules {
+		if fs.Lookup(rule.Name) == nil {
+			t.Errorf("规则 --%s 不是有效的参数", rule.Name)
+		}
+	}
+}
+
+// 严格度 3 与各参数的默认值相同
+func TestStrictnessDefaultLevel(t *testing.T) {
+	fs := newFlagSet(&Options{}, &rawFlags{})
+	for _, bundled := range strictnessFlags(STRICTNESS_DEFAULT) {
+		f := fs.Lookup(bundled.Name)
+		if err := fs.Set(bundled.Name, bundled.Value); err != nil {
+			t.Fatalf("--%s=%s 无效: %v", bundled.Name, bundled.Value, err)
+		}
+		if got := f.Value.String(); got != f.DefValue {
+			t.Errorf("严格度 %d 的 --%s 为 %s，默认值为 %s", STRICTNESS_DEFAULT, bundled.Name, got, f.DefValue)
+		}
+	}
+}
+
+func TestStrictnessLevelsApply(t *testing.T) {
+	for level := 1; level <= STRICTNESS_LEVELS; level++ {
+		fs := newFlagSet(&Options{}, &rawFlags{})
+		if err := fs.Parse([]string{"--video-overlap", "60%"}); err != nil {
+			t.Fatal(err)
+		}
+		settings, err := applyFlagBundle(fs, strictnessSource(level), strictnessFlags(level))
+		if err != nil {
+			t.Fatalf("严格度 %d: %v", level, err)
+		}
+		if len(settings) != len(strictnessRules) {
+			t.Fatalf("严格度 %d 设置了 %d 个参数，应为 %d 个", level, len(settings), len(strictnessRules))
+		}
+		for i, setting := range settings {
+			rule := strictnessRules[i]
+			if rule.Name == "video-overlap" {
+				if !setting.Overridden || fs.Lookup(rule.Name).Value.String() != "60%" {
+					t.Errorf("严格度 %d 覆盖了命令行指定的 --video-overlap", level)
+				}
+				continue
+			}
+			if setting.Overridden || setting.Value != rule.Values[level-1] {
+				t.Errorf("严格度 %d 的 --%s 为 %+v，应为 %s", level, rule.Name, setting, rule.Values[level-1])
+			}
+		}
+	}
+}
+
+func TestRuleSettingsSources(t *testing.T) {
+	t.Cleanup(func() { parseOptions(nil) })
+	opts := parseOptions([]string{"--strictness", "5", "--video-overlap", "60%", "--preset", PRESET_AGGRESSIVE})
+	want := map[string]RuleSetting{
+		"video-overlap":            {Value: "60%", Source: RULE_SOURCE_FLAG},
+		"require-full-containment": {Value: "false", Source: "预设 " + PRESET_AGGRESSIVE},
+		"extra-file-tolerance":     {Value: "0", Source: strictnessSource(5)},
+		"same-size-action":         {Value: SAME_SIZE_PAUSE, Source: "预设 " + PRESET_AGGRESSIVE},
+		"episode-pattern":          {Value: "内置规则", Source: RULE_SOURCE_DEFAULT},
+	}
+	if len(opts.Rules) != len(strictnessRules)+len(displayedRules) {
+		t.Errorf("列出 %d 条规则，应为 %d 条", len(opts.Rules), len(strictnessRules)+len(displayedRules))
+	}
+	for _, setting := range opts.Rules {
+		expected, ok := want[setting.Name]
+		if !ok {
+			continue
+		}
+		if setting.Value != expected.Value || setting.Source != expected.Source {
+			t.Errorf("--%s: %s（%s），应为 %s（%s）", setting.Name, setting.Value, setting.Source, expected.Value, expected.Source)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 名称结尾筛选没有匹配任何种子时的退出码（非交互模式）
+const EXIT_NO_MATCH = 3
+
+// 没有匹配任何种子的名称结尾和建议使用的结尾
+type SuffixSuggestion struct {
+	Suffix    string
+	Suggested string
+	Count     int // 以建议的结尾结尾的种子数量
+}
+
+func (s SuffixSuggestion) describe() string {
+	return fmt.Sprintf("未找到以 '%s' 结尾的种子，但有 %d 个种子以 '%s' 结尾", s.Suffix, s.Count, s.Suggested)
+}
+
+// name 是否以 suffix 结尾（忽略大小写），返回名称中对应的结尾
+func foldedSuffix(name, suffix string) (string, bool) {
+	if len(name) < len(suffix) {
+		return "", false
+	}
+	tail := name[len(name)-len(suffix):]
+	return tail, strings.EqualFold(tail, suffix)
+}
+
+// 出现次数最多的候选，次数相同时取字典序最小的
+func mostCommon(counts map[string]int) (string, int) {
+	candidates := make([]string, 0, len(counts))
+	for candidate := range counts {
+		candidates = append(candidates, candidate)
+	}
+	sort.Strings(candidates)
+	best, bestCount := "", 0
+	for _, candidate := range candidates {
+		if counts[candidate] > bestCount {
+			best, bestCount = candidate, counts[candidate]
+		}
+	}
+	return best, bestCount
+}
+
+// 为没有匹配任何名称的结尾查找近似匹配：依次尝试忽略大小写、去掉首尾标点、作为名称中间的一部分，
+// 使用第一种有结果的方式中出现次数最多的结尾
+func suggestSuffix(names []string, suffix string) (SuffixSuggestion, bool) {
+	suggestion := SuffixSuggestion{Suffix: suffix}
+
+	counts := make(map[string]int)
+	for _, name := range names {
+		if tail, ok := foldedSuffix(name, suffix); ok {
+			counts[tail]++
+		}
+	}
+	if best, count := mostCommon(counts); count > 0 {
+		suggestion.Suggested, suggestion.Count = best, count
+		return suggestion, true
+	}
+
+	trimmed := strings.TrimFunc(suffix, func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSpace(r) || unicode.IsSymbol(r)
+	})
+	if trimmed != "" && trimmed != suffix {
+		counts = make(map[string]int)
+		for _, name := range names {
+			if tail, ok := foldedSuffix(name, trimmed); ok {
+				counts[tail]++
+			}
+		}
+		if best, count := mostCommon(counts); count > 0 {
+			suggestion.Suggested, suggestion.Count = best, count
+			return suggestion, true
+		}
+	}
+
+	// 名称中间出现时，建议从该处到名称末尾的部分，如 ADWeb 出现在 Show-ADWeb[rartv] 中时建议 ADWeb[rartv]
+	needle := strings.ToLower(trimmed)
+	if needle == "" {
+		return suggestion, false
+	}
+	counts = make(map[string]int)
+	for _, name := range names {
+		lower := strings.ToLower(name)
+		// 小写后长度变化的名称无法对应位置，不参与
+		if len(lower) != len(name) {
+			continue
+		}
+		if index := strings.LastIndex(lower, needle); index >= 0 {
+			counts[name[index:]]++
+		}
+	}
+	if best, count := mostCommon(counts); count > 0 {
+		suggestion.Suggested, suggestion.Count = best, count
+		return suggestion, true
+	}
+	return suggestion, false
+}
+
+// 没有匹配任何种子的名称结尾的建议，按筛选的顺序
+func suffixSuggestions(torrents []transmissionrpc.Torrent, suffixFilters []string) []SuffixSuggestion {
+	var names []string
+	for _, torrent := range torrents {
+		if torrent.Name != nil {
+			names = append(names, canonicalName(*torrent.Name))
+		}
+	}
+	var suggestions []SuffixSuggestion
+	for _, suffix := range suffixFilters {
+		if suffix == "" || suffix == COLLECTION_SUFFIX_ANY {
+			continue
+		}
+		matched := false
+		for _, name := range names {
+			if strings.HasSuffix(name, suffix) {
+				matched = true
+				break
+			}
+		}
+		if matched {
+			continue
+		}
+		if suggestion, ok := suggestSuffix(names, suffix); ok {
+			suggestions = append(suggestions, suggestion)
+		}
+	}
+	return suggestions
+}
+
+// 显示扫描时名称结尾的建议：交互模式逐个询问是否使用建议的结尾，返回替换后的筛选和是否有替换；
+// 非交互模式（reader 为nil或 --yes）只显示建议
+func resolveSuffixSuggestions(reader *bufio.Reader, result *ScanResult, opts Options) ([]string, bool) {
+	filters := append([]string{}, opts.SuffixFilters...)
+	changed := false
+	for _, suggestion := range result.SuffixSuggestions {
+		if reader == nil || opts.Yes {
+			fmt.Printf("%s，可以使用 --suffix '%s'\n", suggestion.describe(), suggestion.Suggested)
+			continue
+		}
+		fmt.Printf("%s，是否使用? (y/n): ", suggestion.describe())
+		answer, _ := reader.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+			continue
+		}
+		for i, suffix := range filters {
+			if suffix == suggestion.Suffix {
+				filters[i] = suggestion.Suggested
+			}
+		}
+		changed = true
+	}
+	return filters, changed
+}
+
+// 处理名称结尾的建议：接受建议时按新的筛选重新扫描；非交互模式下没有匹配任何种子时显示建议后退出
+func rescanWithSuggestedSuffixes(reader *bufio.Reader, client *transmissionrpc.Client, capabilities ServerCapabilities, result *ScanResult, opts Options) (*ScanResult, Options, error) {
+	filters, changed := resolveSuffixSuggestions(reader, result, opts)
+	if changed {
+		opts.SuffixFilters = filters
+		fmt.Printf("使用名称筛选结尾: %s\n", strings.Join(filters, ", "))
+		rescanned, err := scan(client, capabilities, opts)
+		return rescanned, opts, err
+	}
+	if result.NoSuffixMatch && (reader == nil || opts.Yes) {
+		os.Exit(EXIT_NO_MATCH)
+	}
+	return result, opts, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 合集做种人数不足时暂缓处理显示的策略名称
+const SWARM_GATE_POLICY = "合集做种人数不足"
+
+// 合集所在种群的健康状况
+type SwarmHealth struct {
+	TrackerSeeders int64 // 各tracker报告的做种人数中的最大值，未报告时为0
+	SelfSeeding    bool  // 本机正在做种，已计入tracker的做种人数
+	WebSeeds       int   // 网络种子数量，始终可用，计为其他做种者
+	PeersConnected int64 // 当前连接的用户数
+	Known          bool  // 是否有tracker报告了做种人数
+}
+
+// 除本机外的做种者数量
+func (h SwarmHealth) OtherSeeders() int64 {
+	others := h.TrackerSeeders
+	if h.SelfSeeding && others > 0 {
+		others--
+	}
+	return others + int64(h.WebSeeds)
+}
+
+func (h SwarmHealth) describe() string {
+	line := fmt.Sprintf("其他做种者 %d 个 (tracker最多 %d 个", h.OtherSeeders(), h.TrackerSeeders)
+	if h.SelfSeeding {
+		line += "，含本机"
+	}
+	line += fmt.Sprintf("，网络种子 %d 个)，已连接用户 %d 个", h.WebSeeds, h.PeersConnected)
+	if !h.Known {
+		line += "；tracker未返回做种人数，按 0 计算"
+	}
+	return line
+}
+
+// 根据tracker统计、网络种子和连接数计算合集的种群状况，多个tracker取最大的做种人数，
+// tracker未返回做种人数（-1）时忽略
+func collectionSwarmHealth(collection *transmissionrpc.Torrent) SwarmHealth {
+	var health SwarmHealth
+	if collection == nil {
+		return health
+	}
+	for _, stat := range collection.TrackerStats {
+		if stat.SeederCount < 0 {
+			continue
+		}
+		health.Known = true
+		if stat.SeederCount > health.TrackerSeeders {
+			health.TrackerSeeders = stat.SeederCount
+		}
+	}
+	health.SelfSeeding = collection.PercentDone != nil && *collection.PercentDone >= 1 && !alreadyStopped(collection)
+	health.WebSeeds = len(collection.WebSeeds)
+	if collection.PeersConnected != nil {
+		health.PeersConnected = *collection.PeersConnected
+	}
+	return health
+}
+
+// 按 --min-collection-seeders 暂缓合集其他做种者不足的组：暂停分集会降低合集内容的可用性，
+// 这些组的分集全部移到仅供参考部分，等合集的做种人数增加后再处理
+func applyCollectionSeeders(result *ScanResult, minSeeders int) {
+	if minSeeders <= 0 {
+		return
+	}
+	for name, group := range result.DuplicateGroups {
+		health := collectionSwarmHealth(group.Collection)
+		group.SwarmNote = health.describe()
+		if health.OtherSeeders() >= int64(minSeeders) {
+			result.DuplicateGroups[name] = group
+			continue
+		}
+		reason := fmt.Sprintf("合集其他做种者 %d 个，少于 %d 个", health.OtherSeeders(), minSeeders)
+		for _, episode := range group.Episodes {
+			group.GatedEpisodes = append(group.GatedEpisodes, GatedEpisode{
+				Episode: episode,
+				Policy:  SWARM_GATE_POLICY,
+				Reason:  reason,
+			})
+		}
+		group.Episodes = nil
+		delete(result.DuplicateGroups, name)
+		result.GatedGroups[name] = group
+	}
+}
+
+// 显示合集的做种情况
+func printSwarmNote(w io.Writer, note string) {
+	if note != "" {
+		fmt.Fprintf(w, "合集做种情况: %s\n", note)
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 未处理的原因
+const (
+	DEFERRED_LIMIT       = "未处理，已达本次上限"
+	DEFERRED_INTERRUPTED = "未处理，已中断"
+)
+
+// 本次运行中未处理的种子
+type DeferredTorrent struct {
+	Group   string
+	Torrent *transmissionrpc.Torrent
+	Reason  string
+}
+
+// 限制一次运行中暂停、删除的种子数量和操作间隔，避免短时间内大量停种触发tracker的检测
+type ActionThrottle struct {
+	max      int           // 本次最多处理的种子数量，0 表示不限制
+	delay    time.Duration // 两次操作之间的间隔，0 表示不等待
+	taken    int
+	started  bool
+	Deferred []DeferredTorrent
+}
+
+// 创建操作限制
+func newActionThrottle(max int, delay time.Duration) *ActionThrottle {
+	return &ActionThrottle{max: max, delay: delay}
+}
+
+// 是否需要逐个处理种子（设置了操作间隔）
+func (t *ActionThrottle) spread() bool {
+	return t.delay > 0
+}
+
+// 按上限取出本次可以处理的种子，其余的记为未处理；已中断时全部记为未处理
+func (t *ActionThrottle) take(ctx context.Context, group string, torrents []*transmissionrpc.Torrent) []*transmissionrpc.Torrent {
+	if ctx.Err() != nil {
+		t.deferRest(group, torrents, DEFERRED_INTERRUPTED)
+		return nil
+	}
+	allowed := len(torrents)
+	if t.max > 0 && allowed > t.max-t.taken {
+		allowed = t.max - t.taken
+	}
+	t.taken += allowed
+	t.deferRest(group, torrents[allowed:], DEFERRED_LIMIT)
+	return torrents[:allowed]
+}
+
+// 把种子记为未处理
+func (t *ActionThrottle) deferRest(group string, torrents []*transmissionrpc.Torrent, reason string) {
+	for _, torrent := range torrents {
+		t.Deferred = append(t.Deferred, DeferredTorrent{Group: group, Torrent: torrent, Reason: reason})
+	}
+}
+
+// 在两次操作之间等待，第一次操作不等待；等待期间收到中断信号时返回false
+func (t *ActionThrottle) wait(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if !t.started || t.delay <= 0 {
+		t.started = true
+		return true
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(t.delay):
+		return true
+	}
+}
+
+// 显示未处理的种子
+func (t *ActionThrottle) printDeferred() {
+	if len(t.Deferred) == 0 {
+		return
+	}
+	fmt.Printf("\n%d 个种子未处理（已完成的操作已记录到操作历史，可以撤销，未处理的种子下次运行时继续处理）:\n", len(t.Deferred))
+	for i, deferred := range t.Deferred {
+		torrent := deferred.Torrent
+		if torrent == nil || torrent.ID == nil {
+			continue
+		}
+		fmt.Printf("  %d. \"%s\" ID: %d, %s\n", i+1, deferred.Group, *torrent.ID, deferred.Reason)
+	}
+}
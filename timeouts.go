@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// RPC请求的超时时间，按操作类型区分
+type Timeouts struct {
+	List   time.Duration // 获取种子列表（每批）
+	Files  time.Duration // 获取单个种子的文件列表
+	Action time.Duration // 暂停、恢复、设置优先级、校验等操作
+	Query  time.Duration // 其他查询，如RPC版本、会话设置、按hash查找种子
+}
+
+// 默认超时时间
+func defaultTimeouts() Timeouts {
+	return Timeouts{
+		List:   60 * time.Second,
+		Files:  30 * time.Second,
+		Action: 30 * time.Second,
+		Query:  30 * time.Second,
+	}
+}
+
+// 当前使用的超时时间，启动时由命令行参数设置
+var timeouts = defaultTimeouts()
+
+// 设置超时时间
+func setTimeouts(t Timeouts) {
+	timeouts = t
+}
+
+// 按倍数放大全部超时时间
+func (t Timeouts) scaled(multiplier float64) Timeouts {
+	scale := func(d time.Duration) time.Duration {
+		return time.Duration(float64(d) * multiplier)
+	}
+	return Timeouts{
+		List:   scale(t.List),
+		Files:  scale(t.Files),
+		Action: scale(t.Action),
+		Query:  scale(t.Query),
+	}
+}
+
+// 批量操作失败后逐个重试时单个种子的超时时间，为操作超时的1/3
+func (t Timeouts) actionRetry() time.Duration {
+	return t.Action / 3
+}
+
+// 检查超时时间是否有效
+func (t Timeouts) validate() error {
+	for _, item := range []struct {
+		Name  string
+		Value time.Duration
+	}{
+		{"--timeout-list", t.List},
+		{"--timeout-files", t.Files},
+		{"--timeout-action", t.Action},
+		{"--timeout", t.Query},
+	} {
+		if item.Value <= 0 {
+			return fmt.Errorf("无效的超时时间 %s: %s", item.Name, item.Value)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+// 组的可释放大小（字节）：分集内容大小之和，不含填充文件，完全相同的副本只计算一次
+func groupReclaimBytes(group DuplicateGroup) int64 {
+	var reclaim int64
+	for _, episode := range group.Episodes {
+		if episode == nil {
+			continue
+		}
+		if _, ok := group.copyOf(episode); ok {
+			continue
+		}
+		reclaim += group.contentBytes(episode)
+	}
+	return reclaim
+}
+
+// 组的排名得分：可释放大小乘以置信度
+func groupRankScore(group DuplicateGroup) float64 {
+	return float64(groupReclaimBytes(group)) * group.Confidence
+}
+
+// 按得分从高到低排列组名，得分相同时按置信度和名称排列
+func rankedGroupNames(duplicateGroups map[string]DuplicateGroup) []string {
+	names := sortedGroupNames(duplicateGroups)
+	scores := make(map[string]float64, len(names))
+	for _, name := range names {
+		scores[name] = groupRankScore(duplicateGroups[name])
+	}
+	sort.SliceStable(names, func(i, j int) bool {
+		return scores[names[i]] > scores[names[j]]
+	})
+	return names
+}
+
+// 按 --top 只保留得分最高的 n 组，其余组不参与本次操作，返回保留的组名（按排名）
+func applyTop(result *ScanResult, n int) []string {
+	names := rankedGroupNames(result.DuplicateGroups)
+	if len(names) > n {
+		for _, name := range names[n:] {
+			delete(result.DuplicateGroups, name)
+		}
+		names = names[:n]
+	}
+	return names
+}
+
+// 快速模式的排名列表：每组一段简短的说明，代替完整报告
+func printTopGroups(w io.Writer, result *ScanResult, names []string, total int) {
+	fmt.Fprintf(w, "\n===== 可释放空间最多的 %d 组（共 %d 组，按可释放大小 × 置信度排列）=====\n", len(names), total)
+	if len(names) == 0 {
+		fmt.Fprintln(w, "无")
+		return
+	}
+	var reclaimTotal int64
+	for i, name := range names {
+		group := result.DuplicateGroups[name]
+		reclaim := groupReclaimBytes(group)
+		reclaimTotal += reclaim
+		fmt.Fprintf(w, "  %d. %s\n", i+1, name)
+		fmt.Fprintf(w, "     可释放 %s，置信度 %.2f，%d 个分集，合集 ID: %s\n",
+			formatSize(reclaim), group.Confidence, len(group.Episodes), torrentIDText(group.Collection))
+		fmt.Fprintf(w, "     依据: %s\n", group.Evidence.describe())
+	}
+	fmt.Fprintf(w, "\n合计可释放 %s，只有以上 %d 组会参与以下操作\n", formatSize(reclaimTotal), len(names))
+}
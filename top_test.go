@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 可释放大小和置信度已知的组，episodeSizes 为各分集的内容大小
+func rankedGroup(confidence float64, episodeSizes ...int64) DuplicateGroup {
+	group := DuplicateGroup{Confidence: confidence}
+	for i, size := range episodeSizes {
+		group.Episodes = append(group.Episodes, testTorrent(int64(i+1), "Show", testFile{"Show/episode.mkv", size}))
+	}
+	return group
+}
+
+func TestGroupReclaimBytes(t *testing.T) {
+	group := rankedGroup(1, 1000, 2000)
+	group.EpisodeCopies = map[int64]int64{2: 1}
+	group.Episodes = append([]*transmissionrpc.Torrent{nil}, group.Episodes...)
+	if got := groupReclaimBytes(group); got != 1000 {
+		t.Errorf("groupReclaimBytes() = %d，完全相同的副本只计算一次，应为 1000", got)
+	}
+}
+
+func TestRankedGroupNames(t *testing.T) {
+	groups := map[string]DuplicateGroup{
+		"small-certain":  rankedGroup(1, 100),      // 100
+		"large-sketchy":  rankedGroup(0.5, 1000),   // 500
+		"large-certain":  rankedGroup(1, 600, 400), // 1000
+		"medium-tie-b":   rankedGroup(0.8, 500),    // 400
+		"medium-tie-a":   rankedGroup(0.8, 500),    // 400，得分和置信度都相同时按名称
+		"medium-certain": rankedGroup(1, 400),      // 400，得分相同时置信度高的在前
+		"empty":          rankedGroup(1),           // 0
+		"nil-episode":    {Confidence: 1, Episodes: []*transmissionrpc.Torrent{nil}},
+	}
+	want := []string{"large-certain", "large-sketchy", "medium-certain", "medium-tie-a", "medium-tie-b", "small-certain", "empty", "nil-episode"}
+	if got := rankedGroupNames(groups); !reflect.DeepEqual(got, want) {
+		t.Errorf("rankedGroupNames() = %v\nwant %v", got, want)
+	}
+}
+
+func TestApplyTop(t *testing.T) {
+	newResult := func() *ScanResult {
+		return &ScanResult{DuplicateGroups: map[string]DuplicateGroup{
+			"a": rankedGroup(1, 300),
+			"b": rankedGroup(1, 200),
+			"c": rankedGroup(1, 100),
+		}}
+	}
+	tests := []struct {
+		n    int
+		want []string
+	}{
+		{1, []string{"a"}},
+		{2, []string{"a", "b"}},
+		{5, []string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		result := newResult()
+		names := applyTop(result, tt.n)
+		if !reflect.DeepEqual(names, tt.want) {
+			t.Errorf("applyTop(%d) = %v, want %v", tt.n, names, tt.want)
+		}
+		if len(result.DuplicateGroups) != len(tt.want) {
+			t.Errorf("applyTop(%d) 后剩余 %d 组，应为 %d 组", tt.n, len(result.DuplicateGroups), len(tt.want))
+		}
+		for _, name := range tt.want {
+			if _, ok := result.DuplicateGroups[name]; !ok {
+				t.Errorf("applyTop(%d) 去掉了排名靠前的组 %s", tt.n, name)
+			}
+		}
+	}
+}
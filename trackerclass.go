@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 分集与合集的tracker关系
+const (
+	TRACKER_SAME  = "same"  // 分集与合集至少有一个相同的tracker
+	TRACKER_CROSS = "cross" // 分集与合集没有相同的tracker
+	TRACKER_MIXED = "mixed" // 组内两种分集都有
+)
+
+// 按tracker关系配置操作时，表示使用tracker策略文件
+const CLASS_ACTION_POLICY = "policy"
+
+// tracker关系的中文名称
+func trackerClassName(class string) string {
+	switch class {
+	case TRACKER_SAME:
+		return "同一tracker"
+	case TRACKER_CROSS:
+		return "跨tracker"
+	case TRACKER_MIXED:
+		return "同一tracker和跨tracker混合"
+	}
+	return "未知"
+}
+
+// 判断分集与合集是否有相同的tracker
+func classifyEpisode(collection, episode *transmissionrpc.Torrent) string {
+	if collection != nil && episode != nil && shareTracker([]*transmissionrpc.Torrent{collection, episode}) {
+		return TRACKER_SAME
+	}
+	return TRACKER_CROSS
+}
+
+// 为全部组的分集标记tracker关系，并据此确定组的分类
+func classifyTrackers(result *ScanResult) {
+	for _, groups := range allGroupMaps(result) {
+		for name, group := range groups {
+			group.EpisodeClasses = make(map[int64]string)
+			group.TrackerClass = ""
+			for _, episode := range group.Episodes {
+				if episode == nil || episode.ID == nil {
+					continue
+				}
+				class := classifyEpisode(group.Collection, episode)
+				group.EpisodeClasses[*episode.ID] = class
+				if group.TrackerClass == "" {
+					group.TrackerClass = class
+				} else if group.TrackerClass != class {
+					group.TrackerClass = TRACKER_MIXED
+				}
+			}
+			groups[name] = group
+		}
+	}
+}
+
+// 分集所属tracker关系配置的操作，未配置时返回空
+func (o Options) classAction(class string) string {
+	switch class {
+	case TRACKER_SAME:
+		return o.SameTrackerAction
+	case TRACKER_CROSS:
+		return o.CrossTrackerAction
+	}
+	return ""
+}
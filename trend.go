@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// trend 命令默认显示的轮数
+const DEFAULT_TREND_CYCLES = 20
+
+// 趋势记录默认保留的时间
+const DEFAULT_TREND_RETENTION = 30 * 24 * time.Hour
+
+// 一轮守护扫描的汇总，追加到状态目录的趋势文件中，用于观察重复分集是否在增加
+type TrendEntry struct {
+	Time             time.Time `json:"time"`
+	Cycle            int       `json:"cycle"`
+	GroupCount       int       `json:"group_count"`
+	EpisodeCount     int       `json:"episode_count"`
+	ReclaimableBytes int64     `json:"reclaimable_bytes"` // 需要处理的分集大小之和，相同的副本只计算一次
+	ActionsTaken     int       `json:"actions_taken"`
+}
+
+// 与上一轮相比的变化
+type TrendDelta struct {
+	GroupCount       int   `json:"group_count"`
+	EpisodeCount     int   `json:"episode_count"`
+	ReclaimableBytes int64 `json:"reclaimable_bytes"`
+}
+
+// 趋势文件路径
+func trendPath() string {
+	return filepath.Join(stateDir(), "trend.jsonl")
+}
+
+// 需要处理的分集大小之和，相同的副本不重复计算，不含填充文件
+func reclaimableBytes(duplicateGroups map[string]DuplicateGroup) int64 {
+	var total int64
+	for _, group := range duplicateGroups {
+		for _, episode := range group.Episodes {
+			if _, isCopy := group.copyOf(episode); isCopy {
+				continue
+			}
+			total += group.contentBytes(episode)
+		}
+	}
+	return total
+}
+
+// 根据一轮扫描的统计生成趋势记录
+func newTrendEntry(summary CycleSummary, duplicateGroups map[string]DuplicateGroup) TrendEntry {
+	return TrendEntry{
+		Time:             summary.Time,
+		Cycle:            summary.Cycle,
+		GroupCount:       summary.GroupCount,
+		EpisodeCount:     summary.EpisodeCount,
+		ReclaimableBytes: reclaimableBytes(duplicateGroups),
+		ActionsTaken:     summary.ActionsTaken,
+	}
+}
+
+// 相对上一轮的变化
+func (e TrendEntry) deltaFrom(previous TrendEntry) TrendDelta {
+	return TrendDelta{
+		GroupCount:       e.GroupCount - previous.GroupCount,
+		EpisodeCount:     e.EpisodeCount - previous.EpisodeCount,
+		ReclaimableBytes: e.ReclaimableBytes - previous.ReclaimableBytes,
+	}
+}
+
+// 描述变化，如 "组 +2, 分集 -1, 可释放 +1.20 GB"
+func (d TrendDelta) describe() string {
+	sign := func(value int64) string {
+		if value > 0 {
+			return "+"
+		}
+		return ""
+	}
+	return fmt.Sprintf("组 %s%d, 分集 %s%d, 可释放 %s",
+		sign(int64(d.GroupCount)), d.GroupCount, sign(int64(d.EpisodeCount)), d.EpisodeCount, formatSizeDelta(d.ReclaimableBytes))
+}
+
+// 读取全部趋势记录，文件不存在时返回空；无法解析的行跳过
+func loadTrend(path string) ([]TrendEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []TrendEntry
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var entry TrendEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			log.Printf("趋势文件第 %d 行格式错误，已跳过: %v", lineNumber, err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// 保存趋势记录，先写临时文件再替换，避免写入中断时丢失全部记录
+func saveTrend(path string, entries []TrendEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	var b strings.Builder
+	for _, entry := range entries {
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		b.Write(data)
+		b.WriteByte('\n')
+	}
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, []byte(b.String()), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// 去掉早于保留时间的记录
+func pruneTrend(entries []TrendEntry, retention time.Duration, now time.Time) []TrendEntry {
+	cutoff := now.Add(-retention)
+	var kept []TrendEntry
+	for _, entry := range entries {
+		if !entry.Time.Before(cutoff) {
+			kept = append(kept, entry)
+		}
+	}
+	return kept
+}
+
+// 追加本轮的记录并清理过期记录，返回上一轮的记录（没有时第二个值为false）
+func recordTrend(path string, entry TrendEntry, retention time.Duration) (TrendEntry, bool, error) {
+	entries, err := loadTrend(path)
+	if err != nil {
+		return TrendEntry{}, false, err
+	}
+	var previous TrendEntry
+	found := len(entries) > 0
+	if found {
+		previous = entries[len(entries)-1]
+	}
+	entries = pruneTrend(append(entries, entry), retention, entry.Time)
+	return previous, found, saveTrend(path, entries)
+}
+
+// trend 命令的JSON输出中的一轮
+type TrendRow struct {
+	TrendEntry
+	Delta *TrendDelta `json:"delta,omitempty"` // 与上一轮相比的变化，第一条记录没有
+}
+
+// trend 命令：显示最近几轮守护扫描的汇总和相对上一轮的变化
+func runTrend(args []string) {
+	opts := parseOptions(args)
+	entries, err := loadTrend(trendPath())
+	if err != nil {
+		log.Fatalf("读取趋势记录失败: %v", err)
+	}
+
+	// 第一行与显示范围之前的一轮比较
+	start := 0
+	if len(entries) > opts.TrendCycles {
+		start = len(entries) - opts.TrendCycles
+	}
+	var rows []TrendRow
+	for i := start; i < len(entries); i++ {
+		row := TrendRow{TrendEntry: entries[i]}
+		if i > 0 {
+			delta := entries[i].deltaFrom(entries[i-1])
+			row.Delta = &delta
+		}
+		rows = append(rows, row)
+	}
+
+	if opts.JSON {
+		if rows == nil {
+			rows = []TrendRow{}
+		}
+		data, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			log.Fatalf("生成JSON失败: %v", err)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	if len(rows) == 0 {
+		fmt.Printf("没有趋势记录（守护模式每轮扫描后记录到 %s）\n", trendPath())
+		return
+	}
+	fmt.Printf("最近 %d 轮守护扫描（共记录 %d 轮）:\n", len(rows), len(entries))
+	fmt.Printf("%-19s  %6s  %8s  %8s  %12s  %8s  %s\n", "时间", "轮次", "组", "分集", "可释放", "操作", "较上一轮")
+	for _, row := range rows {
+		delta := "-"
+		if row.Delta != nil {
+			delta = row.Delta.describe()
+		}
+		fmt.Printf("%-19s  %6d  %8d  %8d  %12s  %8d  %s\n", row.Time.Local().Format("2006-01-02 15:04:05"),
+			row.Cycle, row.GroupCount, row.EpisodeCount, formatSize(row.ReclaimableBytes), row.ActionsTaken, delta)
+	}
+
+	first, last := rows[0], rows[len(rows)-1]
+	fmt.Printf("\n%s 至 %s: %s\n", first.Time.Local().Format("2006-01-02 15:04"), last.Time.Local().Format("2006-01-02 15:04"),
+		last.TrendEntry.deltaFrom(first.TrendEntry).describe())
+}
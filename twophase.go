@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 两阶段暂停中标记待暂停分集的标签
+const TWO_PHASE_LABEL = "pending-pause"
+
+// 两阶段暂停默认的宽限期
+const DEFAULT_TWO_PHASE_GRACE = 72 * time.Hour
+
+// 两阶段暂停写入操作历史的操作类型
+const (
+	ACTION_PENDING_LABEL = "pending-label" // 添加待暂停标签，撤销时恢复原来的标签
+	ACTION_PENDING_CLEAR = "pending-clear" // 移除待暂停标签（已暂停或不再是重复分集），撤销时恢复原来的标签
+	ACTION_PENDING_VETO  = "pending-veto"  // 用户移除了待暂停标签，只记录，不需要撤销
+)
+
+// 一个待暂停的分集：标签添加的时间和是否被用户否决
+type TwoPhaseEntry struct {
+	Name   string    `json:"name"`
+	Since  time.Time `json:"since"`
+	Vetoed bool      `json:"vetoed,omitempty"` // 用户在宽限期内移除了标签，不再添加
+}
+
+// 两阶段暂停的记录，按hash保存在状态目录中，跨运行（和守护模式的轮次）保留
+type TwoPhaseState struct {
+	path    string
+	Entries map[string]TwoPhaseEntry
+}
+
+// 两阶段暂停记录文件路径
+func twoPhasePath() string {
+	return filepath.Join(stateDir(), "two-phase.json")
+}
+
+// 读取两阶段暂停记录，文件不存在时返回空记录
+func loadTwoPhaseState(path string) (*TwoPhaseState, error) {
+	state := &TwoPhaseState{path: path, Entries: make(map[string]TwoPhaseEntry)}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return state, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &state.Entries); err != nil {
+		return nil, err
+	}
+	if state.Entries == nil {
+		state.Entries = make(map[string]TwoPhaseEntry)
+	}
+	return state, nil
+}
+
+// 保存记录，写入失败只打印警告
+func (s *TwoPhaseState) save() {
+	data, err := json.MarshalIndent(s.Entries, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(s.path), 0o755)
+	}
+	if err == nil {
+		err = os.WriteFile(s.path, data, 0o644)
+	}
+	if err != nil {
+		log.Printf("保存两阶段暂停记录失败: %v", err)
+	}
+}
+
+// 两阶段暂停中的一个分集
+type TwoPhaseItem struct {
+	Group   string
+	Torrent *transmissionrpc.Torrent
+	Since   time.Time // 标签添加的时间，新标记的分集为本次运行的时间
+}
+
+// 本次运行的两阶段暂停计划
+type TwoPhasePlan struct {
+	Label   []TwoPhaseItem            // 新识别的重复分集，添加标签
+	Waiting []TwoPhaseItem            // 标签未满宽限期
+	Due     map[string]DuplicateGroup // 标签已满宽限期且仍是重复分集，暂停
+	Vetoed  []TwoPhaseItem            // 本次发现标签被用户移除的分集
+	Clear   []TwoPhaseItem            // 有标签但已不再是重复分集，移除标签
+}
+
+// 计划中需要暂停的分集数量
+func (p TwoPhasePlan) dueCount() int {
+	count := 0
+	for _, group := range p.Due {
+		count += len(group.Episodes)
+	}
+	return count
+}
+
+// 根据本次扫描结果和记录生成计划：只有操作为暂停的分集参与两阶段暂停；
+// 有标签但不再被识别为重复的分集移除标签，只考虑名称符合筛选结尾的种子（未参与分析的种子不算不再重复）
+func planTwoPhase(result *ScanResult, state *TwoPhaseState, opts Options, now time.Time) TwoPhasePlan {
+	plan := TwoPhasePlan{Due: make(map[string]DuplicateGroup)}
+	detected := make(map[string]bool)
+	for _, groupName := range sortedGroupNames(result.DuplicateGroups) {
+		group := result.DuplicateGroups[groupName]
+		var due []*transmissionrpc.Torrent
+		for _, episode := range group.Episodes {
+			if episode == nil || episode.ID == nil || episode.HashString == nil || group.episodeAction(episode, ACTION_PAUSE) != ACTION_PAUSE {
+				continue
+			}
+			hash := *episode.HashString
+			detected[hash] = true
+			labeled := slices.Contains(episode.Labels, TWO_PHASE_LABEL)
+			entry, recorded := state.Entries[hash]
+			item := TwoPhaseItem{Group: groupName, Torrent: episode, Since: now}
+			switch {
+			case !labeled && recorded && entry.Vetoed:
+				// 已否决的分集不再添加标签
+			case !labeled && recorded:
+				item.Since = entry.Since
+				plan.Vetoed = append(plan.Vetoed, item)
+			case !labeled:
+				plan.Label = append(plan.Label, item)
+			case !recorded || entry.Vetoed:
+				// 用户自己添加的标签（或否决后重新添加）从本次开始计算宽限期
+				plan.Waiting = append(plan.Waiting, item)
+			case now.Sub(entry.Since) >= opts.Grace:
+				due = append(due, episode)
+			default:
+				item.Since = entry.Since
+				plan.Waiting = append(plan.Waiting, item)
+			}
+		}
+		if len(due) > 0 {
+			group.Episodes = due
+			plan.Due[groupName] = group
+		}
+	}
+
+	for i := range result.Torrents {
+		torrent := &result.Torrents[i]
+		if torrent.ID == nil || torrent.HashString == nil || torrent.Name == nil || detected[*torrent.HashString] {
+			continue
+		}
+		if !slices.Contains(torrent.Labels, TWO_PHASE_LABEL) {
+			continue
+		}
+		if len(opts.SuffixFilters) > 0 && !matchSuffix(canonicalName(*torrent.Name), opts.SuffixFilters) {
+			continue
+		}
+		item := TwoPhaseItem{Torrent: torrent, Since: now}
+		if entry, ok := state.Entries[*torrent.HashString]; ok {
+			item.Since = entry.Since
+		}
+		plan.Clear = append(plan.Clear, item)
+	}
+	return plan
+}
+
+// 两阶段暂停的结果统计
+type TwoPhaseSummary struct {
+	Labeled int `json:"labeled"` // 新添加标签的分集数量
+	Waiting int `json:"waiting"` // 标签未满宽限期的分集数量
+	Paused  int `json:"paused"`  // 满宽限期后暂停的分集数量
+	Cleared int `json:"cleared"` // 移除标签的分集数量（已暂停或不再是重复分集）
+	Vetoed  int `json:"vetoed"`  // 本次发现被用户否决的分集数量
+	Failed  int `json:"failed"`
+}
+
+// 执行两阶段暂停计划：先记录否决，再添加标签、暂停已满宽限期的分集并移除其标签，最后移除不再重复的分集的标签。
+// 所有状态变化都写入操作历史，记录保存到状态目录
+func applyTwoPhase(ctx context.Context, client *transmissionrpc.Client, plan TwoPhasePlan, state *TwoPhaseState, opts Options, history *HistoryWriter, throttle *ActionThrottle) TwoPhaseSummary {
+	summary := TwoPhaseSummary{Waiting: len(plan.Waiting), Vetoed: len(plan.Vetoed)}
+	runNotices.begin(opts.Connection)
+	now := time.Now()
+
+	for _, item := range plan.Vetoed {
+		hash := *item.Torrent.HashString
+		entry := state.Entries[hash]
+		entry.Vetoed = true
+		state.Entries[hash] = entry
+		history.Record(newHistoryRecord(ACTION_PENDING_VETO, item.Group, item.Torrent))
+		fmt.Printf("标签已被移除，不再暂停 ID: %d\n", *item.Torrent.ID)
+	}
+	for _, item := range plan.Waiting {
+		hash := *item.Torrent.HashString
+		if entry, ok := state.Entries[hash]; !ok || entry.Vetoed {
+			state.Entries[hash] = TwoPhaseEntry{Name: *item.Torrent.Name, Since: item.Since}
+		}
+	}
+
+	labelFailed := 0
+	for _, item := range plan.Label {
+		labels := append(append([]string{}, item.Torrent.Labels...), TWO_PHASE_LABEL)
+		if err := setTorrentLabels(client, *item.Torrent.ID, labels); err != nil {
+			fmt.Printf("添加标签失败 ID: %d: %v\n", *item.Torrent.ID, err)
+			labelFailed++
+			continue
+		}
+		record := newHistoryRecord(ACTION_PENDING_LABEL, item.Group, item.Torrent)
+		record.PrevLabels = item.Torrent.Labels
+		history.Record(record)
+		state.Entries[*item.Torrent.HashString] = TwoPhaseEntry{Name: *item.Torrent.Name, Since: now}
+		fmt.Printf("已添加标签 %s ID: %d\n", TWO_PHASE_LABEL, *item.Torrent.ID)
+		runNotices.succeed(item.Group)
+		summary.Labeled++
+	}
+	runNotices.count(ACTION_PENDING_LABEL, summary.Labeled, labelFailed)
+	summary.Failed += labelFailed
+
+	// 暂停使用普通的暂停流程（处理数量上限、暂停配额、安全模式），之后按实际状态移除已暂停分集的标签
+	var clear []TwoPhaseItem
+	if len(plan.Due) > 0 {
+		summary.Paused = applySingleAction(ctx, client, plan.Due, ACTION_PAUSE, opts, history, throttle)
+		stopped := stoppedTorrentIDs(client, plan.Due)
+		for _, groupName := range sortedGroupNames(plan.Due) {
+			for _, episode := range plan.Due[groupName].Episodes {
+				if stopped[*episode.ID] {
+					clear = append(clear, TwoPhaseItem{Group: groupName, Torrent: episode})
+				}
+			}
+		}
+	}
+	clear = append(clear, plan.Clear...)
+
+	clearFailed := 0
+	for _, item := range clear {
+		labels := slices.DeleteFunc(append([]string{}, item.Torrent.Labels...), func(label string) bool {
+			return label == TWO_PHASE_LABEL
+		})
+		if err := setTorrentLabels(client, *item.Torrent.ID, labels); err != nil {
+			fmt.Printf("移除标签失败 ID: %d: %v\n", *item.Torrent.ID, err)
+			clearFailed++
+			continue
+		}
+		record := newHistoryRecord(ACTION_PENDING_CLEAR, item.Group, item.Torrent)
+		record.PrevLabels = item.Torrent.Labels
+		history.Record(record)
+		delete(state.Entries, *item.Torrent.HashString)
+		fmt.Printf("已移除标签 %s ID: %d\n", TWO_PHASE_LABEL, *item.Torrent.ID)
+	}
+	summary.Cleared = len(clear) - clearFailed
+	runNotices.count(ACTION_PENDING_CLEAR, len(clear)-clearFailed, clearFailed)
+	summary.Failed += clearFailed
+
+	// 否决的分集不再是重复分集时去掉记录，以后再次识别为重复时重新添加标签
+	tracked := make(map[string]bool)
+	for _, items := range [][]TwoPhaseItem{plan.Waiting, plan.Vetoed, plan.Label} {
+		for _, item := range items {
+			tracked[*item.Torrent.HashString] = true
+		}
+	}
+	for _, group := range plan.Due {
+		for _, episode := range group.Episodes {
+			tracked[*episode.HashString] = true
+		}
+	}
+	for hash, entry := range state.Entries {
+		if entry.Vetoed && !tracked[hash] {
+			delete(state.Entries, hash)
+		}
+	}
+	state.save()
+
+	runNotices.send()
+	fmt.Printf("\n两阶段暂停完成: 新标记 %d 个, 等待中 %d 个, 暂停 %d 个, 移除标签 %d 个, 否决 %d 个, 失败 %d 个\n",
+		summary.Labeled, summary.Waiting, summary.Paused, summary.Cleared, summary.Vetoed, summary.Failed)
+	return summary
+}
+
+// 设置种子的标签，空列表（而不是 null）才会清除全部标签
+func setTorrentLabels(client *transmissionrpc.Client, id int64, labels []string) error {
+	if labels == nil {
+		labels = []string{}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+	defer cancel()
+	return client.TorrentSet(ctx, transmissionrpc.TorrentSetPayload{
+		IDs:    []int64{id},
+		Labels: labels,
+	})
+}
+
+// 查询各组分集当前是否已停止，查询失败时返回空（不移除任何标签，下次运行再处理）
+func stoppedTorrentIDs(client *transmissionrpc.Client, groups map[string]DuplicateGroup) map[int64]bool {
+	var ids []int64
+	for _, group := range groups {
+		for _, episode := range group.Episodes {
+			ids = append(ids, *episode.ID)
+		}
+	}
+	stopped := make(map[int64]bool)
+	ctx, cancel := context.WithTimeout(context.Background(), timeouts.Query)
+	defer cancel()
+	torrents, err := client.TorrentGet(ctx, []string{"id", "status"}, ids)
+	if err != nil {
+		log.Printf("查询分集状态失败，标签留到下次运行移除: %v", err)
+		return stopped
+	}
+	for i := range torrents {
+		if torrents[i].ID != nil && alreadyStopped(&torrents[i]) {
+			stopped[*torrents[i].ID] = true
+		}
+	}
+	return stopped
+}
+
+// 显示两阶段暂停计划
+func printTwoPhasePlan(w io.Writer, plan TwoPhasePlan, grace time.Duration, now time.Time) {
+	fmt.Fprintf(w, "\n===== 两阶段暂停（标签 %s，宽限期 %s）=====\n", TWO_PHASE_LABEL, grace)
+	printTwoPhaseItems(w, "新识别的重复分集，添加标签", plan.Label, nil)
+	printTwoPhaseItems(w, "等待宽限期结束", plan.Waiting, func(item TwoPhaseItem) string {
+		return fmt.Sprintf("剩余 %s", (grace - now.Sub(item.Since)).Round(time.Minute))
+	})
+	var due []TwoPhaseItem
+	for _, groupName := range sortedGroupNames(plan.Due) {
+		for _, episode := range plan.Due[groupName].Episodes {
+			due = append(due, TwoPhaseItem{Group: groupName, Torrent: episode})
+		}
+	}
+	printTwoPhaseItems(w, "已满宽限期，暂停并移除标签", due, nil)
+	printTwoPhaseItems(w, "标签已被移除（否决），不再暂停", plan.Vetoed, nil)
+	printTwoPhaseItems(w, "已不再是重复分集，移除标签", plan.Clear, nil)
+	if len(plan.Label)+len(plan.Waiting)+len(due)+len(plan.Vetoed)+len(plan.Clear) == 0 {
+		fmt.Fprintln(w, "没有需要标记或暂停的分集")
+	}
+}
+
+func printTwoPhaseItems(w io.Writer, title string, items []TwoPhaseItem, detail func(TwoPhaseItem) string) {
+	if len(items) == 0 {
+		return
+	}
+	sorted := append([]TwoPhaseItem{}, items...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return *sorted[i].Torrent.ID < *sorted[j].Torrent.ID
+	})
+	fmt.Fprintf(w, "%s %d 个:\n", title, len(sorted))
+	for _, item := range sorted {
+		line := fmt.Sprintf("  - ID: %d, %s", *item.Torrent.ID, *item.Torrent.Name)
+		if detail != nil {
+			line += ", " + detail(item)
+		}
+		fmt.Fprintln(w, line)
+	}
+}
+
+// 生成并显示两阶段暂停计划，确认后执行。reader 为nil（守护模式）时不提示，未指定 --yes 时只显示计划；
+// allowPause 为false（守护模式的暂停时段外）时已满宽限期的分集留到下次再暂停
+func runTwoPhase(ctx context.Context, reader *bufio.Reader, client *transmissionrpc.Client, result *ScanResult, opts Options, history *HistoryWriter, throttle *ActionThrottle, allowPause bool) *TwoPhaseSummary {
+	state, err := loadTwoPhaseState(twoPhasePath())
+	if err != nil {
+		log.Printf("读取两阶段暂停记录失败，本次不执行两阶段暂停: %v", err)
+		return nil
+	}
+	now := time.Now()
+	plan := planTwoPhase(result, state, opts, now)
+	if !allowPause && len(plan.Due) > 0 {
+		fmt.Printf("不在暂停时段内，%d 个已满宽限期的分集留到暂停时段内再暂停\n", plan.dueCount())
+		plan.Due = make(map[string]DuplicateGroup)
+	}
+	printTwoPhasePlan(os.Stdout, plan, opts.Grace, now)
+	// 只有等待中且已有记录的分集时没有需要执行的变化
+	changes := len(plan.Label) + plan.dueCount() + len(plan.Vetoed) + len(plan.Clear)
+	for _, item := range plan.Waiting {
+		if entry, ok := state.Entries[*item.Torrent.HashString]; !ok || entry.Vetoed {
+			changes++
+		}
+	}
+	if changes == 0 {
+		return nil
+	}
+	if opts.DryRun {
+		fmt.Println("\n试运行模式，不添加或移除标签，不暂停分集")
+		return nil
+	}
+	if !opts.Yes {
+		if reader == nil {
+			return nil
+		}
+		fmt.Printf("\n是否添加 %d 个标签、暂停 %d 个分集、移除 %d 个标签? (y/n): ", len(plan.Label), plan.dueCount(), len(plan.Clear)+plan.dueCount())
+		answer, _ := reader.ReadString('\n')
+		if strings.ToLower(strings.TrimSpace(answer)) != "y" {
+			fmt.Println("操作已取消")
+			return nil
+		}
+	}
+	summary := applyTwoPhase(ctx, client, plan, state, opts, history, throttle)
+	return &summary
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// 输出大小使用的单位制
+const (
+	UNITS_SI  = "si"  // 按1000进制，显示为 KB、MB、GB、TB，与 Transmission 的界面一致
+	UNITS_IEC = "iec" // 按1024进制，显示为 KiB、MiB、GiB、TiB
+)
+
+// 各单位制的进制和单位名称，从字节开始
+var sizeUnitSystems = map[string]struct {
+	Base  float64
+	Names []string
+}{
+	UNITS_SI:  {1000, []string{"B", "KB", "MB", "GB", "TB"}},
+	UNITS_IEC: {1024, []string{"B", "KiB", "MiB", "GiB", "TiB"}},
+}
+
+// 当前输出大小使用的单位制
+var sizeUnitSystem = UNITS_SI
+
+// 设置输出大小使用的单位制
+func setSizeUnits(system string) {
+	sizeUnitSystem = system
+}
+
+// 解析参数时使用的大小单位及其幂次：KiB、MiB 等始终按1024进制，
+// KB、MB 等和 K、M 等简写按当前单位制（--units）的进制换算，与输出的大小一致
+var sizeUnits = []struct {
+	Suffix string
+	Power  int
+	Binary bool
+}{
+	{"tib", 4, true}, {"gib", 3, true}, {"mib", 2, true}, {"kib", 1, true},
+	{"tb", 4, false}, {"gb", 3, false}, {"mb", 2, false}, {"kb", 1, false},
+	{"t", 4, false}, {"g", 3, false}, {"m", 2, false}, {"k", 1, false},
+	{"b", 0, false},
+}
+
+// 解析带单位的大小，如 200GB、1.5TiB、500M，没有单位时按字节计算。
+// 需在 setSizeUnits 之后调用，200GB 按当前单位制解析后显示时仍是 200.00 GB
+func parseSize(value string) (int64, error) {
+	text := strings.ToLower(strings.TrimSpace(value))
+	multiplier := 1.0
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(text, unit.Suffix) {
+			text = strings.TrimSpace(strings.TrimSuffix(text, unit.Suffix))
+			base := sizeUnitSystems[sizeUnitSystem].Base
+			if unit.Binary {
+				base = sizeUnitSystems[UNITS_IEC].Base
+			}
+			multiplier = math.Pow(base, float64(unit.Power))
+			break
+		}
+	}
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil || number < 0 {
+		return 0, fmt.Errorf("无效的大小: %s（示例: 200GB、1.5TB、500MB）", value)
+	}
+	return int64(math.Round(number * multiplier)), nil
+}
+
+// 按当前单位制显示字节数，自动选择 B 到 TB 中合适的单位，保留两位小数（字节不带小数）。
+// 报告、统计和通知中的大小都使用这里的格式，JSON 中保留原始字节数
+func formatSize(bytes int64) string {
+	return formatSizeIn(sizeUnitSystem, float64(bytes))
+}
+
+// 按指定的单位制显示字节数，负数显示为带负号的大小
+func formatSizeIn(system string, bytes float64) string {
+	units := sizeUnitSystems[system]
+	sign := ""
+	if bytes < 0 {
+		sign, bytes = "-", -bytes
+	}
+	if bytes < units.Base {
+		return fmt.Sprintf("%s%.0f B", sign, bytes)
+	}
+	value, unit := bytes, 0
+	// 按保留两位小数后的值换算，避免出现 1000.00 KB
+	for unit < len(units.Names)-1 && math.Round(value*100)/100 >= units.Base {
+		value /= units.Base
+		unit++
+	}
+	return fmt.Sprintf("%s%.2f %s", sign, value, units.Names[unit])
+}
+
+// 显示带正负号的大小变化，如 +1.20 GB
+func formatSizeDelta(bytes int64) string {
+	if bytes > 0 {
+		return "+" + formatSize(bytes)
+	}
+	return formatSize(bytes)
+}
+
+// 解析百分比，如 10% 或 10，范围 0-100
+func parsePercent(value string) (float64, error) {
+	text := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(value), "%"))
+	number, err := strconv.ParseFloat(text, 64)
+	if err != nil || number < 0 || number > 100 {
+		return 0, fmt.Errorf("无效的百分比: %s（示例: 10%%）", value)
+	}
+	return number, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import "testing"
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		units string
+		value string
+		want  int64
+	}{
+		{UNITS_SI, "200GB", 200_000_000_000},
+		{UNITS_SI, "1.5TB", 1_500_000_000_000},
+		{UNITS_SI, "500M", 500_000_000},
+		{UNITS_SI, "1GiB", 1 << 30},
+		{UNITS_SI, "2 kib", 2048},
+		{UNITS_SI, "1024", 1024},
+		{UNITS_SI, "10b", 10},
+		{UNITS_IEC, "200GB", 200 << 30},
+		{UNITS_IEC, "500M", 500 << 20},
+		{UNITS_IEC, "1GiB", 1 << 30},
+		{UNITS_IEC, "1k", 1024},
+	}
+	defer setSizeUnits(UNITS_SI)
+	for _, tt := range tests {
+		t.Run(tt.units+"/"+tt.value, func(t *testing.T) {
+			setSizeUnits(tt.units)
+			got, err := parseSize(tt.value)
+			if err != nil {
+				t.Fatalf("parseSize(%q) error: %v", tt.value, err)
+			}
+			if got != tt.want {
+				t.Errorf("parseSize(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSizeInvalid(t *testing.T) {
+	for _, value := range []string{"", "GB", "-1GB", "abc", "1.5PB"} {
+		if _, err := parseSize(value); err == nil {
+			t.Errorf("parseSize(%q) 应返回错误", value)
+		}
+	}
+}
+
+// 按参数解析的大小在同一单位制下显示为原来的数值
+func TestParseSizeRoundTrip(t *testing.T) {
+	defer setSizeUnits(UNITS_SI)
+	for units, want := range map[string]string{UNITS_SI: "200.00 GB", UNITS_IEC: "200.00 GiB"} {
+		setSizeUnits(units)
+		size, err := parseSize("200GB")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := formatSize(size); got != want {
+			t.Errorf("--units %s: formatSize(parseSize(200GB)) = %q, want %q", units, got, want)
+		}
+	}
+}
+
+func TestFormatSizeIn(t *testing.T) {
+	tests := []struct {
+		units string
+		bytes float64
+		want  string
+	}{
+		{UNITS_SI, 0, "0 B"},
+		{UNITS_SI, 999, "999 B"},
+		{UNITS_SI, 1000, "1.00 KB"},
+		{UNITS_SI, 999_994, "999.99 KB"},
+		{UNITS_SI, 999_995, "1.00 MB"},
+		{UNITS_SI, 75_000_000_000, "75.00 GB"},
+		{UNITS_SI, 2_500_000_000_000_000, "2500.00 TB"},
+		{UNITS_SI, -1_200_000_000, "-1.20 GB"},
+		{UNITS_IEC, 1023, "1023 B"},
+		{UNITS_IEC, 1024, "1.00 KiB"},
+		{UNITS_IEC, 1<<20 - 1, "1.00 MiB"},
+		{UNITS_IEC, 75 << 30, "75.00 GiB"},
+	}
+	for _, tt := range tests {
+		if got := formatSizeIn(tt.units, tt.bytes); got != tt.want {
+			t.Errorf("formatSizeIn(%s, %.0f) = %q, want %q", tt.units, tt.bytes, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSizeDelta(t *testing.T) {
+	defer setSizeUnits(UNITS_SI)
+	setSizeUnits(UNITS_SI)
+	for bytes, want := range map[int64]string{1_200_000_000: "+1.20 GB", 0: "0 B", -500: "-500 B"} {
+		if got := formatSizeDelta(bytes); got != want {
+			t.Errorf("formatSizeDelta(%d) = %q, want %q", bytes, got, want)
+		}
+	}
+}
+
+func TestParsePercent(t *testing.T) {
+	for value, want := range map[string]float64{"10%": 10, "10": 10, " 2.5 % ": 2.5, "100": 100} {
+		got, err := parsePercent(value)
+		if err != nil || got != want {
+			t.Errorf("parsePercent(%q) = %g, %v, want %g", value, got, err, want)
+		}
+	}
+	for _, value := range []string{"-1", "101", "abc"} {
+		if _, err := parsePercent(value); err == nil {
+			t.Errorf("parsePercent(%q) 应返回错误", value)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 检查 Unix socket 是否存在，不存在或不是socket时返回可操作的错误信息
+func checkUnixSocket(socketPath string) error {
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		return unixSocketError(socketPath, err)
+	}
+	if info.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%s 不是 Unix socket，请检查 --unix-socket 的路径", socketPath)
+	}
+	return nil
+}
+
+// 把socket不存在、没有权限等错误转换为说明解决方法的错误
+func unixSocketError(socketPath string, err error) error {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return fmt.Errorf("Unix socket %s 不存在，请确认Transmission已启动并检查 --unix-socket 的路径: %w", socketPath, err)
+	case errors.Is(err, fs.ErrPermission):
+		return fmt.Errorf("没有权限访问 Unix socket %s，请把当前用户加入socket所属的组或调整socket的权限: %w", socketPath, err)
+	}
+	return err
+}
+
+// 让客户端的HTTP请求通过 Unix socket 连接，HTTP层（认证、会话ID、--https 时的TLS）保持不变，
+// 请求中的服务器地址和端口只用于HTTP的Host头
+func configureUnixSocket(client *transmissionrpc.Client, socketPath string) error {
+	httpClient, err := rpcHTTPClient(client)
+	if err != nil {
+		return err
+	}
+	dialer := &net.Dialer{}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = func(ctx context.Context, _, _ string) (net.Conn, error) {
+		conn, err := dialer.DialContext(ctx, "unix", socketPath)
+		if err != nil {
+			return nil, unixSocketError(socketPath, err)
+		}
+		return conn, nil
+	}
+	httpClient.Transport = transport
+	return nil
+}
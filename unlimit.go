@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 取消合集做种限制并重新开始的操作类型，记录在操作历史中
+const ACTION_UNLIMIT = "unlimit"
+
+// Transmission 的 seedIdleMode：0 使用全局设置，1 使用种子自己的设置，2 不限制
+const (
+	SEED_IDLE_MODE_CUSTOM    int64 = 1
+	SEED_IDLE_MODE_UNLIMITED int64 = 2
+)
+
+// 合集是否因分享率或空闲时间限制被 Transmission 自动停止（已停止且 isFinished），返回原因
+func stoppedBySeedLimit(collection *transmissionrpc.Torrent) (string, bool) {
+	if collection == nil || !alreadyStopped(collection) || collection.IsFinished == nil || !*collection.IsFinished {
+		return "", false
+	}
+	switch {
+	case collection.SeedRatioMode != nil && *collection.SeedRatioMode == transmissionrpc.SeedRatioModeCustom && collection.SeedRatioLimit != nil:
+		return fmt.Sprintf("达到分享率限制 %.2f", *collection.SeedRatioLimit), true
+	case collection.SeedIdleMode != nil && *collection.SeedIdleMode == SEED_IDLE_MODE_CUSTOM:
+		return "达到空闲时间限制", true
+	default:
+		return "达到全局分享率或空闲时间限制", true
+	}
+}
+
+// 报告中显示合集被自动停止的原因
+func describeSeedLimitStop(collection *transmissionrpc.Torrent) string {
+	reason, ok := stoppedBySeedLimit(collection)
+	if !ok {
+		return ""
+	}
+	return fmt.Sprintf("合集已因%s自动停止，处理分集后将没有种子做种；使用 --unlimit-collection 可在处理前取消合集的限制并重新开始", reason)
+}
+
+// 处理分集前取消被自动停止的合集的分享率和空闲时间限制并重新开始，原来的设置记录到操作历史以便撤销
+func unlimitCollections(client *transmissionrpc.Client, duplicateGroups map[string]DuplicateGroup, history *HistoryWriter, dryRun bool) {
+	done := make(map[int64]bool)
+	for _, groupName := range sortedGroupNames(duplicateGroups) {
+		collection := duplicateGroups[groupName].Collection
+		reason, ok := stoppedBySeedLimit(collection)
+		if !ok || collection.ID == nil || done[*collection.ID] {
+			continue
+		}
+		done[*collection.ID] = true
+		if dryRun {
+			fmt.Printf("试运行: 将取消合集的做种限制并重新开始 ID: %d（%s）\n", *collection.ID, reason)
+			continue
+		}
+
+		noRatio := transmissionrpc.SeedRatioModeNoRatio
+		idleUnlimited := SEED_IDLE_MODE_UNLIMITED
+		ctx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+		err := client.TorrentSet(ctx, transmissionrpc.TorrentSetPayload{
+			IDs:           []int64{*collection.ID},
+			SeedRatioMode: &noRatio,
+			SeedIdleMode:  &idleUnlimited,
+		})
+		if err == nil {
+			err = client.TorrentStartIDs(ctx, []int64{*collection.ID})
+		}
+		cancel()
+		if err != nil {
+			fmt.Printf("取消合集的做种限制失败 ID: %d: %v\n", *collection.ID, err)
+			continue
+		}
+
+		record := newHistoryRecord(ACTION_UNLIMIT, groupName, collection)
+		if collection.SeedRatioMode != nil {
+			mode := int64(*collection.SeedRatioMode)
+			record.PrevSeedRatioMode = &mode
+		}
+		record.PrevSeedIdleMode = collection.SeedIdleMode
+		history.Record(record)
+		fmt.Printf("已取消合集的做种限制并重新开始 ID: %d（%s）\n", *collection.ID, reason)
+	}
+}
+
+// 撤销：恢复合集原来的做种限制并重新停止
+func restoreSeedLimits(ctx context.Context, client *transmissionrpc.Client, torrentID int64, record HistoryRecord) error {
+	payload := transmissionrpc.TorrentSetPayload{
+		IDs:          []int64{torrentID},
+		SeedIdleMode: record.PrevSeedIdleMode,
+	}
+	if record.PrevSeedRatioMode != nil {
+		mode := transmissionrpc.SeedRatioMode(*record.PrevSeedRatioMode)
+		payload.SeedRatioMode = &mode
+	}
+	if err := client.TorrentSet(ctx, payload); err != nil {
+		return err
+	}
+	return client.TorrentStopIDs(ctx, []int64{torrentID})
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 默认的已失效种子tracker错误信息（不区分大小写，包含即匹配）
+var defaultUnregisteredPatterns = []string{
+	"unregistered torrent",
+	"torrent not registered",
+	"torrent not found",
+	"torrent is not registered",
+	"infohash not found",
+	"torrent has been deleted",
+	"种子不存在",
+	"种子未注册",
+}
+
+// tracker报告已失效的种子
+type UnregisteredTorrent struct {
+	Torrent *transmissionrpc.Torrent
+	Message string // tracker返回的错误信息
+}
+
+// 种子错误类型：tracker返回错误（Transmission的 error 字段）
+const TORRENT_ERROR_TRACKER = 2
+
+// 检查种子的tracker错误信息和各tracker最近一次汇报结果，匹配时返回该信息。
+// 有任何已汇报的tracker汇报成功时不算失效；否则种子本身的错误为tracker错误且匹配，
+// 或已汇报的tracker全部报告失效时才算失效，避免一个tracker出错就删除仍在其他tracker做种的种子
+func unregisteredMessage(torrent *transmissionrpc.Torrent, patterns []string) string {
+	if torrent == nil {
+		return ""
+	}
+	trackerMessage := ""
+	allUnregistered := true
+	for _, stat := range torrent.TrackerStats {
+		if !stat.HasAnnounced {
+			continue
+		}
+		if stat.LastAnnounceSucceeded {
+			return ""
+		}
+		if !matchesUnregistered(stat.LastAnnounceResult, patterns) {
+			allUnregistered = false
+		} else if trackerMessage == "" {
+			trackerMessage = stat.LastAnnounceResult
+		}
+	}
+	if torrent.Error != nil && *torrent.Error == TORRENT_ERROR_TRACKER && torrent.ErrorString != nil &&
+		matchesUnregistered(*torrent.ErrorString, patterns) {
+		return *torrent.ErrorString
+	}
+	if !allUnregistered {
+		return ""
+	}
+	return trackerMessage
+}
+
+// 错误信息是否包含任一已失效信息（不区分大小写）
+func matchesUnregistered(message string, patterns []string) bool {
+	lower := strings.ToLower(message)
+	for _, pattern := range patterns {
+		if pattern != "" && strings.Contains(lower, strings.ToLower(pattern)) {
+			return true
+		}
+	}
+	return false
+}
+
+// 标记已失效的种子：组内的分集记录在各组中，不属于任何组的种子记录在扫描结果中
+func markUnregistered(result *ScanResult, torrents []transmissionrpc.Torrent, patterns []string) {
+	grouped := make(map[int64]bool)
+	for _, groups := range allGroupMaps(result) {
+		for name, group := range groups {
+			if group.Collection != nil && group.Collection.ID != nil {
+				grouped[*group.Collection.ID] = true
+			}
+			for _, partial := range group.PartialEpisodes {
+				if partial.Episode != nil && partial.Episode.ID != nil {
+					grouped[*partial.Episode.ID] = true
+				}
+			}
+			group.UnregisteredEpisodes = nil
+			for _, episode := range group.Episodes {
+				if episode == nil || episode.ID == nil {
+					continue
+				}
+				grouped[*episode.ID] = true
+				if message := unregisteredMessage(episode, patterns); message != "" {
+					group.UnregisteredEpisodes = append(group.UnregisteredEpisodes, UnregisteredTorrent{Torrent: episode, Message: message})
+				}
+			}
+			groups[name] = group
+		}
+	}
+
+	result.Unregistered = nil
+	for i := range torrents {
+		torrent := &torrents[i]
+		if torrent.ID == nil || grouped[*torrent.ID] {
+			continue
+		}
+		if message := unregisteredMessage(torrent, patterns); message != "" {
+			result.Unregistered = append(result.Unregistered, UnregisteredTorrent{Torrent: torrent, Message: message})
+		}
+	}
+}
+
+// 扫描结果中的全部组
+func allGroupMaps(result *ScanResult) []map[string]DuplicateGroup {
+	return []map[string]DuplicateGroup{
+		result.DuplicateGroups,
+		result.LowConfidenceGroups,
+		result.SameSizeGroups,
+		result.PartialGroups,
+		result.OversizedGroups,
+		result.GatedGroups,
+		result.ActiveGroups,
+		result.HandledGroups,
+		result.BelowMinEpisodesGroups,
+	}
+}
+
+// 全部已失效的种子（组内的分集和不属于任何组的种子），按ID去重
+func unregisteredTargets(result *ScanResult) []UnregisteredTorrent {
+	seen := make(map[int64]bool)
+	var targets []UnregisteredTorrent
+	add := func(items []UnregisteredTorrent) {
+		for _, item := range items {
+			if item.Torrent == nil || item.Torrent.ID == nil || seen[*item.Torrent.ID] {
+				continue
+			}
+			seen[*item.Torrent.ID] = true
+			targets = append(targets, item)
+		}
+	}
+	for _, groups := range allGroupMaps(result) {
+		for _, name := range sortedGroupNames(groups) {
+			add(groups[name].UnregisteredEpisodes)
+		}
+	}
+	add(result.Unregistered)
+	return targets
+}
+
+// 删除已失效的种子及其数据，删除后从需要处理的组中去掉这些分集，返回成功删除的数量。
+// 删除的大小计入 --max-delete-size，超过上限的种子不删除
+func removeUnregistered(ctx context.Context, client *transmissionrpc.Client, result *ScanResult, dryRun bool, throttle *ActionThrottle, ceiling *DeleteCeiling) int {
+	targets := unregisteredTargets(result)
+	if len(targets) == 0 {
+		return 0
+	}
+	if dryRun {
+		fmt.Printf("\n试运行模式，不删除 %d 个已失效种子\n", len(targets))
+		return 0
+	}
+
+	torrents := make([]*transmissionrpc.Torrent, len(targets))
+	for i, target := range targets {
+		torrents[i] = target.Torrent
+	}
+	torrents = ceiling.admit(throttle.take(ctx, "已失效种子", torrents))
+	if len(torrents) == 0 {
+		return 0
+	}
+
+	fmt.Printf("正在删除 %d 个已失效种子及其数据...\n", len(torrents))
+	removed := make(map[int64]bool)
+	attempted := 0
+	for i, torrent := range torrents {
+		if !throttle.wait(ctx) {
+			throttle.deferRest("已失效种子", torrents[i:], DEFERRED_INTERRUPTED)
+			break
+		}
+		attempted++
+		id := *torrent.ID
+		removeCtx, cancel := context.WithTimeout(context.Background(), timeouts.Action)
+		err := client.TorrentRemove(removeCtx, transmissionrpc.TorrentRemovePayload{
+			IDs:             []int64{id},
+			DeleteLocalData: true,
+		})
+		cancel()
+		if err != nil {
+			fmt.Printf("删除已失效种子失败 ID: %d: %v\n", id, err)
+			continue
+		}
+		removed[id] = true
+	}
+
+	// 已删除的分集不再参与后续操作
+	for _, groups := range allGroupMaps(result) {
+		for name, group := range groups {
+			var kept []*transmissionrpc.Torrent
+			for _, episode := range group.Episodes {
+				if episode != nil && episode.ID != nil && removed[*episode.ID] {
+					continue
+				}
+				kept = append(kept, episode)
+			}
+			if len(kept) == 0 {
+				delete(groups, name)
+				continue
+			}
+			group.Episodes = kept
+			groups[name] = group
+		}
+	}
+
+	fmt.Printf("删除完成: 成功删除 %d 个已失效种子, 失败 %d 个\n", len(removed), attempted-len(removed))
+	return len(removed)
+}
+
+// 显示组内已失效的分集
+func printUnregisteredEpisodes(w io.Writer, unregistered []UnregisteredTorrent) {
+	if len(unregistered) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "已失效分集 %d 个(tracker报告种子已失效):\n", len(unregistered))
+	for i, item := range unregistered {
+		printUnregisteredTorrent(w, i+1, item)
+	}
+}
+
+// 显示不属于任何组的已失效种子
+func printUnregisteredTorrents(w io.Writer, unregistered []UnregisteredTorrent) {
+	if len(unregistered) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n===== 已失效种子（%d 个，不属于任何组）=====\n", len(unregistered))
+	for i, item := range unregistered {
+		printUnregisteredTorrent(w, i+1, item)
+	}
+}
+
+// 显示一个已失效的种子
+func printUnregisteredTorrent(w io.Writer, index int, item UnregisteredTorrent) {
+	torrent := item.Torrent
+	if torrent == nil || torrent.ID == nil {
+		return
+	}
+	line := fmt.Sprintf("  %d. ID: %d", index, *torrent.ID)
+	if torrent.Name != nil {
+		line += ", " + *torrent.Name
+	}
+	if torrent.SizeWhenDone != nil {
+		line += fmt.Sprintf(", 大小: %s", formatSize(torrentBytes(torrent)))
+	}
+	fmt.Fprintf(w, "%s, tracker: %s\n", line, item.Message)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 已汇报过的tracker，succeeded 为最近一次汇报是否成功
+func announcedTracker(succeeded bool, result string) *transmissionrpc.TrackerStats {
+	return &transmissionrpc.TrackerStats{HasAnnounced: true, LastAnnounceSucceeded: succeeded, LastAnnounceResult: result}
+}
+
+func TestUnregisteredMessage(t *testing.T) {
+	trackerError := int64(TORRENT_ERROR_TRACKER)
+	localError := int64(3)
+	unregistered := "Unregistered torrent"
+	tests := []struct {
+		name        string
+		errorCode   *int64
+		errorString string
+		stats       []*transmissionrpc.TrackerStats
+		want        string
+	}{
+		{
+			name:  "唯一的tracker报告失效",
+			stats: []*transmissionrpc.TrackerStats{announcedTracker(false, unregistered)},
+			want:  unregistered,
+		},
+		{
+			name:  "多个tracker全部报告失效",
+			stats: []*transmissionrpc.TrackerStats{announcedTracker(false, "torrent not found"), announcedTracker(false, unregistered)},
+			want:  "torrent not found",
+		},
+		{
+			name:  "一个tracker报告失效，另一个汇报成功",
+			stats: []*transmissionrpc.TrackerStats{announcedTracker(false, unregistered), announcedTracker(true, "Success")},
+		},
+		{
+			name:  "一个tracker报告失效，另一个连接超时",
+			stats: []*transmissionrpc.TrackerStats{announcedTracker(false, unregistered), announcedTracker(false, "Connection timed out")},
+		},
+		{
+			name:  "没有汇报过的tracker不参与判断",
+			stats: []*transmissionrpc.TrackerStats{announcedTracker(false, unregistered), {Announce: "udp://backup"}},
+			want:  unregistered,
+		},
+		{
+			name:        "种子本身的tracker错误匹配",
+			errorCode:   &trackerError,
+			errorString: unregistered,
+			stats:       []*transmissionrpc.TrackerStats{announcedTracker(false, unregistered), announcedTracker(false, "Connection timed out")},
+			want:        unregistered,
+		},
+		{
+			name:        "种子本身的tracker错误匹配，但另一个tracker汇报成功",
+			errorCode:   &trackerError,
+			errorString: unregistered,
+			stats:       []*transmissionrpc.TrackerStats{announcedTracker(false, unregistered), announcedTracker(true, "Success")},
+		},
+		{
+			name:        "不是tracker错误的种子错误不参与判断",
+			errorCode:   &localError,
+			errorString: "No data found! torrent not found",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			torrent := testTorrent(1, "Show")
+			torrent.Error = tt.errorCode
+			if tt.errorString != "" {
+				torrent.ErrorString = &tt.errorString
+			}
+			torrent.TrackerStats = tt.stats
+			if got := unregisteredMessage(torrent, defaultUnregisteredPatterns); got != tt.want {
+				t.Errorf("unregisteredMessage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 各种子累计上传量的快照，按hash记录
+type UploadSnapshot map[string]int64
+
+// 上传量快照文件路径
+func uploadSnapshotPath() string {
+	return filepath.Join(stateDir(), "upload-snapshot.json")
+}
+
+// 根据当前种子列表生成快照，已不存在的种子自然不会出现在新快照中
+func newUploadSnapshot(torrents []transmissionrpc.Torrent) UploadSnapshot {
+	snapshot := make(UploadSnapshot)
+	for _, torrent := range torrents {
+		if torrent.HashString != nil && torrent.UploadedEver != nil {
+			snapshot[*torrent.HashString] = *torrent.UploadedEver
+		}
+	}
+	return snapshot
+}
+
+// 计算重复分集相对上一份快照的上传增量，返回增量和有上一轮记录的分集数
+func duplicateUploadDelta(previous UploadSnapshot, duplicateGroups map[string]DuplicateGroup) (int64, int) {
+	var delta int64
+	known := 0
+	for _, group := range duplicateGroups {
+		for _, episode := range group.Episodes {
+			if episode == nil || episode.HashString == nil || episode.UploadedEver == nil {
+				continue
+			}
+			before, ok := previous[*episode.HashString]
+			if !ok {
+				continue
+			}
+			known++
+			// 种子被重新添加时累计上传量会归零，此时不计入
+			if *episode.UploadedEver > before {
+				delta += *episode.UploadedEver - before
+			}
+		}
+	}
+	return delta, known
+}
+
+// 读取上传量快照，文件不存在时返回空快照
+func loadUploadSnapshot(path string) (UploadSnapshot, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return UploadSnapshot{}, nil
+		}
+		return nil, err
+	}
+	snapshot := make(UploadSnapshot)
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// 保存上传量快照
+func saveUploadSnapshot(path string, snapshot UploadSnapshot) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
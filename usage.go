@@ -0,0 +1,244 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// 一组命令行参数，用于帮助信息和补全脚本
+type flagGroup struct {
+	Title string
+	Names []string
+}
+
+// 帮助信息中的参数分组，未列出的参数显示在"其他"中
+var flagGroups = []flagGroup{
+	{"连接", []string{"host", "port", "https", "user", "password", "netrc", "proxy", "unix-socket", "timeout", "timeout-list", "timeout-files", "timeout-action", "parallel"}},
+	{"筛选", []string{"suffix", "collection-suffix", "exclude-status", "shows-file", "name-tag-pattern", "name-map", "deep-scan", "deep-scan-min-percent"}},
+	{"识别", []string{"episode-pattern", "test-pattern", "preset", "strictness", "require-full-containment", "require-complete-collection", "require-parent-match", "extra-file-tolerance", "padding-pattern", "no-rename-fallback", "video-overlap", "skip-size-check", "size-tolerance", "same-size-action", "min-confidence", "max-size-ratio", "allow-cross-quality", "allow-cross-cut", "cut-token", "policy-file", "same-tracker-action", "cross-tracker-action", "keep-active-uploaders", "min-weekly-upload-to-keep", "keep-latest", "min-collection-seeders", "min-episodes", "old-pack-action", "include-extras", "unregistered-message", "pack-duplicates"}},
+	{"操作", []string{"action", "idle-minutes", "bandwidth-group", "bandwidth-group-limit", "yes", "dry-run", "data-root", "link-type", "allow-delete-private", "max-delete-size", "max-tracker-impact", "unlimit-collection", "collection-dir", "move-timeout", "relocate-episodes", "remove-unregistered", "remove-stale-magnets", "remove-missing-data", "max-actions", "action-delay", "pause-budget", "two-phase", "grace", "safe-mode", "rollback-threshold", "daemon", "interval", "skip-unchanged", "trend-retention", "pause-window", "pause-window-tz", "api-listen", "api-token"}},
+	{"输出", []string{"verbose", "format", "units", "stats-only", "top", "list-archive-packs", "benchmark", "reasons-out", "no-stats-wait", "json", "trend-cycles", "discord-webhook", "post-hook", "post-hook-timeout", "diag-bundle", "from-dump", "from-torrents"}},
+	{"计划", []string{"plan-out", "diff", "diff-json", "force", "review-out", "review-in", "export-kept"}},
+}
+
+// 参数的可选值，用于补全
+var flagChoices = map[string][]string{
+	"action":               {ACTION_PAUSE, ACTION_PRIORITY, ACTION_DESELECT, ACTION_LINK, ACTION_IDLE_LIMIT, ACTION_BANDWIDTH_GROUP},
+	"link-type":            {LINK_SYMLINK, LINK_HARDLINK},
+	"same-size-action":     {SAME_SIZE_SKIP, SAME_SIZE_PAUSE},
+	"same-tracker-action":  {ACTION_PAUSE, ACTION_PRIORITY, ACTION_SKIP, CLASS_ACTION_POLICY},
+	"cross-tracker-action": {ACTION_PAUSE, ACTION_PRIORITY, ACTION_SKIP, CLASS_ACTION_POLICY},
+	"old-pack-action":      {OLD_PACK_PAUSE, OLD_PACK_DELETE, OLD_PACK_SKIP},
+	"format":               {FORMAT_TEXT, FORMAT_COMPACT},
+	"units":                {UNITS_SI, UNITS_IEC},
+	"preset":               presetNames(),
+	"strictness":           {"1", "2", "3", "4", "5"},
+	"exclude-status":       torrentStatusChoices(),
+	"relocate-episodes":    {RELOCATE_NONE, RELOCATE_SET, RELOCATE_MOVE},
+}
+
+// 参数值为文件路径的参数，用于补全
+var fileFlags = map[string]bool{
+	"policy-file": true,
+	"unix-socket": true,
+	"name-map":    true,
+	"shows-file":  true,
+	"reasons-out": true,
+	"plan-out":    true,
+	"diff":        true,
+	"diff-json":   true,
+	"review-out":  true,
+	"review-in":   true,
+	"export-kept": true,
+	"diag-bundle": true,
+	"from-dump":   true,
+
+	"from-torrents": true,
+}
+
+// 子命令
+var subcommands = []string{"scan", "apply", "inspect", "undo", "ignore", "ignores", "notes", "trend", "doctor", "completion"}
+
+// 帮助信息中的示例
+var usageExamples = []struct {
+	Comment string
+	Command string
+}{
+	{"定时任务：不交互，直接暂停指定站点的重复分集", "delete-episode --host 127.0.0.1 --suffix 'ADWeb;HHWEB' --yes"},
+	{"试运行原地升级：只打印计划执行的文件系统操作", "delete-episode --action link --data-root /downloads=/mnt/nas/downloads --dry-run --yes"},
+	{"守护模式：每30分钟扫描一次，按tracker策略处理", "delete-episode --daemon --interval 30m --policy-file policy.json --yes"},
+	{"先保存计划，检查后再执行", "delete-episode scan --host 127.0.0.1 --plan-out plan.json && delete-episode apply plan.json --host 127.0.0.1"},
+	{"撤销上一次操作", "delete-episode undo --host 127.0.0.1 --yes"},
+}
+
+// 判断参数是否为布尔参数（不需要值）
+func isBoolFlag(f *flag.Flag) bool {
+	boolFlag, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && boolFlag.IsBoolFlag()
+}
+
+// 按分组顺序排列参数，未分组的参数归入"其他"
+func groupedFlags(fs *flag.FlagSet) []flagGroup {
+	grouped := make(map[string]bool)
+	for _, group := range flagGroups {
+		for _, name := range group.Names {
+			grouped[name] = true
+		}
+	}
+	var others []string
+	fs.VisitAll(func(f *flag.Flag) {
+		if !grouped[f.Name] {
+			others = append(others, f.Name)
+		}
+	})
+
+	groups := append([]flagGroup{}, flagGroups...)
+	if len(others) > 0 {
+		groups = append(groups, flagGroup{"其他", others})
+	}
+	return groups
+}
+
+// 打印分组的帮助信息和示例
+func printUsage(fs *flag.FlagSet) {
+	out := fs.Output()
+	fmt.Fprintln(out, "删除重复种子工具：查找Transmission中同时存在合集和分集的种子并处理分集")
+	fmt.Fprintln(out)
+	fmt.Fprintln(out, "用法:")
+	fmt.Fprintln(out, "  delete-episode [参数]                      扫描并处理重复分集（未指定的参数会交互提示）")
+	fmt.Fprintln(out, "  delete-episode scan [参数]                 只扫描并显示报告，可保存计划或与已保存的计划比较")
+	fmt.Fprintln(out, "  delete-episode apply <计划文件> [参数]     重新扫描确认后执行已保存的计划")
+	fmt.Fprintln(out, "  delete-episode inspect <ID|hash> [参数]    显示种子从RPC获取的字段、文件列表和识别的剧集标识")
+	fmt.Fprintln(out, "  delete-episode undo [参数]                 撤销上一次操作")
+	fmt.Fprintln(out, "  delete-episode ignore <组名> [参数]        把指定的组标记为误判，以后不再显示")
+	fmt.Fprintln(out, "  delete-episode ignores list|remove         列出或删除误判记录")
+	fmt.Fprintln(out, "  delete-episode notes list|set|remove       列出、设置或删除组的备注")
+	fmt.Fprintln(out, "  delete-episode trend [参数]                显示守护模式最近几轮的组、分集和可释放空间的变化")
+	fmt.Fprintln(out, "  delete-episode doctor [计划文件] [参数]    检查状态文件，迁移旧格式，隔离损坏的文件")
+	fmt.Fprintln(out, "  delete-episode completion bash|zsh|fish    输出shell补全脚本")
+
+	for _, group := range groupedFlags(fs) {
+		fmt.Fprintf(out, "\n%s:\n", group.Title)
+		for _, name := range group.Names {
+			if f := fs.Lookup(name); f != nil {
+				printFlagUsage(out, f)
+			}
+		}
+	}
+
+	fmt.Fprintln(out, "\n示例:")
+	for _, example := range usageExamples {
+		fmt.Fprintf(out, "  # %s\n  %s\n\n", example.Comment, example.Command)
+	}
+}
+
+// 打印单个参数的用法，如 "--host string  Transmission服务器地址 (默认 127.0.0.1)"
+func printFlagUsage(out io.Writer, f *flag.Flag) {
+	valueName, usage := flag.UnquoteUsage(f)
+	line := "  --" + f.Name
+	if valueName != "" {
+		line += " " + valueName
+	}
+	fmt.Fprintf(out, "%-32s %s", line, usage)
+	if f.DefValue != "" && f.DefValue != "false" && f.DefValue != "0" {
+		fmt.Fprintf(out, " (默认 %s)", f.DefValue)
+	}
+	fmt.Fprintln(out)
+}
+
+// 输出指定shell的补全脚本
+func runCompletion(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "用法: delete-episode completion bash|zsh|fish")
+		os.Exit(2)
+	}
+
+	fs := newFlagSet(&Options{}, &rawFlags{})
+	switch args[0] {
+	case "bash":
+		writeBashCompletion(os.Stdout, fs)
+	case "zsh":
+		writeZshCompletion(os.Stdout, fs)
+	case "fish":
+		writeFishCompletion(os.Stdout, fs)
+	default:
+		fmt.Fprintf(os.Stderr, "不支持的shell: %s（可选: bash, zsh, fish）\n", args[0])
+		os.Exit(2)
+	}
+}
+
+// bash补全脚本
+func writeBashCompletion(out io.Writer, fs *flag.FlagSet) {
+	var flagNames []string
+	fs.VisitAll(func(f *flag.Flag) {
+		flagNames = append(flagNames, "--"+f.Name)
+	})
+
+	fmt.Fprintln(out, "# delete-episode bash 补全，使用方法: source <(delete-episode completion bash)")
+	fmt.Fprintln(out, "_delete_episode() {")
+	fmt.Fprintln(out, "    local cur=\"${COMP_WORDS[COMP_CWORD]}\"")
+	fmt.Fprintln(out, "    local prev=\"${COMP_WORDS[COMP_CWORD-1]}\"")
+	fmt.Fprintln(out, "    case \"$prev\" in")
+	fs.VisitAll(func(f *flag.Flag) {
+		if choices, ok := flagChoices[f.Name]; ok {
+			fmt.Fprintf(out, "        --%s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")); return ;;\n", f.Name, strings.Join(choices, " "))
+		} else if fileFlags[f.Name] {
+			fmt.Fprintf(out, "        --%s) COMPREPLY=($(compgen -f -- \"$cur\")); return ;;\n", f.Name)
+		}
+	})
+	fmt.Fprintln(out, "        completion) COMPREPLY=($(compgen -W \"bash zsh fish\" -- \"$cur\")); return ;;")
+	fmt.Fprintln(out, "        ignores) COMPREPLY=($(compgen -W \"list remove\" -- \"$cur\")); return ;;")
+	fmt.Fprintln(out, "        notes) COMPREPLY=($(compgen -W \"list set remove\" -- \"$cur\")); return ;;")
+	fmt.Fprintln(out, "    esac")
+	fmt.Fprintln(out, "    if [[ $COMP_CWORD -eq 1 && \"$cur\" != -* ]]; then")
+	fmt.Fprintf(out, "        COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(subcommands, " "))
+	fmt.Fprintln(out, "        return")
+	fmt.Fprintln(out, "    fi")
+	fmt.Fprintf(out, "    COMPREPLY=($(compgen -W \"%s\" -- \"$cur\"))\n", strings.Join(flagNames, " "))
+	fmt.Fprintln(out, "}")
+	fmt.Fprintln(out, "complete -F _delete_episode delete-episode")
+}
+
+// zsh补全脚本
+func writeZshCompletion(out io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(out, "#compdef delete-episode")
+	fmt.Fprintln(out, "# delete-episode zsh 补全，使用方法: delete-episode completion zsh > \"${fpath[1]}/_delete-episode\"")
+	fmt.Fprintln(out, "_arguments \\")
+	fs.VisitAll(func(f *flag.Flag) {
+		description := strings.NewReplacer("'", "'\\''", "[", "\\[", "]", "\\]").Replace(f.Usage)
+		spec := fmt.Sprintf("--%s[%s]", f.Name, description)
+		if choices, ok := flagChoices[f.Name]; ok {
+			spec += fmt.Sprintf(":%s:(%s)", f.Name, strings.Join(choices, " "))
+		} else if fileFlags[f.Name] {
+			spec += ":file:_files"
+		} else if !isBoolFlag(f) {
+			spec += ":" + f.Name + ":"
+		}
+		fmt.Fprintf(out, "    '%s' \\\n", spec)
+	})
+	fmt.Fprintf(out, "    '1::command:(%s)'\n", strings.Join(subcommands, " "))
+}
+
+// fish补全脚本
+func writeFishCompletion(out io.Writer, fs *flag.FlagSet) {
+	fmt.Fprintln(out, "# delete-episode fish 补全，使用方法: delete-episode completion fish | source")
+	fmt.Fprintf(out, "complete -c delete-episode -n '__fish_use_subcommand' -f -a '%s'\n", strings.Join(subcommands, " "))
+	fmt.Fprintln(out, "complete -c delete-episode -n '__fish_seen_subcommand_from completion' -f -a 'bash zsh fish'")
+	fmt.Fprintln(out, "complete -c delete-episode -n '__fish_seen_subcommand_from ignores' -f -a 'list remove'")
+	fmt.Fprintln(out, "complete -c delete-episode -n '__fish_seen_subcommand_from notes' -f -a 'list set remove'")
+	fs.VisitAll(func(f *flag.Flag) {
+		description := strings.ReplaceAll(f.Usage, "'", "\\'")
+		line := fmt.Sprintf("complete -c delete-episode -l %s -d '%s'", f.Name, description)
+		if choices, ok := flagChoices[f.Name]; ok {
+			line += fmt.Sprintf(" -x -a '%s'", strings.Join(choices, " "))
+		} else if fileFlags[f.Name] {
+			line += " -r -F"
+		} else if !isBoolFlag(f) {
+			line += " -x"
+		}
+		fmt.Fprintln(out, line)
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+// 版本标识：名称相近、大小相近但内容可能不同的发布版本
+var variantTokenRegexes = []struct {
+	Token string
+	Regex *regexp.Regexp
+}{
+	{"extended", regexp.MustCompile(`(?i)\bextended\b`)},
+	{"dual-audio", regexp.MustCompile(`(?i)\bdual(?:[ ._\-]?audio)?\b`)},
+	{"hybrid", regexp.MustCompile(`(?i)\bhybrid\b`)},
+	{"remux", regexp.MustCompile(`(?i)\bremux\b`)},
+	{"web-dl", regexp.MustCompile(`(?i)\bweb[ ._\-]?dl\b`)},
+}
+
+// 从名称中提取版本标识
+func variantTokens(name string) map[string]bool {
+	tokens := make(map[string]bool)
+	for _, variant := range variantTokenRegexes {
+		if variant.Regex.MatchString(name) {
+			tokens[variant.Token] = true
+		}
+	}
+	return tokens
+}
+
+// 只出现在其中一方名称中的版本标识，按名称排序
+func variantDifference(collectionName, episodeName string) []string {
+	collectionTokens, episodeTokens := variantTokens(collectionName), variantTokens(episodeName)
+	var difference []string
+	for token := range collectionTokens {
+		if !episodeTokens[token] {
+			difference = append(difference, token)
+		}
+	}
+	for token := range episodeTokens {
+		if !collectionTokens[token] {
+			difference = append(difference, token)
+		}
+	}
+	sort.Strings(difference)
+	return difference
+}
+
+// 分集的每个内容文件在合集中都有同名且大小完全相同的文件，合集中有多个同名文件时与其中大小最接近的比较
+func exactSizeMatch(collectionFiles, episodeFiles []*transmissionrpc.TorrentFile) bool {
+	index := newFileNameIndex(contentFiles(collectionFiles))
+	for _, file := range contentFiles(episodeFiles) {
+		best := index.bestBySize(getFileName(file.Name), file.Length)
+		if best == nil || best.Length != file.Length {
+			return false
+		}
+	}
+	return true
+}
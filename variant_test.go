@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hekmon/transmissionrpc/v2"
+)
+
+func TestVariantDifference(t *testing.T) {
+	tests := []struct {
+		collection, episode string
+		want                []string
+	}{
+		{"Show.S01.Extended.1080p.BluRay", "Show.S01E01.1080p.BluRay", []string{"extended"}},
+		{"Show.S01.1080p.WEB-DL", "Show.S01E01.1080p.WEB.DL.DUAL", []string{"dual-audio"}},
+		{"Show.S01.REMUX", "Show.S01E01.Extended.Hybrid", []string{"extended", "hybrid", "remux"}},
+		{"Show.S01.Extended.Cut", "Show.S01E01.EXTENDED", nil},
+		{"Show.S01.Extendedition", "Show.S01E01", nil},
+	}
+	for _, tt := range tests {
+		if got := variantDifference(tt.collection, tt.episode); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("variantDifference(%q, %q) = %v, want %v", tt.collection, tt.episode, got, tt.want)
+		}
+	}
+}
+
+// Extended 合集与普通版分集：文件大小完全相同时仍按普通的组处理，否则需人工确认
+func TestExtendedPackEvidence(t *testing.T) {
+	pack := []testFile{
+		{"Show.S01.Extended.1080p/Show.S01E01.1080p.mkv", 1_000_000},
+		{"Show.S01.Extended.1080p/Show.S01E02.1080p.mkv", 1_000_000},
+	}
+	tests := []struct {
+		name     string
+		size     int64
+		mismatch bool
+		describe string
+	}{
+		{"文件大小完全相同", 1_000_000, false, "版本标识不同: extended, 文件大小完全相同"},
+		{"大小相差 1 字节", 999_999, true, "版本标识不同: extended, 版本差异，需人工确认"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			collection := testTorrent(1, "Show.S01.Extended.1080p", pack...)
+			episodeFiles := []testFile{{"Show.S01E01.1080p/Show.S01E01.1080p.mkv", tt.size}}
+			episode := testTorrent(2, "Show.S01E01.1080p", episodeFiles...)
+			evidence := collectGroupEvidence(collection, torrentFiles(pack...),
+				[]*transmissionrpc.Torrent{episode}, [][]*transmissionrpc.TorrentFile{torrentFiles(episodeFiles...)})
+			if !reflect.DeepEqual(evidence.VariantTokens, []string{"extended"}) {
+				t.Errorf("版本标识 %v，应为 [extended]", evidence.VariantTokens)
+			}
+			if evidence.VariantMismatch != tt.mismatch {
+				t.Errorf("VariantMismatch = %v, want %v", evidence.VariantMismatch, tt.mismatch)
+			}
+			if got := evidence.describe(); !strings.Contains(got, tt.describe) {
+				t.Errorf("证据说明 %q 中没有 %q", got, tt.describe)
+			}
+		})
+	}
+}
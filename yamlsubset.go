@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// 审阅文件使用的YAML子集：块格式的映射和列表、带引号或不带引号的标量、# 注释。
+// 不支持锚点、多行字符串和行内映射，足够读取程序写出并由用户修改过的审阅文件
+
+// 一行有效内容
+type yamlLine struct {
+	number int // 原文件中的行号
+	indent int
+	text   string
+}
+
+// 去掉不在引号中的 # 注释
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote == '"' && c == '\\':
+			i++ // 跳过被转义的字符
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// 解析YAML子集，返回由 map[string]any、[]any 和标量组成的值
+func parseYAMLSubset(data string) (any, error) {
+	var lines []yamlLine
+	for i, raw := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		text := strings.TrimRight(stripYAMLComment(raw), " \t")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("第 %d 行: 不能使用制表符缩进", i+1)
+		}
+		lines = append(lines, yamlLine{number: i + 1, indent: len(text) - len(trimmed), text: trimmed})
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	value, next, err := parseYAMLBlock(lines, 0, lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if next < len(lines) {
+		return nil, fmt.Errorf("第 %d 行: 缩进不正确", lines[next].number)
+	}
+	return value, nil
+}
+
+// 解析从第 i 行开始、缩进为 indent 的映射或列表，返回值和下一行的位置
+func parseYAMLBlock(lines []yamlLine, i, indent int) (any, int, error) {
+	if lines[i].text == "-" || strings.HasPrefix(lines[i].text, "- ") {
+		return parseYAMLSequence(lines, i, indent)
+	}
+	return parseYAMLMapping(lines, i, indent)
+}
+
+func parseYAMLSequence(lines []yamlLine, i, indent int) (any, int, error) {
+	items := []any{}
+	for i < len(lines) && lines[i].indent == indent && (lines[i].text == "-" || strings.HasPrefix(lines[i].text, "- ")) {
+		rest := strings.TrimLeft(strings.TrimPrefix(lines[i].text, "-"), " ")
+		if rest == "" {
+			// 列表项的内容在下一行
+			if i+1 >= len(lines) || lines[i+1].indent <= indent {
+				items = append(items, nil)
+				i++
+				continue
+			}
+			value, next, err := parseYAMLBlock(lines, i+1, lines[i+1].indent)
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, value)
+			i = next
+			continue
+		}
+		if _, _, isPair := splitYAMLPair(rest); !isPair {
+			scalar, err := parseYAMLScalar(rest, lines[i].number)
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, scalar)
+			i++
+			continue
+		}
+		// "- key: value" 开始的映射，后续的键与 key 对齐
+		itemIndent := indent + len(lines[i].text) - len(rest)
+		lines[i] = yamlLine{number: lines[i].number, indent: itemIndent, text: rest}
+		value, next, err := parseYAMLMapping(lines, i, itemIndent)
+		if err != nil {
+			return nil, 0, err
+		}
+		items = append(items, value)
+		i = next
+	}
+	return items, i, nil
+}
+
+func parseYAMLMapping(lines []yamlLine, i, indent int) (any, int, error) {
+	mapping := make(map[string]any)
+	for i < len(lines) && lines[i].indent == indent {
+		line := lines[i]
+		key, value, isPair := splitYAMLPair(line.text)
+		if !isPair {
+			return nil, 0, fmt.Errorf("第 %d 行: 应为 \"键: 值\"", line.number)
+		}
+		if _, exists := mapping[key]; exists {
+			return nil, 0, fmt.Errorf("第 %d 行: 重复的键 %s", line.number, key)
+		}
+		if value != "" {
+			scalar, err := parseYAMLScalar(value, line.number)
+			if err != nil {
+				return nil, 0, err
+			}
+			mapping[key] = scalar
+			i++
+			continue
+		}
+		// 值在后续的行中：缩进更深的块，或与键对齐的列表
+		i++
+		if i < len(lines) && (lines[i].indent > indent || (lines[i].indent == indent && strings.HasPrefix(lines[i].text, "-"))) {
+			nested, next, err := parseYAMLBlock(lines, i, lines[i].indent)
+			if err != nil {
+				return nil, 0, err
+			}
+			mapping[key] = nested
+			i = next
+			continue
+		}
+		mapping[key] = nil
+	}
+	if i < len(lines) && lines[i].indent > indent {
+		return nil, 0, fmt.Errorf("第 %d 行: 缩进不正确", lines[i].number)
+	}
+	return mapping, i, nil
+}
+
+// 拆分 "键: 值"，键可以带引号
+func splitYAMLPair(text string) (string, string, bool) {
+	if strings.HasPrefix(text, "\"") || strings.HasPrefix(text, "'") {
+		end := strings.Index(text[1:], text[:1])
+		if end < 0 {
+			return "", "", false
+		}
+		key, rest := text[1:end+1], text[end+2:]
+		if !strings.HasPrefix(rest, ":") {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(rest[1:]), true
+	}
+	index := strings.Index(text, ": ")
+	if index < 0 {
+		if strings.HasSuffix(text, ":") {
+			return text[:len(text)-1], "", true
+		}
+		return "", "", false
+	}
+	return text[:index], strings.TrimSpace(text[index+2:]), true
+}
+
+// 解析标量：带引号的字符串、true/false、null、整数和空列表，其余作为字符串
+func parseYAMLScalar(value string, lineNumber int) (any, error) {
+	switch {
+	case strings.HasPrefix(value, "\""):
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("第 %d 行: 无效的字符串 %s", lineNumber, value)
+		}
+		return unquoted, nil
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return nil, fmt.Errorf("第 %d 行: 无效的字符串 %s", lineNumber, value)
+		}
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	case value == "[]":
+		return []any{}, nil
+	case value == "~" || value == "null":
+		return nil, nil
+	case value == "true" || value == "false":
+		return value == "true", nil
+	}
+	if number, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return number, nil
+	}
+	return value, nil
+}